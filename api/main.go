@@ -7,12 +7,23 @@ package main
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"customer-service/internal/abuse"
+	"customer-service/internal/audit"
+	"customer-service/internal/auth"
+	"customer-service/internal/graphqlhandler"
+	"customer-service/internal/grpchandler"
 	"customer-service/internal/handler"
+	"customer-service/internal/mailer"
 	"customer-service/internal/repository"
 	"customer-service/internal/usecase"
+	"customer-service/pkg/fieldcrypt"
+	"customer-service/pkg/ratelimit"
 	"fmt"
 	"log"
+	"net"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -23,6 +34,7 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -30,6 +42,90 @@ func main() {
 	mongoURI := getEnv("MONGODB_URI", "mongodb://localhost:27017")
 	dbName := getEnv("MONGODB_DATABASE", "customer_db")
 	port := getEnv("PORT", "8080")
+	grpcPort := getEnv("GRPC_PORT", "9090")
+	idempotencyTTL, err := time.ParseDuration(getEnv("IDEMPOTENCY_TTL", "24h"))
+	if err != nil {
+		log.Fatalf("Invalid IDEMPOTENCY_TTL: %v", err)
+	}
+	var v1Sunset time.Time
+	if s := os.Getenv("API_V1_SUNSET"); s != "" {
+		if v1Sunset, err = time.Parse("2006-01-02", s); err != nil {
+			log.Fatalf("Invalid API_V1_SUNSET: %v", err)
+		}
+	}
+
+	// Requests are limited per client only when a limit is configured. HTTP
+	// and gRPC calls spend from the same buckets
+	var rateLimit gin.HandlerFunc
+	var grpcRateLimit grpc.UnaryServerInterceptor
+	var clientRateLimit gin.HandlerFunc
+	var grpcClientRateLimit grpc.UnaryServerInterceptor
+	if defaultLimit, cpfLimit := os.Getenv("RATE_LIMIT"), os.Getenv("RATE_LIMIT_CPF"); defaultLimit != "" || cpfLimit != "" {
+		var limits handler.RateLimits
+		if defaultLimit != "" {
+			if limits.Default, err = ratelimit.ParseLimit(defaultLimit); err != nil {
+				log.Fatalf("Invalid RATE_LIMIT: %v", err)
+			}
+		}
+		if cpfLimit != "" {
+			if limits.CPFLookup, err = ratelimit.ParseLimit(cpfLimit); err != nil {
+				log.Fatalf("Invalid RATE_LIMIT_CPF: %v", err)
+			}
+		}
+		store := ratelimit.NewMemoryStore()
+		clientRateLimit = handler.ClientRateLimit(store, limits.Default)
+		rateLimit = handler.RateLimit(store, limits)
+		grpcClientRateLimit = grpchandler.ClientRateLimit(store, limits.Default)
+		grpcRateLimit = grpchandler.RateLimit(store, limits.Default, limits.CPFLookup)
+	}
+
+	// Clients enumerating CPFs are blocked from CPF lookups when enabled
+	var enumeration gin.HandlerFunc
+	var grpcEnumeration grpc.UnaryServerInterceptor
+	if os.Getenv("ENUMERATION_DETECTION") == "true" {
+		detector, allowlist := newEnumerationDetector()
+		enumeration = handler.EnumerationGuard(detector, allowlist)
+		grpcEnumeration = grpchandler.EnumerationGuard(detector, allowlist)
+	}
+
+	// CPF and email are stored encrypted
+	encryptor := newFieldEncryptor()
+
+	// Kiosk sessions get tokens signed by the service itself
+	var tokenIssuer *auth.TokenIssuer
+	var signingKeys *auth.KeyRing
+	if issuer := os.Getenv("AUTH_TOKEN_ISSUER"); issuer != "" {
+		tokenIssuer, signingKeys = newTokenIssuer(issuer)
+	}
+
+	// Callers are identified by their own tokens or by the gateway in front of
+	// the service. Without either the API stays open, as before
+	// authentication existed
+	var authentication gin.HandlerFunc
+	var verifier auth.Verifier
+	trustedGateway := os.Getenv("AUTH_TRUSTED_GATEWAY") == "true"
+	if source := os.Getenv("AUTH_JWKS"); source != "" {
+		issuer, audience := os.Getenv("AUTH_ISSUER"), os.Getenv("AUTH_AUDIENCE")
+		if issuer == "" || audience == "" {
+			log.Fatal("AUTH_ISSUER and AUTH_AUDIENCE are required with AUTH_JWKS")
+		}
+		jwks, err := auth.LoadJWKS(context.Background(), source)
+		if err != nil {
+			log.Fatalf("Failed to load JWKS: %v", err)
+		}
+		verifier = auth.NewTokenVerifier(jwks, issuer, audience)
+		if tokenIssuer != nil {
+			verifier = auth.AnyVerifier(tokenIssuer.Verifier(), verifier)
+		}
+		authentication = handler.Authentication(verifier)
+	} else if trustedGateway {
+		authentication = handler.GatewayAuthentication()
+	} else if tokenIssuer != nil {
+		// Only authenticated kiosks may exchange a CPF for a customer token
+		log.Fatal("AUTH_TOKEN_ISSUER requires AUTH_JWKS or AUTH_TRUSTED_GATEWAY")
+	} else {
+		log.Println("AUTH_JWKS not set: customer endpoints are unauthenticated")
+	}
 
 	// Connect to MongoDB
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -57,28 +153,86 @@ func main() {
 
 	// Check if running seed command
 	if len(os.Args) > 1 && os.Args[1] == "seed" {
-		if err := runSeed(db); err != nil {
+		if err := runSeed(db, encryptor); err != nil {
 			log.Fatalf("Seed failed: %v", err)
 		}
 		log.Println("Seed completed successfully")
 		return
 	}
 
+	// Encrypt customers stored in plaintext or with their data key inline
+	if len(os.Args) > 1 && os.Args[1] == "encrypt-pii" {
+		migrated, err := repository.NewMongoDBCustomerRepository(db, encryptor).MigrateEncryption(context.Background())
+		if err != nil {
+			log.Fatalf("Encryption failed after %d customers: %v", migrated, err)
+		}
+		log.Printf("Encrypted %d customers", migrated)
+		return
+	}
+
 	// Initialize repository
-	customerRepo := repository.NewMongoDBCustomerRepository(db)
+	customerRepo := repository.NewMongoDBCustomerRepository(db, encryptor)
+	if err := customerRepo.EnsureSchema(context.Background()); err != nil {
+		log.Fatalf("Customers are not ready to be served: %v", err)
+	}
+	idempotencyRepo := repository.NewMongoDBIdempotencyRepository(db, idempotencyTTL)
+	if err := idempotencyRepo.EnsureSchema(context.Background()); err != nil {
+		log.Fatalf("Idempotency keys are not ready to be stored: %v", err)
+	}
+	apiKeyRepo := repository.NewMongoDBAPIKeyRepository(db)
+	piiAccessRepo := repository.NewMongoDBPIIAccessRepository(db)
+	accessLog := audit.NewAccessLog(piiAccessRepo)
 
 	// Initialize use cases
-	createUC := usecase.NewCreateCustomerUseCase(customerRepo)
-	getByCPFUC := usecase.NewGetCustomerByCPFUseCase(customerRepo)
-	updateUC := usecase.NewUpdateCustomerUseCase(customerRepo)
+	createUC := usecase.NewCreateCustomerUseCase(customerRepo, accessLog)
+	getByCPFUC := usecase.NewGetCustomerByCPFUseCase(customerRepo, accessLog)
+	updateUC := usecase.NewUpdateCustomerUseCase(customerRepo, newMailer(), accessLog)
 	deleteUC := usecase.NewDeleteCustomerUseCase(customerRepo)
+	getByIDUC := usecase.NewGetCustomerByIDUseCase(customerRepo, accessLog)
+	batchGetUC := usecase.NewBatchGetCustomersUseCase(customerRepo, accessLog)
+	validateUC := usecase.NewValidateCustomerUseCase(customerRepo)
+	confirmEmailUC := usecase.NewConfirmEmailUseCase(customerRepo, accessLog)
+	correctCPFUC := usecase.NewCorrectCPFUseCase(customerRepo, accessLog)
+	getByCodeUC := usecase.NewGetCustomerByCodeUseCase(customerRepo, accessLog)
+	createAPIKeyUC := usecase.NewCreateAPIKeyUseCase(apiKeyRepo)
+	listAPIKeysUC := usecase.NewListAPIKeysUseCase(apiKeyRepo)
+	revokeAPIKeyUC := usecase.NewRevokeAPIKeyUseCase(apiKeyRepo)
+	authenticateAPIKeyUC := usecase.NewAuthenticateAPIKeyUseCase(apiKeyRepo)
+	listPIIAccessUC := usecase.NewListPIIAccessUseCase(piiAccessRepo)
 
 	// Initialize handler
 	customerHandler := handler.NewCustomerHandler(createUC, getByCPFUC, updateUC, deleteUC)
+	meHandler := handler.NewMeHandler(getByIDUC, updateUC, deleteUC)
+	apiKeyHandler := handler.NewAPIKeyHandler(createAPIKeyUC, listAPIKeysUC, revokeAPIKeyUC)
+	var identifyHandler *handler.IdentifyHandler
+	if tokenIssuer != nil {
+		identifyHandler = handler.NewIdentifyHandler(usecase.NewIdentifyCustomerUseCase(getByCPFUC, tokenIssuer), signingKeys)
+	}
+
+	// Batch jobs and partners send an X-API-Key instead of a bearer token
+	authentication = handler.APIKeyAuthentication(authenticateAPIKeyUC, authentication)
+
+	graphqlHandler, err := graphqlhandler.NewHandler(createUC, updateUC, deleteUC, batchGetUC)
+	if err != nil {
+		log.Fatalf("Failed to build GraphQL schema: %v", err)
+	}
 
 	// Setup Gin router
 	router := gin.Default()
 
+	// Client IPs, which rate limits fall back to, are only read from
+	// X-Forwarded-For when the request comes through a trusted proxy
+	var trustedProxies []string
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		trustedProxies = strings.Split(proxies, ",")
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
+	// Reads of unmasked CPFs and emails are logged with the declared purpose
+	router.Use(handler.AccessPurpose())
+
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -88,7 +242,47 @@ func main() {
 	})
 
 	// Setup routes
-	handler.SetupRoutes(router, customerHandler)
+	codeHandler := handler.NewCodeHandler(getByCodeUC)
+	routeConfig := handler.RouteConfig{
+		ClientRateLimit: clientRateLimit,
+		Authentication:  authentication,
+		Authorization:   handler.Authorization(),
+		RateLimit:       rateLimit,
+		Enumeration:     enumeration,
+		Idempotency:     handler.Idempotency(idempotencyRepo),
+		V1Deprecation:   handler.Deprecation(v1Sunset, "/v2/customers"),
+		Lookup:          handler.NewLookupHandler(batchGetUC).LookupCustomers,
+		Validate:        handler.NewValidateHandler(validateUC).ValidateCustomer,
+		ConfirmEmail:    handler.NewEmailHandler(confirmEmailUC).ConfirmEmail,
+		CorrectCPF:      handler.NewAdminHandler(correctCPFUC).CorrectCPF,
+		GetByCode:       codeHandler.GetCustomerByCode,
+		GetByCodeV2:     codeHandler.GetCustomerByCodeV2,
+		GetMe:           meHandler.GetMe,
+		UpdateMe:        meHandler.UpdateMe,
+		DeleteMe:        meHandler.DeleteMe,
+		CreateAPIKey:    apiKeyHandler.CreateAPIKey,
+		ListAPIKeys:     apiKeyHandler.ListAPIKeys,
+		RevokeAPIKey:    apiKeyHandler.RevokeAPIKey,
+		ListPIIAccess:   handler.NewPIIAccessHandler(listPIIAccessUC).ListPIIAccess,
+	}
+	if identifyHandler != nil {
+		routeConfig.Identify = identifyHandler.Identify
+		routeConfig.JWKS = identifyHandler.JWKS
+	}
+	handler.SetupRoutes(router, customerHandler, routeConfig)
+	var graphqlChain []gin.HandlerFunc
+	if clientRateLimit != nil {
+		graphqlChain = append(graphqlChain, clientRateLimit)
+	}
+	graphqlChain = append(graphqlChain, authentication)
+	if rateLimit != nil {
+		graphqlChain = append(graphqlChain, rateLimit)
+	}
+	graphqlChain = append(graphqlChain, routeConfig.Authorization)
+	if enumeration != nil {
+		graphqlChain = append(graphqlChain, enumeration)
+	}
+	router.POST("/graphql", append(graphqlChain, graphqlHandler.Handle)...)
 
 	// Configure Swagger defaults from environment (can be overridden per-request)
 	docs.SwaggerInfo.BasePath = getEnv("SWAGGER_BASEPATH", "/")
@@ -124,6 +318,33 @@ func main() {
 		ginSwagger.WrapHandler(swaggerFiles.Handler)(c)
 	})
 
+	// Start gRPC server on its own port
+	// gRPC callers are identified the same way as HTTP ones, and the server
+	// is just as open when authentication is turned off
+	grpcConfig := grpchandler.ServerConfig{
+		ClientRateLimit: grpcClientRateLimit,
+		Authentication:  grpchandler.Authentication(verifier, authenticateAPIKeyUC),
+		RateLimit:       grpcRateLimit,
+		Enumeration:     grpcEnumeration,
+	}
+	if verifier == nil && trustedGateway {
+		grpcConfig.Authentication = grpchandler.GatewayAuthentication(authenticateAPIKeyUC)
+	} else if verifier == nil {
+		grpcConfig.AllowUnauthenticated = true
+	}
+	grpcServer := grpchandler.NewServer(grpchandler.NewCustomerServer(createUC, getByCPFUC, getByIDUC, updateUC, deleteUC, batchGetUC), grpcConfig)
+	grpcListener, err := net.Listen("tcp", fmt.Sprintf(":%s", grpcPort))
+	if err != nil {
+		log.Fatalf("Failed to listen on gRPC port: %v", err)
+	}
+	go func() {
+		log.Printf("Starting gRPC server on :%s", grpcPort)
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			log.Fatalf("Failed to start gRPC server: %v", err)
+		}
+	}()
+	defer grpcServer.GracefulStop()
+
 	// Start server
 	addr := fmt.Sprintf(":%s", port)
 	log.Printf("Starting server on %s", addr)
@@ -132,6 +353,153 @@ func main() {
 	}
 }
 
+// newTokenIssuer signs with the PEM keys listed in AUTH_SIGNING_KEYS, the
+// first one signing and the others only verifying tokens issued before a
+// rotation. Without them a key is generated at startup and rotated every
+// AUTH_KEY_ROTATION, which only suits a single replica.
+func newTokenIssuer(issuer string) (*auth.TokenIssuer, *auth.KeyRing) {
+	audience := os.Getenv("AUTH_TOKEN_AUDIENCE")
+	if audience == "" {
+		log.Fatal("AUTH_TOKEN_AUDIENCE is required with AUTH_TOKEN_ISSUER")
+	}
+	ttl, err := time.ParseDuration(getEnv("AUTH_TOKEN_TTL", "15m"))
+	if err != nil {
+		log.Fatalf("Invalid AUTH_TOKEN_TTL: %v", err)
+	}
+
+	var keys []*ecdsa.PrivateKey
+	var rotation time.Duration
+	if paths := os.Getenv("AUTH_SIGNING_KEYS"); paths != "" {
+		for _, path := range strings.Split(paths, ",") {
+			key, err := auth.LoadSigningKey(strings.TrimSpace(path))
+			if err != nil {
+				log.Fatalf("Failed to load signing key: %v", err)
+			}
+			keys = append(keys, key)
+		}
+	} else {
+		rotation, err = time.ParseDuration(getEnv("AUTH_KEY_ROTATION", "24h"))
+		if err != nil {
+			log.Fatalf("Invalid AUTH_KEY_ROTATION: %v", err)
+		}
+		key, err := auth.GenerateSigningKey()
+		if err != nil {
+			log.Fatalf("Failed to generate signing key: %v", err)
+		}
+		keys = append(keys, key)
+		log.Println("AUTH_SIGNING_KEYS not set: signing with a generated key, rotated every " + rotation.String())
+	}
+
+	// Replaced keys stay published until the last token they signed expires
+	ring := auth.NewKeyRing(ttl+time.Minute, keys[0], keys[1:]...)
+	if rotation > 0 {
+		go ring.RotateEvery(context.Background(), rotation)
+	}
+	return auth.NewTokenIssuer(ring, issuer, audience, ttl), ring
+}
+
+// newEnumerationDetector starts from abuse.DefaultThresholds, overridden by
+// the ENUMERATION_* variables. A zero count or ratio turns its check off.
+func newEnumerationDetector() (*abuse.Detector, abuse.Allowlist) {
+	thresholds := abuse.DefaultThresholds
+	var err error
+	if s := os.Getenv("ENUMERATION_WINDOW"); s != "" {
+		if thresholds.Window, err = time.ParseDuration(s); err != nil {
+			log.Fatalf("Invalid ENUMERATION_WINDOW: %v", err)
+		}
+	}
+	if s := os.Getenv("ENUMERATION_BLOCK"); s != "" {
+		if thresholds.BlockFor, err = time.ParseDuration(s); err != nil {
+			log.Fatalf("Invalid ENUMERATION_BLOCK: %v", err)
+		}
+	}
+	if s := os.Getenv("ENUMERATION_MAX_CPFS"); s != "" {
+		if thresholds.MaxDistinctCPFs, err = strconv.Atoi(s); err != nil {
+			log.Fatalf("Invalid ENUMERATION_MAX_CPFS: %v", err)
+		}
+	}
+	if s := os.Getenv("ENUMERATION_MIN_LOOKUPS"); s != "" {
+		if thresholds.MinLookups, err = strconv.Atoi(s); err != nil {
+			log.Fatalf("Invalid ENUMERATION_MIN_LOOKUPS: %v", err)
+		}
+	}
+	if s := os.Getenv("ENUMERATION_MAX_INVALID_RATIO"); s != "" {
+		if thresholds.MaxInvalidRatio, err = strconv.ParseFloat(s, 64); err != nil {
+			log.Fatalf("Invalid ENUMERATION_MAX_INVALID_RATIO: %v", err)
+		}
+	}
+	if s := os.Getenv("ENUMERATION_MAX_NOT_FOUND_RATIO"); s != "" {
+		if thresholds.MaxNotFoundRatio, err = strconv.ParseFloat(s, 64); err != nil {
+			log.Fatalf("Invalid ENUMERATION_MAX_NOT_FOUND_RATIO: %v", err)
+		}
+	}
+
+	allowlist, err := abuse.ParseAllowlist(os.Getenv("ENUMERATION_ALLOWLIST"))
+	if err != nil {
+		log.Fatalf("Invalid ENUMERATION_ALLOWLIST: %v", err)
+	}
+	return abuse.NewDetector(thresholds, abuse.LogSink{}), allowlist
+}
+
+// newMailer delivers through the SMTP relay at MAIL_SMTP_ADDR, such as the
+// Amazon SES SMTP endpoint, from MAIL_FROM. MAIL_SMTP_USERNAME and
+// MAIL_SMTP_PASSWORD authenticate with it. Without a relay it returns nil,
+// which turns email changes off.
+func newMailer() mailer.Mailer {
+	addr := os.Getenv("MAIL_SMTP_ADDR")
+	if addr == "" {
+		log.Println("MAIL_SMTP_ADDR not set: email changes are disabled")
+		return nil
+	}
+	smtpMailer, err := mailer.NewSMTPMailer(addr, os.Getenv("MAIL_FROM"), os.Getenv("MAIL_SMTP_USERNAME"), os.Getenv("MAIL_SMTP_PASSWORD"))
+	if err != nil {
+		log.Fatalf("Invalid mail settings: %v", err)
+	}
+	return smtpMailer
+}
+
+// newFieldEncryptor reads the master keys from PII_MASTER_KEYS, base64 and
+// comma-separated, or from the file named by PII_MASTER_KEYS_FILE, one per
+// line. The first one wraps new data keys; the others only unwrap data keys
+// wrapped before a rotation. The blind index key comes from PII_INDEX_KEY or
+// PII_INDEX_KEY_FILE and can never change.
+func newFieldEncryptor() *fieldcrypt.Encryptor {
+	masterKeys := loadKeys("PII_MASTER_KEYS")
+	indexKeys := loadKeys("PII_INDEX_KEY")
+	if len(indexKeys) != 1 {
+		log.Fatal("PII_INDEX_KEY must hold exactly one key")
+	}
+	encryptor, err := fieldcrypt.New(indexKeys[0], masterKeys...)
+	if err != nil {
+		log.Fatalf("Invalid PII keys: %v", err)
+	}
+	return encryptor
+}
+
+func loadKeys(name string) [][]byte {
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		keys, err := fieldcrypt.LoadKeys(path)
+		if err != nil {
+			log.Fatalf("Invalid %s_FILE: %v", name, err)
+		}
+		return keys
+	}
+
+	value := os.Getenv(name)
+	if value == "" {
+		log.Fatalf("%s or %s_FILE is required", name, name)
+	}
+	var keys [][]byte
+	for _, s := range strings.Split(value, ",") {
+		key, err := fieldcrypt.DecodeKey(s)
+		if err != nil {
+			log.Fatalf("Invalid %s: %v", name, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
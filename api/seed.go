@@ -4,19 +4,26 @@ import (
 	"context"
 	"customer-service/internal/domain"
 	"customer-service/internal/repository"
+	"customer-service/pkg/fieldcrypt"
 	"log"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func runSeed(db *mongo.Database) error {
+func runSeed(db *mongo.Database, encryptor *fieldcrypt.Encryptor) error {
 	log.Println("Starting database seeding...")
 
-	customerRepo := repository.NewMongoDBCustomerRepository(db)
+	customerRepo := repository.NewMongoDBCustomerRepository(db, encryptor)
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	// Without the unique indexes, and with customers FindByCPF cannot see,
+	// seeding could insert duplicates
+	if err := customerRepo.EnsureSchema(ctx); err != nil {
+		return err
+	}
+
 	seedCustomers := []struct {
 		name  string
 		cpf   string
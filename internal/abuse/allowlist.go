@@ -0,0 +1,62 @@
+package abuse
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Allowlist names the internal callers exempt from enumeration detection,
+// such as the order service, which legitimately looks up many CPFs.
+type Allowlist struct {
+	subjects map[string]bool
+	networks []*net.IPNet
+}
+
+// ParseAllowlist reads a comma-separated list of token subjects, IP addresses
+// and CIDR networks, e.g. "order-service,10.0.0.0/8".
+func ParseAllowlist(s string) (Allowlist, error) {
+	list := Allowlist{subjects: make(map[string]bool)}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				return Allowlist{}, fmt.Errorf("invalid network %q: %w", entry, err)
+			}
+			list.networks = append(list.networks, network)
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			list.networks = append(list.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		list.subjects[entry] = true
+	}
+	return list, nil
+}
+
+// Allows reports whether a caller with the given token subject, empty when
+// unauthenticated, or IP address is exempt.
+func (l Allowlist) Allows(subject, ip string) bool {
+	if subject != "" && l.subjects[subject] {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, network := range l.networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,24 @@
+package abuse
+
+import "context"
+
+type budgetKey struct{}
+
+// WithBudget has the CPF lookups made with the returned context paid for by
+// spend, one token per CPF, which returns an error once the client's budget
+// is spent. Like WithWatch, each transport sets it up and the use cases pay
+// with Spend, so a batch or a GraphQL query costs as much as looking up its
+// CPFs one by one.
+func WithBudget(ctx context.Context, spend func(ctx context.Context, n int) error) context.Context {
+	return context.WithValue(ctx, budgetKey{}, spend)
+}
+
+// Spend pays for looking up n CPFs from the budget in ctx before they are
+// looked up. Lookups made without a budget are free.
+func Spend(ctx context.Context, n int) error {
+	spend, ok := ctx.Value(budgetKey{}).(func(context.Context, int) error)
+	if !ok || n == 0 {
+		return nil
+	}
+	return spend(ctx, n)
+}
@@ -0,0 +1,244 @@
+package abuse
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// Outcome is how a CPF lookup ended.
+type Outcome int
+
+const (
+	OutcomeFound Outcome = iota
+	OutcomeNotFound
+	OutcomeInvalid
+	// OutcomeAvailable is a CPF checked before registration and not taken.
+	// It counts towards the distinct CPFs but not the not-found ratio, since
+	// a kiosk mostly registers customers who are new.
+	OutcomeAvailable
+)
+
+// maxWindowLookups caps the lookups kept per client. Past it the oldest are
+// dropped early, which keeps memory bounded for a client flooding lookups
+// within one window; the distinct-CPF check fires long before.
+const maxWindowLookups = 1000
+
+// Thresholds configure when a client is considered to be enumerating CPFs.
+// A zero threshold disables its check.
+type Thresholds struct {
+	// Window is how far back lookups are counted.
+	Window time.Duration
+	// MaxDistinctCPFs is how many different CPFs a client may look up
+	// within the window.
+	MaxDistinctCPFs int
+	// MinLookups is how many lookups the window must hold before the ratios
+	// below are checked, so one early miss does not block a client.
+	MinLookups int
+	// MaxInvalidRatio and MaxNotFoundRatio are the highest shares of
+	// lookups with a malformed CPF or for an unknown customer.
+	MaxInvalidRatio  float64
+	MaxNotFoundRatio float64
+	// BlockFor is how long a client stays blocked once caught.
+	BlockFor time.Duration
+}
+
+// DefaultThresholds leave room for a kiosk serving a queue of customers while
+// catching scripts that walk through CPFs.
+var DefaultThresholds = Thresholds{
+	Window:           10 * time.Minute,
+	MaxDistinctCPFs:  50,
+	MinLookups:       20,
+	MaxInvalidRatio:  0.5,
+	MaxNotFoundRatio: 0.8,
+	BlockFor:         15 * time.Minute,
+}
+
+// Detector watches each client's CPF lookups over a sliding window and blocks
+// clients that look like they are enumerating CPFs. State is kept in memory,
+// so each replica watches the requests it serves.
+type Detector struct {
+	thresholds Thresholds
+	events     EventSink
+	now        func() time.Time
+
+	mu        sync.Mutex
+	clients   map[string]*window
+	blocked   map[string]time.Time
+	lastSweep time.Time
+}
+
+// lookup is one recorded lookup. CPFs are kept as hashes only.
+type lookup struct {
+	at      time.Time
+	cpf     [sha256.Size]byte
+	outcome Outcome
+}
+
+// window holds a client's lookups in time order together with running
+// counts, so checking a lookup does not rescan the window.
+type window struct {
+	lookups  []lookup
+	cpfs     map[[sha256.Size]byte]int
+	invalid  int
+	notFound int
+}
+
+func NewDetector(thresholds Thresholds, events EventSink) *Detector {
+	return &Detector{
+		thresholds: thresholds,
+		events:     events,
+		now:        time.Now,
+		clients:    make(map[string]*window),
+		blocked:    make(map[string]time.Time),
+	}
+}
+
+// BlockedUntil returns when the client's block ends, if it is blocked.
+func (d *Detector) BlockedUntil(client string) (time.Time, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	until, ok := d.blocked[client]
+	if !ok {
+		return time.Time{}, false
+	}
+	if !d.now().Before(until) {
+		delete(d.blocked, client)
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+// Record adds a lookup to the client's window. When it crosses a threshold
+// the client is blocked and a security event is emitted.
+func (d *Detector) Record(ctx context.Context, client, cpf string, outcome Outcome) {
+	event, caught := d.record(client, cpf, outcome)
+	if caught {
+		d.events.Emit(ctx, event)
+	}
+}
+
+func (d *Detector) record(client, cpf string, outcome Outcome) (Event, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	d.sweep(now)
+
+	w, ok := d.clients[client]
+	if !ok {
+		w = &window{cpfs: make(map[[sha256.Size]byte]int)}
+		d.clients[client] = w
+	}
+	w.prune(now.Add(-d.thresholds.Window))
+	w.add(lookup{at: now, cpf: sha256.Sum256([]byte(cpf)), outcome: outcome})
+
+	stats := w.stats()
+	reason := d.violation(stats)
+	if reason == "" {
+		return Event{}, false
+	}
+
+	until := now.Add(d.thresholds.BlockFor)
+	d.blocked[client] = until
+	// The client starts over once the block ends
+	delete(d.clients, client)
+
+	return Event{
+		Type:         EventCPFEnumeration,
+		Client:       client,
+		Reason:       reason,
+		Lookups:      stats.lookups,
+		DistinctCPFs: stats.distinct,
+		Invalid:      stats.invalid,
+		NotFound:     stats.notFound,
+		BlockedUntil: until,
+		At:           now,
+	}, true
+}
+
+func (d *Detector) violation(s windowStats) string {
+	t := d.thresholds
+	switch {
+	case t.MaxDistinctCPFs > 0 && s.distinct > t.MaxDistinctCPFs:
+		return ReasonDistinctCPFs
+	case s.lookups < t.MinLookups:
+		return ""
+	case t.MaxInvalidRatio > 0 && float64(s.invalid)/float64(s.lookups) > t.MaxInvalidRatio:
+		return ReasonInvalidRatio
+	case t.MaxNotFoundRatio > 0 && float64(s.notFound)/float64(s.lookups) > t.MaxNotFoundRatio:
+		return ReasonNotFoundRatio
+	}
+	return ""
+}
+
+// sweep forgets idle clients and ended blocks, at most once per window. The
+// caller holds the lock.
+func (d *Detector) sweep(now time.Time) {
+	if now.Sub(d.lastSweep) < d.thresholds.Window {
+		return
+	}
+	d.lastSweep = now
+
+	cutoff := now.Add(-d.thresholds.Window)
+	for client, w := range d.clients {
+		w.prune(cutoff)
+		if len(w.lookups) == 0 {
+			delete(d.clients, client)
+		}
+	}
+	for client, until := range d.blocked {
+		if !now.Before(until) {
+			delete(d.blocked, client)
+		}
+	}
+}
+
+// add appends l, dropping the oldest lookup once the window is full.
+func (w *window) add(l lookup) {
+	if len(w.lookups) >= maxWindowLookups {
+		w.drop(1)
+	}
+	w.lookups = append(w.lookups, l)
+	w.cpfs[l.cpf]++
+	w.count(l.outcome, 1)
+}
+
+// prune drops lookups made before cutoff. Lookups are in time order.
+func (w *window) prune(cutoff time.Time) {
+	i := 0
+	for i < len(w.lookups) && w.lookups[i].at.Before(cutoff) {
+		i++
+	}
+	w.drop(i)
+}
+
+// drop forgets the n oldest lookups.
+func (w *window) drop(n int) {
+	for _, l := range w.lookups[:n] {
+		if w.cpfs[l.cpf]--; w.cpfs[l.cpf] == 0 {
+			delete(w.cpfs, l.cpf)
+		}
+		w.count(l.outcome, -1)
+	}
+	w.lookups = w.lookups[n:]
+}
+
+func (w *window) count(outcome Outcome, delta int) {
+	switch outcome {
+	case OutcomeInvalid:
+		w.invalid += delta
+	case OutcomeNotFound:
+		w.notFound += delta
+	}
+}
+
+type windowStats struct {
+	lookups, distinct, invalid, notFound int
+}
+
+func (w *window) stats() windowStats {
+	return windowStats{lookups: len(w.lookups), distinct: len(w.cpfs), invalid: w.invalid, notFound: w.notFound}
+}
@@ -0,0 +1,247 @@
+package abuse
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingSink struct {
+	events []Event
+}
+
+func (s *recordingSink) Emit(_ context.Context, event Event) {
+	s.events = append(s.events, event)
+}
+
+var testThresholds = Thresholds{
+	Window:           10 * time.Minute,
+	MaxDistinctCPFs:  5,
+	MinLookups:       4,
+	MaxInvalidRatio:  0.5,
+	MaxNotFoundRatio: 0.8,
+	BlockFor:         15 * time.Minute,
+}
+
+func newTestDetector(thresholds Thresholds) (*Detector, *recordingSink, *time.Time) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	sink := &recordingSink{}
+	detector := NewDetector(thresholds, sink)
+	detector.now = func() time.Time { return now }
+	return detector, sink, &now
+}
+
+func cpfN(i int) string {
+	return fmt.Sprintf("%011d", i)
+}
+
+func TestDetector_DistinctCPFs(t *testing.T) {
+	detector, sink, now := newTestDetector(testThresholds)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		detector.Record(ctx, "ip:10.0.0.1", cpfN(i), OutcomeFound)
+	}
+	_, blocked := detector.BlockedUntil("ip:10.0.0.1")
+	assert.False(t, blocked)
+	assert.Empty(t, sink.events)
+
+	detector.Record(ctx, "ip:10.0.0.1", cpfN(5), OutcomeFound)
+
+	until, blocked := detector.BlockedUntil("ip:10.0.0.1")
+	assert.True(t, blocked)
+	assert.Equal(t, now.Add(15*time.Minute), until)
+	require.Len(t, sink.events, 1)
+	event := sink.events[0]
+	assert.Equal(t, EventCPFEnumeration, event.Type)
+	assert.Equal(t, "ip:10.0.0.1", event.Client)
+	assert.Equal(t, ReasonDistinctCPFs, event.Reason)
+	assert.Equal(t, 6, event.Lookups)
+	assert.Equal(t, 6, event.DistinctCPFs)
+	assert.Equal(t, until, event.BlockedUntil)
+
+	_, blocked = detector.BlockedUntil("ip:10.0.0.2")
+	assert.False(t, blocked, "other clients are not blocked")
+}
+
+func TestDetector_RepeatedCPFCountsOnce(t *testing.T) {
+	detector, sink, _ := newTestDetector(testThresholds)
+
+	for i := 0; i < 20; i++ {
+		detector.Record(context.Background(), "sub:kiosk", cpfN(i%3), OutcomeFound)
+	}
+
+	_, blocked := detector.BlockedUntil("sub:kiosk")
+	assert.False(t, blocked)
+	assert.Empty(t, sink.events)
+}
+
+func TestDetector_Ratios(t *testing.T) {
+	tests := []struct {
+		name     string
+		outcomes []Outcome
+		reason   string
+	}{
+		{
+			name:     "mostly invalid CPFs",
+			outcomes: []Outcome{OutcomeInvalid, OutcomeInvalid, OutcomeFound, OutcomeInvalid},
+			reason:   ReasonInvalidRatio,
+		},
+		{
+			name:     "mostly unknown CPFs",
+			outcomes: []Outcome{OutcomeNotFound, OutcomeNotFound, OutcomeNotFound, OutcomeNotFound},
+			reason:   ReasonNotFoundRatio,
+		},
+		{
+			name:     "half invalid is allowed",
+			outcomes: []Outcome{OutcomeInvalid, OutcomeFound, OutcomeInvalid, OutcomeFound},
+		},
+		{
+			name:     "free CPFs checked before registration are allowed",
+			outcomes: []Outcome{OutcomeAvailable, OutcomeAvailable, OutcomeAvailable, OutcomeAvailable},
+		},
+		{
+			name:     "ratios wait for enough lookups",
+			outcomes: []Outcome{OutcomeNotFound, OutcomeNotFound, OutcomeNotFound},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detector, sink, _ := newTestDetector(testThresholds)
+
+			for i, outcome := range tt.outcomes {
+				detector.Record(context.Background(), "ip:10.0.0.1", cpfN(i), outcome)
+			}
+
+			_, blocked := detector.BlockedUntil("ip:10.0.0.1")
+			if tt.reason == "" {
+				assert.False(t, blocked)
+				assert.Empty(t, sink.events)
+				return
+			}
+			assert.True(t, blocked)
+			require.Len(t, sink.events, 1)
+			assert.Equal(t, tt.reason, sink.events[0].Reason)
+		})
+	}
+}
+
+func TestDetector_SlidingWindow(t *testing.T) {
+	detector, sink, now := newTestDetector(testThresholds)
+
+	for i := 0; i < 5; i++ {
+		detector.Record(context.Background(), "ip:10.0.0.1", cpfN(i), OutcomeFound)
+		*now = now.Add(3 * time.Minute)
+	}
+	// Only the lookups of the last 10 minutes count
+	detector.Record(context.Background(), "ip:10.0.0.1", cpfN(5), OutcomeFound)
+
+	_, blocked := detector.BlockedUntil("ip:10.0.0.1")
+	assert.False(t, blocked)
+	assert.Empty(t, sink.events)
+}
+
+func TestDetector_WindowIsCapped(t *testing.T) {
+	detector, sink, _ := newTestDetector(Thresholds{Window: time.Hour, BlockFor: time.Minute})
+
+	for i := 0; i <= maxWindowLookups; i++ {
+		detector.Record(context.Background(), "ip:10.0.0.1", cpfN(i%2), OutcomeFound)
+	}
+
+	window := detector.clients["ip:10.0.0.1"]
+	require.NotNil(t, window)
+	assert.Len(t, window.lookups, maxWindowLookups)
+	assert.Equal(t, windowStats{lookups: maxWindowLookups, distinct: 2}, window.stats())
+	assert.Empty(t, sink.events)
+}
+
+func TestDetector_PruneUpdatesCounts(t *testing.T) {
+	detector, _, now := newTestDetector(testThresholds)
+
+	detector.Record(context.Background(), "ip:10.0.0.1", cpfN(0), OutcomeInvalid)
+	detector.Record(context.Background(), "ip:10.0.0.1", cpfN(1), OutcomeNotFound)
+	*now = now.Add(5 * time.Minute)
+	detector.Record(context.Background(), "ip:10.0.0.1", cpfN(1), OutcomeFound)
+	*now = now.Add(6 * time.Minute)
+	detector.Record(context.Background(), "ip:10.0.0.1", cpfN(2), OutcomeFound)
+
+	assert.Equal(t, windowStats{lookups: 2, distinct: 2}, detector.clients["ip:10.0.0.1"].stats())
+}
+
+func TestObserve(t *testing.T) {
+	detector, _, _ := newTestDetector(testThresholds)
+
+	Observe(context.Background(), cpfN(0), OutcomeFound)
+	assert.Empty(t, detector.clients, "lookups without a watch are not recorded")
+
+	Observe(WithWatch(context.Background(), detector, "sub:kiosk"), cpfN(0), OutcomeNotFound)
+	require.Contains(t, detector.clients, "sub:kiosk")
+	assert.Equal(t, windowStats{lookups: 1, distinct: 1, notFound: 1}, detector.clients["sub:kiosk"].stats())
+}
+
+func TestDetector_BlockExpires(t *testing.T) {
+	detector, sink, now := newTestDetector(testThresholds)
+
+	for i := 0; i < 6; i++ {
+		detector.Record(context.Background(), "ip:10.0.0.1", cpfN(i), OutcomeFound)
+	}
+	require.Len(t, sink.events, 1)
+
+	*now = now.Add(15 * time.Minute)
+	_, blocked := detector.BlockedUntil("ip:10.0.0.1")
+	assert.False(t, blocked)
+
+	// The window started over with the block
+	detector.Record(context.Background(), "ip:10.0.0.1", cpfN(6), OutcomeFound)
+	_, blocked = detector.BlockedUntil("ip:10.0.0.1")
+	assert.False(t, blocked)
+	assert.Len(t, sink.events, 1)
+}
+
+func TestDetector_ZeroThresholdsDisableChecks(t *testing.T) {
+	detector, sink, _ := newTestDetector(Thresholds{Window: time.Minute, BlockFor: time.Minute})
+
+	for i := 0; i < 100; i++ {
+		detector.Record(context.Background(), "ip:10.0.0.1", cpfN(i), OutcomeInvalid)
+	}
+
+	_, blocked := detector.BlockedUntil("ip:10.0.0.1")
+	assert.False(t, blocked)
+	assert.Empty(t, sink.events)
+}
+
+func TestDetector_SweepForgetsIdleClients(t *testing.T) {
+	detector, _, now := newTestDetector(testThresholds)
+
+	detector.Record(context.Background(), "ip:10.0.0.1", cpfN(0), OutcomeFound)
+	*now = now.Add(11 * time.Minute)
+	detector.Record(context.Background(), "ip:10.0.0.2", cpfN(0), OutcomeFound)
+
+	assert.NotContains(t, detector.clients, "ip:10.0.0.1")
+	assert.Contains(t, detector.clients, "ip:10.0.0.2")
+}
+
+func TestParseAllowlist(t *testing.T) {
+	list, err := ParseAllowlist(" order-service, 10.0.0.0/8 ,192.168.1.5,,2001:db8::1")
+	require.NoError(t, err)
+
+	assert.True(t, list.Allows("order-service", "203.0.113.1"))
+	assert.True(t, list.Allows("", "10.1.2.3"))
+	assert.True(t, list.Allows("", "192.168.1.5"))
+	assert.True(t, list.Allows("", "2001:db8::1"))
+	assert.False(t, list.Allows("", "192.168.1.6"))
+	assert.False(t, list.Allows("kiosk-1", "203.0.113.1"))
+	assert.False(t, list.Allows("", "not-an-ip"))
+
+	empty, err := ParseAllowlist("")
+	require.NoError(t, err)
+	assert.False(t, empty.Allows("order-service", "10.1.2.3"))
+
+	_, err = ParseAllowlist("10.0.0.0/99")
+	assert.Error(t, err)
+}
@@ -0,0 +1,45 @@
+package abuse
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// EventCPFEnumeration is the type of the event emitted when a client is
+// blocked for enumerating CPFs.
+const EventCPFEnumeration = "cpf_enumeration"
+
+// Reasons a client was blocked.
+const (
+	ReasonDistinctCPFs  = "distinct_cpfs"
+	ReasonInvalidRatio  = "invalid_ratio"
+	ReasonNotFoundRatio = "not_found_ratio"
+)
+
+// Event is a security event. It carries counts only, never the CPFs looked
+// up.
+type Event struct {
+	Type         string
+	Client       string
+	Reason       string
+	Lookups      int
+	DistinctCPFs int
+	Invalid      int
+	NotFound     int
+	BlockedUntil time.Time
+	At           time.Time
+}
+
+// EventSink receives security events, e.g. to forward them to a SIEM.
+type EventSink interface {
+	Emit(ctx context.Context, event Event)
+}
+
+// LogSink writes security events to the service log.
+type LogSink struct{}
+
+func (LogSink) Emit(_ context.Context, e Event) {
+	log.Printf("security event %s: client=%s reason=%s lookups=%d distinct_cpfs=%d invalid=%d not_found=%d blocked_until=%s",
+		e.Type, e.Client, e.Reason, e.Lookups, e.DistinctCPFs, e.Invalid, e.NotFound, e.BlockedUntil.Format(time.RFC3339))
+}
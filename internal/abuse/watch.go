@@ -0,0 +1,29 @@
+package abuse
+
+import "context"
+
+type watchKey struct{}
+
+type watch struct {
+	detector *Detector
+	client   string
+}
+
+// WithWatch has the CPF lookups made with the returned context recorded by
+// detector for client. Each transport sets it up once it knows the caller,
+// and the use cases that look up CPFs report them with Observe, so that
+// every way of looking up a CPF is watched alike.
+func WithWatch(ctx context.Context, detector *Detector, client string) context.Context {
+	return context.WithValue(ctx, watchKey{}, watch{detector: detector, client: client})
+}
+
+// Observe records a lookup of cpf, which should already be cleaned, for the
+// client watched in ctx. Lookups made without a watch, such as those of
+// allowlisted callers, are not recorded.
+func Observe(ctx context.Context, cpf string, outcome Outcome) {
+	w, ok := ctx.Value(watchKey{}).(watch)
+	if !ok {
+		return
+	}
+	w.detector.Record(ctx, w.client, cpf, outcome)
+}
@@ -0,0 +1,142 @@
+// Package audit records which customers' personal data each caller was
+// shown unmasked, so LGPD audits can tell who accessed what and why.
+package audit
+
+import (
+	"context"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/repository"
+	"customer-service/pkg/errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MaxPurposeLength keeps callers from filling the access log through the
+// declared purpose.
+const MaxPurposeLength = 200
+
+type purposeKey struct{}
+
+type disclosedKey struct{}
+
+// WithPurpose stores the purpose the caller declared for the request,
+// trimmed and cut to MaxPurposeLength. A blank purpose leaves ctx as is.
+func WithPurpose(ctx context.Context, purpose string) context.Context {
+	purpose = strings.TrimSpace(purpose)
+	if len(purpose) > MaxPurposeLength {
+		purpose = purpose[:MaxPurposeLength]
+	}
+	if purpose == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, purposeKey{}, purpose)
+}
+
+// Purpose returns the purpose stored in ctx, or "" when none was declared.
+func Purpose(ctx context.Context) string {
+	purpose, _ := ctx.Value(purposeKey{}).(string)
+	return purpose
+}
+
+// WithDisclosedFields declares which of domain.PIIFields the response will
+// show unmasked, for transports that choose the fields themselves. With no
+// fields, reads are not logged at all.
+func WithDisclosedFields(ctx context.Context, fields ...string) context.Context {
+	return context.WithValue(ctx, disclosedKey{}, fields)
+}
+
+// DisclosedFields returns the fields the response shows unmasked: none when
+// the caller may not read the customer's personal data, as Redact masks
+// them, otherwise the fields declared in ctx or every field without a
+// declaration.
+func DisclosedFields(ctx context.Context, customerID string) []string {
+	if !auth.CanReadPII(ctx, customerID) {
+		return nil
+	}
+	if fields, ok := ctx.Value(disclosedKey{}).([]string); ok {
+		return fields
+	}
+	return domain.PIIFields
+}
+
+// Redact returns customer as the caller in ctx may see it: unchanged when
+// they may read its personal data, masked otherwise.
+func Redact(ctx context.Context, customer *domain.Customer) *domain.Customer {
+	if customer == nil || customer.Anonymized || auth.CanReadPII(ctx, customer.ID) {
+		return customer
+	}
+	return customer.Masked()
+}
+
+// AnonymousActor is recorded for callers without a principal, who only exist
+// with HTTP authentication turned off and then see personal data unmasked.
+const AnonymousActor = "anonymous"
+
+// AccessLog appends an entry to the PII access log for every customer a
+// read returns. Use cases take a nil *AccessLog to record nothing, as tests
+// that do not look at the log do.
+type AccessLog struct {
+	repo repository.PIIAccessRepository
+	now  func() time.Time
+}
+
+func NewAccessLog(repo repository.PIIAccessRepository) *AccessLog {
+	return &AccessLog{repo: repo, now: time.Now}
+}
+
+// Record logs that the caller in ctx was shown customers. Anonymized
+// customers and fields the customer has no value for are left out.
+func (l *AccessLog) Record(ctx context.Context, customers ...*domain.Customer) error {
+	if l == nil {
+		return nil
+	}
+	actor := AnonymousActor
+	if principal := auth.FromContext(ctx); principal != nil {
+		actor = principal.Subject
+	}
+	purpose := Purpose(ctx)
+	now := l.now()
+
+	entries := make([]*domain.PIIAccess, 0, len(customers))
+	logged := make(map[string]bool, len(customers))
+	for _, customer := range customers {
+		if customer == nil || customer.Anonymized || logged[customer.ID] {
+			continue
+		}
+		fields := shownFields(customer, DisclosedFields(ctx, customer.ID))
+		if len(fields) == 0 {
+			continue
+		}
+		logged[customer.ID] = true
+		entries = append(entries, domain.NewPIIAccess(actor, purpose, customer.ID, fields, now))
+	}
+	return l.repo.Append(ctx, entries)
+}
+
+// RecordWrite records customers a write has already stored. The write
+// cannot be undone, so a failure to record is logged for the operators
+// instead of failing the request, which a retry would then repeat.
+func (l *AccessLog) RecordWrite(ctx context.Context, customers ...*domain.Customer) {
+	if err := l.Record(ctx, customers...); err != nil {
+		log.Printf("Failed to record PII access after a write: %s", errors.From(err).Internal())
+	}
+}
+
+func shownFields(customer *domain.Customer, disclosed []string) []string {
+	shown := map[string]bool{
+		domain.PIIFieldCPF:          customer.CPF != "",
+		domain.PIIFieldEmail:        customer.Email != "",
+		domain.PIIFieldPendingEmail: customer.PendingEmail != "",
+		domain.PIIFieldCPFHistory:   len(customer.CPFHistory) > 0,
+	}
+	fields := make([]string, 0, len(shown))
+	for _, field := range append(slices.Clip(domain.PIIFields), domain.PIIFieldCPFHistory) {
+		if shown[field] && slices.Contains(disclosed, field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
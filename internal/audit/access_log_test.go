@@ -0,0 +1,197 @@
+package audit
+
+import (
+	"context"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingRepository struct {
+	entries []*domain.PIIAccess
+	err     error
+}
+
+func (r *recordingRepository) Append(_ context.Context, entries []*domain.PIIAccess) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.entries = append(r.entries, entries...)
+	return nil
+}
+
+func (r *recordingRepository) FindByCustomer(context.Context, string, int) ([]*domain.PIIAccess, error) {
+	return nil, nil
+}
+
+func (r *recordingRepository) FindByActor(context.Context, string, int) ([]*domain.PIIAccess, error) {
+	return nil, nil
+}
+
+func newTestLog() (*AccessLog, *recordingRepository, time.Time) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo := &recordingRepository{}
+	log := NewAccessLog(repo)
+	log.now = func() time.Time { return now }
+	return log, repo, now
+}
+
+func TestAccessLog_Record(t *testing.T) {
+	john, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+	jane, _ := domain.NewCustomer("Jane Doe", "52998224725", "jane@example.com")
+	jane.PendingEmail = "jane.doe@example.com"
+	piiReader := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "backoffice", Scopes: []string{auth.ScopeReadPII}})
+
+	t.Run("one entry per customer shown", func(t *testing.T) {
+		log, repo, now := newTestLog()
+		ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "order-service", Scopes: []string{auth.ScopeReadPII}})
+		ctx = WithPurpose(ctx, "order-fulfillment")
+
+		err := log.Record(ctx, john, jane, john)
+
+		require.NoError(t, err)
+		require.Len(t, repo.entries, 2)
+		entry := repo.entries[0]
+		assert.NotEmpty(t, entry.ID)
+		assert.Equal(t, "order-service", entry.Actor)
+		assert.Equal(t, "order-fulfillment", entry.Purpose)
+		assert.Equal(t, john.ID, entry.CustomerID)
+		assert.Equal(t, []string{"cpf", "email"}, entry.Fields)
+		assert.Equal(t, now, entry.AccessedAt)
+		assert.Equal(t, []string{"cpf", "email", "pendingEmail"}, repo.entries[1].Fields)
+	})
+
+	t.Run("masked reads are not logged", func(t *testing.T) {
+		log, repo, _ := newTestLog()
+		ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "kiosk", Scopes: []string{auth.ScopeRead}})
+
+		require.NoError(t, log.Record(ctx, john))
+
+		assert.Empty(t, repo.entries)
+	})
+
+	t.Run("callers without authentication", func(t *testing.T) {
+		log, repo, _ := newTestLog()
+
+		require.NoError(t, log.Record(context.Background(), john))
+
+		require.Len(t, repo.entries, 1)
+		assert.Equal(t, AnonymousActor, repo.entries[0].Actor)
+		assert.Equal(t, []string{"cpf", "email"}, repo.entries[0].Fields)
+	})
+
+	t.Run("customers reading their own record", func(t *testing.T) {
+		log, repo, _ := newTestLog()
+		ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: john.ID, Scopes: []string{auth.ScopeSelf}})
+
+		require.NoError(t, log.Record(ctx, john, jane))
+
+		require.Len(t, repo.entries, 1)
+		assert.Equal(t, john.ID, repo.entries[0].Actor)
+		assert.Equal(t, john.ID, repo.entries[0].CustomerID)
+	})
+
+	t.Run("declared fields the caller may not read", func(t *testing.T) {
+		log, repo, _ := newTestLog()
+		kiosk := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "kiosk", Scopes: []string{auth.ScopeRead}})
+
+		require.NoError(t, log.Record(WithDisclosedFields(kiosk, domain.PIIFieldCPF), john))
+
+		assert.Empty(t, repo.entries)
+	})
+
+	t.Run("only the disclosed fields", func(t *testing.T) {
+		log, repo, _ := newTestLog()
+		ctx := WithDisclosedFields(piiReader, domain.PIIFieldEmail)
+
+		require.NoError(t, log.Record(ctx, john))
+
+		require.Len(t, repo.entries, 1)
+		assert.Equal(t, []string{"email"}, repo.entries[0].Fields)
+	})
+
+	t.Run("nothing disclosed", func(t *testing.T) {
+		log, repo, _ := newTestLog()
+		ctx := WithDisclosedFields(piiReader)
+
+		require.NoError(t, log.Record(ctx, john))
+
+		assert.Empty(t, repo.entries)
+	})
+
+	t.Run("anonymized customers have no personal data", func(t *testing.T) {
+		log, repo, _ := newTestLog()
+		anonymized := &domain.Customer{ID: "shredded", Anonymized: true}
+
+		require.NoError(t, log.Record(context.Background(), anonymized, nil))
+
+		assert.Empty(t, repo.entries)
+	})
+
+	t.Run("storage errors are returned", func(t *testing.T) {
+		log, repo, _ := newTestLog()
+		repo.err = stderrors.New("boom")
+
+		assert.Error(t, log.Record(WithDisclosedFields(piiReader, domain.PIIFieldCPF), john))
+	})
+
+	t.Run("CPF history only when declared", func(t *testing.T) {
+		log, repo, _ := newTestLog()
+		corrected, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		require.NoError(t, corrected.CorrectCPF("52998224725", "typo", "admin"))
+
+		require.NoError(t, log.Record(piiReader, corrected))
+		require.NoError(t, log.Record(WithDisclosedFields(piiReader, domain.PIIFieldCPF, domain.PIIFieldCPFHistory), corrected))
+
+		require.Len(t, repo.entries, 2)
+		assert.Equal(t, []string{"cpf", "email"}, repo.entries[0].Fields)
+		assert.Equal(t, []string{"cpf", "cpfHistory"}, repo.entries[1].Fields)
+	})
+
+	t.Run("write failures are not returned", func(t *testing.T) {
+		log, repo, _ := newTestLog()
+		repo.err = stderrors.New("boom")
+
+		assert.NotPanics(t, func() { log.RecordWrite(piiReader, john) })
+	})
+
+	t.Run("nil log", func(t *testing.T) {
+		var log *AccessLog
+		assert.NoError(t, log.Record(context.Background(), john))
+	})
+}
+
+func TestRedact(t *testing.T) {
+	john, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+
+	t.Run("masked without the PII scope", func(t *testing.T) {
+		ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "kiosk", Scopes: []string{auth.ScopeRead}})
+
+		shown := Redact(ctx, john)
+
+		assert.Equal(t, "***.444.777-**", shown.CPF)
+		assert.Equal(t, "j***@example.com", shown.Email)
+		assert.Equal(t, "11144477735", john.CPF, "the original is left alone")
+	})
+
+	t.Run("unmasked with the PII scope", func(t *testing.T) {
+		ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "backoffice", Scopes: []string{auth.ScopeRead, auth.ScopeReadPII}})
+
+		assert.Same(t, john, Redact(ctx, john))
+	})
+
+	t.Run("customers see their own record unmasked", func(t *testing.T) {
+		ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: john.ID, Scopes: []string{auth.ScopeSelf}})
+
+		assert.Same(t, john, Redact(ctx, john))
+	})
+
+	t.Run("unmasked without authentication", func(t *testing.T) {
+		assert.Same(t, john, Redact(context.Background(), john))
+	})
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// TokenIssuer signs the service's own short-lived ES256 access tokens with
+// the current key of its KeyRing.
+type TokenIssuer struct {
+	keys     *KeyRing
+	issuer   string
+	audience string
+	ttl      time.Duration
+}
+
+func NewTokenIssuer(keys *KeyRing, issuer, audience string, ttl time.Duration) *TokenIssuer {
+	return &TokenIssuer{keys: keys, issuer: issuer, audience: audience, ttl: ttl}
+}
+
+// Token is a signed access token and when it expires.
+type Token struct {
+	Value     string
+	ExpiresAt time.Time
+	Scopes    []string
+}
+
+// Issue signs a token for subject with the given scopes.
+func (i *TokenIssuer) Issue(subject string, scopes []string) (*Token, error) {
+	now := time.Now()
+	expiresAt := now.Add(i.ttl)
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    i.issuer,
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{i.audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			ID:        uuid.New().String(),
+		},
+		Scope: strings.Join(scopes, " "),
+	}
+
+	kid, key := i.keys.Signer()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Token{Value: signed, ExpiresAt: expiresAt, Scopes: scopes}, nil
+}
+
+// Verifier returns a verifier for the tokens this issuer signs.
+func (i *TokenIssuer) Verifier() *TokenVerifier {
+	return NewTokenVerifier(i.keys, i.issuer, i.audience)
+}
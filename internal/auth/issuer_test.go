@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"customer-service/pkg/errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenIssuer(t *testing.T) {
+	ring := NewKeyRing(time.Hour, newTestKey(t))
+	issuer := NewTokenIssuer(ring, testIssuer, testAudience, 15*time.Minute)
+
+	t.Run("Issued tokens verify", func(t *testing.T) {
+		token, err := issuer.Issue("customer-1", []string{ScopeSelf})
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(15*time.Minute), token.ExpiresAt, time.Second)
+
+		principal, err := issuer.Verifier().Verify(context.Background(), token.Value)
+
+		require.NoError(t, err)
+		assert.Equal(t, "customer-1", principal.Subject)
+		assert.Equal(t, []string{ScopeSelf}, principal.Scopes)
+	})
+
+	t.Run("Tokens survive a rotation", func(t *testing.T) {
+		token, err := issuer.Issue("customer-1", []string{ScopeSelf})
+		require.NoError(t, err)
+		ring.Rotate(newTestKey(t))
+
+		_, err = issuer.Verifier().Verify(context.Background(), token.Value)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Other audiences reject them", func(t *testing.T) {
+		token, err := issuer.Issue("customer-1", []string{ScopeSelf})
+		require.NoError(t, err)
+
+		_, err = NewTokenVerifier(ring, testIssuer, "order-service").Verify(context.Background(), token.Value)
+
+		assert.Equal(t, "INVALID_TOKEN", errors.From(err).Code)
+	})
+}
+
+func TestAnyVerifier(t *testing.T) {
+	ring := NewKeyRing(time.Hour, newTestKey(t))
+	own := NewTokenIssuer(ring, "customer-service", testAudience, time.Minute)
+	idpKey := newTestKey(t)
+	idp := NewTokenVerifier(NewJWKS(map[string]crypto.PublicKey{"idp-1": &idpKey.PublicKey}), testIssuer, testAudience)
+	verifier := AnyVerifier(own.Verifier(), idp)
+
+	t.Run("Accepts the service's own tokens", func(t *testing.T) {
+		token, err := own.Issue("customer-1", []string{ScopeSelf})
+		require.NoError(t, err)
+
+		principal, err := verifier.Verify(context.Background(), token.Value)
+
+		require.NoError(t, err)
+		assert.Equal(t, "customer-1", principal.Subject)
+	})
+
+	t.Run("Accepts the identity provider's tokens", func(t *testing.T) {
+		principal, err := verifier.Verify(context.Background(), signToken(t, jwt.SigningMethodES256, "idp-1", idpKey, validClaims()))
+
+		require.NoError(t, err)
+		assert.Equal(t, "order-service", principal.Subject)
+	})
+
+	t.Run("Rejects anything else", func(t *testing.T) {
+		_, err := verifier.Verify(context.Background(), "not-a-token")
+
+		appErr := errors.From(err)
+		assert.Equal(t, 401, appErr.StatusCode)
+		assert.Equal(t, "INVALID_TOKEN", appErr.Code)
+	})
+}
@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jwksRefreshInterval limits how often an unknown key ID makes a remote JWKS
+// be fetched again, so forged kids cannot flood the identity provider.
+const jwksRefreshInterval = time.Minute
+
+// JWKS holds the public keys that may sign access tokens, by key ID. Keys
+// loaded from a URL are fetched again when a token names an unknown key, so
+// the identity provider can rotate keys without a restart.
+type JWKS struct {
+	source string
+	client *http.Client
+
+	mu        sync.RWMutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// NewJWKS returns a fixed key set, e.g. for keys generated in tests.
+func NewJWKS(keys map[string]crypto.PublicKey) *JWKS {
+	return &JWKS{keys: keys}
+}
+
+// LoadJWKS reads a JWK Set (RFC 7517) from an http(s) URL or a file path.
+func LoadJWKS(ctx context.Context, source string) (*JWKS, error) {
+	jwks := &JWKS{source: source, client: &http.Client{Timeout: 10 * time.Second}}
+	if err := jwks.refresh(ctx); err != nil {
+		return nil, err
+	}
+	return jwks, nil
+}
+
+// Key returns the public key with the given ID.
+func (s *JWKS) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	s.mu.RLock()
+	key, ok := s.keys[kid]
+	stale := time.Since(s.fetchedAt) > jwksRefreshInterval
+	s.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	if s.isRemote() && stale {
+		if err := s.refresh(ctx); err != nil {
+			return nil, err
+		}
+		s.mu.RLock()
+		key, ok = s.keys[kid]
+		s.mu.RUnlock()
+		if ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (s *JWKS) isRemote() bool {
+	return strings.HasPrefix(s.source, "http://") || strings.HasPrefix(s.source, "https://")
+}
+
+func (s *JWKS) refresh(ctx context.Context) error {
+	var data []byte
+	var err error
+	if s.isRemote() {
+		data, err = s.fetch(ctx)
+	} else {
+		data, err = os.ReadFile(s.source)
+	}
+	if err != nil {
+		return fmt.Errorf("loading JWKS from %s: %w", s.source, err)
+	}
+
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return fmt.Errorf("loading JWKS from %s: %w", s.source, err)
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *JWKS) fetch(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS decodes the RSA and P-256 signing keys of a JWK Set. Keys of
+// other types or meant for encryption are skipped.
+func ParseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		var key crypto.PublicKey
+		var err error
+		switch {
+		case k.Kty == "RSA":
+			key, err = k.rsaKey()
+		case k.Kty == "EC" && k.Crv == "P-256":
+			key, err = k.ecKey()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS has no usable signing keys")
+	}
+	return keys, nil
+}
+
+func (k jwk) rsaKey() (*rsa.PublicKey, error) {
+	n, err := decodeBigInt(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeBigInt(k.E)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsInt64() || e.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("exponent out of range")
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (k jwk) ecKey() (*ecdsa.PublicKey, error) {
+	x, err := decodeBigInt(k.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeBigInt(k.Y)
+	if err != nil {
+		return nil, err
+	}
+	if len(x.Bytes()) > 32 || len(y.Bytes()) > 32 {
+		return nil, fmt.Errorf("coordinate too long")
+	}
+
+	// crypto/ecdh rejects points that are not on the curve
+	point := make([]byte, 65)
+	point[0] = 4
+	x.FillBytes(point[1:33])
+	y.FillBytes(point[33:])
+	if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+		return nil, err
+	}
+	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("empty value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "EC",
+		"kid": kid,
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func jwksJSON(t *testing.T, keys ...map[string]string) []byte {
+	data, err := json.Marshal(map[string]interface{}{"keys": keys})
+	require.NoError(t, err)
+	return data
+}
+
+func TestParseJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	t.Run("Reads RSA and EC keys", func(t *testing.T) {
+		keys, err := ParseJWKS(jwksJSON(t, rsaJWK("rsa-1", &rsaKey.PublicKey), ecJWK("ec-1", &ecKey.PublicKey)))
+
+		require.NoError(t, err)
+		assert.True(t, rsaKey.PublicKey.Equal(keys["rsa-1"]))
+		assert.True(t, ecKey.PublicKey.Equal(keys["ec-1"]))
+	})
+
+	t.Run("Skips encryption keys and unsupported types", func(t *testing.T) {
+		encryption := rsaJWK("enc", &rsaKey.PublicKey)
+		encryption["use"] = "enc"
+		symmetric := map[string]string{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"}
+
+		keys, err := ParseJWKS(jwksJSON(t, encryption, symmetric, ecJWK("ec-1", &ecKey.PublicKey)))
+
+		require.NoError(t, err)
+		assert.Len(t, keys, 1)
+		assert.Contains(t, keys, "ec-1")
+	})
+
+	t.Run("Rejects a point off the curve", func(t *testing.T) {
+		invalid := ecJWK("ec-1", &ecKey.PublicKey)
+		invalid["y"] = invalid["x"]
+
+		_, err := ParseJWKS(jwksJSON(t, invalid))
+		assert.Error(t, err)
+	})
+
+	t.Run("Rejects a set without signing keys", func(t *testing.T) {
+		_, err := ParseJWKS([]byte(`{"keys": []}`))
+		assert.Error(t, err)
+
+		_, err = ParseJWKS([]byte(`not json`))
+		assert.Error(t, err)
+	})
+}
+
+func TestLoadJWKS(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	t.Run("From a file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "jwks.json")
+		require.NoError(t, os.WriteFile(path, jwksJSON(t, ecJWK("ec-1", &ecKey.PublicKey)), 0o600))
+
+		jwks, err := LoadJWKS(context.Background(), path)
+		require.NoError(t, err)
+
+		key, err := jwks.Key(context.Background(), "ec-1")
+		assert.NoError(t, err)
+		assert.True(t, ecKey.PublicKey.Equal(key))
+		_, err = jwks.Key(context.Background(), "unknown")
+		assert.Error(t, err)
+	})
+
+	t.Run("From a URL, fetched again for unknown keys", func(t *testing.T) {
+		rotated, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) == 1 {
+				_, _ = w.Write(jwksJSON(t, ecJWK("ec-1", &ecKey.PublicKey)))
+				return
+			}
+			_, _ = w.Write(jwksJSON(t, ecJWK("ec-1", &ecKey.PublicKey), ecJWK("ec-2", &rotated.PublicKey)))
+		}))
+		defer server.Close()
+
+		jwks, err := LoadJWKS(context.Background(), server.URL)
+		require.NoError(t, err)
+
+		// Fetched moments ago, so an unknown key does not trigger a request yet
+		_, err = jwks.Key(context.Background(), "ec-2")
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), requests.Load())
+
+		jwks.fetchedAt = jwks.fetchedAt.Add(-2 * jwksRefreshInterval)
+		key, err := jwks.Key(context.Background(), "ec-2")
+		assert.NoError(t, err)
+		assert.True(t, rotated.PublicKey.Equal(key))
+		assert.Equal(t, int32(2), requests.Load())
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		_, err := LoadJWKS(context.Background(), filepath.Join(t.TempDir(), "missing.json"))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// KeyRing holds the ES256 keys the service signs its own tokens with. The
+// newest key signs; keys it replaced stay published for retention, so tokens
+// they signed remain verifiable until they expire.
+type KeyRing struct {
+	retention time.Duration
+
+	mu   sync.RWMutex
+	keys []signingKey // newest first
+}
+
+type signingKey struct {
+	kid       string
+	key       *ecdsa.PrivateKey
+	retiredAt time.Time
+}
+
+// NewKeyRing returns a ring signing with the first key. The other keys are
+// treated as already replaced: they verify tokens for retention and are then
+// dropped.
+func NewKeyRing(retention time.Duration, active *ecdsa.PrivateKey, previous ...*ecdsa.PrivateKey) *KeyRing {
+	ring := &KeyRing{retention: retention}
+	now := time.Now()
+	ring.keys = append(ring.keys, signingKey{kid: Thumbprint(&active.PublicKey), key: active})
+	for _, key := range previous {
+		ring.keys = append(ring.keys, signingKey{kid: Thumbprint(&key.PublicKey), key: key, retiredAt: now})
+	}
+	return ring
+}
+
+// GenerateSigningKey returns a new P-256 key.
+func GenerateSigningKey() (*ecdsa.PrivateKey, error) {
+	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
+
+// LoadSigningKey reads a P-256 private key from a PEM file, in PKCS #8 or
+// SEC 1 form.
+func LoadSigningKey(path string) (*ecdsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block", path)
+	}
+
+	var key any
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unexpected PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok || ecKey.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("%s: not a P-256 key", path)
+	}
+	return ecKey, nil
+}
+
+// Rotate makes key the signing key. The key it replaces keeps verifying
+// tokens for the retention period.
+func (r *KeyRing) Rotate(key *ecdsa.PrivateKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.keys[0].retiredAt = now
+	r.keys = append([]signingKey{{kid: Thumbprint(&key.PublicKey), key: key}}, r.pruned(now)...)
+}
+
+// RotateEvery generates a new signing key every interval until ctx is done.
+func (r *KeyRing) RotateEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			key, err := GenerateSigningKey()
+			if err != nil {
+				log.Printf("Signing key rotation failed: %v", err)
+				continue
+			}
+			r.Rotate(key)
+		}
+	}
+}
+
+// Signer returns the current signing key and its ID.
+func (r *KeyRing) Signer() (string, *ecdsa.PrivateKey) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.keys[0].kid, r.keys[0].key
+}
+
+// Key returns the public key with the given ID while it is published. It lets
+// a TokenVerifier check the service's own tokens.
+func (r *KeyRing) Key(_ context.Context, kid string) (crypto.PublicKey, error) {
+	for _, key := range r.published() {
+		if key.kid == kid {
+			return &key.key.PublicKey, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// JWKS returns the published keys as a JWK Set (RFC 7517).
+func (r *KeyRing) JWKS() JWKSet {
+	published := r.published()
+	set := JWKSet{Keys: make([]JWK, 0, len(published))}
+	for _, key := range published {
+		set.Keys = append(set.Keys, publicJWK(key.kid, &key.key.PublicKey))
+	}
+	return set
+}
+
+func (r *KeyRing) published() []signingKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.pruned(time.Now())
+}
+
+// pruned returns the keys that are active or retired less than retention
+// ago. The caller holds the lock.
+func (r *KeyRing) pruned(now time.Time) []signingKey {
+	keys := make([]signingKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		if key.retiredAt.IsZero() || now.Sub(key.retiredAt) < r.retention {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// JWKSet is the JSON form of a JWK Set.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK is the JSON form of a P-256 public signing key.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func publicJWK(kid string, key *ecdsa.PublicKey) JWK {
+	return JWK{
+		Kty: "EC",
+		Kid: kid,
+		Use: "sig",
+		Alg: "ES256",
+		Crv: "P-256",
+		X:   encodeCoordinate(key.X.FillBytes(make([]byte, 32))),
+		Y:   encodeCoordinate(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+// Thumbprint returns the RFC 7638 thumbprint of a P-256 public key, used as
+// its key ID so every replica loading the same key names it the same way.
+func Thumbprint(key *ecdsa.PublicKey) string {
+	x := encodeCoordinate(key.X.FillBytes(make([]byte, 32)))
+	y := encodeCoordinate(key.Y.FillBytes(make([]byte, 32)))
+	sum := sha256.Sum256([]byte(`{"crv":"P-256","kty":"EC","x":"` + x + `","y":"` + y + `"}`))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func encodeCoordinate(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := GenerateSigningKey()
+	require.NoError(t, err)
+	return key
+}
+
+func TestKeyRing_Rotate(t *testing.T) {
+	first, second := newTestKey(t), newTestKey(t)
+
+	t.Run("Replaced key keeps verifying during retention", func(t *testing.T) {
+		ring := NewKeyRing(time.Hour, first)
+		ring.Rotate(second)
+
+		kid, key := ring.Signer()
+		assert.Equal(t, Thumbprint(&second.PublicKey), kid)
+		assert.Same(t, second, key)
+
+		old, err := ring.Key(context.Background(), Thumbprint(&first.PublicKey))
+		require.NoError(t, err)
+		assert.Equal(t, &first.PublicKey, old)
+		assert.Len(t, ring.JWKS().Keys, 2)
+	})
+
+	t.Run("Replaced key is dropped after retention", func(t *testing.T) {
+		ring := NewKeyRing(0, first)
+		ring.Rotate(second)
+
+		_, err := ring.Key(context.Background(), Thumbprint(&first.PublicKey))
+		assert.Error(t, err)
+		assert.Len(t, ring.JWKS().Keys, 1)
+	})
+
+	t.Run("Previous keys given at startup only verify", func(t *testing.T) {
+		ring := NewKeyRing(time.Hour, second, first)
+
+		kid, _ := ring.Signer()
+		assert.Equal(t, Thumbprint(&second.PublicKey), kid)
+		_, err := ring.Key(context.Background(), Thumbprint(&first.PublicKey))
+		assert.NoError(t, err)
+	})
+}
+
+func TestKeyRing_JWKS(t *testing.T) {
+	key := newTestKey(t)
+	ring := NewKeyRing(time.Hour, key)
+
+	data, err := json.Marshal(ring.JWKS())
+	require.NoError(t, err)
+
+	// Other services read the set with the same parser as an IdP's
+	keys, err := ParseJWKS(data)
+	require.NoError(t, err)
+	assert.Equal(t, &key.PublicKey, keys[Thumbprint(&key.PublicKey)])
+	assert.NotContains(t, string(data), `"d"`)
+}
+
+func TestLoadSigningKey(t *testing.T) {
+	key := newTestKey(t)
+	dir := t.TempDir()
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	sec1, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	for name, block := range map[string]*pem.Block{
+		"pkcs8.pem": {Type: "PRIVATE KEY", Bytes: pkcs8},
+		"sec1.pem":  {Type: "EC PRIVATE KEY", Bytes: sec1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), 0o600))
+
+			loaded, err := LoadSigningKey(path)
+
+			require.NoError(t, err)
+			assert.True(t, key.Equal(loaded))
+		})
+	}
+
+	t.Run("Not a PEM file", func(t *testing.T) {
+		path := filepath.Join(dir, "garbage.pem")
+		require.NoError(t, os.WriteFile(path, []byte("garbage"), 0o600))
+
+		_, err := LoadSigningKey(path)
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"customer-service/pkg/errors"
+	"slices"
+)
+
+// Scopes granted to callers of the customer API.
+const (
+	ScopeRead   = "customers:read"
+	ScopeCreate = "customers:create"
+	ScopeUpdate = "customers:update"
+	ScopeDelete = "customers:delete"
+	// ScopeReadPII allows reading unmasked CPF and email.
+	ScopeReadPII = "customers:pii:read"
+	// ScopeAdmin allows every operation, including back-office ones such as
+	// correcting a CPF.
+	ScopeAdmin = "customers:admin"
+	// ScopeSelf marks a customer's own token, whose subject is their ID.
+	ScopeSelf = "customers:self"
+	// ScopeIdentify allows a kiosk to exchange a customer's CPF for a token.
+	ScopeIdentify = "customers:identify"
+	// ScopeAnonymous marks a kiosk session whose customer did not identify
+	// themselves. It grants no operation of this service.
+	ScopeAnonymous = "customers:anonymous"
+	// ScopeIdentified marks a kiosk session whose customer typed in their
+	// CPF, whose subject is their ID. Anyone may type in a CPF, so it only
+	// lets other services attach orders and loyalty points to the customer
+	// and grants no operation of this service, unlike ScopeSelf.
+	ScopeIdentified = "customers:identified"
+)
+
+// Operation names something a caller can do with customers. Every use case
+// authorizes exactly one operation.
+type Operation string
+
+const (
+	OpCreateCustomer   Operation = "customer.create"
+	OpReadCustomer     Operation = "customer.read"
+	OpValidateCustomer Operation = "customer.validate"
+	OpUpdateCustomer   Operation = "customer.update"
+	OpConfirmEmail     Operation = "customer.confirm_email"
+	OpDeleteCustomer   Operation = "customer.delete"
+	OpCorrectCPF       Operation = "customer.correct_cpf"
+	OpIdentifyCustomer Operation = "customer.identify"
+	OpManageAPIKeys    Operation = "api_key.manage"
+	OpReadAccessLog    Operation = "pii_access.read"
+
+	// OpQueryGraphQL admits a caller to the GraphQL endpoint. Each query
+	// and mutation then authorizes its own operation.
+	OpQueryGraphQL Operation = "graphql.query"
+)
+
+// Rule lists the scopes that each allow an operation. Internal operations
+// may also run without a principal, in a deployment with authentication
+// turned off. Self operations may also be performed by a customer on their
+// own record.
+type Rule struct {
+	Scopes   []string
+	Internal bool
+	Self     bool
+}
+
+// Policy maps every operation to its rule. Operations missing from the
+// table are denied.
+var Policy = map[Operation]Rule{
+	OpCreateCustomer:   {Scopes: []string{ScopeCreate, ScopeAdmin}, Internal: true},
+	OpReadCustomer:     {Scopes: []string{ScopeRead, ScopeAdmin}, Internal: true, Self: true},
+	OpValidateCustomer: {Scopes: []string{ScopeCreate, ScopeUpdate, ScopeAdmin}, Internal: true},
+	OpUpdateCustomer:   {Scopes: []string{ScopeUpdate, ScopeAdmin}, Internal: true, Self: true},
+	OpConfirmEmail:     {Scopes: []string{ScopeUpdate, ScopeAdmin}, Internal: true, Self: true},
+	OpDeleteCustomer:   {Scopes: []string{ScopeDelete, ScopeAdmin}, Self: true},
+	OpCorrectCPF:       {Scopes: []string{ScopeAdmin}},
+	OpIdentifyCustomer: {Scopes: []string{ScopeIdentify, ScopeAdmin}},
+	OpManageAPIKeys:    {Scopes: []string{ScopeAdmin}},
+	OpReadAccessLog:    {Scopes: []string{ScopeAdmin}},
+	OpQueryGraphQL:     {Scopes: []string{ScopeRead, ScopeCreate, ScopeUpdate, ScopeDelete, ScopeAdmin}, Internal: true},
+}
+
+// GrantableScopes are the scopes an API key may carry. Customer and kiosk
+// session scopes are tied to a token's subject and are never granted to keys.
+var GrantableScopes = []string{ScopeRead, ScopeCreate, ScopeUpdate, ScopeDelete, ScopeReadPII, ScopeIdentify, ScopeAdmin}
+
+// Authorize reports whether the caller in ctx may perform op, returning a
+// 403 error when it may not.
+func Authorize(ctx context.Context, op Operation) error {
+	rule, ok := Policy[op]
+	if !ok {
+		return forbidden(op)
+	}
+
+	principal := FromContext(ctx)
+	if principal == nil {
+		if rule.Internal {
+			return nil
+		}
+		return forbidden(op)
+	}
+
+	if slices.ContainsFunc(rule.Scopes, principal.HasScope) {
+		return nil
+	}
+	return forbidden(op)
+}
+
+// AuthorizeCustomer is Authorize for an operation on the customer with the
+// given ID, which a customer holding ScopeSelf may also perform on their own
+// record when the rule allows it.
+func AuthorizeCustomer(ctx context.Context, op Operation, customerID string) error {
+	principal := FromContext(ctx)
+	if Policy[op].Self && principal.HasScope(ScopeSelf) && customerID != "" && principal.Subject == customerID {
+		return nil
+	}
+	return Authorize(ctx, op)
+}
+
+// CanReadPII reports whether the caller in ctx may see the CPF and email of
+// the customer with the given ID unmasked: callers holding ScopeReadPII, and
+// customers looking at their own record. Callers without a principal, like
+// those of internal operations, only exist with authentication turned off; masking depends on authentication, so they see every field as they
+// did before it existed.
+func CanReadPII(ctx context.Context, customerID string) bool {
+	principal := FromContext(ctx)
+	if principal == nil || principal.HasScope(ScopeReadPII) {
+		return true
+	}
+	return principal.HasScope(ScopeSelf) && customerID != "" && principal.Subject == customerID
+}
+
+func forbidden(op Operation) error {
+	err := errors.NewForbiddenError("You are not allowed to perform this operation", "FORBIDDEN")
+	err.Detail = "operation " + string(op)
+	return err
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"context"
+	"customer-service/pkg/errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorize(t *testing.T) {
+	kiosk := &Principal{Subject: "kiosk-42", Scopes: []string{ScopeCreate, ScopeRead}}
+	orderService := &Principal{Subject: "order-service", Scopes: []string{ScopeRead}}
+	admin := &Principal{Subject: "admin", Scopes: []string{ScopeAdmin}}
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		op        Operation
+		allowed   bool
+	}{
+		{name: "Kiosk creates", principal: kiosk, op: OpCreateCustomer, allowed: true},
+		{name: "Kiosk looks up", principal: kiosk, op: OpReadCustomer, allowed: true},
+		{name: "Kiosk validates", principal: kiosk, op: OpValidateCustomer, allowed: true},
+		{name: "Kiosk cannot update", principal: kiosk, op: OpUpdateCustomer, allowed: false},
+		{name: "Kiosk cannot delete", principal: kiosk, op: OpDeleteCustomer, allowed: false},
+		{name: "Order service reads", principal: orderService, op: OpReadCustomer, allowed: true},
+		{name: "Order service cannot create", principal: orderService, op: OpCreateCustomer, allowed: false},
+		{name: "Admin deletes", principal: admin, op: OpDeleteCustomer, allowed: true},
+		{name: "Admin corrects CPF", principal: admin, op: OpCorrectCPF, allowed: true},
+		{name: "Kiosk cannot correct CPF", principal: kiosk, op: OpCorrectCPF, allowed: false},
+		{name: "Internal call reads", principal: nil, op: OpReadCustomer, allowed: true},
+		{name: "Internal call cannot delete", principal: nil, op: OpDeleteCustomer, allowed: false},
+		{name: "Internal call cannot correct CPF", principal: nil, op: OpCorrectCPF, allowed: false},
+		{name: "Kiosk without identify scope cannot issue tokens", principal: kiosk, op: OpIdentifyCustomer, allowed: false},
+		{name: "Identifying kiosk issues tokens", principal: &Principal{Subject: "kiosk-7", Scopes: []string{ScopeIdentify}}, op: OpIdentifyCustomer, allowed: true},
+		{name: "Internal call cannot issue tokens", principal: nil, op: OpIdentifyCustomer, allowed: false},
+		{name: "Anonymous session has no operations", principal: &Principal{Subject: "anonymous:1", Scopes: []string{ScopeAnonymous}}, op: OpReadCustomer, allowed: false},
+		{name: "Order service queries GraphQL", principal: orderService, op: OpQueryGraphQL, allowed: true},
+		{name: "Identifying kiosk cannot query GraphQL", principal: &Principal{Subject: "kiosk-7", Scopes: []string{ScopeIdentify}}, op: OpQueryGraphQL, allowed: false},
+		{name: "Unknown operation", principal: admin, op: "customer.export", allowed: false},
+		{name: "Unknown operation without principal", principal: nil, op: "", allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = WithPrincipal(ctx, tt.principal)
+			}
+
+			err := Authorize(ctx, tt.op)
+
+			if tt.allowed {
+				assert.NoError(t, err)
+			} else {
+				appErr := errors.From(err)
+				assert.Equal(t, 403, appErr.StatusCode)
+				assert.Equal(t, "FORBIDDEN", appErr.Code)
+			}
+		})
+	}
+}
+
+func TestAuthorizeCustomer(t *testing.T) {
+	self := &Principal{Subject: "customer-1", Scopes: []string{ScopeSelf}}
+	admin := &Principal{Subject: "admin", Scopes: []string{ScopeAdmin}}
+
+	tests := []struct {
+		name       string
+		principal  *Principal
+		op         Operation
+		customerID string
+		allowed    bool
+	}{
+		{name: "Customer reads own record", principal: self, op: OpReadCustomer, customerID: "customer-1", allowed: true},
+		{name: "Customer updates own record", principal: self, op: OpUpdateCustomer, customerID: "customer-1", allowed: true},
+		{name: "Customer confirms own email", principal: self, op: OpConfirmEmail, customerID: "customer-1", allowed: true},
+		{name: "Customer deletes own record", principal: self, op: OpDeleteCustomer, customerID: "customer-1", allowed: true},
+		{name: "Customer cannot read someone else", principal: self, op: OpReadCustomer, customerID: "customer-2", allowed: false},
+		{name: "Customer cannot delete someone else", principal: self, op: OpDeleteCustomer, customerID: "customer-2", allowed: false},
+		{name: "Customer cannot correct own CPF", principal: self, op: OpCorrectCPF, customerID: "customer-1", allowed: false},
+		{name: "Subject without self scope", principal: &Principal{Subject: "customer-1"}, op: OpReadCustomer, customerID: "customer-1", allowed: false},
+		{name: "Empty ID never matches", principal: &Principal{Scopes: []string{ScopeSelf}}, op: OpReadCustomer, customerID: "", allowed: false},
+		{name: "Admin falls back to scopes", principal: admin, op: OpDeleteCustomer, customerID: "customer-2", allowed: true},
+		{name: "Internal call falls back to rule", principal: nil, op: OpUpdateCustomer, customerID: "customer-1", allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = WithPrincipal(ctx, tt.principal)
+			}
+
+			err := AuthorizeCustomer(ctx, tt.op, tt.customerID)
+
+			if tt.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, 403, errors.From(err).StatusCode)
+			}
+		})
+	}
+}
+
+func TestCanReadPII(t *testing.T) {
+	self := &Principal{Subject: "customer-1", Scopes: []string{ScopeSelf}}
+
+	tests := []struct {
+		name       string
+		principal  *Principal
+		customerID string
+		allowed    bool
+	}{
+		{name: "PII scope", principal: &Principal{Subject: "backoffice", Scopes: []string{ScopeReadPII}}, customerID: "customer-1", allowed: true},
+		{name: "Read scope only", principal: &Principal{Subject: "kiosk", Scopes: []string{ScopeRead}}, customerID: "customer-1", allowed: false},
+		{name: "Customer reading own record", principal: self, customerID: "customer-1", allowed: true},
+		{name: "Customer reading someone else", principal: self, customerID: "customer-2", allowed: false},
+		{name: "Identified kiosk session", principal: &Principal{Subject: "customer-1", Scopes: []string{ScopeIdentified}}, customerID: "customer-1", allowed: false},
+		{name: "Authentication turned off", principal: nil, customerID: "customer-1", allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = WithPrincipal(ctx, tt.principal)
+			}
+
+			assert.Equal(t, tt.allowed, CanReadPII(ctx, tt.customerID))
+		})
+	}
+}
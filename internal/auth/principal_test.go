@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrincipal_HasScope(t *testing.T) {
+	principal := &Principal{Subject: "backoffice", Scopes: []string{ScopeReadPII}}
+
+	assert.True(t, principal.HasScope(ScopeReadPII))
+	assert.False(t, principal.HasScope("customers:write"))
+
+	var anonymous *Principal
+	assert.False(t, anonymous.HasScope(ScopeReadPII))
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Nil(t, FromContext(context.Background()))
+
+	principal := &Principal{Subject: "backoffice"}
+	ctx := WithPrincipal(context.Background(), principal)
+
+	assert.Same(t, principal, FromContext(ctx))
+}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"customer-service/pkg/errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// clockSkew tolerates small clock differences with the token issuer.
+const clockSkew = 30 * time.Second
+
+// Claims are the access token claims the service reads. Scopes come either
+// as the space-separated OAuth "scope" claim or as an "scp" array.
+type Claims struct {
+	jwt.RegisteredClaims
+	Scope string   `json:"scope,omitempty"`
+	Scp   []string `json:"scp,omitempty"`
+}
+
+// Verifier turns an access token into the caller it identifies.
+type Verifier interface {
+	Verify(ctx context.Context, token string) (*Principal, error)
+}
+
+// KeySource looks up a token's verification key by key ID. Both a JWKS
+// fetched from an identity provider and the service's own KeyRing are key
+// sources.
+type KeySource interface {
+	Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+// TokenVerifier validates RS256 and ES256 access tokens signed by a key of
+// the key source and issued by issuer for audience.
+type TokenVerifier struct {
+	keys     KeySource
+	issuer   string
+	audience string
+}
+
+func NewTokenVerifier(keys KeySource, issuer, audience string) *TokenVerifier {
+	return &TokenVerifier{keys: keys, issuer: issuer, audience: audience}
+}
+
+// Verify checks the token's signature, issuer, audience and expiry and
+// returns the caller it identifies.
+func (v *TokenVerifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims,
+		func(t *jwt.Token) (interface{}, error) {
+			kid, _ := t.Header["kid"].(string)
+			return v.keys.Key(ctx, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256", "ES256"}),
+		jwt.WithIssuer(v.issuer),
+		jwt.WithAudience(v.audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(clockSkew),
+	)
+	if err != nil {
+		appErr := errors.NewUnauthorizedError("Invalid access token", "INVALID_TOKEN")
+		appErr.Cause = err
+		return nil, appErr
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.NewUnauthorizedError("Invalid access token", "INVALID_TOKEN")
+	}
+
+	scopes := claims.Scp
+	if claims.Scope != "" {
+		scopes = append(scopes, strings.Fields(claims.Scope)...)
+	}
+	return &Principal{Subject: claims.Subject, Scopes: scopes}, nil
+}
+
+// AnyVerifier accepts a token any of verifiers accepts, such as tokens of an
+// identity provider and the service's own. It fails with the last error.
+func AnyVerifier(verifiers ...Verifier) Verifier {
+	return anyVerifier(verifiers)
+}
+
+type anyVerifier []Verifier
+
+func (v anyVerifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	err := errors.NewUnauthorizedError("Invalid access token", "INVALID_TOKEN")
+	for _, verifier := range v {
+		principal, verr := verifier.Verify(ctx, token)
+		if verr == nil {
+			return principal, nil
+		}
+		err = errors.From(verr)
+	}
+	return nil, err
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"customer-service/pkg/errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testIssuer   = "https://auth.example.com"
+	testAudience = "customer-service"
+)
+
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, key crypto.Signer, claims jwt.Claims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func validClaims() Claims {
+	return Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "order-service",
+			Issuer:    testIssuer,
+			Audience:  jwt.ClaimStrings{testAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Scope: "customers:read " + ScopeReadPII,
+	}
+}
+
+func TestTokenVerifier_Verify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	verifier := NewTokenVerifier(NewJWKS(map[string]crypto.PublicKey{
+		"rsa-1": &rsaKey.PublicKey,
+		"ec-1":  &ecKey.PublicKey,
+	}), testIssuer, testAudience)
+
+	t.Run("RS256", func(t *testing.T) {
+		token := signToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, validClaims())
+
+		principal, err := verifier.Verify(context.Background(), token)
+
+		require.NoError(t, err)
+		assert.Equal(t, "order-service", principal.Subject)
+		assert.Equal(t, []string{"customers:read", ScopeReadPII}, principal.Scopes)
+	})
+
+	t.Run("ES256 with scp array", func(t *testing.T) {
+		claims := validClaims()
+		claims.Scope = ""
+		claims.Scp = []string{ScopeAdmin}
+		token := signToken(t, jwt.SigningMethodES256, "ec-1", ecKey, claims)
+
+		principal, err := verifier.Verify(context.Background(), token)
+
+		require.NoError(t, err)
+		assert.True(t, principal.HasScope(ScopeAdmin))
+	})
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	rejected := []struct {
+		name  string
+		token func() string
+	}{
+		{name: "Expired", token: func() string {
+			claims := validClaims()
+			claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+			return signToken(t, jwt.SigningMethodES256, "ec-1", ecKey, claims)
+		}},
+		{name: "Without expiry", token: func() string {
+			claims := validClaims()
+			claims.ExpiresAt = nil
+			return signToken(t, jwt.SigningMethodES256, "ec-1", ecKey, claims)
+		}},
+		{name: "Wrong issuer", token: func() string {
+			claims := validClaims()
+			claims.Issuer = "https://evil.example.com"
+			return signToken(t, jwt.SigningMethodES256, "ec-1", ecKey, claims)
+		}},
+		{name: "Wrong audience", token: func() string {
+			claims := validClaims()
+			claims.Audience = jwt.ClaimStrings{"another-service"}
+			return signToken(t, jwt.SigningMethodES256, "ec-1", ecKey, claims)
+		}},
+		{name: "Without subject", token: func() string {
+			claims := validClaims()
+			claims.Subject = ""
+			return signToken(t, jwt.SigningMethodES256, "ec-1", ecKey, claims)
+		}},
+		{name: "Signed by an unknown key", token: func() string {
+			return signToken(t, jwt.SigningMethodES256, "ec-1", otherKey, validClaims())
+		}},
+		{name: "Unknown key ID", token: func() string {
+			return signToken(t, jwt.SigningMethodES256, "ec-2", ecKey, validClaims())
+		}},
+		{name: "Key of another type", token: func() string {
+			return signToken(t, jwt.SigningMethodES256, "rsa-1", ecKey, validClaims())
+		}},
+		{name: "Algorithm not allowed", token: func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims())
+			token.Header["kid"] = "ec-1"
+			signed, err := token.SignedString([]byte("secret"))
+			require.NoError(t, err)
+			return signed
+		}},
+		{name: "Malformed", token: func() string { return "not-a-token" }},
+	}
+
+	for _, tt := range rejected {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := verifier.Verify(context.Background(), tt.token())
+
+			assert.Nil(t, principal)
+			appErr := errors.From(err)
+			assert.Equal(t, 401, appErr.StatusCode)
+			assert.Equal(t, "INVALID_TOKEN", appErr.Code)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"customer-service/pkg/errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAPIKey(t *testing.T) {
+	t.Run("Valid key", func(t *testing.T) {
+		expiresAt := time.Now().Add(90 * 24 * time.Hour)
+
+		key, secret, err := NewAPIKey("  Nightly export  ", []string{"customers:read"}, expiresAt, "admin")
+
+		require.NoError(t, err)
+		assert.Equal(t, "Nightly export", key.Name)
+		assert.Equal(t, "admin", key.CreatedBy)
+		assert.True(t, strings.HasPrefix(secret, "csk_"+key.ID+"."))
+		assert.NotContains(t, key.SecretHash, secret)
+		assert.NotEmpty(t, key.Salt)
+		assert.True(t, key.Active(time.Now()))
+	})
+
+	t.Run("Every invalid field is reported", func(t *testing.T) {
+		_, _, err := NewAPIKey("", nil, time.Now().Add(-time.Hour), "admin")
+
+		appErr := errors.From(err)
+		assert.Equal(t, 400, appErr.StatusCode)
+		codes := make([]string, 0, len(appErr.Fields))
+		for _, field := range appErr.Fields {
+			codes = append(codes, field.Code)
+		}
+		assert.Equal(t, []string{"NAME_EMPTY", "SCOPES_EMPTY", "INVALID_EXPIRY"}, codes)
+	})
+
+	t.Run("Equal secrets hash differently", func(t *testing.T) {
+		assert.NotEqual(t, hashAPIKeySecret("salt-1", "secret"), hashAPIKeySecret("salt-2", "secret"))
+	})
+}
+
+func TestAPIKey_Matches(t *testing.T) {
+	key, apiKey, err := NewAPIKey("partner", []string{"customers:read"}, time.Now().Add(time.Hour), "")
+	require.NoError(t, err)
+
+	id, secret, ok := ParseAPIKey(apiKey)
+	require.True(t, ok)
+	assert.Equal(t, key.ID, id)
+	assert.True(t, key.Matches(secret))
+	assert.False(t, key.Matches(secret+"x"))
+}
+
+func TestParseAPIKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		apiKey string
+		ok     bool
+	}{
+		{name: "Well formed", apiKey: "csk_123.secret", ok: true},
+		{name: "Missing prefix", apiKey: "123.secret", ok: false},
+		{name: "Missing secret", apiKey: "csk_123.", ok: false},
+		{name: "Missing ID", apiKey: "csk_.secret", ok: false},
+		{name: "Missing separator", apiKey: "csk_123secret", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, ok := ParseAPIKey(tt.apiKey)
+			assert.Equal(t, tt.ok, ok)
+		})
+	}
+}
+
+func TestAPIKey_Active(t *testing.T) {
+	now := time.Now()
+	revokedAt := now.Add(-time.Minute)
+
+	assert.True(t, (&APIKey{ExpiresAt: now.Add(time.Hour)}).Active(now))
+	assert.False(t, (&APIKey{ExpiresAt: now}).Active(now))
+	assert.False(t, (&APIKey{ExpiresAt: now.Add(time.Hour), RevokedAt: &revokedAt}).Active(now))
+}
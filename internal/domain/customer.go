@@ -1,14 +1,24 @@
 package domain
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"customer-service/pkg/errors"
+	"customer-service/pkg/shortcode"
 	"customer-service/pkg/validator"
+	"encoding/base64"
+	"encoding/hex"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// EmailVerificationTTL is how long the token sent to a pending email stays
+// valid.
+const EmailVerificationTTL = 24 * time.Hour
+
 type Customer struct {
 	ID        string    `json:"id" bson:"_id"`
 	Name      string    `json:"name" bson:"name"`
@@ -16,53 +26,251 @@ type Customer struct {
 	Email     string    `json:"email" bson:"email"`
 	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+
+	// Code is a short public identifier that can be read aloud, e.g. at the
+	// pickup counter. Customers created before codes existed have none.
+	Code string `json:"code,omitempty" bson:"code,omitempty"`
+
+	// PendingEmail replaces Email once the customer proves they own it
+	PendingEmail      string             `json:"pendingEmail,omitempty" bson:"pendingEmail,omitempty"`
+	EmailVerification *EmailVerification `json:"-" bson:"emailVerification,omitempty"`
+
+	// CPFHistory lists every CPF correction, oldest first
+	CPFHistory []CPFChange `json:"cpfHistory,omitempty" bson:"cpfHistory,omitempty"`
+
+	// Anonymized is set on customers whose personal data was erased. Only
+	// their ID, code and timestamps are left.
+	Anonymized bool `json:"anonymized,omitempty" bson:"-"`
 }
 
+// CPFChange records a CPF that was replaced by an administrator.
+type CPFChange struct {
+	PreviousCPF string    `json:"previousCpf" bson:"previousCpf"`
+	ChangedBy   string    `json:"changedBy" bson:"changedBy"`
+	Reason      string    `json:"reason" bson:"reason"`
+	ChangedAt   time.Time `json:"changedAt" bson:"changedAt"`
+}
+
+// EmailVerification holds a hash of the token sent to the pending email. The
+// token itself is never stored.
+type EmailVerification struct {
+	TokenHash string    `bson:"tokenHash"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+// CustomerVersion identifies a revision of a customer. It is cheap to load
+// and is enough to answer conditional requests.
+type CustomerVersion struct {
+	ID        string    `bson:"_id"`
+	UpdatedAt time.Time `bson:"updatedAt"`
+}
+
+var (
+	errNameEmpty    = errors.FieldError{Pointer: "/name", Code: "NAME_EMPTY", Message: "Name cannot be empty"}
+	errInvalidCPF   = errors.FieldError{Pointer: "/cpf", Code: "INVALID_CPF", Message: "Invalid CPF"}
+	errInvalidEmail = errors.FieldError{Pointer: "/email", Code: "INVALID_EMAIL", Message: "Invalid Email"}
+	errCPFUnchanged = errors.FieldError{Pointer: "/cpf", Code: "CPF_UNCHANGED", Message: "CPF is already the current one"}
+	errReasonEmpty  = errors.FieldError{Pointer: "/reason", Code: "REASON_EMPTY", Message: "Reason cannot be empty"}
+)
+
+// CPF and email are unique among customers. These report which one collided.
+var (
+	ErrCPFAlreadyExists   = errors.FieldError{Pointer: "/cpf", Code: "CPF_ALREADY_EXISTS", Message: "CPF is already registered"}
+	ErrEmailAlreadyExists = errors.FieldError{Pointer: "/email", Code: "EMAIL_ALREADY_EXISTS", Message: "Email is already registered"}
+)
+
+// NewCustomer validates every field before failing so that all mistakes are
+// reported in a single response.
 func NewCustomer(name, cpf, email string) (*Customer, error) {
+	var fields []errors.FieldError
+
 	// Validate name
 	if strings.TrimSpace(name) == "" {
-		return nil, errors.NewValidationError("Name cannot be empty", "NAME_EMPTY")
+		fields = append(fields, errNameEmpty)
 	}
 
 	// Validate and clean CPF
 	cleanCPF := validator.CleanCPF(cpf)
 	if !validator.IsValidCPF(cleanCPF) {
-		return nil, errors.NewValidationError("Invalid CPF", "INVALID_CPF")
+		fields = append(fields, errInvalidCPF)
 	}
 
 	// Validate email
 	cleanEmail := strings.ToLower(strings.TrimSpace(email))
 	if !validator.IsValidEmail(cleanEmail) {
-		return nil, errors.NewValidationError("Invalid Email", "INVALID_EMAIL")
+		fields = append(fields, errInvalidEmail)
+	}
+
+	if len(fields) > 0 {
+		return nil, errors.NewFieldValidationError(fields...)
 	}
 
 	now := time.Now()
-	return &Customer{
+	customer := &Customer{
 		ID:        uuid.New().String(),
 		Name:      name,
 		CPF:       cleanCPF,
 		Email:     cleanEmail,
 		CreatedAt: now,
 		UpdatedAt: now,
-	}, nil
+	}
+	if err := customer.RegenerateCode(); err != nil {
+		return nil, err
+	}
+	return customer, nil
+}
+
+// RegenerateCode draws a new random Code. Uniqueness is only enforced when
+// the customer is stored, so creation retries with a new code on collision.
+func (c *Customer) RegenerateCode() error {
+	code, err := shortcode.Generate()
+	if err != nil {
+		return errors.WrapError(err, "Failed to generate customer code")
+	}
+	c.Code = code
+	return nil
 }
 
+// Update changes the name right away. A different email only becomes the
+// PendingEmail, so that nobody can take over the customer's contact address
+// by knowing their ID; asking for the current email cancels a pending change.
+// Either way any previous verification token stops working.
 func (c *Customer) Update(name, email *string) error {
-	if name != nil {
-		if strings.TrimSpace(*name) == "" {
-			return errors.NewValidationError("Name cannot be empty", "NAME_EMPTY")
-		}
-		c.Name = *name
+	var fields []errors.FieldError
+
+	if name != nil && strings.TrimSpace(*name) == "" {
+		fields = append(fields, errNameEmpty)
 	}
 
+	var cleanEmail string
 	if email != nil {
-		cleanEmail := strings.ToLower(strings.TrimSpace(*email))
+		cleanEmail = strings.ToLower(strings.TrimSpace(*email))
 		if !validator.IsValidEmail(cleanEmail) {
-			return errors.NewValidationError("Invalid Email", "INVALID_EMAIL")
+			fields = append(fields, errInvalidEmail)
 		}
-		c.Email = cleanEmail
+	}
+
+	// Leave the customer untouched unless every field is valid
+	if len(fields) > 0 {
+		return errors.NewFieldValidationError(fields...)
+	}
+
+	if name != nil {
+		c.Name = *name
+	}
+	if email != nil {
+		c.PendingEmail = cleanEmail
+		if cleanEmail == c.Email {
+			c.PendingEmail = ""
+		}
+		c.EmailVerification = nil
 	}
 
 	c.UpdatedAt = time.Now()
 	return nil
 }
+
+// IssueEmailVerification starts verification of the pending email and
+// returns the token to send to it. Any earlier token is replaced.
+func (c *Customer) IssueEmailVerification() (string, error) {
+	if c.PendingEmail == "" {
+		return "", errors.NewValidationError("No email change is pending", "NO_PENDING_EMAIL")
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", errors.WrapError(err, "Failed to generate email verification token")
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	c.EmailVerification = &EmailVerification{
+		TokenHash: hashEmailToken(token),
+		ExpiresAt: time.Now().Add(EmailVerificationTTL),
+	}
+	return token, nil
+}
+
+// ConfirmEmail promotes the pending email when token matches the one issued
+// for it and has not expired.
+func (c *Customer) ConfirmEmail(token string) error {
+	if c.PendingEmail == "" || c.EmailVerification == nil {
+		return errors.NewValidationError("No email change is pending", "NO_PENDING_EMAIL")
+	}
+	if subtle.ConstantTimeCompare([]byte(hashEmailToken(token)), []byte(c.EmailVerification.TokenHash)) != 1 {
+		return errors.NewValidationError("Invalid email verification token", "INVALID_EMAIL_TOKEN")
+	}
+	if time.Now().After(c.EmailVerification.ExpiresAt) {
+		return errors.NewValidationError("Email verification token has expired", "EMAIL_TOKEN_EXPIRED")
+	}
+
+	c.Email = c.PendingEmail
+	c.PendingEmail = ""
+	c.EmailVerification = nil
+	c.UpdatedAt = time.Now()
+	return nil
+}
+
+func hashEmailToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CorrectCPF replaces a mistyped CPF and records the previous one. The CPF
+// is otherwise immutable, so this is only offered to administrators.
+func (c *Customer) CorrectCPF(cpf, reason, changedBy string) error {
+	var fields []errors.FieldError
+
+	cleanCPF := validator.CleanCPF(cpf)
+	if !validator.IsValidCPF(cleanCPF) {
+		fields = append(fields, errInvalidCPF)
+	} else if cleanCPF == c.CPF {
+		fields = append(fields, errCPFUnchanged)
+	}
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		fields = append(fields, errReasonEmpty)
+	}
+
+	if len(fields) > 0 {
+		return errors.NewFieldValidationError(fields...)
+	}
+
+	now := time.Now()
+	c.CPFHistory = append(c.CPFHistory, CPFChange{
+		PreviousCPF: c.CPF,
+		ChangedBy:   changedBy,
+		Reason:      reason,
+		ChangedAt:   now,
+	})
+	c.CPF = cleanCPF
+	c.UpdatedAt = now
+	return nil
+}
+
+// Masked returns a copy of the customer with the CPF, the emails and the
+// previous CPFs masked, for callers that may not read personal data. Empty
+// values, such as those of an anonymized customer, stay empty.
+func (c *Customer) Masked() *Customer {
+	masked := *c
+	masked.CPF = maskNonEmpty(c.CPF, validator.MaskCPF)
+	masked.Email = maskNonEmpty(c.Email, validator.MaskEmail)
+	masked.PendingEmail = maskNonEmpty(c.PendingEmail, validator.MaskEmail)
+	masked.CPFHistory = nil
+	for _, change := range c.CPFHistory {
+		change.PreviousCPF = validator.MaskCPF(change.PreviousCPF)
+		masked.CPFHistory = append(masked.CPFHistory, change)
+	}
+	return &masked
+}
+
+func maskNonEmpty(value string, mask func(string) string) string {
+	if value == "" {
+		return ""
+	}
+	return mask(value)
+}
+
+func (c *Customer) Version() CustomerVersion {
+	return CustomerVersion{ID: c.ID, UpdatedAt: c.UpdatedAt}
+}
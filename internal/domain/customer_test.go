@@ -1,7 +1,10 @@
 package domain
 
 import (
+	"customer-service/pkg/errors"
+	"customer-service/pkg/shortcode"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -60,6 +63,7 @@ func TestNewCustomer(t *testing.T) {
 				assert.NotNil(t, customer)
 				assert.Equal(t, tt.customerName, customer.Name)
 				assert.NotEmpty(t, customer.ID)
+				assert.Len(t, customer.Code, shortcode.Length)
 				assert.NotZero(t, customer.CreatedAt)
 				assert.NotZero(t, customer.UpdatedAt)
 			}
@@ -67,6 +71,40 @@ func TestNewCustomer(t *testing.T) {
 	}
 }
 
+func TestCustomer_RegenerateCode(t *testing.T) {
+	customer, _ := NewCustomer("John Doe", "11144477735", "john@example.com")
+	previous := customer.Code
+
+	assert.NoError(t, customer.RegenerateCode())
+	assert.NotEqual(t, previous, customer.Code)
+	_, ok := shortcode.Normalize(customer.Code)
+	assert.True(t, ok)
+}
+
+func TestNewCustomer_CollectsAllFieldErrors(t *testing.T) {
+	customer, err := NewCustomer(" ", "12345678901", "invalid-email")
+
+	assert.Nil(t, customer)
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, "NAME_EMPTY", appErr.Code)
+	assert.Equal(t, []errors.FieldError{
+		{Pointer: "/name", Code: "NAME_EMPTY", Message: "Name cannot be empty"},
+		{Pointer: "/cpf", Code: "INVALID_CPF", Message: "Invalid CPF"},
+		{Pointer: "/email", Code: "INVALID_EMAIL", Message: "Invalid Email"},
+	}, appErr.Fields)
+}
+
+func TestCustomer_UpdateIsAtomic(t *testing.T) {
+	customer, _ := NewCustomer("John Doe", "11144477735", "john@example.com")
+
+	err := customer.Update(stringPtr("Jane Doe"), stringPtr("invalid"))
+
+	assert.Error(t, err)
+	assert.Equal(t, "John Doe", customer.Name)
+	assert.Equal(t, "john@example.com", customer.Email)
+}
+
 func TestCustomer_Update(t *testing.T) {
 	customer, _ := NewCustomer("John Doe", "11144477735", "john@example.com")
 	initialUpdatedAt := customer.UpdatedAt
@@ -123,13 +161,119 @@ func TestCustomer_Update(t *testing.T) {
 					assert.Equal(t, *tt.newName, customer.Name)
 				}
 				if tt.newEmail != nil {
-					assert.Equal(t, *tt.newEmail, customer.Email)
+					assert.Equal(t, "john@example.com", customer.Email)
+					assert.Equal(t, *tt.newEmail, customer.PendingEmail)
 				}
 			}
 		})
 	}
 }
 
+func TestCustomer_EmailChange(t *testing.T) {
+	t.Run("Confirming with the issued token promotes the pending email", func(t *testing.T) {
+		customer, _ := NewCustomer("John Doe", "11144477735", "john@example.com")
+		assert.NoError(t, customer.Update(nil, stringPtr(" Jane@Example.com ")))
+
+		token, err := customer.IssueEmailVerification()
+		assert.NoError(t, err)
+		assert.NotEqual(t, token, customer.EmailVerification.TokenHash)
+
+		assert.NoError(t, customer.ConfirmEmail(token))
+		assert.Equal(t, "jane@example.com", customer.Email)
+		assert.Empty(t, customer.PendingEmail)
+		assert.Nil(t, customer.EmailVerification)
+	})
+
+	t.Run("Requesting the current email cancels the change", func(t *testing.T) {
+		customer, _ := NewCustomer("John Doe", "11144477735", "john@example.com")
+		assert.NoError(t, customer.Update(nil, stringPtr("jane@example.com")))
+		_, _ = customer.IssueEmailVerification()
+
+		assert.NoError(t, customer.Update(nil, stringPtr("john@example.com")))
+		assert.Empty(t, customer.PendingEmail)
+		assert.Nil(t, customer.EmailVerification)
+	})
+
+	t.Run("A new request invalidates the previous token", func(t *testing.T) {
+		customer, _ := NewCustomer("John Doe", "11144477735", "john@example.com")
+		assert.NoError(t, customer.Update(nil, stringPtr("jane@example.com")))
+		first, _ := customer.IssueEmailVerification()
+		_, _ = customer.IssueEmailVerification()
+
+		err := customer.ConfirmEmail(first)
+		assert.Equal(t, "INVALID_EMAIL_TOKEN", err.(*errors.AppError).Code)
+		assert.Equal(t, "john@example.com", customer.Email)
+	})
+
+	t.Run("Expired token", func(t *testing.T) {
+		customer, _ := NewCustomer("John Doe", "11144477735", "john@example.com")
+		assert.NoError(t, customer.Update(nil, stringPtr("jane@example.com")))
+		token, _ := customer.IssueEmailVerification()
+		customer.EmailVerification.ExpiresAt = time.Now().Add(-time.Minute)
+
+		err := customer.ConfirmEmail(token)
+		assert.Equal(t, "EMAIL_TOKEN_EXPIRED", err.(*errors.AppError).Code)
+		assert.Equal(t, "john@example.com", customer.Email)
+	})
+
+	t.Run("Nothing pending", func(t *testing.T) {
+		customer, _ := NewCustomer("John Doe", "11144477735", "john@example.com")
+
+		_, err := customer.IssueEmailVerification()
+		assert.Equal(t, "NO_PENDING_EMAIL", err.(*errors.AppError).Code)
+		err = customer.ConfirmEmail("token")
+		assert.Equal(t, "NO_PENDING_EMAIL", err.(*errors.AppError).Code)
+	})
+}
+
+func TestCustomer_CorrectCPF(t *testing.T) {
+	t.Run("Keeps the previous CPF in the history", func(t *testing.T) {
+		customer, _ := NewCustomer("John Doe", "11144477735", "john@example.com")
+
+		assert.NoError(t, customer.CorrectCPF("529.982.247-25", " Typo at registration ", "admin"))
+		assert.NoError(t, customer.CorrectCPF("86288366757", "Second typo", "other-admin"))
+
+		assert.Equal(t, "86288366757", customer.CPF)
+		assert.Len(t, customer.CPFHistory, 2)
+		assert.Equal(t, "11144477735", customer.CPFHistory[0].PreviousCPF)
+		assert.Equal(t, "admin", customer.CPFHistory[0].ChangedBy)
+		assert.Equal(t, "Typo at registration", customer.CPFHistory[0].Reason)
+		assert.Equal(t, "52998224725", customer.CPFHistory[1].PreviousCPF)
+		assert.Equal(t, customer.UpdatedAt, customer.CPFHistory[1].ChangedAt)
+	})
+
+	t.Run("Rejects invalid corrections", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			cpf    string
+			reason string
+			codes  []string
+		}{
+			{name: "Invalid CPF", cpf: "12345678900", reason: "Typo", codes: []string{"INVALID_CPF"}},
+			{name: "Same CPF", cpf: "111.444.777-35", reason: "Typo", codes: []string{"CPF_UNCHANGED"}},
+			{name: "Missing reason", cpf: "52998224725", reason: "  ", codes: []string{"REASON_EMPTY"}},
+			{name: "Everything wrong", cpf: "123", reason: "", codes: []string{"INVALID_CPF", "REASON_EMPTY"}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				customer, _ := NewCustomer("John Doe", "11144477735", "john@example.com")
+
+				err := customer.CorrectCPF(tt.cpf, tt.reason, "admin")
+
+				appErr := err.(*errors.AppError)
+				codes := make([]string, 0, len(appErr.Fields))
+				for _, field := range appErr.Fields {
+					codes = append(codes, field.Code)
+				}
+				assert.Equal(t, tt.codes, codes)
+				assert.Equal(t, "11144477735", customer.CPF)
+				assert.Empty(t, customer.CPFHistory)
+			})
+		}
+	})
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
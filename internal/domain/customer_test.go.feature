@@ -62,7 +62,8 @@ Funcionalidade: Gerenciamento de Cliente
       | email    | john@example.com    |
     Quando eu atualizar o email para "jane@example.com"
     Então a atualização deve ser bem-sucedida
-    E o email do cliente deve ser "jane@example.com"
+    E o email do cliente deve continuar "john@example.com"
+    E o email pendente do cliente deve ser "jane@example.com"
     E a data de atualização deve ser posterior à data inicial
 
   Cenário: Atualizar nome e email do cliente
@@ -75,7 +76,7 @@ Funcionalidade: Gerenciamento de Cliente
       | email    | jane.smith@example.com  |
     Então a atualização deve ser bem-sucedida
     E o nome do cliente deve ser "Jane Smith"
-    E o email do cliente deve ser "jane.smith@example.com"
+    E o email pendente do cliente deve ser "jane.smith@example.com"
     E a data de atualização deve ser posterior à data inicial
 
   Cenário: Tentar atualizar cliente com nome vazio
@@ -95,3 +96,18 @@ Funcionalidade: Gerenciamento de Cliente
     Quando eu atualizar o email para "invalid"
     Então deve retornar um erro
     E o email do cliente não deve ser alterado
+
+  Cenário: Confirmar a troca de email com o token enviado
+    Dado que existe um cliente com o email "john@example.com"
+    E que foi solicitada a troca do email para "jane@example.com"
+    Quando eu confirmar a troca com o token enviado para "jane@example.com"
+    Então o email do cliente deve ser "jane@example.com"
+    E o cliente não deve ter email pendente
+
+  Cenário: Tentar confirmar a troca de email com um token expirado
+    Dado que existe um cliente com o email "john@example.com"
+    E que foi solicitada a troca do email para "jane@example.com" há mais de 24 horas
+    Quando eu confirmar a troca com o token enviado
+    Então deve retornar um erro
+    E o código do erro deve ser "EMAIL_TOKEN_EXPIRED"
+    E o email do cliente deve continuar "john@example.com"
@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// IdempotencyRecord stores the outcome of a request sent with an
+// Idempotency-Key header so that retries can be answered with the same response.
+// Only the status and the ID of the customer created are kept, never the
+// response body with its personal data: retries render the customer again.
+type IdempotencyRecord struct {
+	Key         string    `bson:"_id"`
+	Fingerprint string    `bson:"fingerprint"`
+	StatusCode  int       `bson:"statusCode"`
+	CustomerID  string    `bson:"customerId,omitempty"`
+	CreatedAt   time.Time `bson:"createdAt"`
+}
+
+// Completed reports whether the original request has already produced a response.
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
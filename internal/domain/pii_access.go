@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Personal data fields recorded in the PII access log.
+const (
+	PIIFieldCPF          = "cpf"
+	PIIFieldEmail        = "email"
+	PIIFieldPendingEmail = "pendingEmail"
+	PIIFieldCPFHistory   = "cpfHistory"
+)
+
+// PIIFields are the fields of a customer representation whose unmasked value
+// is logged when read. PIIFieldCPFHistory is left out: only CPF corrections
+// show it, and they declare it themselves.
+var PIIFields = []string{PIIFieldCPF, PIIFieldEmail, PIIFieldPendingEmail}
+
+// PIIAccess records that an actor was shown a customer's personal data
+// unmasked, for LGPD audits.
+type PIIAccess struct {
+	ID         string    `bson:"_id"`
+	Actor      string    `bson:"actor"`
+	Purpose    string    `bson:"purpose,omitempty"`
+	CustomerID string    `bson:"customerId"`
+	Fields     []string  `bson:"fields"`
+	AccessedAt time.Time `bson:"accessedAt"`
+}
+
+func NewPIIAccess(actor, purpose, customerID string, fields []string, accessedAt time.Time) *PIIAccess {
+	return &PIIAccess{
+		ID:         uuid.New().String(),
+		Actor:      actor,
+		Purpose:    purpose,
+		CustomerID: customerID,
+		Fields:     fields,
+		AccessedAt: accessedAt,
+	}
+}
@@ -0,0 +1,69 @@
+package graphqlhandler
+
+import (
+	"customer-service/internal/handler"
+	"customer-service/internal/usecase"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+)
+
+// Request is the standard GraphQL-over-HTTP request body.
+type Request struct {
+	Query         string                 `json:"query" binding:"required"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+type Handler struct {
+	schema       graphql.Schema
+	batchUseCase *usecase.BatchGetCustomersUseCase
+}
+
+func NewHandler(
+	createUC *usecase.CreateCustomerUseCase,
+	updateUC *usecase.UpdateCustomerUseCase,
+	deleteUC *usecase.DeleteCustomerUseCase,
+	batchUC *usecase.BatchGetCustomersUseCase,
+) (*Handler, error) {
+	schema, err := NewSchema(createUC, updateUC, deleteUC)
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{
+		schema:       schema,
+		batchUseCase: batchUC,
+	}, nil
+}
+
+// Handle executes a GraphQL operation
+// @Summary Execute a GraphQL operation
+// @Description Executes queries and mutations over customers. Lookups within one request are batched.
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Param request body Request true "GraphQL request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /graphql [post]
+func (h *Handler) Handle(c *gin.Context) {
+	var req Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handler.HandleBindingError(c, &req, err)
+		return
+	}
+
+	// Loaders are scoped to the request so cached results never leak between callers
+	ctx := withLoaders(c.Request.Context(), newLoaders(h.batchUseCase))
+
+	result := graphql.Do(graphql.Params{
+		Schema:         h.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        ctx,
+	})
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,445 @@
+package graphqlhandler
+
+import (
+	"bytes"
+	"context"
+	"customer-service/internal/abuse"
+	"customer-service/internal/audit"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/handler"
+	"customer-service/internal/mailer"
+	"customer-service/internal/usecase"
+	"customer-service/pkg/errors"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Create(ctx context.Context, customer *domain.Customer) error {
+	args := m.Called(ctx, customer)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindByID(ctx context.Context, id string) (*domain.Customer, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Customer), args.Error(1)
+}
+
+func (m *MockRepository) FindByIDs(ctx context.Context, ids []string) ([]*domain.Customer, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Customer), args.Error(1)
+}
+
+func (m *MockRepository) FindByCPF(ctx context.Context, cpf string) (*domain.Customer, error) {
+	args := m.Called(ctx, cpf)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Customer), args.Error(1)
+}
+
+func (m *MockRepository) FindByCode(ctx context.Context, code string) (*domain.Customer, error) {
+	args := m.Called(ctx, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Customer), args.Error(1)
+}
+
+func (m *MockRepository) GetVersionByCPF(ctx context.Context, cpf string) (*domain.CustomerVersion, error) {
+	args := m.Called(ctx, cpf)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.CustomerVersion), args.Error(1)
+}
+
+func (m *MockRepository) FindByCPFs(ctx context.Context, cpfs []string) ([]*domain.Customer, error) {
+	args := m.Called(ctx, cpfs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Customer), args.Error(1)
+}
+
+func (m *MockRepository) FindByIDsOrCPFs(ctx context.Context, ids, cpfs []string) ([]*domain.Customer, error) {
+	args := m.Called(ctx, ids, cpfs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Customer), args.Error(1)
+}
+
+func (m *MockRepository) FindConflicts(ctx context.Context, cpf, email string) ([]*domain.Customer, error) {
+	args := m.Called(ctx, cpf, email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Customer), args.Error(1)
+}
+
+func (m *MockRepository) Update(ctx context.Context, customer *domain.Customer) error {
+	args := m.Called(ctx, customer)
+	return args.Error(0)
+}
+
+func (m *MockRepository) UpdateCPF(ctx context.Context, customer *domain.Customer) error {
+	args := m.Called(ctx, customer)
+	return args.Error(0)
+}
+
+func (m *MockRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetEmailByID(ctx context.Context, id string) (string, error) {
+	args := m.Called(ctx, id)
+	return args.String(0), args.Error(1)
+}
+
+type graphqlResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+// sameKeys matches a batch regardless of order, since graphql-go resolves
+// the fields of a level in map order.
+func sameKeys(keys ...string) interface{} {
+	expected := slices.Sorted(slices.Values(keys))
+	return mock.MatchedBy(func(actual []string) bool {
+		return slices.Equal(expected, slices.Sorted(slices.Values(actual)))
+	})
+}
+
+// backoffice may run every query and mutation and read personal data.
+var backoffice = &auth.Principal{
+	Subject: "backoffice",
+	Scopes:  []string{auth.ScopeCreate, auth.ScopeRead, auth.ScopeUpdate, auth.ScopeDelete, auth.ScopeReadPII},
+}
+
+func setupTestRouter(t *testing.T, mockRepo *MockRepository) *gin.Engine {
+	return setupTestRouterAs(t, mockRepo, backoffice)
+}
+
+func setupTestRouterAs(t *testing.T, mockRepo *MockRepository, principal *auth.Principal) *gin.Engine {
+	return setupTestRouterWithLog(t, mockRepo, principal, nil)
+}
+
+func setupTestRouterWithLog(t *testing.T, mockRepo *MockRepository, principal *auth.Principal, accessLog *audit.AccessLog) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	h, err := NewHandler(
+		usecase.NewCreateCustomerUseCase(mockRepo, accessLog),
+		usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), accessLog),
+		usecase.NewDeleteCustomerUseCase(mockRepo),
+		usecase.NewBatchGetCustomersUseCase(mockRepo, accessLog),
+	)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+	})
+	router.POST("/graphql", h.Handle)
+	return router
+}
+
+func doGraphQL(t *testing.T, router *gin.Engine, query string, variables map[string]interface{}) (int, graphqlResponse) {
+	body, _ := json.Marshal(Request{Query: query, Variables: variables})
+	req, _ := http.NewRequest(http.MethodPost, "/graphql", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response graphqlResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return w.Code, response
+}
+
+func TestHandle_BatchesLookupsByID(t *testing.T) {
+	mockRepo := new(MockRepository)
+	first, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+	second, _ := domain.NewCustomer("Jane Doe", "52998224725", "jane@example.com")
+	mockRepo.On("FindByIDs", mock.Anything, sameKeys(first.ID, second.ID, "unknown")).
+		Return([]*domain.Customer{second, first}, nil).Once()
+
+	router := setupTestRouter(t, mockRepo)
+	code, response := doGraphQL(t, router, `query($a: ID!, $b: ID!) {
+		a: customer(id: $a) { id name }
+		b: customer(id: $b) { id email }
+		c: customer(id: "unknown") { id }
+		again: customer(id: $a) { cpf }
+	}`, map[string]interface{}{"a": first.ID, "b": second.ID})
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, response.Errors)
+	assert.JSONEq(t, `{"id":"`+first.ID+`","name":"John Doe"}`, string(response.Data["a"]))
+	assert.JSONEq(t, `{"id":"`+second.ID+`","email":"jane@example.com"}`, string(response.Data["b"]))
+	assert.JSONEq(t, `null`, string(response.Data["c"]))
+	assert.JSONEq(t, `{"cpf":"11144477735"}`, string(response.Data["again"]))
+	mockRepo.AssertNumberOfCalls(t, "FindByIDs", 1)
+}
+
+func TestHandle_MasksPII(t *testing.T) {
+	mockRepo := new(MockRepository)
+	customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+	mockRepo.On("FindByIDs", mock.Anything, []string{customer.ID}).Return([]*domain.Customer{customer}, nil)
+	reader := &auth.Principal{Subject: "kiosk", Scopes: []string{auth.ScopeRead}}
+
+	_, response := doGraphQL(t, setupTestRouterAs(t, mockRepo, reader), `query($id: ID!) {
+		customer(id: $id) { name cpf email }
+	}`, map[string]interface{}{"id": customer.ID})
+
+	assert.Empty(t, response.Errors)
+	assert.JSONEq(t, `{"name":"John Doe","cpf":"***.444.777-**","email":"j***@example.com"}`, string(response.Data["customer"]))
+}
+
+// recordingAccessRepository keeps the PII access log entries appended.
+type recordingAccessRepository struct {
+	entries []*domain.PIIAccess
+}
+
+func (r *recordingAccessRepository) Append(_ context.Context, entries []*domain.PIIAccess) error {
+	r.entries = append(r.entries, entries...)
+	return nil
+}
+
+func (r *recordingAccessRepository) FindByCustomer(context.Context, string, int) ([]*domain.PIIAccess, error) {
+	return nil, nil
+}
+
+func (r *recordingAccessRepository) FindByActor(context.Context, string, int) ([]*domain.PIIAccess, error) {
+	return nil, nil
+}
+
+func TestHandle_LogsSelectedPII(t *testing.T) {
+	customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+
+	t.Run("Only the PII fields selected are logged", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByIDs", mock.Anything, []string{customer.ID}).Return([]*domain.Customer{customer}, nil)
+		logRepo := &recordingAccessRepository{}
+		router := setupTestRouterWithLog(t, mockRepo, backoffice, audit.NewAccessLog(logRepo))
+
+		_, response := doGraphQL(t, router, `query($id: ID!) {
+			customer(id: $id) { name ...contact }
+		}
+		fragment contact on Customer { email }`, map[string]interface{}{"id": customer.ID})
+
+		assert.Empty(t, response.Errors)
+		require.Len(t, logRepo.entries, 1)
+		assert.Equal(t, customer.ID, logRepo.entries[0].CustomerID)
+		assert.Equal(t, []string{"email"}, logRepo.entries[0].Fields)
+	})
+
+	t.Run("Selecting no PII logs nothing", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPFs", mock.Anything, []string{"11144477735"}).Return([]*domain.Customer{customer}, nil)
+		logRepo := &recordingAccessRepository{}
+		router := setupTestRouterWithLog(t, mockRepo, backoffice, audit.NewAccessLog(logRepo))
+
+		_, response := doGraphQL(t, router, `{ customerByCpf(cpf: "11144477735") { id name } }`, nil)
+
+		assert.Empty(t, response.Errors)
+		assert.Empty(t, logRepo.entries)
+	})
+
+	t.Run("Mutations log the PII fields selected", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("Update", mock.Anything, mock.Anything).Return(nil)
+		logRepo := &recordingAccessRepository{}
+		router := setupTestRouterWithLog(t, mockRepo, backoffice, audit.NewAccessLog(logRepo))
+
+		_, response := doGraphQL(t, router, `mutation($id: ID!) {
+			updateCustomer(id: $id, input: {name: "Jane Doe"}) { name cpf }
+		}`, map[string]interface{}{"id": customer.ID})
+
+		assert.Empty(t, response.Errors)
+		require.Len(t, logRepo.entries, 1)
+		assert.Equal(t, []string{"cpf"}, logRepo.entries[0].Fields)
+	})
+}
+
+func TestHandle_BatchesLookupsByCPF(t *testing.T) {
+	mockRepo := new(MockRepository)
+	first, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+	second, _ := domain.NewCustomer("Jane Doe", "52998224725", "jane@example.com")
+	mockRepo.On("FindByCPFs", mock.Anything, sameKeys("11144477735", "52998224725")).
+		Return([]*domain.Customer{first, second}, nil).Once()
+
+	router := setupTestRouter(t, mockRepo)
+	code, response := doGraphQL(t, router, `{
+		a: customerByCpf(cpf: "111.444.777-35") { name }
+		b: customerByCpf(cpf: "52998224725") { name }
+		c: customerByCpf(cpf: "11144477735") { id }
+	}`, nil)
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, response.Errors)
+	assert.JSONEq(t, `{"name":"John Doe"}`, string(response.Data["a"]))
+	assert.JSONEq(t, `{"name":"Jane Doe"}`, string(response.Data["b"]))
+	assert.JSONEq(t, `{"id":"`+first.ID+`"}`, string(response.Data["c"]))
+	mockRepo.AssertNumberOfCalls(t, "FindByCPFs", 1)
+}
+
+func TestHandle_CPFLookupsAreWatched(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("FindByCPFs", mock.Anything, sameKeys("11144477735", "52998224725")).Return([]*domain.Customer{}, nil)
+	detector := abuse.NewDetector(abuse.Thresholds{Window: time.Minute, MaxDistinctCPFs: 2, BlockFor: time.Minute}, abuse.LogSink{})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		ctx := auth.WithPrincipal(c.Request.Context(), backoffice)
+		c.Request = c.Request.WithContext(abuse.WithWatch(ctx, detector, "sub:backoffice"))
+	})
+	h, err := NewHandler(nil, nil, nil, usecase.NewBatchGetCustomersUseCase(mockRepo, nil))
+	require.NoError(t, err)
+	router.POST("/graphql", h.Handle)
+
+	_, response := doGraphQL(t, router, `{
+		a: customerByCpf(cpf: "111.444.777-35") { id }
+		b: customerByCpf(cpf: "52998224725") { id }
+		c: customerByCpf(cpf: "123") { id }
+	}`, nil)
+
+	assert.Empty(t, response.Errors)
+	_, blocked := detector.BlockedUntil("sub:backoffice")
+	assert.True(t, blocked)
+}
+
+func TestHandle_LookupError(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("FindByIDs", mock.Anything, []string{"123"}).
+		Return(nil, errors.WrapError(fmt.Errorf("connection refused"), "Failed to find customers by ID"))
+
+	router := setupTestRouter(t, mockRepo)
+	code, response := doGraphQL(t, router, `{ customer(id: "123") { id } }`, nil)
+
+	assert.Equal(t, http.StatusOK, code)
+	require.Len(t, response.Errors, 1)
+	assert.Equal(t, "Internal server error", response.Errors[0].Message)
+	assert.JSONEq(t, `null`, string(response.Data["customer"]))
+}
+
+func TestHandle_Mutations(t *testing.T) {
+	t.Run("Create customer", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+		router := setupTestRouter(t, mockRepo)
+		_, response := doGraphQL(t, router, `mutation {
+			createCustomer(input: {name: "John Doe", cpf: "111.444.777-35", email: "john@example.com"}) { cpf email }
+		}`, nil)
+
+		assert.Empty(t, response.Errors)
+		assert.JSONEq(t, `{"cpf":"11144477735","email":"john@example.com"}`, string(response.Data["createCustomer"]))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Create customer with invalid CPF exposes error code", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+
+		router := setupTestRouter(t, mockRepo)
+		_, response := doGraphQL(t, router, `mutation {
+			createCustomer(input: {name: "John Doe", cpf: "invalid", email: "john@example.com"}) { id }
+		}`, nil)
+
+		require.Len(t, response.Errors, 1)
+		assert.Equal(t, "INVALID_CPF", response.Errors[0].Extensions["code"])
+		assert.Equal(t, float64(http.StatusBadRequest), response.Errors[0].Extensions["statusCode"])
+	})
+
+	t.Run("Update customer", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("Update", mock.Anything, mock.Anything).Return(nil)
+
+		router := setupTestRouter(t, mockRepo)
+		_, response := doGraphQL(t, router, `mutation($id: ID!) {
+			updateCustomer(id: $id, input: {name: "Jane Doe"}) { name email }
+		}`, map[string]interface{}{"id": customer.ID})
+
+		assert.Empty(t, response.Errors)
+		assert.JSONEq(t, `{"name":"Jane Doe","email":"john@example.com"}`, string(response.Data["updateCustomer"]))
+	})
+
+	t.Run("Delete customer", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("Delete", mock.Anything, customer.ID).Return(nil)
+
+		router := setupTestRouter(t, mockRepo)
+		_, response := doGraphQL(t, router, `mutation($id: ID!) { deleteCustomer(id: $id) }`,
+			map[string]interface{}{"id": customer.ID})
+
+		assert.Empty(t, response.Errors)
+		assert.JSONEq(t, `true`, string(response.Data["deleteCustomer"]))
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestHandle_InvalidBody(t *testing.T) {
+	post := func(router *gin.Engine, headers map[string]string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "/graphql", bytes.NewBufferString(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Legacy error body", func(t *testing.T) {
+		w := post(setupTestRouter(t, new(MockRepository)), nil)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "INVALID_REQUEST")
+	})
+
+	t.Run("Problem details in the requested language", func(t *testing.T) {
+		w := post(setupTestRouter(t, new(MockRepository)), map[string]string{
+			"Accept":          handler.ProblemContentType,
+			"Accept-Language": "pt-BR",
+		})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, handler.ProblemContentType, w.Header().Get("Content-Type"))
+		assert.Equal(t, "pt-BR", w.Header().Get("Content-Language"))
+
+		var problem handler.Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, "INVALID_REQUEST", problem.Code)
+		assert.Equal(t, "Corpo da requisição inválido", problem.Detail)
+		assert.Equal(t, []handler.ProblemField{
+			{Pointer: "/query", Code: "FIELD_REQUIRED", Detail: "query é obrigatório"},
+		}, problem.Errors)
+	})
+}
@@ -0,0 +1,140 @@
+package graphqlhandler
+
+import (
+	"context"
+	"customer-service/internal/audit"
+	"customer-service/internal/domain"
+	"customer-service/internal/usecase"
+	"customer-service/pkg/validator"
+	"slices"
+	"sync"
+)
+
+type loadersKey struct{}
+
+// batchFunc fetches the customers for a set of keys and indexes them by key.
+type batchFunc func(ctx context.Context, keys []string) (map[string]*domain.Customer, error)
+
+// customerLoader collects the keys requested while a GraphQL level is being
+// resolved and fetches them with a single batch call the first time one of
+// the returned thunks is evaluated. The batch is logged as disclosing every
+// PII field any of its keys was queried for.
+type customerLoader struct {
+	mu        sync.Mutex
+	fetch     batchFunc
+	pending   []string
+	disclosed []string
+	loaded    map[string]*domain.Customer
+	errs      map[string]error
+}
+
+func newCustomerLoader(fetch batchFunc) *customerLoader {
+	return &customerLoader{
+		fetch:  fetch,
+		loaded: make(map[string]*domain.Customer),
+		errs:   make(map[string]error),
+	}
+}
+
+// Load queues the key, to be shown with the disclosed PII fields, and
+// returns a thunk that graphql-go resolves after every field of the current
+// level has queued its keys.
+func (l *customerLoader) Load(ctx context.Context, key string, disclosed []string) func() (interface{}, error) {
+	l.mu.Lock()
+	if _, done := l.loaded[key]; !done && l.errs[key] == nil && !l.isPending(key) {
+		l.pending = append(l.pending, key)
+	}
+	for _, field := range disclosed {
+		if !slices.Contains(l.disclosed, field) {
+			l.disclosed = append(l.disclosed, field)
+		}
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if len(l.pending) > 0 {
+			keys, disclosed := l.pending, l.disclosed
+			l.pending, l.disclosed = nil, nil
+
+			found, err := l.fetch(audit.WithDisclosedFields(ctx, disclosed...), keys)
+			if err != nil {
+				err = toGraphQLError(err)
+			}
+			for _, k := range keys {
+				if err != nil {
+					l.errs[k] = err
+				} else {
+					l.loaded[k] = found[k]
+				}
+			}
+		}
+
+		if err := l.errs[key]; err != nil {
+			return nil, err
+		}
+		customer := l.loaded[key]
+		if customer == nil {
+			return nil, nil
+		}
+		return customer, nil
+	}
+}
+
+func (l *customerLoader) isPending(key string) bool {
+	for _, k := range l.pending {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// loaders holds the per-request batching state.
+type loaders struct {
+	byID  *customerLoader
+	byCPF *customerLoader
+}
+
+func newLoaders(batchUseCase *usecase.BatchGetCustomersUseCase) *loaders {
+	return &loaders{
+		byID: newCustomerLoader(func(ctx context.Context, ids []string) (map[string]*domain.Customer, error) {
+			result, err := batchUseCase.ExecuteByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			found := make(map[string]*domain.Customer, len(result.Customers))
+			for _, customer := range result.Customers {
+				found[customer.ID] = customer
+			}
+			return found, nil
+		}),
+		byCPF: newCustomerLoader(func(ctx context.Context, cpfs []string) (map[string]*domain.Customer, error) {
+			result, err := batchUseCase.ExecuteByCPFs(ctx, cpfs)
+			if err != nil {
+				return nil, err
+			}
+			found := make(map[string]*domain.Customer, len(result.Customers))
+			for _, customer := range result.Customers {
+				found[customer.CPF] = customer
+			}
+			return found, nil
+		}),
+	}
+}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFromContext(ctx context.Context) *loaders {
+	l, _ := ctx.Value(loadersKey{}).(*loaders)
+	return l
+}
+
+// cpfKey normalizes a CPF so that formatted and unformatted lookups share a batch entry.
+func cpfKey(cpf string) string {
+	return validator.CleanCPF(cpf)
+}
@@ -0,0 +1,244 @@
+package graphqlhandler
+
+import (
+	"customer-service/internal/audit"
+	"customer-service/internal/domain"
+	"customer-service/internal/usecase"
+	"customer-service/pkg/errors"
+	"log"
+	"net/http"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// gqlError exposes the stable AppError code to GraphQL clients through the
+// error extensions.
+type gqlError struct {
+	appErr *errors.AppError
+}
+
+func (e *gqlError) Error() string {
+	return e.appErr.Message
+}
+
+func (e *gqlError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":       e.appErr.Code,
+		"statusCode": e.appErr.StatusCode,
+	}
+}
+
+func toGraphQLError(err error) error {
+	appErr := errors.From(err)
+	if appErr.StatusCode >= http.StatusInternalServerError {
+		log.Printf("GraphQL resolver failed: %s", appErr.Internal())
+	}
+	return &gqlError{appErr: appErr}
+}
+
+// customerField resolves a member of the customer as the caller may see it,
+// with CPF and emails masked unless they may read personal data.
+func customerField(resolve func(*domain.Customer) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		customer, ok := p.Source.(*domain.Customer)
+		if !ok {
+			return nil, nil
+		}
+		return resolve(audit.Redact(p.Context, customer)), nil
+	}
+}
+
+// selectedPII returns the domain.PIIFields the query selects on the
+// customer resolved by p, for the access log.
+func selectedPII(p graphql.ResolveParams) []string {
+	selected := make(map[string]bool)
+	var collect func(*ast.SelectionSet)
+	collect = func(set *ast.SelectionSet) {
+		if set == nil {
+			return
+		}
+		for _, selection := range set.Selections {
+			switch selection := selection.(type) {
+			case *ast.Field:
+				selected[selection.Name.Value] = true
+			case *ast.InlineFragment:
+				collect(selection.SelectionSet)
+			case *ast.FragmentSpread:
+				if fragment, ok := p.Info.Fragments[selection.Name.Value].(*ast.FragmentDefinition); ok {
+					collect(fragment.SelectionSet)
+				}
+			}
+		}
+	}
+	for _, field := range p.Info.FieldASTs {
+		collect(field.SelectionSet)
+	}
+
+	fields := []string{}
+	for _, field := range domain.PIIFields {
+		if selected[field] {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+var customerType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Customer",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type:    graphql.NewNonNull(graphql.ID),
+			Resolve: customerField(func(c *domain.Customer) interface{} { return c.ID }),
+		},
+		"name": &graphql.Field{
+			Type:    graphql.NewNonNull(graphql.String),
+			Resolve: customerField(func(c *domain.Customer) interface{} { return c.Name }),
+		},
+		"cpf": &graphql.Field{
+			Type:    graphql.NewNonNull(graphql.String),
+			Resolve: customerField(func(c *domain.Customer) interface{} { return c.CPF }),
+		},
+		"email": &graphql.Field{
+			Type:    graphql.NewNonNull(graphql.String),
+			Resolve: customerField(func(c *domain.Customer) interface{} { return c.Email }),
+		},
+		"code": &graphql.Field{
+			Type: graphql.String,
+			Resolve: customerField(func(c *domain.Customer) interface{} {
+				if c.Code == "" {
+					return nil
+				}
+				return c.Code
+			}),
+		},
+		"pendingEmail": &graphql.Field{
+			Type: graphql.String,
+			Resolve: customerField(func(c *domain.Customer) interface{} {
+				if c.PendingEmail == "" {
+					return nil
+				}
+				return c.PendingEmail
+			}),
+		},
+		"createdAt": &graphql.Field{
+			Type:    graphql.NewNonNull(graphql.DateTime),
+			Resolve: customerField(func(c *domain.Customer) interface{} { return c.CreatedAt }),
+		},
+		"updatedAt": &graphql.Field{
+			Type:    graphql.NewNonNull(graphql.DateTime),
+			Resolve: customerField(func(c *domain.Customer) interface{} { return c.UpdatedAt }),
+		},
+	},
+})
+
+var createCustomerInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "CreateCustomerInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"name":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"cpf":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"email": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+	},
+})
+
+var updateCustomerInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "UpdateCustomerInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"name":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"email": &graphql.InputObjectFieldConfig{Type: graphql.String},
+	},
+})
+
+// NewSchema builds the customer schema. Lookups go through the per-request
+// loaders so that every lookup of one query level becomes a single batch.
+func NewSchema(
+	createUC *usecase.CreateCustomerUseCase,
+	updateUC *usecase.UpdateCustomerUseCase,
+	deleteUC *usecase.DeleteCustomerUseCase,
+) (graphql.Schema, error) {
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"customer": &graphql.Field{
+				Type: customerType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loadersFromContext(p.Context).byID.Load(p.Context, p.Args["id"].(string), selectedPII(p)), nil
+				},
+			},
+			"customerByCpf": &graphql.Field{
+				Type: customerType,
+				Args: graphql.FieldConfigArgument{
+					"cpf": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loadersFromContext(p.Context).byCPF.Load(p.Context, cpfKey(p.Args["cpf"].(string)), selectedPII(p)), nil
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createCustomer": &graphql.Field{
+				Type: graphql.NewNonNull(customerType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(createCustomerInput)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					input := p.Args["input"].(map[string]interface{})
+					ctx := audit.WithDisclosedFields(p.Context, selectedPII(p)...)
+					customer, err := createUC.Execute(ctx, input["name"].(string), input["cpf"].(string), input["email"].(string))
+					if err != nil {
+						return nil, toGraphQLError(err)
+					}
+					return customer, nil
+				},
+			},
+			"updateCustomer": &graphql.Field{
+				Type: graphql.NewNonNull(customerType),
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateCustomerInput)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					input := p.Args["input"].(map[string]interface{})
+					ctx := audit.WithDisclosedFields(p.Context, selectedPII(p)...)
+					customer, err := updateUC.Execute(ctx, p.Args["id"].(string), optionalString(input, "name"), optionalString(input, "email"))
+					if err != nil {
+						return nil, toGraphQLError(err)
+					}
+					return customer, nil
+				},
+			},
+			"deleteCustomer": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := deleteUC.Execute(p.Context, p.Args["id"].(string)); err != nil {
+						return nil, toGraphQLError(err)
+					}
+					return true, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:    query,
+		Mutation: mutation,
+	})
+}
+
+func optionalString(input map[string]interface{}, key string) *string {
+	value, ok := input[key].(string)
+	if !ok {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,22 @@
+package grpchandler
+
+import (
+	"context"
+	"customer-service/internal/audit"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// AccessPurposeMetadata carries why the caller reads customer data, the
+// gRPC counterpart of the X-Access-Purpose header.
+const AccessPurposeMetadata = "x-access-purpose"
+
+// accessPurpose stores the declared purpose of the call for the PII access
+// log.
+func accessPurpose(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+	if values := metadata.ValueFromIncomingContext(ctx, AccessPurposeMetadata); len(values) > 0 {
+		ctx = audit.WithPurpose(ctx, values[0])
+	}
+	return next(ctx, req)
+}
@@ -0,0 +1,125 @@
+package grpchandler
+
+import (
+	"context"
+	"customer-service/internal/auth"
+	"customer-service/internal/usecase"
+	"customer-service/pkg/errors"
+	customerv1 "customer-service/pkg/pb/customer/v1"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Metadata through which callers authenticate, the gRPC counterparts of the
+// Authorization and X-API-Key headers.
+const (
+	AuthorizationMetadata = "authorization"
+	APIKeyMetadata        = "x-api-key"
+)
+
+// Metadata through which a trusted gateway or service mesh passes the caller
+// it authenticated, the gRPC counterparts of the X-Authenticated-* headers.
+const (
+	GatewaySubjectMetadata = "x-authenticated-subject"
+	GatewayScopesMetadata  = "x-authenticated-scopes"
+)
+
+// Authentication identifies callers by an "authorization: Bearer" token or
+// an x-api-key, the same credentials the HTTP API accepts, and stores the
+// caller in the context. A nil verifier or use case turns that credential
+// off. Calls without credentials are left to requirePrincipal.
+func Authentication(verifier auth.Verifier, apiKeys *usecase.AuthenticateAPIKeyUseCase) grpc.UnaryServerInterceptor {
+	return authenticate(apiKeys, func(ctx context.Context) (*auth.Principal, error) {
+		header := firstMetadata(ctx, AuthorizationMetadata)
+		if header == "" {
+			return nil, nil
+		}
+		token, ok := bearerToken(header)
+		if !ok || verifier == nil {
+			return nil, errors.NewUnauthorizedError("Invalid access token", "INVALID_TOKEN")
+		}
+		return verifier.Verify(ctx, token)
+	})
+}
+
+// GatewayAuthentication trusts the caller identity set in the
+// x-authenticated-* metadata in place of validating tokens, as
+// handler.GatewayAuthentication does for HTTP. API keys are still checked.
+// It must only be used when every caller reaches the port through a gateway
+// or mesh that overwrites this metadata.
+func GatewayAuthentication(apiKeys *usecase.AuthenticateAPIKeyUseCase) grpc.UnaryServerInterceptor {
+	return authenticate(apiKeys, func(ctx context.Context) (*auth.Principal, error) {
+		subject := strings.TrimSpace(firstMetadata(ctx, GatewaySubjectMetadata))
+		if subject == "" {
+			return nil, nil
+		}
+		return &auth.Principal{
+			Subject: subject,
+			Scopes:  strings.Fields(firstMetadata(ctx, GatewayScopesMetadata)),
+		}, nil
+	})
+}
+
+// authenticate checks the x-api-key of customer service calls, or else asks
+// identify for the caller, which returns nil when the call carries no
+// credentials.
+func authenticate(apiKeys *usecase.AuthenticateAPIKeyUseCase, identify func(context.Context) (*auth.Principal, error)) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+		if !isCustomerMethod(info) {
+			return next(ctx, req)
+		}
+
+		var principal *auth.Principal
+		var err error
+		if apiKey := strings.TrimSpace(firstMetadata(ctx, APIKeyMetadata)); apiKey != "" {
+			if apiKeys == nil {
+				return nil, toStatus(errors.NewUnauthorizedError("Invalid API key", "INVALID_API_KEY"))
+			}
+			principal, err = apiKeys.Execute(ctx, apiKey)
+		} else {
+			principal, err = identify(ctx)
+		}
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		if principal != nil {
+			ctx = auth.WithPrincipal(ctx, principal)
+		}
+		return next(ctx, req)
+	}
+}
+
+// requirePrincipal rejects customer service calls that were not
+// authenticated, unless ServerConfig.AllowUnauthenticated opens the server.
+func requirePrincipal(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+	if isCustomerMethod(info) && auth.FromContext(ctx) == nil {
+		return nil, toStatus(errors.NewUnauthorizedError("Missing access token", "UNAUTHENTICATED"))
+	}
+	return next(ctx, req)
+}
+
+// isCustomerMethod tells the customer service apart from the health service,
+// which probes call without credentials.
+func isCustomerMethod(info *grpc.UnaryServerInfo) bool {
+	return strings.HasPrefix(info.FullMethod, "/"+customerv1.CustomerService_ServiceDesc.ServiceName+"/")
+}
+
+func firstMetadata(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// bearerToken extracts the token of a "Bearer" authorization value. The
+// scheme is case-insensitive (RFC 7235).
+func bearerToken(value string) (string, bool) {
+	scheme, token, found := strings.Cut(value, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
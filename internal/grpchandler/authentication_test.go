@@ -0,0 +1,180 @@
+package grpchandler
+
+import (
+	"context"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	customerv1 "customer-service/pkg/pb/customer/v1"
+	"customer-service/pkg/ratelimit"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthentication(t *testing.T) {
+	customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+	config := ServerConfig{Authentication: Authentication(testVerifier{}, nil)}
+
+	t.Run("Call without credentials is rejected", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		client := customerv1.NewCustomerServiceClient(dialTestServer(t, newTestServer(mockRepo, nil, config)))
+
+		_, err := client.DeleteCustomer(context.Background(), &customerv1.DeleteCustomerRequest{Id: customer.ID})
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.Equal(t, "UNAUTHENTICATED", errorReason(t, err))
+		mockRepo.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Server without authentication rejects every call", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		client := customerv1.NewCustomerServiceClient(dialTestServer(t, newTestServer(mockRepo, nil, ServerConfig{}), withBearer("order-service")))
+
+		_, err := client.GetCustomerByCPF(context.Background(), &customerv1.GetCustomerByCPFRequest{Cpf: "11144477735"})
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Invalid token is rejected", func(t *testing.T) {
+		client := customerv1.NewCustomerServiceClient(dialTestServer(t, newTestServer(new(MockRepository), nil, config), withBearer("forged")))
+
+		_, err := client.GetCustomerByCPF(context.Background(), &customerv1.GetCustomerByCPFRequest{Cpf: "11144477735"})
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.Equal(t, "INVALID_TOKEN", errorReason(t, err))
+	})
+
+	t.Run("API key without an API key store is rejected", func(t *testing.T) {
+		client := customerv1.NewCustomerServiceClient(dialTestServer(t, newTestServer(new(MockRepository), nil, config)))
+
+		ctx := metadata.AppendToOutgoingContext(context.Background(), APIKeyMetadata, "csk_key.secret")
+		_, err := client.GetCustomerByCPF(ctx, &customerv1.GetCustomerByCPFRequest{Cpf: "11144477735"})
+
+		assert.Equal(t, "INVALID_API_KEY", errorReason(t, err))
+	})
+
+	t.Run("Caller is authorized by its scopes", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		client := customerv1.NewCustomerServiceClient(dialTestServer(t, newTestServer(mockRepo, nil, config), withBearer("reader")))
+
+		_, err := client.DeleteCustomer(context.Background(), &customerv1.DeleteCustomerRequest{Id: customer.ID})
+
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Health checks need no credentials", func(t *testing.T) {
+		conn := dialTestServer(t, newTestServer(new(MockRepository), nil, config))
+
+		_, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+
+		assert.NoError(t, err)
+	})
+}
+
+// TestAuthentication_TurnedOff covers the deployed configuration, where no
+// AUTH_* variable is set and HTTP is open as well.
+func TestAuthentication_TurnedOff(t *testing.T) {
+	customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+	config := ServerConfig{Authentication: Authentication(nil, nil), AllowUnauthenticated: true}
+
+	t.Run("Internal operations need no credentials", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+		client := customerv1.NewCustomerServiceClient(dialTestServer(t, newTestServer(mockRepo, nil, config)))
+
+		resp, err := client.GetCustomerByCPF(context.Background(), &customerv1.GetCustomerByCPFRequest{Cpf: "11144477735"})
+
+		require.NoError(t, err)
+		assert.Equal(t, "11144477735", resp.Cpf)
+	})
+
+	t.Run("Other operations are still denied", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		client := customerv1.NewCustomerServiceClient(dialTestServer(t, newTestServer(mockRepo, nil, config)))
+
+		_, err := client.DeleteCustomer(context.Background(), &customerv1.DeleteCustomerRequest{Id: customer.ID})
+
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Tokens it cannot verify are rejected", func(t *testing.T) {
+		client := customerv1.NewCustomerServiceClient(dialTestServer(t, newTestServer(new(MockRepository), nil, config), withBearer("reader")))
+
+		_, err := client.GetCustomerByCPF(context.Background(), &customerv1.GetCustomerByCPFRequest{Cpf: "11144477735"})
+
+		assert.Equal(t, "INVALID_TOKEN", errorReason(t, err))
+	})
+}
+
+func TestGatewayAuthentication(t *testing.T) {
+	customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+	config := ServerConfig{Authentication: GatewayAuthentication(nil)}
+
+	t.Run("Caller set by the gateway is authorized by its scopes", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+		client := customerv1.NewCustomerServiceClient(dialTestServer(t, newTestServer(mockRepo, nil, config)))
+		ctx := metadata.AppendToOutgoingContext(context.Background(), GatewaySubjectMetadata, "kiosk", GatewayScopesMetadata, auth.ScopeRead)
+
+		resp, err := client.GetCustomerByCPF(ctx, &customerv1.GetCustomerByCPFRequest{Cpf: "11144477735"})
+		require.NoError(t, err)
+		assert.Equal(t, "***.444.777-**", resp.Cpf)
+
+		_, err = client.DeleteCustomer(ctx, &customerv1.DeleteCustomerRequest{Id: customer.ID})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("Call the gateway did not authenticate is rejected", func(t *testing.T) {
+		client := customerv1.NewCustomerServiceClient(dialTestServer(t, newTestServer(new(MockRepository), nil, config)))
+
+		_, err := client.GetCustomerByCPF(context.Background(), &customerv1.GetCustomerByCPFRequest{Cpf: "11144477735"})
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+func TestRateLimit(t *testing.T) {
+	customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+	mockRepo := new(MockRepository)
+	mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+	server := newTestServer(mockRepo, nil, ServerConfig{
+		Authentication: Authentication(testVerifier{}, nil),
+		RateLimit:      RateLimit(ratelimit.NewMemoryStore(), ratelimit.Limit{}, ratelimit.Limit{Requests: 1, Period: time.Minute}),
+	})
+	client := customerv1.NewCustomerServiceClient(dialTestServer(t, server, withBearer("reader")))
+
+	_, err := client.GetCustomerByCPF(context.Background(), &customerv1.GetCustomerByCPFRequest{Cpf: "11144477735"})
+	require.NoError(t, err)
+
+	var header metadata.MD
+	_, err = client.GetCustomerByCPF(context.Background(), &customerv1.GetCustomerByCPFRequest{Cpf: "11144477735"}, grpc.Header(&header))
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, "RATE_LIMITED", errorReason(t, err))
+	assert.NotEmpty(t, header.Get("retry-after"))
+}
+
+func TestClientRateLimit(t *testing.T) {
+	server := newTestServer(new(MockRepository), nil, ServerConfig{
+		ClientRateLimit: ClientRateLimit(ratelimit.NewMemoryStore(), ratelimit.Limit{Requests: 1, Period: time.Minute}),
+		Authentication:  Authentication(testVerifier{}, nil),
+	})
+	client := customerv1.NewCustomerServiceClient(dialTestServer(t, server, withBearer("forged")))
+
+	_, err := client.GetCustomerByCPF(context.Background(), &customerv1.GetCustomerByCPFRequest{Cpf: "11144477735"})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.GetCustomerByCPF(context.Background(), &customerv1.GetCustomerByCPFRequest{Cpf: "11144477735"})
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
@@ -0,0 +1,157 @@
+package grpchandler
+
+import (
+	"context"
+	"customer-service/internal/audit"
+	"customer-service/internal/domain"
+	"customer-service/internal/usecase"
+	"customer-service/pkg/errors"
+	customerv1 "customer-service/pkg/pb/customer/v1"
+	"log"
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const errorDomain = "customer-service"
+
+type CustomerServer struct {
+	customerv1.UnimplementedCustomerServiceServer
+
+	createUseCase   *usecase.CreateCustomerUseCase
+	getByCPFUseCase *usecase.GetCustomerByCPFUseCase
+	getByIDUseCase  *usecase.GetCustomerByIDUseCase
+	updateUseCase   *usecase.UpdateCustomerUseCase
+	deleteUseCase   *usecase.DeleteCustomerUseCase
+	batchUseCase    *usecase.BatchGetCustomersUseCase
+}
+
+func NewCustomerServer(
+	createUC *usecase.CreateCustomerUseCase,
+	getByCPFUC *usecase.GetCustomerByCPFUseCase,
+	getByIDUC *usecase.GetCustomerByIDUseCase,
+	updateUC *usecase.UpdateCustomerUseCase,
+	deleteUC *usecase.DeleteCustomerUseCase,
+	batchUC *usecase.BatchGetCustomersUseCase,
+) *CustomerServer {
+	return &CustomerServer{
+		createUseCase:   createUC,
+		getByCPFUseCase: getByCPFUC,
+		getByIDUseCase:  getByIDUC,
+		updateUseCase:   updateUC,
+		deleteUseCase:   deleteUC,
+		batchUseCase:    batchUC,
+	}
+}
+
+func (s *CustomerServer) CreateCustomer(ctx context.Context, req *customerv1.CreateCustomerRequest) (*customerv1.Customer, error) {
+	customer, err := s.createUseCase.Execute(ctx, req.GetName(), req.GetCpf(), req.GetEmail())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(ctx, customer), nil
+}
+
+func (s *CustomerServer) GetCustomerByCPF(ctx context.Context, req *customerv1.GetCustomerByCPFRequest) (*customerv1.Customer, error) {
+	customer, err := s.getByCPFUseCase.Execute(ctx, req.GetCpf())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(ctx, customer), nil
+}
+
+func (s *CustomerServer) GetCustomerByID(ctx context.Context, req *customerv1.GetCustomerByIDRequest) (*customerv1.Customer, error) {
+	customer, err := s.getByIDUseCase.Execute(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(ctx, customer), nil
+}
+
+func (s *CustomerServer) UpdateCustomer(ctx context.Context, req *customerv1.UpdateCustomerRequest) (*customerv1.Customer, error) {
+	customer, err := s.updateUseCase.Execute(ctx, req.GetId(), req.Name, req.Email)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(ctx, customer), nil
+}
+
+func (s *CustomerServer) DeleteCustomer(ctx context.Context, req *customerv1.DeleteCustomerRequest) (*customerv1.DeleteCustomerResponse, error) {
+	if err := s.deleteUseCase.Execute(ctx, req.GetId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &customerv1.DeleteCustomerResponse{}, nil
+}
+
+func (s *CustomerServer) BatchGetCustomers(ctx context.Context, req *customerv1.BatchGetCustomersRequest) (*customerv1.BatchGetCustomersResponse, error) {
+	result, err := s.batchUseCase.ExecuteByIDs(ctx, req.GetIds())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	customers := make([]*customerv1.Customer, 0, len(result.Customers))
+	for _, customer := range result.Customers {
+		customers = append(customers, toProto(ctx, customer))
+	}
+	return &customerv1.BatchGetCustomersResponse{
+		Customers:  customers,
+		MissingIds: result.Missing,
+	}, nil
+}
+
+// toProto converts the customer as the caller may see it, with CPF and email
+// masked unless they may read personal data.
+func toProto(ctx context.Context, customer *domain.Customer) *customerv1.Customer {
+	customer = audit.Redact(ctx, customer)
+	return &customerv1.Customer{
+		Id:        customer.ID,
+		Name:      customer.Name,
+		Cpf:       customer.CPF,
+		Email:     customer.Email,
+		CreatedAt: timestamppb.New(customer.CreatedAt),
+		UpdatedAt: timestamppb.New(customer.UpdatedAt),
+	}
+}
+
+// toStatus maps an AppError to the gRPC status matching its HTTP status code.
+// The stable error code travels as the ErrorInfo reason so clients can branch
+// on it the same way HTTP clients use the "error" field.
+func toStatus(err error) error {
+	appErr := errors.From(err)
+	if appErr.StatusCode >= http.StatusInternalServerError {
+		log.Printf("gRPC call failed: %s", appErr.Internal())
+	}
+
+	st := status.New(grpcCode(appErr.StatusCode), appErr.Message)
+	if detailed, detailErr := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: appErr.Code,
+		Domain: errorDomain,
+	}); detailErr == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+func grpcCode(statusCode int) codes.Code {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusUnprocessableEntity:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,406 @@
+package grpchandler
+
+import (
+	"context"
+	"customer-service/internal/audit"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/mailer"
+	"customer-service/internal/usecase"
+	"customer-service/pkg/errors"
+	customerv1 "customer-service/pkg/pb/customer/v1"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Create(ctx context.Context, customer *domain.Customer) error {
+	args := m.Called(ctx, customer)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindByID(ctx context.Context, id string) (*domain.Customer, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Customer), args.Error(1)
+}
+
+func (m *MockRepository) FindByIDs(ctx context.Context, ids []string) ([]*domain.Customer, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Customer), args.Error(1)
+}
+
+func (m *MockRepository) FindByCPF(ctx context.Context, cpf string) (*domain.Customer, error) {
+	args := m.Called(ctx, cpf)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Customer), args.Error(1)
+}
+
+func (m *MockRepository) FindByCode(ctx context.Context, code string) (*domain.Customer, error) {
+	args := m.Called(ctx, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Customer), args.Error(1)
+}
+
+func (m *MockRepository) GetVersionByCPF(ctx context.Context, cpf string) (*domain.CustomerVersion, error) {
+	args := m.Called(ctx, cpf)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.CustomerVersion), args.Error(1)
+}
+
+func (m *MockRepository) FindByCPFs(ctx context.Context, cpfs []string) ([]*domain.Customer, error) {
+	args := m.Called(ctx, cpfs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Customer), args.Error(1)
+}
+
+func (m *MockRepository) FindByIDsOrCPFs(ctx context.Context, ids, cpfs []string) ([]*domain.Customer, error) {
+	args := m.Called(ctx, ids, cpfs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Customer), args.Error(1)
+}
+
+func (m *MockRepository) FindConflicts(ctx context.Context, cpf, email string) ([]*domain.Customer, error) {
+	args := m.Called(ctx, cpf, email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Customer), args.Error(1)
+}
+
+func (m *MockRepository) Update(ctx context.Context, customer *domain.Customer) error {
+	args := m.Called(ctx, customer)
+	return args.Error(0)
+}
+
+func (m *MockRepository) UpdateCPF(ctx context.Context, customer *domain.Customer) error {
+	args := m.Called(ctx, customer)
+	return args.Error(0)
+}
+
+func (m *MockRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetEmailByID(ctx context.Context, id string) (string, error) {
+	args := m.Called(ctx, id)
+	return args.String(0), args.Error(1)
+}
+
+// MockPIIAccessRepository is a mock implementation of PIIAccessRepository
+type MockPIIAccessRepository struct {
+	mock.Mock
+}
+
+func (m *MockPIIAccessRepository) Append(ctx context.Context, entries []*domain.PIIAccess) error {
+	args := m.Called(ctx, entries)
+	return args.Error(0)
+}
+
+func (m *MockPIIAccessRepository) FindByCustomer(ctx context.Context, customerID string, limit int) ([]*domain.PIIAccess, error) {
+	args := m.Called(ctx, customerID, limit)
+	return args.Get(0).([]*domain.PIIAccess), args.Error(1)
+}
+
+func (m *MockPIIAccessRepository) FindByActor(ctx context.Context, actor string, limit int) ([]*domain.PIIAccess, error) {
+	args := m.Called(ctx, actor, limit)
+	return args.Get(0).([]*domain.PIIAccess), args.Error(1)
+}
+
+// testVerifier accepts the tokens of testPrincipals.
+type testVerifier struct{}
+
+var testPrincipals = map[string]*auth.Principal{
+	"order-service": {Subject: "order-service", Scopes: []string{auth.ScopeCreate, auth.ScopeRead, auth.ScopeReadPII, auth.ScopeUpdate, auth.ScopeDelete}},
+	"reader":        {Subject: "reader", Scopes: []string{auth.ScopeRead}},
+}
+
+func (testVerifier) Verify(_ context.Context, token string) (*auth.Principal, error) {
+	if principal, ok := testPrincipals[token]; ok {
+		return principal, nil
+	}
+	return nil, errors.NewUnauthorizedError("Invalid access token", "INVALID_TOKEN")
+}
+
+// withBearer authenticates every call of the client with token.
+func withBearer(token string) grpc.DialOption {
+	return grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx = metadata.AppendToOutgoingContext(ctx, AuthorizationMetadata, "Bearer "+token)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	})
+}
+
+// startTestServer serves the customer service over an in-memory listener
+// to a client authenticated as the order service.
+func startTestServer(t *testing.T, mockRepo *MockRepository) *grpc.ClientConn {
+	return startLoggedTestServer(t, mockRepo, nil)
+}
+
+// startLoggedTestServer is startTestServer recording reads to accessLog.
+func startLoggedTestServer(t *testing.T, mockRepo *MockRepository, accessLog *audit.AccessLog) *grpc.ClientConn {
+	return dialTestServer(t, newTestServer(mockRepo, accessLog, ServerConfig{
+		Authentication: Authentication(testVerifier{}, nil),
+	}), withBearer("order-service"))
+}
+
+func newTestServer(mockRepo *MockRepository, accessLog *audit.AccessLog, config ServerConfig) *grpc.Server {
+	return NewServer(NewCustomerServer(
+		usecase.NewCreateCustomerUseCase(mockRepo, nil),
+		usecase.NewGetCustomerByCPFUseCase(mockRepo, accessLog),
+		usecase.NewGetCustomerByIDUseCase(mockRepo, accessLog),
+		usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
+		usecase.NewDeleteCustomerUseCase(mockRepo),
+		usecase.NewBatchGetCustomersUseCase(mockRepo, accessLog),
+	), config)
+}
+
+func dialTestServer(t *testing.T, server *grpc.Server, opts ...grpc.DialOption) *grpc.ClientConn {
+	listener := bufconn.Listen(1024 * 1024)
+	go func() { _ = server.Serve(listener) }()
+
+	opts = append(opts,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	conn, err := grpc.NewClient("passthrough:///bufnet", opts...)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop()
+	})
+	return conn
+}
+
+func errorReason(t *testing.T, err error) string {
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info.Reason
+		}
+	}
+	return ""
+}
+
+func TestCustomerServer_CreateCustomer(t *testing.T) {
+	t.Run("Successfully create customer", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+		client := customerv1.NewCustomerServiceClient(startTestServer(t, mockRepo))
+		customer, err := client.CreateCustomer(context.Background(), &customerv1.CreateCustomerRequest{
+			Name:  "John Doe",
+			Cpf:   "111.444.777-35",
+			Email: "john@example.com",
+		})
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, customer.GetId())
+		assert.Equal(t, "11144477735", customer.GetCpf())
+		assert.NotNil(t, customer.GetCreatedAt())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid CPF maps to InvalidArgument", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+
+		client := customerv1.NewCustomerServiceClient(startTestServer(t, mockRepo))
+		_, err := client.CreateCustomer(context.Background(), &customerv1.CreateCustomerRequest{
+			Name:  "John Doe",
+			Cpf:   "invalid",
+			Email: "john@example.com",
+		})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Equal(t, "INVALID_CPF", errorReason(t, err))
+	})
+
+	t.Run("Existing customer maps to AlreadyExists", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("Create", mock.Anything, mock.Anything).Return(errors.NewFieldConflictError(domain.ErrCPFAlreadyExists))
+
+		client := customerv1.NewCustomerServiceClient(startTestServer(t, mockRepo))
+		_, err := client.CreateCustomer(context.Background(), &customerv1.CreateCustomerRequest{
+			Name:  "John Doe",
+			Cpf:   "111.444.777-35",
+			Email: "john@example.com",
+		})
+
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+		assert.Equal(t, "CPF_ALREADY_EXISTS", errorReason(t, err))
+	})
+}
+
+func TestCustomerServer_GetCustomer(t *testing.T) {
+	customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+
+	t.Run("Get by CPF", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+
+		client := customerv1.NewCustomerServiceClient(startTestServer(t, mockRepo))
+		result, err := client.GetCustomerByCPF(context.Background(), &customerv1.GetCustomerByCPFRequest{Cpf: "11144477735"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, customer.ID, result.GetId())
+	})
+
+	t.Run("Get by CPF records the access with its purpose", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+		var recorded []*domain.PIIAccess
+		accessRepo := new(MockPIIAccessRepository)
+		accessRepo.On("Append", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			recorded = append(recorded, args.Get(1).([]*domain.PIIAccess)...)
+		}).Return(nil)
+
+		client := customerv1.NewCustomerServiceClient(startLoggedTestServer(t, mockRepo, audit.NewAccessLog(accessRepo)))
+		ctx := metadata.AppendToOutgoingContext(context.Background(), AccessPurposeMetadata, "order-fulfillment")
+		_, err := client.GetCustomerByCPF(ctx, &customerv1.GetCustomerByCPFRequest{Cpf: "11144477735"})
+
+		require.NoError(t, err)
+		require.Len(t, recorded, 1)
+		assert.Equal(t, "order-service", recorded[0].Actor)
+		assert.Equal(t, "order-fulfillment", recorded[0].Purpose)
+		assert.Equal(t, customer.ID, recorded[0].CustomerID)
+	})
+
+	t.Run("Get by ID", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+
+		client := customerv1.NewCustomerServiceClient(startTestServer(t, mockRepo))
+		result, err := client.GetCustomerByID(context.Background(), &customerv1.GetCustomerByIDRequest{Id: customer.ID})
+
+		assert.NoError(t, err)
+		assert.Equal(t, customer.Email, result.GetEmail())
+	})
+
+	t.Run("Personal data is masked without the PII scope", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+
+		server := newTestServer(mockRepo, nil, ServerConfig{Authentication: Authentication(testVerifier{}, nil)})
+		client := customerv1.NewCustomerServiceClient(dialTestServer(t, server, withBearer("reader")))
+		result, err := client.GetCustomerByID(context.Background(), &customerv1.GetCustomerByIDRequest{Id: customer.ID})
+
+		require.NoError(t, err)
+		assert.Equal(t, "***.444.777-**", result.GetCpf())
+		assert.Equal(t, "j***@example.com", result.GetEmail())
+	})
+
+	t.Run("Not found maps to NotFound", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByID", mock.Anything, "999").Return(nil, nil)
+
+		client := customerv1.NewCustomerServiceClient(startTestServer(t, mockRepo))
+		_, err := client.GetCustomerByID(context.Background(), &customerv1.GetCustomerByIDRequest{Id: "999"})
+
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		assert.Equal(t, "CUSTOMER_NOT_FOUND", errorReason(t, err))
+	})
+
+	t.Run("Unexpected error maps to Internal", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByID", mock.Anything, "123").Return(nil, errors.NewInternalError("database error"))
+
+		client := customerv1.NewCustomerServiceClient(startTestServer(t, mockRepo))
+		_, err := client.GetCustomerByID(context.Background(), &customerv1.GetCustomerByIDRequest{Id: "123"})
+
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.Equal(t, "INTERNAL_ERROR", errorReason(t, err))
+	})
+}
+
+func TestCustomerServer_UpdateCustomer(t *testing.T) {
+	mockRepo := new(MockRepository)
+	customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+	mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+	mockRepo.On("Update", mock.Anything, mock.Anything).Return(nil)
+
+	name := "Jane Doe"
+	client := customerv1.NewCustomerServiceClient(startTestServer(t, mockRepo))
+	result, err := client.UpdateCustomer(context.Background(), &customerv1.UpdateCustomerRequest{
+		Id:   customer.ID,
+		Name: &name,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, name, result.GetName())
+	assert.Equal(t, "john@example.com", result.GetEmail())
+}
+
+func TestCustomerServer_DeleteCustomer(t *testing.T) {
+	mockRepo := new(MockRepository)
+	customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+	mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+	mockRepo.On("Delete", mock.Anything, customer.ID).Return(nil)
+
+	client := customerv1.NewCustomerServiceClient(startTestServer(t, mockRepo))
+	_, err := client.DeleteCustomer(context.Background(), &customerv1.DeleteCustomerRequest{Id: customer.ID})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCustomerServer_BatchGetCustomers(t *testing.T) {
+	mockRepo := new(MockRepository)
+	customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+	mockRepo.On("FindByIDs", mock.Anything, []string{customer.ID, "unknown"}).
+		Return([]*domain.Customer{customer}, nil)
+
+	client := customerv1.NewCustomerServiceClient(startTestServer(t, mockRepo))
+	result, err := client.BatchGetCustomers(context.Background(), &customerv1.BatchGetCustomersRequest{
+		Ids: []string{customer.ID, "unknown"},
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, result.GetCustomers(), 1)
+	assert.Equal(t, customer.ID, result.GetCustomers()[0].GetId())
+	assert.Equal(t, []string{"unknown"}, result.GetMissingIds())
+}
+
+func TestNewServer_Health(t *testing.T) {
+	conn := startTestServer(t, new(MockRepository))
+
+	response, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{
+		Service: customerv1.CustomerService_ServiceDesc.ServiceName,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, response.GetStatus())
+}
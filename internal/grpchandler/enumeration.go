@@ -0,0 +1,59 @@
+package grpchandler
+
+import (
+	"context"
+	"customer-service/internal/abuse"
+	"customer-service/internal/auth"
+	"customer-service/pkg/errors"
+	customerv1 "customer-service/pkg/pb/customer/v1"
+	"math"
+	"net"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// cpfLookupMethods tell whether a CPF belongs to a customer and are watched
+// by EnumerationGuard.
+var cpfLookupMethods = map[string]bool{
+	customerv1.CustomerService_GetCustomerByCPF_FullMethodName: true,
+}
+
+// EnumerationGuard is the gRPC counterpart of the HTTP guard and shares its
+// detector, so a client is counted and blocked across both transports.
+// Blocked calls fail with ResourceExhausted CLIENT_BLOCKED and a
+// retry-after header.
+func EnumerationGuard(detector *abuse.Detector, allowlist abuse.Allowlist) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+		var subject string
+		if principal := auth.FromContext(ctx); principal != nil {
+			subject = principal.Subject
+		}
+		if !cpfLookupMethods[info.FullMethod] || allowlist.Allows(subject, peerIP(ctx)) {
+			return next(ctx, req)
+		}
+
+		client := clientKey(ctx)
+		if until, blocked := detector.BlockedUntil(client); blocked {
+			retryAfter := int(math.Ceil(time.Until(until).Seconds()))
+			_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(retryAfter)))
+			return nil, toStatus(errors.NewTooManyRequestsError("Client temporarily blocked", "CLIENT_BLOCKED"))
+		}
+		return next(abuse.WithWatch(ctx, detector, client), req)
+	}
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
@@ -0,0 +1,72 @@
+package grpchandler
+
+import (
+	"context"
+	"customer-service/internal/abuse"
+	customerv1 "customer-service/pkg/pb/customer/v1"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type countingSink struct {
+	events []abuse.Event
+}
+
+func (s *countingSink) Emit(_ context.Context, event abuse.Event) {
+	s.events = append(s.events, event)
+}
+
+func TestEnumerationGuard(t *testing.T) {
+	thresholds := abuse.Thresholds{Window: time.Minute, MinLookups: 3, MaxNotFoundRatio: 0.5, BlockFor: time.Minute}
+
+	t.Run("blocks callers looking up unknown CPFs", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, mock.Anything).Return(nil, nil)
+		sink := &countingSink{}
+		server := newTestServer(mockRepo, nil, ServerConfig{
+			Authentication: Authentication(testVerifier{}, nil),
+			Enumeration:    EnumerationGuard(abuse.NewDetector(thresholds, sink), abuse.Allowlist{}),
+		})
+		client := customerv1.NewCustomerServiceClient(dialTestServer(t, server, withBearer("reader")))
+
+		for _, cpf := range []string{"11144477735", "529.982.247-25", "not-a-cpf"} {
+			_, err := client.GetCustomerByCPF(context.Background(), &customerv1.GetCustomerByCPFRequest{Cpf: cpf})
+			assert.Error(t, err)
+		}
+
+		var header metadata.MD
+		_, err := client.GetCustomerByCPF(context.Background(), &customerv1.GetCustomerByCPFRequest{Cpf: "11144477735"}, grpc.Header(&header))
+
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		assert.Equal(t, "CLIENT_BLOCKED", errorReason(t, err))
+		assert.NotEmpty(t, header.Get("retry-after"))
+		if assert.Len(t, sink.events, 1) {
+			assert.Equal(t, "sub:reader", sink.events[0].Client)
+		}
+	})
+
+	t.Run("exempts allowlisted callers", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, mock.Anything).Return(nil, nil)
+		allowlist, _ := abuse.ParseAllowlist("reader")
+		sink := &countingSink{}
+		server := newTestServer(mockRepo, nil, ServerConfig{
+			Authentication: Authentication(testVerifier{}, nil),
+			Enumeration:    EnumerationGuard(abuse.NewDetector(thresholds, sink), allowlist),
+		})
+		client := customerv1.NewCustomerServiceClient(dialTestServer(t, server, withBearer("reader")))
+
+		for i := 0; i < 5; i++ {
+			_, err := client.GetCustomerByCPF(context.Background(), &customerv1.GetCustomerByCPFRequest{Cpf: "11144477735"})
+			assert.Equal(t, codes.NotFound, status.Code(err))
+		}
+		assert.Empty(t, sink.events)
+	})
+}
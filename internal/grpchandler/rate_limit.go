@@ -0,0 +1,87 @@
+package grpchandler
+
+import (
+	"context"
+	"customer-service/internal/abuse"
+	"customer-service/internal/auth"
+	"customer-service/pkg/errors"
+	"customer-service/pkg/ratelimit"
+	"log"
+	"math"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// ClientRateLimit is the gRPC counterpart of the HTTP ClientRateLimit and
+// spends from the same per-IP buckets, before the call is authenticated.
+func ClientRateLimit(store ratelimit.Store, limit ratelimit.Limit) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+		if !isCustomerMethod(info) || limit.Requests == 0 {
+			return next(ctx, req)
+		}
+		if err := spend(ctx, store, "client:ip:"+peerIP(ctx), limit, 1); err != nil {
+			return nil, toStatus(err)
+		}
+		return next(ctx, req)
+	}
+}
+
+// RateLimit spends from the same per-client token buckets as the HTTP API,
+// so a client cannot double its budget by switching transports: one token
+// per call from the default bucket, and one per CPF looked up from the
+// cpfLookup bucket. A zero cpfLookup limit makes CPF lookups count against
+// defaultLimit. Rejected calls fail with ResourceExhausted and a
+// retry-after header.
+func RateLimit(store ratelimit.Store, defaultLimit, cpfLookup ratelimit.Limit) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+		if !isCustomerMethod(info) {
+			return next(ctx, req)
+		}
+
+		client := clientKey(ctx)
+		if defaultLimit.Requests > 0 {
+			if err := spend(ctx, store, "default:"+client, defaultLimit, 1); err != nil {
+				return nil, toStatus(err)
+			}
+		}
+
+		class, limit := "cpf", cpfLookup
+		if limit.Requests == 0 {
+			class, limit = "default", defaultLimit
+		}
+		if limit.Requests > 0 {
+			ctx = abuse.WithBudget(ctx, func(ctx context.Context, n int) error {
+				return spend(ctx, store, class+":"+client, limit, n)
+			})
+		}
+		return next(ctx, req)
+	}
+}
+
+// spend takes n tokens from the bucket of key, failing with RATE_LIMITED
+// and a retry-after header when there are not enough. An unavailable store
+// must not take the API down with it, so its errors let the call through.
+func spend(ctx context.Context, store ratelimit.Store, key string, limit ratelimit.Limit, n int) error {
+	result, err := store.Take(ctx, key, limit, n)
+	if err != nil {
+		log.Printf("Rate limit store failed: %v", err)
+		return nil
+	}
+	if !result.Allowed {
+		retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+		_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(retryAfter)))
+		return errors.NewTooManyRequestsError("Too many requests", "RATE_LIMITED")
+	}
+	return nil
+}
+
+// clientKey tells clients apart like the HTTP API does: by API key or token
+// subject once authenticated, by IP address otherwise.
+func clientKey(ctx context.Context) string {
+	if principal := auth.FromContext(ctx); principal != nil {
+		return "sub:" + principal.Subject
+	}
+	return "ip:" + peerIP(ctx)
+}
@@ -0,0 +1,58 @@
+package grpchandler
+
+import (
+	customerv1 "customer-service/pkg/pb/customer/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// ServerConfig holds the optional interceptors run before every customer
+// service call. Nil entries are skipped.
+type ServerConfig struct {
+	// ClientRateLimit runs before Authentication, RateLimit after it
+	ClientRateLimit grpc.UnaryServerInterceptor
+	Authentication  grpc.UnaryServerInterceptor
+	RateLimit       grpc.UnaryServerInterceptor
+	Enumeration     grpc.UnaryServerInterceptor
+	// AllowUnauthenticated lets calls without credentials through as
+	// internal callers, like HTTP requests with authentication turned off.
+	// Credentials that are sent are still checked.
+	AllowUnauthenticated bool
+}
+
+// NewServer builds a gRPC server exposing the customer service together
+// with the standard health and reflection services. Customer service calls
+// must be authenticated unless config.AllowUnauthenticated is set; without
+// an Authentication interceptor every one is then rejected. The declared
+// access purpose is read from the call metadata.
+func NewServer(customerServer *CustomerServer, config ServerConfig, opts ...grpc.ServerOption) *grpc.Server {
+	var authenticated grpc.UnaryServerInterceptor = requirePrincipal
+	if config.AllowUnauthenticated {
+		authenticated = nil
+	}
+	opts = append(opts, grpc.ChainUnaryInterceptor(chain(config.ClientRateLimit, config.Authentication, authenticated, config.RateLimit, config.Enumeration, accessPurpose)...))
+	server := grpc.NewServer(opts...)
+	customerv1.RegisterCustomerServiceServer(server, customerServer)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(customerv1.CustomerService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	reflection.Register(server)
+	return server
+}
+
+// chain drops nil interceptors so optional features can be left unconfigured.
+func chain(interceptors ...grpc.UnaryServerInterceptor) []grpc.UnaryServerInterceptor {
+	result := make([]grpc.UnaryServerInterceptor, 0, len(interceptors))
+	for _, interceptor := range interceptors {
+		if interceptor != nil {
+			result = append(result, interceptor)
+		}
+	}
+	return result
+}
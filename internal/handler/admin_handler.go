@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"context"
+	"customer-service/internal/audit"
+	"customer-service/internal/domain"
+	"customer-service/internal/usecase"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler serves back-office operations. Every use case behind it checks
+// the caller's scopes itself.
+type AdminHandler struct {
+	correctCPFUseCase *usecase.CorrectCPFUseCase
+}
+
+func NewAdminHandler(correctCPFUC *usecase.CorrectCPFUseCase) *AdminHandler {
+	return &AdminHandler{correctCPFUseCase: correctCPFUC}
+}
+
+type CorrectCPFRequest struct {
+	CPF    string `json:"cpf" binding:"required"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+type CPFChangeResponse struct {
+	PreviousCPF string    `json:"previousCpf"`
+	ChangedBy   string    `json:"changedBy"`
+	Reason      string    `json:"reason"`
+	ChangedAt   time.Time `json:"changedAt"`
+}
+
+// AdminCustomerResponse is the full customer as seen by administrators,
+// including the CPF history. Current and previous CPFs and the email are
+// masked unless the administrator may read personal data.
+type AdminCustomerResponse struct {
+	ID         string              `json:"id"`
+	Name       string              `json:"name"`
+	CPF        string              `json:"cpf"`
+	Email      string              `json:"email"`
+	CPFHistory []CPFChangeResponse `json:"cpfHistory"`
+	CreatedAt  time.Time           `json:"createdAt"`
+	UpdatedAt  time.Time           `json:"updatedAt"`
+}
+
+func newAdminCustomerResponse(ctx context.Context, customer *domain.Customer) AdminCustomerResponse {
+	customer = audit.Redact(ctx, customer)
+	response := AdminCustomerResponse{
+		ID:         customer.ID,
+		Name:       customer.Name,
+		CPF:        customer.CPF,
+		Email:      customer.Email,
+		CPFHistory: make([]CPFChangeResponse, 0, len(customer.CPFHistory)),
+		CreatedAt:  customer.CreatedAt,
+		UpdatedAt:  customer.UpdatedAt,
+	}
+	for _, change := range customer.CPFHistory {
+		response.CPFHistory = append(response.CPFHistory, CPFChangeResponse(change))
+	}
+	return response
+}
+
+// CorrectCPF godoc
+// @Summary Correct a customer's CPF
+// @Description Replaces a mistyped CPF. Requires the customers:admin scope and a reason; the previous CPF is kept in cpfHistory.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Customer ID"
+// @Param correction body CorrectCPFRequest true "New CPF and the reason for the change"
+// @Success 200 {object} AdminCustomerResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/customers/{id}/cpf [put]
+func (h *AdminHandler) CorrectCPF(c *gin.Context) {
+	var req CorrectCPFRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleBindingError(c, &req, err)
+		return
+	}
+
+	// The response also shows every CPF the customer held before
+	ctx := audit.WithDisclosedFields(c.Request.Context(), append(domain.PIIFields, domain.PIIFieldCPFHistory)...)
+	customer, err := h.correctCPFUseCase.Execute(ctx, c.Param("id"), req.CPF, req.Reason)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newAdminCustomerResponse(c.Request.Context(), customer))
+}
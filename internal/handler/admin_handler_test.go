@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"bytes"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/usecase"
+	"customer-service/pkg/errors"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func setupAdminRouter(mockRepo *MockRepository, principal *auth.Principal) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+	})
+	router.PUT("/admin/customers/:id/cpf", NewAdminHandler(usecase.NewCorrectCPFUseCase(mockRepo, nil)).CorrectCPF)
+	return router
+}
+
+func putJSON(router http.Handler, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPut, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCorrectCPF(t *testing.T) {
+	admin := &auth.Principal{Subject: "admin@fiap.com", Scopes: []string{auth.ScopeAdmin, auth.ScopeReadPII}}
+
+	t.Run("Replaces the CPF and records the history", func(t *testing.T) {
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("UpdateCPF", mock.Anything, customer).Return(nil)
+
+		w := putJSON(setupAdminRouter(mockRepo, admin), "/admin/customers/"+customer.ID+"/cpf",
+			`{"cpf":"529.982.247-25","reason":"Typo at registration"}`)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var response AdminCustomerResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "52998224725", response.CPF)
+		require.Len(t, response.CPFHistory, 1)
+		assert.Equal(t, "11144477735", response.CPFHistory[0].PreviousCPF)
+		assert.Equal(t, "admin@fiap.com", response.CPFHistory[0].ChangedBy)
+		assert.Equal(t, "Typo at registration", response.CPFHistory[0].Reason)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Requires the admin scope", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		reader := &auth.Principal{Subject: "support", Scopes: []string{auth.ScopeReadPII}}
+
+		w := putJSON(setupAdminRouter(mockRepo, reader), "/admin/customers/123/cpf",
+			`{"cpf":"52998224725","reason":"Typo at registration"}`)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "FORBIDDEN")
+		mockRepo.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Reason is required", func(t *testing.T) {
+		w := putJSON(setupAdminRouter(new(MockRepository), admin), "/admin/customers/123/cpf", `{"cpf":"52998224725"}`)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "INVALID_REQUEST")
+	})
+
+	t.Run("CPF held by another customer", func(t *testing.T) {
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("UpdateCPF", mock.Anything, mock.Anything).Return(errors.NewFieldConflictError(domain.ErrCPFAlreadyExists))
+
+		w := putJSON(setupAdminRouter(mockRepo, admin), "/admin/customers/"+customer.ID+"/cpf",
+			`{"cpf":"52998224725","reason":"Typo at registration"}`)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "CPF_ALREADY_EXISTS")
+	})
+}
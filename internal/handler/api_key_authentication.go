@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"customer-service/internal/auth"
+	"customer-service/internal/usecase"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader carries the API key of callers that cannot do OAuth.
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuthentication identifies callers sending an X-API-Key header. Other
+// requests go on to next, the bearer token or gateway authentication, when
+// there is one.
+func APIKeyAuthentication(authenticateUC *usecase.AuthenticateAPIKeyUseCase, next gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := strings.TrimSpace(c.GetHeader(APIKeyHeader))
+		if apiKey == "" {
+			if next != nil {
+				next(c)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		principal, err := authenticateUC.Execute(c.Request.Context(), apiKey)
+		if err != nil {
+			handleError(c, err)
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+		c.Next()
+	}
+}
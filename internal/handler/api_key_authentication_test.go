@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/usecase"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	key, apiKey, err := domain.NewAPIKey("partner", []string{auth.ScopeRead}, time.Now().Add(time.Hour), "admin")
+	require.NoError(t, err)
+
+	setup := func(mockRepo *MockAPIKeyRepository, next gin.HandlerFunc) *gin.Engine {
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.GET("/whoami", APIKeyAuthentication(usecase.NewAuthenticateAPIKeyUseCase(mockRepo), next), func(c *gin.Context) {
+			principal := auth.FromContext(c.Request.Context())
+			if principal == nil {
+				c.Status(http.StatusNoContent)
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"subject": principal.Subject, "scopes": principal.Scopes})
+		})
+		return router
+	}
+
+	t.Run("Reads the caller from the key", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+		mockRepo.On("FindByID", mock.Anything, key.ID).Return(key, nil)
+		mockRepo.On("TouchLastUsed", mock.Anything, key.ID, mock.Anything).Return(nil)
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		req.Header.Set(APIKeyHeader, apiKey)
+		w := httptest.NewRecorder()
+
+		setup(mockRepo, nil).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"subject":"apikey:`+key.ID+`","scopes":["customers:read"]}`, w.Body.String())
+	})
+
+	t.Run("Invalid key", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+		mockRepo.On("FindByID", mock.Anything, key.ID).Return(key, nil)
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		req.Header.Set(APIKeyHeader, "csk_"+key.ID+".guess")
+		w := httptest.NewRecorder()
+
+		setup(mockRepo, nil).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "INVALID_API_KEY")
+	})
+
+	t.Run("Without a key the next authentication decides", func(t *testing.T) {
+		bearer := Authentication(auth.NewTokenVerifier(auth.NewJWKS(nil), "issuer", "audience"))
+		w := httptest.NewRecorder()
+
+		setup(new(MockAPIKeyRepository), bearer).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/whoami", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "UNAUTHENTICATED")
+	})
+
+	t.Run("Without a key or other authentication the request goes on", func(t *testing.T) {
+		w := httptest.NewRecorder()
+
+		setup(new(MockAPIKeyRepository), nil).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/whoami", nil))
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"customer-service/internal/domain"
+	"customer-service/internal/usecase"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHandler lets administrators manage API keys.
+type APIKeyHandler struct {
+	createUseCase *usecase.CreateAPIKeyUseCase
+	listUseCase   *usecase.ListAPIKeysUseCase
+	revokeUseCase *usecase.RevokeAPIKeyUseCase
+}
+
+func NewAPIKeyHandler(
+	createUC *usecase.CreateAPIKeyUseCase,
+	listUC *usecase.ListAPIKeysUseCase,
+	revokeUC *usecase.RevokeAPIKeyUseCase,
+) *APIKeyHandler {
+	return &APIKeyHandler{
+		createUseCase: createUC,
+		listUseCase:   listUC,
+		revokeUseCase: revokeUC,
+	}
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string    `json:"name" binding:"required"`
+	Scopes    []string  `json:"scopes" binding:"required"`
+	ExpiresAt time.Time `json:"expiresAt" binding:"required"`
+}
+
+// APIKeyResponse describes a key without its secret.
+type APIKeyResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"createdBy,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+// CreatedAPIKeyResponse is the only response that includes the key itself.
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+func newAPIKeyResponse(key *domain.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Scopes:     key.Scopes,
+		CreatedBy:  key.CreatedBy,
+		CreatedAt:  key.CreatedAt,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+	}
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Creates a key for a batch job or partner. The key is only returned in this response; store it right away.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateAPIKeyRequest true "Name, scopes and expiry of the key"
+// @Success 201 {object} CreatedAPIKeyResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleBindingError(c, &req, err)
+		return
+	}
+
+	key, secret, err := h.createUseCase.Execute(c.Request.Context(), req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, CreatedAPIKeyResponse{APIKeyResponse: newAPIKeyResponse(key), Key: secret})
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description Lists every key, newest first, including revoked and expired ones. Secrets are never returned.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} APIKeyResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.listUseCase.Execute(c.Request.Context())
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response := make([]APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		response = append(response, newAPIKeyResponse(key))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Rejects the key from now on. Revoking a revoked key succeeds.
+// @Tags admin
+// @Security BearerAuth
+// @Param id path string true "API key ID"
+// @Success 204
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	if err := h.revokeUseCase.Execute(c.Request.Context(), c.Param("id")); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/usecase"
+	"customer-service/pkg/errors"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockAPIKeyRepository is a mock implementation of APIKeyRepository
+type MockAPIKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) FindByID(ctx context.Context, id string) (*domain.APIKey, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) List(ctx context.Context) ([]*domain.APIKey, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func newTestAPIKeyHandler(mockRepo *MockAPIKeyRepository) *APIKeyHandler {
+	return NewAPIKeyHandler(
+		usecase.NewCreateAPIKeyUseCase(mockRepo),
+		usecase.NewListAPIKeysUseCase(mockRepo),
+		usecase.NewRevokeAPIKeyUseCase(mockRepo),
+	)
+}
+
+func setupAPIKeyRouter(mockRepo *MockAPIKeyRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		admin := &auth.Principal{Subject: "admin@fiap.com", Scopes: []string{auth.ScopeAdmin}}
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), admin))
+	})
+
+	h := newTestAPIKeyHandler(mockRepo)
+	router.POST("/admin/api-keys", h.CreateAPIKey)
+	router.GET("/admin/api-keys", h.ListAPIKeys)
+	router.DELETE("/admin/api-keys/:id", h.RevokeAPIKey)
+	return router
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	t.Run("Returns the key once", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+		mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.APIKey")).Return(nil)
+		expiresAt := time.Now().Add(30 * 24 * time.Hour).UTC().Format(time.RFC3339)
+
+		w := postJSON(setupAPIKeyRouter(mockRepo), "/admin/api-keys",
+			`{"name":"Nightly export","scopes":["customers:read"],"expiresAt":"`+expiresAt+`"}`, "")
+
+		require.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		var response CreatedAPIKeyResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "Nightly export", response.Name)
+		assert.Equal(t, "admin@fiap.com", response.CreatedBy)
+		assert.Contains(t, response.Key, "csk_"+response.ID+".")
+
+		stored := mockRepo.Calls[0].Arguments.Get(1).(*domain.APIKey)
+		_, secret, _ := domain.ParseAPIKey(response.Key)
+		assert.NotContains(t, stored.SecretHash, secret)
+	})
+
+	t.Run("Missing fields", func(t *testing.T) {
+		w := postJSON(setupAPIKeyRouter(new(MockAPIKeyRepository)), "/admin/api-keys", `{"name":"Nightly export"}`, "")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestListAPIKeys(t *testing.T) {
+	key, _, _ := domain.NewAPIKey("partner", []string{auth.ScopeRead}, time.Now().Add(time.Hour), "admin")
+	mockRepo := new(MockAPIKeyRepository)
+	mockRepo.On("List", mock.Anything).Return([]*domain.APIKey{key}, nil)
+
+	w := httptest.NewRecorder()
+	setupAPIKeyRouter(mockRepo).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/api-keys", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"partner"`)
+	assert.NotContains(t, w.Body.String(), key.SecretHash)
+	assert.NotContains(t, w.Body.String(), key.Salt)
+	assert.NotContains(t, w.Body.String(), `"key"`)
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	t.Run("Revoked", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+		mockRepo.On("Revoke", mock.Anything, "key-1", mock.Anything).Return(nil)
+
+		w := httptest.NewRecorder()
+		setupAPIKeyRouter(mockRepo).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/api-keys/key-1", nil))
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("Unknown key", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+		mockRepo.On("Revoke", mock.Anything, "key-1", mock.Anything).
+			Return(errors.NewNotFoundError("API key not found", "API_KEY_NOT_FOUND"))
+
+		w := httptest.NewRecorder()
+		setupAPIKeyRouter(mockRepo).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/api-keys/key-1", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "API_KEY_NOT_FOUND")
+	})
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"customer-service/internal/auth"
+	"customer-service/pkg/errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Authentication requires a valid bearer token on every request and stores
+// the caller it identifies in the request context, where use cases read it
+// through auth.FromContext.
+func Authentication(verifier auth.Verifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := bearerToken(c.GetHeader("Authorization"))
+		if !ok {
+			c.Header("WWW-Authenticate", "Bearer")
+			handleError(c, errors.NewUnauthorizedError("Missing access token", "UNAUTHENTICATED"))
+			c.Abort()
+			return
+		}
+
+		principal, err := verifier.Verify(c.Request.Context(), token)
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			handleError(c, err)
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+		c.Next()
+	}
+}
+
+// bearerToken extracts the token of an "Authorization: Bearer" header. The
+// scheme is case-insensitive (RFC 7235).
+func bearerToken(header string) (string, bool) {
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"customer-service/internal/auth"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupAuthenticatedRouter(t *testing.T) (*gin.Engine, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	verifier := auth.NewTokenVerifier(
+		auth.NewJWKS(map[string]crypto.PublicKey{"test": &key.PublicKey}),
+		"https://auth.example.com", "customer-service",
+	)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/whoami", Authentication(verifier), func(c *gin.Context) {
+		principal := auth.FromContext(c.Request.Context())
+		c.JSON(http.StatusOK, gin.H{"subject": principal.Subject, "scopes": principal.Scopes})
+	})
+	return router, key
+}
+
+func testToken(t *testing.T, key *ecdsa.PrivateKey, expiresAt time.Time) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "kiosk-42",
+			Issuer:    "https://auth.example.com",
+			Audience:  jwt.ClaimStrings{"customer-service"},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		Scope: "customers:create customers:read",
+	})
+	token.Header["kid"] = "test"
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestAuthentication(t *testing.T) {
+	t.Run("Valid token puts the caller in the context", func(t *testing.T) {
+		router, key := setupAuthenticatedRouter(t)
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		req.Header.Set("Authorization", "Bearer "+testToken(t, key, time.Now().Add(time.Hour)))
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"subject":"kiosk-42","scopes":["customers:create","customers:read"]}`, w.Body.String())
+	})
+
+	t.Run("Missing token", func(t *testing.T) {
+		router, _ := setupAuthenticatedRouter(t)
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+		assert.Contains(t, w.Body.String(), "UNAUTHENTICATED")
+	})
+
+	t.Run("Other scheme", func(t *testing.T) {
+		router, _ := setupAuthenticatedRouter(t)
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		req.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "UNAUTHENTICATED")
+	})
+
+	t.Run("Expired token", func(t *testing.T) {
+		router, key := setupAuthenticatedRouter(t)
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		req.Header.Set("Authorization", "bearer "+testToken(t, key, time.Now().Add(-time.Hour)))
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, `Bearer error="invalid_token"`, w.Header().Get("WWW-Authenticate"))
+		assert.Contains(t, w.Body.String(), "INVALID_TOKEN")
+	})
+}
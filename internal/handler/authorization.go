@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"customer-service/internal/auth"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// routeOperations maps every route to the operation it performs, keyed by
+// method and path. /v1/customer mirrors /customer and is looked up without
+// its prefix. Routes missing from the table are denied.
+var routeOperations = map[string]auth.Operation{
+	"POST /customer":                       auth.OpCreateCustomer,
+	"POST /customer/lookup":                auth.OpReadCustomer,
+	"POST /customer/validate":              auth.OpValidateCustomer,
+	"GET /customer/code/:code":             auth.OpReadCustomer,
+	"GET /customer/me":                     auth.OpReadCustomer,
+	"PATCH /customer/me":                   auth.OpUpdateCustomer,
+	"DELETE /customer/me":                  auth.OpDeleteCustomer,
+	"GET /customer/:cpf":                   auth.OpReadCustomer,
+	"PATCH /customer/:id":                  auth.OpUpdateCustomer,
+	"DELETE /customer/:id":                 auth.OpDeleteCustomer,
+	"POST /v2/customers":                   auth.OpCreateCustomer,
+	"POST /v2/customers/lookup":            auth.OpReadCustomer,
+	"POST /v2/customers/validate":          auth.OpValidateCustomer,
+	"GET /v2/customers/code/:code":         auth.OpReadCustomer,
+	"GET /v2/customers/me":                 auth.OpReadCustomer,
+	"PATCH /v2/customers/me":               auth.OpUpdateCustomer,
+	"DELETE /v2/customers/me":              auth.OpDeleteCustomer,
+	"GET /v2/customers/cpf/:cpf":           auth.OpReadCustomer,
+	"PATCH /v2/customers/:id":              auth.OpUpdateCustomer,
+	"DELETE /v2/customers/:id":             auth.OpDeleteCustomer,
+	"POST /v2/customers/:id/email/confirm": auth.OpConfirmEmail,
+	"PUT /admin/customers/:id/cpf":         auth.OpCorrectCPF,
+	"POST /auth/identify":                  auth.OpIdentifyCustomer,
+	"POST /admin/api-keys":                 auth.OpManageAPIKeys,
+	"GET /admin/api-keys":                  auth.OpManageAPIKeys,
+	"DELETE /admin/api-keys/:id":           auth.OpManageAPIKeys,
+	"GET /admin/pii-access":                auth.OpReadAccessLog,
+	"POST /graphql":                        auth.OpQueryGraphQL,
+}
+
+// selfRoutes act on the caller's own customer record, so a customer token
+// is checked against its own subject.
+var selfRoutes = map[string]bool{
+	"GET /customer/me":        true,
+	"PATCH /customer/me":      true,
+	"DELETE /customer/me":     true,
+	"GET /v2/customers/me":    true,
+	"PATCH /v2/customers/me":  true,
+	"DELETE /v2/customers/me": true,
+}
+
+// Authorization rejects callers whose scopes do not allow the route's
+// operation before the request reaches the handler. The use cases check the
+// same policy again, which also covers GraphQL and gRPC.
+func Authorization() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + strings.TrimPrefix(c.FullPath(), "/v1")
+		if err := authorizeRoute(c, route); err != nil {
+			handleError(c, err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func authorizeRoute(c *gin.Context, route string) error {
+	ctx := c.Request.Context()
+	if !selfRoutes[route] {
+		return auth.Authorize(ctx, routeOperations[route])
+	}
+
+	var subject string
+	if principal := auth.FromContext(ctx); principal != nil {
+		subject = principal.Subject
+	}
+	return auth.AuthorizeCustomer(ctx, routeOperations[route], subject)
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/mailer"
+	"customer-service/internal/usecase"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupAuthorizedRouter(mockRepo *MockRepository, principal *auth.Principal) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	handler := NewCustomerHandler(
+		usecase.NewCreateCustomerUseCase(mockRepo, nil),
+		usecase.NewGetCustomerByCPFUseCase(mockRepo, nil),
+		usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
+		usecase.NewDeleteCustomerUseCase(mockRepo),
+	)
+	me := NewMeHandler(
+		usecase.NewGetCustomerByIDUseCase(mockRepo, nil),
+		usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
+		usecase.NewDeleteCustomerUseCase(mockRepo),
+	)
+	signingKey, _ := auth.GenerateSigningKey()
+	keys := auth.NewKeyRing(time.Hour, signingKey)
+	identify := NewIdentifyHandler(
+		usecase.NewIdentifyCustomerUseCase(usecase.NewGetCustomerByCPFUseCase(mockRepo, nil), auth.NewTokenIssuer(keys, "customer-service", "kiosk", time.Minute)),
+		keys,
+	)
+	apiKeys := newTestAPIKeyHandler(new(MockAPIKeyRepository))
+	SetupRoutes(router, handler, RouteConfig{
+		Authentication: func(c *gin.Context) {
+			if principal != nil {
+				c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+			}
+		},
+		Authorization: Authorization(),
+		Lookup:        NewLookupHandler(usecase.NewBatchGetCustomersUseCase(mockRepo, nil)).LookupCustomers,
+		Validate:      NewValidateHandler(usecase.NewValidateCustomerUseCase(mockRepo)).ValidateCustomer,
+		ConfirmEmail:  NewEmailHandler(usecase.NewConfirmEmailUseCase(mockRepo, nil)).ConfirmEmail,
+		CorrectCPF:    NewAdminHandler(usecase.NewCorrectCPFUseCase(mockRepo, nil)).CorrectCPF,
+		GetByCode:     NewCodeHandler(usecase.NewGetCustomerByCodeUseCase(mockRepo, nil)).GetCustomerByCode,
+		GetMe:         me.GetMe,
+		UpdateMe:      me.UpdateMe,
+		DeleteMe:      me.DeleteMe,
+		Identify:      identify.Identify,
+		CreateAPIKey:  apiKeys.CreateAPIKey,
+		ListAPIKeys:   apiKeys.ListAPIKeys,
+		RevokeAPIKey:  apiKeys.RevokeAPIKey,
+		ListPIIAccess: NewPIIAccessHandler(usecase.NewListPIIAccessUseCase(new(MockPIIAccessRepository))).ListPIIAccess,
+	})
+	return router
+}
+
+func TestRouteOperations_CoverEveryRoute(t *testing.T) {
+	router := setupAuthorizedRouter(new(MockRepository), nil)
+
+	for _, route := range router.Routes() {
+		key := route.Method + " " + strings.TrimPrefix(route.Path, "/v1")
+		assert.Contains(t, routeOperations, key, "route %s %s has no operation", route.Method, route.Path)
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	kiosk := &auth.Principal{Subject: "kiosk-42", Scopes: []string{auth.ScopeCreate, auth.ScopeRead}}
+
+	t.Run("Denied before reaching the handler", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		router := setupAuthorizedRouter(mockRepo, kiosk)
+
+		for _, path := range []string{"/customer/123", "/v1/customer/123", "/v2/customers/123"} {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, path, nil))
+
+			assert.Equal(t, http.StatusForbidden, w.Code, path)
+			assert.Contains(t, w.Body.String(), "FORBIDDEN")
+		}
+		mockRepo.AssertNotCalled(t, "FindByID")
+	})
+
+	t.Run("Allowed operations pass through", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(nil, nil)
+		router := setupAuthorizedRouter(mockRepo, kiosk)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/customer/11144477735", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Unknown routes are denied", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.GET("/customer/export", Authorization(), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/customer/export", nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+	t.Run("Customers reach only their own record", func(t *testing.T) {
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		self := &auth.Principal{Subject: customer.ID, Scopes: []string{auth.ScopeSelf}}
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		router := setupAuthorizedRouter(mockRepo, self)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/customer/me", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/customer/someone-else", nil))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+	t.Run("Only identifying callers issue tokens", func(t *testing.T) {
+		router := setupAuthorizedRouter(new(MockRepository), kiosk)
+
+		w := postJSON(router, "/auth/identify", `{}`, "")
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+	t.Run("Only administrators manage API keys", func(t *testing.T) {
+		router := setupAuthorizedRouter(new(MockRepository), kiosk)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/api-keys", nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+	t.Run("GraphQL needs a customer scope", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		identifying := &auth.Principal{Subject: "kiosk-7", Scopes: []string{auth.ScopeIdentify}}
+		for _, tt := range []struct {
+			principal *auth.Principal
+			expected  int
+		}{
+			{principal: kiosk, expected: http.StatusOK},
+			{principal: identifying, expected: http.StatusForbidden},
+		} {
+			router := gin.New()
+			router.POST("/graphql", func(c *gin.Context) {
+				c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), tt.principal))
+			}, Authorization(), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := postJSON(router, "/graphql", `{}`, "")
+
+			assert.Equal(t, tt.expected, w.Code, tt.principal.Subject)
+		}
+	})
+}
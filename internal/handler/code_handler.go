@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"customer-service/internal/audit"
+	"customer-service/internal/usecase"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CodeHandler struct {
+	getByCodeUseCase *usecase.GetCustomerByCodeUseCase
+}
+
+func NewCodeHandler(getByCodeUC *usecase.GetCustomerByCodeUseCase) *CodeHandler {
+	return &CodeHandler{getByCodeUseCase: getByCodeUC}
+}
+
+// GetCustomerByCode godoc
+// @Summary Get customer by short code
+// @Description Returns the customer identified by the short code given at creation. Case, hyphens and the letters I, L and O are tolerated.
+// @Tags customers
+// @Produce json
+// @Param code path string true "Short customer code"
+// @Success 200 {object} CustomerResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /customer/code/{code} [get]
+func (h *CodeHandler) GetCustomerByCode(c *gin.Context) {
+	customer, err := h.getByCodeUseCase.Execute(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newCustomerResponse(c.Request.Context(), customer))
+}
+
+// GetCustomerByCodeV2 godoc
+// @Summary Get customer by short code (v2)
+// @Description Returns the customer identified by the short code given at creation. Case, hyphens and the letters I, L and O are tolerated.
+// @Tags customers-v2
+// @Produce json
+// @Param code path string true "Short customer code"
+// @Param fields query string false "Comma-separated fields to return (id,name,cpf,email,code,pendingEmail,createdAt,updatedAt)"
+// @Success 200 {object} CustomerEnvelopeV2
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v2/customers/code/{code} [get]
+func (h *CodeHandler) GetCustomerByCodeV2(c *gin.Context) {
+	view, err := parseCustomerView(c)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	ctx := audit.WithDisclosedFields(c.Request.Context(), view.disclosed()...)
+	customer, err := h.getByCodeUseCase.Execute(ctx, c.Param("code"))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, view.render(customer))
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"customer-service/internal/domain"
+	"customer-service/internal/usecase"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func setupCodeRouter(mockRepo *MockRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	codes := NewCodeHandler(usecase.NewGetCustomerByCodeUseCase(mockRepo, nil))
+	router.GET("/customer/code/:code", codes.GetCustomerByCode)
+	router.GET("/v2/customers/code/:code", codes.GetCustomerByCodeV2)
+	return router
+}
+
+func TestGetCustomerByCode(t *testing.T) {
+	t.Run("Found", func(t *testing.T) {
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCode", mock.Anything, customer.Code).Return(customer, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/customer/code/"+customer.Code, nil)
+		w := httptest.NewRecorder()
+		setupCodeRouter(mockRepo).ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var response CustomerResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, customer.ID, response.ID)
+		assert.Equal(t, "John Doe", response.Name)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCode", mock.Anything, "7K3M9QXD").Return(nil, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/customer/code/7k3m-9qxd", nil)
+		w := httptest.NewRecorder()
+		setupCodeRouter(mockRepo).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "CUSTOMER_NOT_FOUND")
+	})
+
+	t.Run("Invalid code", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/customer/code/uuuu", nil)
+		w := httptest.NewRecorder()
+		setupCodeRouter(new(MockRepository)).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "INVALID_CODE")
+	})
+}
+
+func TestGetCustomerByCodeV2(t *testing.T) {
+	t.Run("Renders the v2 envelope with the selected fields", func(t *testing.T) {
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCode", mock.Anything, customer.Code).Return(customer, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/v2/customers/code/"+customer.Code+"?fields=id,code", nil)
+		w := httptest.NewRecorder()
+		setupCodeRouter(mockRepo).ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"data":{"id":"`+customer.ID+`","code":"`+customer.Code+`"}}`, w.Body.String())
+	})
+
+	t.Run("Unknown field", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v2/customers/code/7K3M9QXD?fields=password", nil)
+		w := httptest.NewRecorder()
+		setupCodeRouter(new(MockRepository)).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "INVALID_FIELDS")
+	})
+}
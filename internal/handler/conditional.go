@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"customer-service/internal/domain"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// customerCacheControl lets only the client cache customer data, and makes it
+// revalidate on every use since the record can change at any time.
+const customerCacheControl = "private, no-cache"
+
+// customerETag derives a strong validator from the customer revision and the
+// representation variant, so v1, v2 and each v2 view get distinct tags.
+func customerETag(version domain.CustomerVersion, variant string) string {
+	sum := sha256.Sum256([]byte(version.ID + "|" + version.UpdatedAt.UTC().Format(time.RFC3339Nano) + "|" + variant))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+func setCacheHeaders(c *gin.Context, version domain.CustomerVersion, variant string) {
+	c.Header("ETag", customerETag(version, variant))
+	c.Header("Last-Modified", version.UpdatedAt.UTC().Format(http.TimeFormat))
+	c.Header("Cache-Control", customerCacheControl)
+}
+
+func hasPreconditions(c *gin.Context) bool {
+	return c.GetHeader("If-None-Match") != "" || c.GetHeader("If-Modified-Since") != ""
+}
+
+// isNotModified evaluates If-None-Match and, only when it is absent,
+// If-Modified-Since (RFC 9110, section 13.2.2).
+func isNotModified(c *gin.Context, version domain.CustomerVersion, variant string) bool {
+	if header := c.GetHeader("If-None-Match"); header != "" {
+		etag := customerETag(version, variant)
+		for _, candidate := range strings.Split(header, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(c.GetHeader("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !version.UpdatedAt.Truncate(time.Second).After(since)
+}
+
+// respondNotModified answers a conditional GET by CPF from the version
+// projection. It returns false when the full customer must be sent.
+func (h *CustomerHandler) respondNotModified(c *gin.Context, cpf, variant string) bool {
+	if !hasPreconditions(c) {
+		return false
+	}
+
+	version, err := h.getByCPFUseCase.Version(c.Request.Context(), cpf)
+	if err != nil {
+		handleError(c, err)
+		return true
+	}
+
+	if !isNotModified(c, *version, variant) {
+		return false
+	}
+
+	setCacheHeaders(c, *version, variant)
+	c.Status(http.StatusNotModified)
+	return true
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"customer-service/internal/domain"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func conditionalGet(router http.Handler, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestConditionalGet(t *testing.T) {
+	customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+	customer.UpdatedAt = time.Date(2024, 5, 10, 12, 30, 15, 500_000_000, time.UTC)
+	version := customer.Version()
+
+	t.Run("Full response carries validators", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+
+		w := conditionalGet(setupTestRouterV2(mockRepo), "/customer/11144477735", nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, customerETag(version, "v1"), w.Header().Get("ETag"))
+		assert.Equal(t, "Fri, 10 May 2024 12:30:15 GMT", w.Header().Get("Last-Modified"))
+		assert.Equal(t, "private, no-cache", w.Header().Get("Cache-Control"))
+		mockRepo.AssertNotCalled(t, "GetVersionByCPF", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Matching If-None-Match returns 304 from the projection", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetVersionByCPF", mock.Anything, "11144477735").Return(&version, nil)
+
+		w := conditionalGet(setupTestRouterV2(mockRepo), "/customer/111.444.777-35", map[string]string{
+			"If-None-Match": `"other", ` + customerETag(version, "v1"),
+		})
+
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Empty(t, w.Body.String())
+		assert.Equal(t, customerETag(version, "v1"), w.Header().Get("ETag"))
+		assert.Equal(t, "private, no-cache", w.Header().Get("Cache-Control"))
+		mockRepo.AssertNotCalled(t, "FindByCPF", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Stale If-None-Match returns the full customer", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetVersionByCPF", mock.Anything, "11144477735").Return(&version, nil)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+
+		w := conditionalGet(setupTestRouterV2(mockRepo), "/customer/11144477735", map[string]string{
+			"If-None-Match": `"stale"`,
+		})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), customer.ID)
+	})
+
+	t.Run("If-Modified-Since at the last modification returns 304", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetVersionByCPF", mock.Anything, "11144477735").Return(&version, nil)
+
+		w := conditionalGet(setupTestRouterV2(mockRepo), "/customer/11144477735", map[string]string{
+			"If-Modified-Since": "Fri, 10 May 2024 12:30:15 GMT",
+		})
+
+		assert.Equal(t, http.StatusNotModified, w.Code)
+	})
+
+	t.Run("If-Modified-Since before the last modification returns 200", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetVersionByCPF", mock.Anything, "11144477735").Return(&version, nil)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+
+		w := conditionalGet(setupTestRouterV2(mockRepo), "/customer/11144477735", map[string]string{
+			"If-Modified-Since": "Fri, 10 May 2024 12:30:14 GMT",
+		})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("If-None-Match takes precedence over If-Modified-Since", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetVersionByCPF", mock.Anything, "11144477735").Return(&version, nil)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+
+		w := conditionalGet(setupTestRouterV2(mockRepo), "/customer/11144477735", map[string]string{
+			"If-None-Match":     `"stale"`,
+			"If-Modified-Since": "Fri, 10 May 2024 12:30:15 GMT",
+		})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Missing customer returns 404", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetVersionByCPF", mock.Anything, "11144477735").Return(nil, nil)
+
+		w := conditionalGet(setupTestRouterV2(mockRepo), "/customer/11144477735", map[string]string{
+			"If-None-Match": `"anything"`,
+		})
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("v2 views have their own validators", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+		router := setupTestRouterV2(mockRepo)
+
+		full := conditionalGet(router, "/v2/customers/cpf/11144477735", nil)
+		sparse := conditionalGet(router, "/v2/customers/cpf/11144477735?fields=id", nil)
+		v1 := conditionalGet(router, "/customer/11144477735", nil)
+
+		assert.NotEmpty(t, full.Header().Get("ETag"))
+		assert.NotEqual(t, full.Header().Get("ETag"), sparse.Header().Get("ETag"))
+		assert.NotEqual(t, full.Header().Get("ETag"), v1.Header().Get("ETag"))
+	})
+
+	t.Run("v2 revalidation returns 304", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil).Once()
+		mockRepo.On("GetVersionByCPF", mock.Anything, "11144477735").Return(&version, nil)
+		router := setupTestRouterV2(mockRepo)
+
+		first := conditionalGet(router, "/v2/customers/cpf/11144477735?fields=id,name", nil)
+		second := conditionalGet(router, "/v2/customers/cpf/11144477735?fields=id,name", map[string]string{
+			"If-None-Match": first.Header().Get("ETag"),
+		})
+
+		assert.Equal(t, http.StatusNotModified, second.Code)
+	})
+}
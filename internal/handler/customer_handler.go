@@ -1,9 +1,15 @@
 package handler
 
 import (
+	"context"
+	"customer-service/internal/audit"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
 	"customer-service/internal/usecase"
 	"customer-service/pkg/errors"
+	"customer-service/pkg/i18n"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -40,6 +46,30 @@ type UpdateCustomerRequest struct {
 	Email *string `json:"email,omitempty"`
 }
 
+// CustomerResponse is the v1 representation of a customer, frozen to the
+// contract of the original NestJS service. CPF and email are masked unless
+// the caller may read them.
+type CustomerResponse struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CPF       string    `json:"cpf"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func newCustomerResponse(ctx context.Context, customer *domain.Customer) CustomerResponse {
+	customer = audit.Redact(ctx, customer)
+	return CustomerResponse{
+		ID:        customer.ID,
+		Name:      customer.Name,
+		CPF:       customer.CPF,
+		Email:     customer.Email,
+		CreatedAt: customer.CreatedAt,
+		UpdatedAt: customer.UpdatedAt,
+	}
+}
+
 // CreateCustomer godoc
 // @Summary Create a new customer
 // @Description Create a new customer with name, cpf and email
@@ -47,18 +77,45 @@ type UpdateCustomerRequest struct {
 // @Accept json
 // @Produce json
 // @Param customer body CreateCustomerRequest true "Customer to create"
-// @Success 201 {object} domain.Customer
+// @Param Idempotency-Key header string false "Key that makes retries return the original response"
+// @Param dryRun query bool false "Run every check and return the would-be customer without creating it"
+// @Success 200 {object} CustomerResponse "Dry run"
+// @Success 201 {object} CustomerResponse
 // @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /customer [post]
 func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
+	if replay, ok := idempotentReplay(c); ok {
+		customer, err := h.createUseCase.Replay(c.Request.Context(), replay.CustomerID)
+		if err != nil {
+			handleError(c, err)
+			return
+		}
+		c.JSON(replay.StatusCode, newCustomerResponse(c.Request.Context(), customer))
+		return
+	}
+
+	dryRun, err := parseDryRun(c)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
 	var req CreateCustomerRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message":    "Invalid request body",
-			"statusCode": 400,
-			"error":      "INVALID_REQUEST",
-		})
+		handleBindingError(c, &req, err)
+		return
+	}
+
+	if dryRun {
+		customer, err := h.createUseCase.DryRun(c.Request.Context(), req.Name, req.CPF, req.Email)
+		if err != nil {
+			handleError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, newCustomerResponse(c.Request.Context(), customer))
 		return
 	}
 
@@ -68,7 +125,8 @@ func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, customer)
+	rememberCreatedCustomer(c, customer.ID)
+	c.JSON(http.StatusCreated, newCustomerResponse(c.Request.Context(), customer))
 }
 
 // GetCustomerByCPF godoc
@@ -77,55 +135,82 @@ func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
 // @Tags customers
 // @Produce json
 // @Param cpf path string true "CPF"
-// @Success 200 {object} domain.Customer
+// @Param If-None-Match header string false "ETag from a previous response"
+// @Param If-Modified-Since header string false "Last-Modified from a previous response"
+// @Success 200 {object} CustomerResponse
+// @Success 304 "Not modified"
 // @Failure 404 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /customer/{cpf} [get]
 func (h *CustomerHandler) GetCustomerByCPF(c *gin.Context) {
 	cpf := c.Param("cpf")
 
+	variant := v1Variant(c)
+	if h.respondNotModified(c, cpf, variant) {
+		return
+	}
+
 	customer, err := h.getByCPFUseCase.Execute(c.Request.Context(), cpf)
 	if err != nil {
 		handleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, customer)
+	setCacheHeaders(c, customer.Version(), variant)
+	c.JSON(http.StatusOK, newCustomerResponse(c.Request.Context(), customer))
+}
+
+// v1Variant tells masked and unmasked v1 representations apart for cache
+// validators.
+func v1Variant(c *gin.Context) string {
+	if auth.FromContext(c.Request.Context()).HasScope(auth.ScopeReadPII) {
+		return "v1|unmasked"
+	}
+	return "v1"
 }
 
 // UpdateCustomer godoc
 // @Summary Update a customer
-// @Description Update customer's name and/or email
+// @Description Update customer's name right away. A new email only takes effect once confirmed through POST /v2/customers/{id}/email/confirm.
 // @Tags customers
 // @Accept json
 // @Produce json
 // @Param id path string true "Customer ID"
 // @Param customer body UpdateCustomerRequest true "Customer fields to update"
-// @Success 200 {object} domain.Customer
+// @Param dryRun query bool false "Run every check and return the would-be customer without updating it"
+// @Success 200 {object} CustomerResponse
 // @Failure 400 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /customer/{id} [patch]
 func (h *CustomerHandler) UpdateCustomer(c *gin.Context) {
 	id := c.Param("id")
 
+	dryRun, err := parseDryRun(c)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
 	var req UpdateCustomerRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message":    "Invalid request body",
-			"statusCode": 400,
-			"error":      "INVALID_REQUEST",
-		})
+		handleBindingError(c, &req, err)
 		return
 	}
 
-	customer, err := h.updateUseCase.Execute(c.Request.Context(), id, req.Name, req.Email)
+	update := h.updateUseCase.Execute
+	if dryRun {
+		update = h.updateUseCase.DryRun
+	}
+
+	customer, err := update(c.Request.Context(), id, req.Name, req.Email)
 	if err != nil {
 		handleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, customer)
+	c.JSON(http.StatusOK, newCustomerResponse(c.Request.Context(), customer))
 }
 
 // DeleteCustomer godoc
@@ -150,18 +235,25 @@ func (h *CustomerHandler) DeleteCustomer(c *gin.Context) {
 }
 
 func handleError(c *gin.Context, err error) {
-	if appErr, ok := err.(*errors.AppError); ok {
-		c.JSON(appErr.StatusCode, gin.H{
-			"message":    appErr.Message,
-			"statusCode": appErr.StatusCode,
-			"error":      appErr.Code,
-		})
+	appErr := errors.From(err)
+	if appErr.StatusCode >= http.StatusInternalServerError {
+		logError(c, appErr)
+	}
+
+	// Only translate when asked so existing clients keep the English messages
+	if lang := requestLanguage(c); lang != "" {
+		appErr = i18n.Localize(appErr, lang)
+		c.Header("Content-Language", lang)
+	}
+
+	if wantsProblem(c) {
+		writeProblem(c, appErr)
 		return
 	}
 
-	c.JSON(http.StatusInternalServerError, gin.H{
-		"message":    "Internal server error",
-		"statusCode": 500,
-		"error":      "INTERNAL_ERROR",
+	c.JSON(appErr.StatusCode, gin.H{
+		"message":    appErr.Message,
+		"statusCode": appErr.StatusCode,
+		"error":      appErr.Code,
 	})
 }
@@ -3,7 +3,9 @@ package handler
 import (
 	"bytes"
 	"context"
+	"customer-service/internal/auth"
 	"customer-service/internal/domain"
+	"customer-service/internal/mailer"
 	"customer-service/internal/usecase"
 	"customer-service/pkg/errors"
 	"encoding/json"
@@ -34,6 +36,14 @@ func (m *MockRepository) FindByID(ctx context.Context, id string) (*domain.Custo
 	return args.Get(0).(*domain.Customer), args.Error(1)
 }
 
+func (m *MockRepository) FindByIDs(ctx context.Context, ids []string) ([]*domain.Customer, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Customer), args.Error(1)
+}
+
 func (m *MockRepository) FindByCPF(ctx context.Context, cpf string) (*domain.Customer, error) {
 	args := m.Called(ctx, cpf)
 	if args.Get(0) == nil {
@@ -42,19 +52,56 @@ func (m *MockRepository) FindByCPF(ctx context.Context, cpf string) (*domain.Cus
 	return args.Get(0).(*domain.Customer), args.Error(1)
 }
 
-func (m *MockRepository) FindByCPFOrEmail(ctx context.Context, cpf, email string) (*domain.Customer, error) {
-	args := m.Called(ctx, cpf, email)
+func (m *MockRepository) FindByCode(ctx context.Context, code string) (*domain.Customer, error) {
+	args := m.Called(ctx, code)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*domain.Customer), args.Error(1)
 }
 
+func (m *MockRepository) GetVersionByCPF(ctx context.Context, cpf string) (*domain.CustomerVersion, error) {
+	args := m.Called(ctx, cpf)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.CustomerVersion), args.Error(1)
+}
+
+func (m *MockRepository) FindByCPFs(ctx context.Context, cpfs []string) ([]*domain.Customer, error) {
+	args := m.Called(ctx, cpfs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Customer), args.Error(1)
+}
+
+func (m *MockRepository) FindByIDsOrCPFs(ctx context.Context, ids, cpfs []string) ([]*domain.Customer, error) {
+	args := m.Called(ctx, ids, cpfs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Customer), args.Error(1)
+}
+
+func (m *MockRepository) FindConflicts(ctx context.Context, cpf, email string) ([]*domain.Customer, error) {
+	args := m.Called(ctx, cpf, email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Customer), args.Error(1)
+}
+
 func (m *MockRepository) Update(ctx context.Context, customer *domain.Customer) error {
 	args := m.Called(ctx, customer)
 	return args.Error(0)
 }
 
+func (m *MockRepository) UpdateCPF(ctx context.Context, customer *domain.Customer) error {
+	args := m.Called(ctx, customer)
+	return args.Error(0)
+}
+
 func (m *MockRepository) Delete(ctx context.Context, id string) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
@@ -93,8 +140,6 @@ func TestCreateCustomer(t *testing.T) {
 				Email: "john@example.com",
 			},
 			mockSetup: func(m *MockRepository) {
-				m.On("FindByCPFOrEmail", mock.Anything, mock.Anything, mock.Anything).
-					Return(nil, nil)
 				m.On("Create", mock.Anything, mock.Anything).
 					Return(nil)
 			},
@@ -117,19 +162,18 @@ func TestCreateCustomer(t *testing.T) {
 			expectedError:  "INVALID_REQUEST",
 		},
 		{
-			name: "Customer already exists",
+			name: "Email already exists",
 			requestBody: CreateCustomerRequest{
 				Name:  "John Doe",
 				CPF:   "111.444.777-35",
 				Email: "john@example.com",
 			},
 			mockSetup: func(m *MockRepository) {
-				existing, _ := domain.NewCustomer("Jane", "11144477735", "jane@example.com")
-				m.On("FindByCPFOrEmail", mock.Anything, mock.Anything, mock.Anything).
-					Return(existing, nil)
+				m.On("Create", mock.Anything, mock.Anything).
+					Return(errors.NewFieldConflictError(domain.ErrEmailAlreadyExists))
 			},
 			expectedStatus: http.StatusConflict,
-			expectedError:  "CUSTOMER_ALREADY_EXISTS",
+			expectedError:  "EMAIL_ALREADY_EXISTS",
 		},
 		{
 			name: "Invalid CPF",
@@ -150,9 +194,9 @@ func TestCreateCustomer(t *testing.T) {
 			tt.mockSetup(mockRepo)
 
 			handler := NewCustomerHandler(
-				usecase.NewCreateCustomerUseCase(mockRepo),
-				usecase.NewGetCustomerByCPFUseCase(mockRepo),
-				usecase.NewUpdateCustomerUseCase(mockRepo),
+				usecase.NewCreateCustomerUseCase(mockRepo, nil),
+				usecase.NewGetCustomerByCPFUseCase(mockRepo, nil),
+				usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
 				usecase.NewDeleteCustomerUseCase(mockRepo),
 			)
 			router := setupTestRouter(handler)
@@ -219,9 +263,9 @@ func TestGetCustomerByCPF(t *testing.T) {
 			tt.mockSetup(mockRepo)
 
 			handler := NewCustomerHandler(
-				usecase.NewCreateCustomerUseCase(mockRepo),
-				usecase.NewGetCustomerByCPFUseCase(mockRepo),
-				usecase.NewUpdateCustomerUseCase(mockRepo),
+				usecase.NewCreateCustomerUseCase(mockRepo, nil),
+				usecase.NewGetCustomerByCPFUseCase(mockRepo, nil),
+				usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
 				usecase.NewDeleteCustomerUseCase(mockRepo),
 			)
 			router := setupTestRouter(handler)
@@ -266,6 +310,8 @@ func TestUpdateCustomer(t *testing.T) {
 				customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
 				m.On("FindByID", mock.Anything, "123").
 					Return(customer, nil)
+				m.On("FindConflicts", mock.Anything, "", "jane@example.com").
+					Return([]*domain.Customer{}, nil)
 				m.On("Update", mock.Anything, mock.Anything).
 					Return(nil)
 			},
@@ -300,9 +346,9 @@ func TestUpdateCustomer(t *testing.T) {
 			tt.mockSetup(mockRepo)
 
 			handler := NewCustomerHandler(
-				usecase.NewCreateCustomerUseCase(mockRepo),
-				usecase.NewGetCustomerByCPFUseCase(mockRepo),
-				usecase.NewUpdateCustomerUseCase(mockRepo),
+				usecase.NewCreateCustomerUseCase(mockRepo, nil),
+				usecase.NewGetCustomerByCPFUseCase(mockRepo, nil),
+				usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
 				usecase.NewDeleteCustomerUseCase(mockRepo),
 			)
 			router := setupTestRouter(handler)
@@ -326,6 +372,9 @@ func TestUpdateCustomer(t *testing.T) {
 	}
 }
 
+// deleter may erase customers, which requires an identified caller.
+var deleter = &auth.Principal{Subject: "backoffice", Scopes: []string{auth.ScopeDelete}}
+
 func TestDeleteCustomer(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -364,14 +413,15 @@ func TestDeleteCustomer(t *testing.T) {
 			tt.mockSetup(mockRepo)
 
 			handler := NewCustomerHandler(
-				usecase.NewCreateCustomerUseCase(mockRepo),
-				usecase.NewGetCustomerByCPFUseCase(mockRepo),
-				usecase.NewUpdateCustomerUseCase(mockRepo),
+				usecase.NewCreateCustomerUseCase(mockRepo, nil),
+				usecase.NewGetCustomerByCPFUseCase(mockRepo, nil),
+				usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
 				usecase.NewDeleteCustomerUseCase(mockRepo),
 			)
 			router := setupTestRouter(handler)
 
 			req := httptest.NewRequest(http.MethodDelete, "/customer/"+tt.customerID, nil)
+			req = req.WithContext(auth.WithPrincipal(req.Context(), deleter))
 			w := httptest.NewRecorder()
 
 			router.ServeHTTP(w, req)
@@ -436,3 +486,25 @@ func TestHandleError(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateCustomer_IgnoresCPF(t *testing.T) {
+	customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+	mockRepo := new(MockRepository)
+	mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+	mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(c *domain.Customer) bool {
+		return c.CPF == "11144477735"
+	})).Return(nil)
+
+	handler := NewCustomerHandler(
+		usecase.NewCreateCustomerUseCase(mockRepo, nil),
+		usecase.NewGetCustomerByCPFUseCase(mockRepo, nil),
+		usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
+		usecase.NewDeleteCustomerUseCase(mockRepo),
+	)
+	router := setupTestRouter(handler)
+	w := patchJSON(router, "/customer/"+customer.ID, `{"name":"Jane Doe","cpf":"52998224725"}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"cpf":"11144477735"`)
+	mockRepo.AssertNotCalled(t, "UpdateCPF", mock.Anything, mock.Anything)
+}
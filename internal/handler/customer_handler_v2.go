@@ -0,0 +1,229 @@
+package handler
+
+import (
+	"customer-service/internal/audit"
+	"customer-service/internal/domain"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateCustomerRequestV2 struct {
+	Name  string `json:"name" binding:"required"`
+	CPF   string `json:"cpf" binding:"required"`
+	Email string `json:"email" binding:"required,email"`
+}
+
+type UpdateCustomerRequestV2 struct {
+	Name  *string `json:"name,omitempty"`
+	Email *string `json:"email,omitempty"`
+}
+
+type CustomerResponseV2 struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	CPF          string    `json:"cpf"`
+	Email        string    `json:"email"`
+	Code         string    `json:"code,omitempty"`
+	PendingEmail string    `json:"pendingEmail,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+	Anonymized   bool      `json:"anonymized,omitempty"`
+}
+
+// CustomerEnvelopeV2 wraps v2 payloads so metadata can be added without
+// changing the customer representation.
+type CustomerEnvelopeV2 struct {
+	Data CustomerResponseV2 `json:"data"`
+}
+
+func newCustomerEnvelopeV2(customer *domain.Customer) CustomerEnvelopeV2 {
+	return CustomerEnvelopeV2{
+		Data: CustomerResponseV2{
+			ID:           customer.ID,
+			Name:         customer.Name,
+			CPF:          customer.CPF,
+			Email:        customer.Email,
+			Code:         customer.Code,
+			PendingEmail: customer.PendingEmail,
+			CreatedAt:    customer.CreatedAt,
+			UpdatedAt:    customer.UpdatedAt,
+			Anonymized:   customer.Anonymized,
+		},
+	}
+}
+
+// CreateCustomerV2 godoc
+// @Summary Create a new customer (v2)
+// @Description Create a new customer with name, cpf and email
+// @Tags customers-v2
+// @Accept json
+// @Produce json
+// @Param customer body CreateCustomerRequestV2 true "Customer to create"
+// @Param Idempotency-Key header string false "Key that makes retries return the original response"
+// @Param fields query string false "Comma-separated fields to return (id,name,cpf,email,code,pendingEmail,createdAt,updatedAt)"
+// @Param dryRun query bool false "Run every check and return the would-be customer without creating it"
+// @Success 200 {object} CustomerEnvelopeV2 "Dry run"
+// @Success 201 {object} CustomerEnvelopeV2
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v2/customers [post]
+func (h *CustomerHandler) CreateCustomerV2(c *gin.Context) {
+	view, err := parseCustomerView(c)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	if replay, ok := idempotentReplay(c); ok {
+		ctx := audit.WithDisclosedFields(c.Request.Context(), view.disclosed()...)
+		customer, err := h.createUseCase.Replay(ctx, replay.CustomerID)
+		if err != nil {
+			handleError(c, err)
+			return
+		}
+		c.Header("Location", "/v2/customers/"+customer.ID)
+		c.JSON(replay.StatusCode, view.render(customer))
+		return
+	}
+
+	dryRun, err := parseDryRun(c)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	var req CreateCustomerRequestV2
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleBindingError(c, &req, err)
+		return
+	}
+
+	ctx := audit.WithDisclosedFields(c.Request.Context(), view.disclosed()...)
+	if dryRun {
+		customer, err := h.createUseCase.DryRun(ctx, req.Name, req.CPF, req.Email)
+		if err != nil {
+			handleError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, view.render(customer))
+		return
+	}
+
+	customer, err := h.createUseCase.Execute(ctx, req.Name, req.CPF, req.Email)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	rememberCreatedCustomer(c, customer.ID)
+	c.Header("Location", "/v2/customers/"+customer.ID)
+	c.JSON(http.StatusCreated, view.render(customer))
+}
+
+// GetCustomerByCPFV2 godoc
+// @Summary Get customer by CPF (v2)
+// @Description Returns a customer identified by CPF
+// @Tags customers-v2
+// @Produce json
+// @Param cpf path string true "CPF"
+// @Param fields query string false "Comma-separated fields to return (id,name,cpf,email,code,pendingEmail,createdAt,updatedAt)"
+// @Param If-None-Match header string false "ETag from a previous response"
+// @Param If-Modified-Since header string false "Last-Modified from a previous response"
+// @Success 200 {object} CustomerEnvelopeV2
+// @Success 304 "Not modified"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v2/customers/cpf/{cpf} [get]
+func (h *CustomerHandler) GetCustomerByCPFV2(c *gin.Context) {
+	view, err := parseCustomerView(c)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	if h.respondNotModified(c, c.Param("cpf"), view.variant()) {
+		return
+	}
+
+	ctx := audit.WithDisclosedFields(c.Request.Context(), view.disclosed()...)
+	customer, err := h.getByCPFUseCase.Execute(ctx, c.Param("cpf"))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	setCacheHeaders(c, customer.Version(), view.variant())
+	c.JSON(http.StatusOK, view.render(customer))
+}
+
+// UpdateCustomerV2 godoc
+// @Summary Update a customer (v2)
+// @Description Update customer's name right away. A new email is returned as pendingEmail until it is confirmed.
+// @Tags customers-v2
+// @Accept json
+// @Produce json
+// @Param id path string true "Customer ID"
+// @Param customer body UpdateCustomerRequestV2 true "Customer fields to update"
+// @Param fields query string false "Comma-separated fields to return (id,name,cpf,email,code,pendingEmail,createdAt,updatedAt)"
+// @Param dryRun query bool false "Run every check and return the would-be customer without updating it"
+// @Success 200 {object} CustomerEnvelopeV2
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v2/customers/{id} [patch]
+func (h *CustomerHandler) UpdateCustomerV2(c *gin.Context) {
+	view, err := parseCustomerView(c)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	dryRun, err := parseDryRun(c)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	var req UpdateCustomerRequestV2
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleBindingError(c, &req, err)
+		return
+	}
+
+	update := h.updateUseCase.Execute
+	if dryRun {
+		update = h.updateUseCase.DryRun
+	}
+
+	ctx := audit.WithDisclosedFields(c.Request.Context(), view.disclosed()...)
+	customer, err := update(ctx, c.Param("id"), req.Name, req.Email)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, view.render(customer))
+}
+
+// DeleteCustomerV2 godoc
+// @Summary Delete a customer (v2)
+// @Description Delete a customer by ID
+// @Tags customers-v2
+// @Param id path string true "Customer ID"
+// @Success 204
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v2/customers/{id} [delete]
+func (h *CustomerHandler) DeleteCustomerV2(c *gin.Context) {
+	if err := h.deleteUseCase.Execute(c.Request.Context(), c.Param("id")); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
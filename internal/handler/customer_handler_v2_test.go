@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"bytes"
+	"customer-service/internal/domain"
+	"customer-service/internal/mailer"
+	"customer-service/internal/usecase"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupTestRouterV2(mockRepo *MockRepository) *gin.Engine {
+	handler := NewCustomerHandler(
+		usecase.NewCreateCustomerUseCase(mockRepo, nil),
+		usecase.NewGetCustomerByCPFUseCase(mockRepo, nil),
+		usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
+		usecase.NewDeleteCustomerUseCase(mockRepo),
+	)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	SetupRoutes(router, handler, RouteConfig{})
+	return router
+}
+
+func TestCreateCustomerV2(t *testing.T) {
+	t.Run("Successfully create customer", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+		body, _ := json.Marshal(CreateCustomerRequestV2{
+			Name:  "John Doe",
+			CPF:   "111.444.777-35",
+			Email: "john@example.com",
+		})
+		req := httptest.NewRequest(http.MethodPost, "/v2/customers", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		setupTestRouterV2(mockRepo).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response CustomerEnvelopeV2
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "11144477735", response.Data.CPF)
+		assert.Equal(t, "/v2/customers/"+response.Data.ID, w.Header().Get("Location"))
+		assert.Empty(t, w.Header().Get("Deprecation"))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid request body", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+
+		req := httptest.NewRequest(http.MethodPost, "/v2/customers", bytes.NewBufferString("invalid json"))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		setupTestRouterV2(mockRepo).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "INVALID_REQUEST", response["error"])
+	})
+}
+
+func TestGetCustomerByCPFV2(t *testing.T) {
+	t.Run("Successfully get customer", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+
+		w := httptest.NewRecorder()
+		setupTestRouterV2(mockRepo).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v2/customers/cpf/11144477735", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response CustomerEnvelopeV2
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, customer.ID, response.Data.ID)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Customer not found", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(nil, nil)
+
+		w := httptest.NewRecorder()
+		setupTestRouterV2(mockRepo).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v2/customers/cpf/11144477735", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "CUSTOMER_NOT_FOUND", response["error"])
+	})
+}
+
+func TestUpdateCustomerV2(t *testing.T) {
+	name := "Jane Doe"
+
+	t.Run("Successfully update customer", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo.On("FindByID", mock.Anything, "123").Return(customer, nil)
+		mockRepo.On("Update", mock.Anything, mock.Anything).Return(nil)
+
+		body, _ := json.Marshal(UpdateCustomerRequestV2{Name: &name})
+		req := httptest.NewRequest(http.MethodPatch, "/v2/customers/123", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		setupTestRouterV2(mockRepo).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response CustomerEnvelopeV2
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, name, response.Data.Name)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid request body", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+
+		req := httptest.NewRequest(http.MethodPatch, "/v2/customers/123", bytes.NewBufferString("invalid json"))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		setupTestRouterV2(mockRepo).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestDeleteCustomerV2(t *testing.T) {
+	t.Run("Successfully delete customer", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo.On("FindByID", mock.Anything, "123").Return(customer, nil)
+		mockRepo.On("Delete", mock.Anything, "123").Return(nil)
+
+		w := httptest.NewRecorder()
+		setupTestRouterWithPrincipal(mockRepo, deleter).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/v2/customers/123", nil))
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Customer not found", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByID", mock.Anything, "999").Return(nil, nil)
+
+		w := httptest.NewRecorder()
+		setupTestRouterWithPrincipal(mockRepo, deleter).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/v2/customers/999", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestV1RoutesAreDeprecated(t *testing.T) {
+	mockRepo := new(MockRepository)
+	customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+	mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+
+	handler := NewCustomerHandler(
+		usecase.NewCreateCustomerUseCase(mockRepo, nil),
+		usecase.NewGetCustomerByCPFUseCase(mockRepo, nil),
+		usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
+		usecase.NewDeleteCustomerUseCase(mockRepo),
+	)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	SetupRoutes(router, handler, RouteConfig{V1Deprecation: Deprecation(time.Time{}, "/v2/customers")})
+
+	for _, path := range []string{"/customer/11144477735", "/v1/customer/11144477735"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "true", w.Header().Get("Deprecation"), path)
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "11144477735", response["cpf"])
+	}
+}
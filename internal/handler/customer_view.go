@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/pkg/errors"
+	"customer-service/pkg/validator"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// customerFieldsV2 lists the CustomerResponseV2 members accepted by ?fields=.
+var customerFieldsV2 = []string{"id", "name", "cpf", "email", "code", "pendingEmail", "createdAt", "updatedAt"}
+
+// customerView describes how a v2 customer is rendered for the current
+// caller: which fields were selected and whether PII may be shown in full.
+type customerView struct {
+	fields   []string
+	unmasked bool
+}
+
+// parseCustomerView reads ?fields= and the caller's scopes. It runs before
+// the use case so an invalid selection never triggers a write.
+func parseCustomerView(c *gin.Context) (customerView, error) {
+	view := customerView{
+		unmasked: auth.CanReadPII(c.Request.Context(), ""),
+	}
+
+	for _, field := range strings.Split(c.Query("fields"), ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || slices.Contains(view.fields, field) {
+			continue
+		}
+		if !slices.Contains(customerFieldsV2, field) {
+			err := errors.NewValidationError("Unknown field: "+field, "INVALID_FIELDS")
+			err.Params = map[string]string{"field": field}
+			return customerView{}, err
+		}
+		view.fields = append(view.fields, field)
+	}
+	return view, nil
+}
+
+// disclosed returns the PII fields the view shows unmasked, for the access
+// log.
+func (v customerView) disclosed() []string {
+	if !v.unmasked {
+		return nil
+	}
+	if len(v.fields) == 0 {
+		return domain.PIIFields
+	}
+	var fields []string
+	for _, field := range v.fields {
+		if slices.Contains(domain.PIIFields, field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// variant identifies the representation for cache validators.
+func (v customerView) variant() string {
+	return fmt.Sprintf("v2|%s|%t", strings.Join(v.fields, ","), v.unmasked)
+}
+
+// render masks CPF and email unless the caller holds auth.ScopeReadPII and
+// keeps only the selected fields.
+func (v customerView) render(customer *domain.Customer) interface{} {
+	envelope := newCustomerEnvelopeV2(customer)
+	if !v.unmasked {
+		// Anonymized customers have no CPF or email left to mask
+		if envelope.Data.CPF != "" {
+			envelope.Data.CPF = validator.MaskCPF(envelope.Data.CPF)
+		}
+		if envelope.Data.Email != "" {
+			envelope.Data.Email = validator.MaskEmail(envelope.Data.Email)
+		}
+		if envelope.Data.PendingEmail != "" {
+			envelope.Data.PendingEmail = validator.MaskEmail(envelope.Data.PendingEmail)
+		}
+	}
+	if len(v.fields) == 0 {
+		return envelope
+	}
+
+	all := map[string]interface{}{
+		"id":           envelope.Data.ID,
+		"name":         envelope.Data.Name,
+		"cpf":          envelope.Data.CPF,
+		"email":        envelope.Data.Email,
+		"code":         envelope.Data.Code,
+		"pendingEmail": envelope.Data.PendingEmail,
+		"createdAt":    envelope.Data.CreatedAt,
+		"updatedAt":    envelope.Data.UpdatedAt,
+	}
+	selected := make(map[string]interface{}, len(v.fields))
+	for _, field := range v.fields {
+		selected[field] = all[field]
+	}
+	return gin.H{"data": selected}
+}
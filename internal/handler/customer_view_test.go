@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"bytes"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/mailer"
+	"customer-service/internal/usecase"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestRouterWithPrincipal authenticates every request as principal.
+func setupTestRouterWithPrincipal(mockRepo *MockRepository, principal *auth.Principal) *gin.Engine {
+	handler := NewCustomerHandler(
+		usecase.NewCreateCustomerUseCase(mockRepo, nil),
+		usecase.NewGetCustomerByCPFUseCase(mockRepo, nil),
+		usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
+		usecase.NewDeleteCustomerUseCase(mockRepo),
+	)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+		c.Next()
+	})
+	SetupRoutes(router, handler, RouteConfig{})
+	return router
+}
+
+func getCustomerData(t *testing.T, router *gin.Engine, path string) (int, map[string]interface{}) {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var response struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return w.Code, response.Data
+}
+
+func TestCustomerView(t *testing.T) {
+	customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+
+	t.Run("Unmasked without authentication", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+
+		code, data := getCustomerData(t, setupTestRouterV2(mockRepo), "/v2/customers/cpf/11144477735")
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "11144477735", data["cpf"])
+		assert.Equal(t, "john@example.com", data["email"])
+	})
+
+	t.Run("Scope without PII access is masked", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+		router := setupTestRouterWithPrincipal(mockRepo, &auth.Principal{Subject: "kiosk", Scopes: []string{auth.ScopeRead}})
+
+		code, data := getCustomerData(t, router, "/v2/customers/cpf/11144477735")
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "***.444.777-**", data["cpf"])
+		assert.Equal(t, "j***@example.com", data["email"])
+		assert.Equal(t, "John Doe", data["name"])
+	})
+
+	t.Run("Anonymized fields stay empty when masked", func(t *testing.T) {
+		anonymized := &domain.Customer{ID: customer.ID, Name: "Anonymized", Anonymized: true}
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(anonymized, nil)
+		router := setupTestRouterWithPrincipal(mockRepo, &auth.Principal{Subject: "kiosk", Scopes: []string{auth.ScopeRead}})
+
+		_, data := getCustomerData(t, router, "/v2/customers/cpf/11144477735")
+
+		assert.Equal(t, "", data["cpf"])
+		assert.Equal(t, "", data["email"])
+	})
+
+	t.Run("PII scope returns unmasked data", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+		router := setupTestRouterWithPrincipal(mockRepo, &auth.Principal{Subject: "backoffice", Scopes: []string{auth.ScopeRead, auth.ScopeReadPII}})
+
+		_, data := getCustomerData(t, router, "/v2/customers/cpf/11144477735")
+
+		assert.Equal(t, "11144477735", data["cpf"])
+		assert.Equal(t, "john@example.com", data["email"])
+	})
+
+	t.Run("Returns only the selected fields", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+
+		code, data := getCustomerData(t, setupTestRouterV2(mockRepo), "/v2/customers/cpf/11144477735?fields=id,name,id")
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, map[string]interface{}{"id": customer.ID, "name": "John Doe"}, data)
+	})
+
+	t.Run("Selected PII fields are still masked", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+
+		router := setupTestRouterWithPrincipal(mockRepo, &auth.Principal{Subject: "kiosk", Scopes: []string{auth.ScopeRead}})
+
+		_, data := getCustomerData(t, router, "/v2/customers/cpf/11144477735?fields=cpf")
+
+		assert.Equal(t, map[string]interface{}{"cpf": "***.444.777-**"}, data)
+	})
+
+	t.Run("Unknown field is rejected before any write", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+
+		body, _ := json.Marshal(CreateCustomerRequestV2{Name: "John Doe", CPF: "11144477735", Email: "john@example.com"})
+		req := httptest.NewRequest(http.MethodPost, "/v2/customers?fields=id,password", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		setupTestRouterV2(mockRepo).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "INVALID_FIELDS", response["error"])
+		assert.Equal(t, "Unknown field: password", response["message"])
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("v1 responses are masked too", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+		principal := &auth.Principal{Subject: "kiosk", Scopes: []string{auth.ScopeRead}}
+
+		w := httptest.NewRecorder()
+		setupTestRouterWithPrincipal(mockRepo, principal).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/customer/11144477735", nil))
+
+		var response CustomerResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "***.444.777-**", response.CPF)
+		assert.Equal(t, "j***@example.com", response.Email)
+	})
+
+	t.Run("v1 keeps its contract without authentication", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+
+		w := httptest.NewRecorder()
+		setupTestRouterV2(mockRepo).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/customer/11144477735", nil))
+
+		var response CustomerResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "11144477735", response.CPF)
+		assert.Equal(t, "john@example.com", response.Email)
+	})
+
+	t.Run("v1 responses are unmasked with the PII scope", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+		principal := &auth.Principal{Subject: "backoffice", Scopes: []string{auth.ScopeRead, auth.ScopeReadPII}}
+
+		w := httptest.NewRecorder()
+		setupTestRouterWithPrincipal(mockRepo, principal).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/customer/11144477735", nil))
+
+		var response CustomerResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "11144477735", response.CPF)
+		assert.Equal(t, "john@example.com", response.Email)
+	})
+}
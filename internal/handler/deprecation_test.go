@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeprecation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Sets deprecation, sunset and successor headers", func(t *testing.T) {
+		router := gin.New()
+		sunset := time.Date(2027, time.June, 30, 0, 0, 0, 0, time.UTC)
+		router.GET("/v1/customer/:cpf", Deprecation(sunset, "/v2/customers"), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/customer/11144477735", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "true", w.Header().Get("Deprecation"))
+		assert.Equal(t, "Wed, 30 Jun 2027 00:00:00 GMT", w.Header().Get("Sunset"))
+		assert.Equal(t, `</v2/customers>; rel="successor-version"`, w.Header().Get("Link"))
+	})
+
+	t.Run("Omits sunset when not configured", func(t *testing.T) {
+		router := gin.New()
+		router.GET("/v1/customer/:cpf", Deprecation(time.Time{}, ""), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/customer/11144477735", nil))
+
+		assert.Equal(t, "true", w.Header().Get("Deprecation"))
+		assert.Empty(t, w.Header().Get("Sunset"))
+		assert.Empty(t, w.Header().Get("Link"))
+	})
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"customer-service/pkg/errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DryRunParam is the query parameter that makes create and update run every
+// check and return the would-be customer without persisting it.
+const DryRunParam = "dryRun"
+
+func parseDryRun(c *gin.Context) (bool, error) {
+	value, ok := c.GetQuery(DryRunParam)
+	if !ok {
+		return false, nil
+	}
+	dryRun, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.NewValidationError("dryRun must be true or false", "INVALID_DRY_RUN")
+	}
+	return dryRun, nil
+}
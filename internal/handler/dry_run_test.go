@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"bytes"
+	"customer-service/internal/domain"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func patchJSON(router http.Handler, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPatch, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestDryRun(t *testing.T) {
+	t.Run("Create returns the would-be customer without creating it", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindConflicts", mock.Anything, "11144477735", "john@example.com").Return([]*domain.Customer{}, nil)
+
+		w := postJSON(setupTestRouterV2(mockRepo), "/customer?dryRun=true",
+			`{"name":"John Doe","cpf":"111.444.777-35","email":"john@example.com"}`, "")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response CustomerResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "11144477735", response.CPF)
+		assert.NotEmpty(t, response.ID)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Create v2 has no Location", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindConflicts", mock.Anything, mock.Anything, mock.Anything).Return([]*domain.Customer{}, nil)
+
+		w := postJSON(setupTestRouterV2(mockRepo), "/v2/customers?dryRun=true",
+			`{"name":"John Doe","cpf":"11144477735","email":"john@example.com"}`, "")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Location"))
+		assert.Contains(t, w.Body.String(), `"data"`)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Create reports conflicts", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		existing, _ := domain.NewCustomer("Jane Doe", "11144477735", "jane@example.com")
+		mockRepo.On("FindConflicts", mock.Anything, mock.Anything, mock.Anything).Return([]*domain.Customer{existing}, nil)
+
+		w := postJSON(setupTestRouterV2(mockRepo), "/customer?dryRun=true",
+			`{"name":"John Doe","cpf":"11144477735","email":"john@example.com"}`, ProblemContentType)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		var problem Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, "CPF_ALREADY_EXISTS", problem.Code)
+		assert.Equal(t, []ProblemField{{Pointer: "/cpf", Code: "CPF_ALREADY_EXISTS", Detail: "CPF is already registered"}}, problem.Errors)
+	})
+
+	t.Run("Update returns the would-be customer without updating it", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("FindConflicts", mock.Anything, "", "jane@example.com").Return([]*domain.Customer{}, nil)
+
+		w := patchJSON(setupTestRouterV2(mockRepo), "/v2/customers/"+customer.ID+"?dryRun=true&fields=email,pendingEmail", `{"email":"jane@example.com"}`)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"data":{"email":"john@example.com","pendingEmail":"jane@example.com"}}`, w.Body.String())
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Update reports a taken email", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		other, _ := domain.NewCustomer("Jane Doe", "52998224725", "jane@example.com")
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("FindConflicts", mock.Anything, "", "jane@example.com").Return([]*domain.Customer{other}, nil)
+
+		w := patchJSON(setupTestRouterV2(mockRepo), "/customer/"+customer.ID+"?dryRun=true", `{"email":"jane@example.com"}`)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "EMAIL_ALREADY_EXISTS")
+	})
+
+	t.Run("dryRun=false persists", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+		w := postJSON(setupTestRouterV2(mockRepo), "/customer?dryRun=false",
+			`{"name":"John Doe","cpf":"11144477735","email":"john@example.com"}`, "")
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid value", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+
+		w := postJSON(setupTestRouterV2(mockRepo), "/customer?dryRun=maybe",
+			`{"name":"John Doe","cpf":"11144477735","email":"john@example.com"}`, "")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "INVALID_DRY_RUN")
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
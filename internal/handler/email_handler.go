@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"customer-service/internal/audit"
+	"customer-service/internal/domain"
+	"customer-service/internal/usecase"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EmailHandler struct {
+	confirmUseCase *usecase.ConfirmEmailUseCase
+}
+
+func NewEmailHandler(confirmUC *usecase.ConfirmEmailUseCase) *EmailHandler {
+	return &EmailHandler{confirmUseCase: confirmUC}
+}
+
+type ConfirmEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ConfirmEmail godoc
+// @Summary Confirm a new email
+// @Description Replaces the customer's email with the pending one, using the token sent to the new address
+// @Tags customers-v2
+// @Accept json
+// @Param id path string true "Customer ID"
+// @Param confirmation body ConfirmEmailRequest true "Token from the verification email"
+// @Success 204
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v2/customers/{id}/email/confirm [post]
+func (h *EmailHandler) ConfirmEmail(c *gin.Context) {
+	var req ConfirmEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleBindingError(c, &req, err)
+		return
+	}
+
+	// Nothing is returned, but the email it confirms is logged as accessed
+	ctx := audit.WithDisclosedFields(c.Request.Context(), domain.PIIFieldEmail)
+	if _, err := h.confirmUseCase.Execute(ctx, c.Param("id"), req.Token); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"customer-service/internal/domain"
+	"customer-service/internal/usecase"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func setupEmailRouter(mockRepo *MockRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/customer/:id/email/confirm", NewEmailHandler(usecase.NewConfirmEmailUseCase(mockRepo, nil)).ConfirmEmail)
+	return router
+}
+
+func TestConfirmEmail(t *testing.T) {
+	pendingCustomer := func(t *testing.T) (*domain.Customer, string) {
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		newEmail := "jane@example.com"
+		require.NoError(t, customer.Update(nil, &newEmail))
+		token, err := customer.IssueEmailVerification()
+		require.NoError(t, err)
+		return customer, token
+	}
+
+	t.Run("Valid token", func(t *testing.T) {
+		customer, token := pendingCustomer(t)
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("Update", mock.Anything, mock.Anything).Return(nil)
+
+		w := postJSON(setupEmailRouter(mockRepo), "/customer/"+customer.ID+"/email/confirm", `{"token":"`+token+`"}`, "")
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "jane@example.com", customer.Email)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		customer, _ := pendingCustomer(t)
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+
+		w := postJSON(setupEmailRouter(mockRepo), "/customer/"+customer.ID+"/email/confirm", `{"token":"wrong"}`, "")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "INVALID_EMAIL_TOKEN")
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Missing token", func(t *testing.T) {
+		w := postJSON(setupEmailRouter(new(MockRepository)), "/customer/123/email/confirm", `{}`, ProblemContentType)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), `"pointer":"/token"`)
+	})
+}
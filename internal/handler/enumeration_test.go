@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"context"
+	"customer-service/internal/abuse"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/mailer"
+	"customer-service/internal/usecase"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingSink struct {
+	events []abuse.Event
+}
+
+func (s *recordingSink) Emit(_ context.Context, event abuse.Event) {
+	s.events = append(s.events, event)
+}
+
+// setupEnumerationRouter serves the CPF lookups behind guard, authenticating
+// every request as principal when it is set.
+func setupEnumerationRouter(mockRepo *MockRepository, guard gin.HandlerFunc, principal *auth.Principal) *gin.Engine {
+	handler := NewCustomerHandler(
+		usecase.NewCreateCustomerUseCase(mockRepo, nil),
+		usecase.NewGetCustomerByCPFUseCase(mockRepo, nil),
+		usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
+		usecase.NewDeleteCustomerUseCase(mockRepo),
+	)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	SetupRoutes(router, handler, RouteConfig{
+		Authentication: func(c *gin.Context) {
+			if principal != nil {
+				c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+			}
+		},
+		Enumeration: guard,
+		Lookup:      NewLookupHandler(usecase.NewBatchGetCustomersUseCase(mockRepo, nil)).LookupCustomers,
+		Validate:    NewValidateHandler(usecase.NewValidateCustomerUseCase(mockRepo)).ValidateCustomer,
+	})
+	return router
+}
+
+// validCPF returns a distinct valid CPF for each i.
+func validCPF(i int) string {
+	digits := fmt.Sprintf("%09d", i+1)
+	for length := 9; length < 11; length++ {
+		sum := 0
+		for j := 0; j < length; j++ {
+			sum += int(digits[j]-'0') * (length + 1 - j)
+		}
+		check := sum * 10 % 11 % 10
+		digits += fmt.Sprint(check)
+	}
+	return digits
+}
+
+var enumerationThresholds = abuse.Thresholds{
+	Window:           time.Minute,
+	MaxDistinctCPFs:  3,
+	MinLookups:       3,
+	MaxInvalidRatio:  0.5,
+	MaxNotFoundRatio: 0.5,
+	BlockFor:         time.Minute,
+}
+
+func TestEnumerationGuard(t *testing.T) {
+	customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+
+	t.Run("blocks a client walking through CPFs", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, mock.Anything).Return(customer, nil)
+		sink := &recordingSink{}
+		guard := EnumerationGuard(abuse.NewDetector(enumerationThresholds, sink), abuse.Allowlist{})
+		router := setupEnumerationRouter(mockRepo, guard, nil)
+
+		for i := 0; i < 4; i++ {
+			w := doRequest(router, http.MethodGet, "/customer/"+validCPF(i), "192.0.2.1:1234")
+			assert.Equal(t, http.StatusOK, w.Code)
+		}
+
+		require.Len(t, sink.events, 1)
+		assert.Equal(t, "ip:192.0.2.1", sink.events[0].Client)
+		assert.Equal(t, abuse.ReasonDistinctCPFs, sink.events[0].Reason)
+
+		w := doRequest(router, http.MethodGet, "/v2/customers/cpf/"+validCPF(0), "192.0.2.1:1234")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "60", w.Header().Get("Retry-After"))
+		assert.Contains(t, w.Body.String(), "CLIENT_BLOCKED")
+
+		w = doRequest(router, http.MethodGet, "/customer/"+validCPF(0), "192.0.2.2:1234")
+		assert.Equal(t, http.StatusOK, w.Code, "other clients are not blocked")
+	})
+
+	t.Run("counts invalid CPFs and unknown customers", func(t *testing.T) {
+		for _, cpf := range []func(int) string{
+			func(i int) string { return fmt.Sprintf("abc%d", i) },
+			validCPF,
+		} {
+			mockRepo := new(MockRepository)
+			mockRepo.On("FindByCPF", mock.Anything, mock.Anything).Return(nil, nil)
+			sink := &recordingSink{}
+			guard := EnumerationGuard(abuse.NewDetector(enumerationThresholds, sink), abuse.Allowlist{})
+			router := setupEnumerationRouter(mockRepo, guard, nil)
+
+			for i := 0; i < 3; i++ {
+				doRequest(router, http.MethodGet, "/customer/"+cpf(i), "192.0.2.1:1234")
+			}
+
+			w := doRequest(router, http.MethodGet, "/customer/"+validCPF(0), "192.0.2.1:1234")
+			assert.Equal(t, http.StatusTooManyRequests, w.Code, cpf(0))
+			assert.Len(t, sink.events, 1, cpf(0))
+		}
+	})
+
+	t.Run("counts a formatted CPF as the same CPF", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+		sink := &recordingSink{}
+		guard := EnumerationGuard(abuse.NewDetector(enumerationThresholds, sink), abuse.Allowlist{})
+		router := setupEnumerationRouter(mockRepo, guard, nil)
+
+		for _, cpf := range []string{"11144477735", "111.444.777-35", "111.444.77735", "111444777-35"} {
+			doRequest(router, http.MethodGet, "/customer/"+cpf, "192.0.2.1:1234")
+		}
+
+		assert.Empty(t, sink.events)
+	})
+
+	t.Run("watches batch lookups", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByIDsOrCPFs", mock.Anything, mock.Anything, mock.Anything).Return([]*domain.Customer{}, nil)
+		sink := &recordingSink{}
+		guard := EnumerationGuard(abuse.NewDetector(enumerationThresholds, sink), abuse.Allowlist{})
+		router := setupEnumerationRouter(mockRepo, guard, nil)
+
+		w := postJSON(router, "/customer/lookup",
+			fmt.Sprintf(`{"cpfs":[%q,%q,%q,%q]}`, validCPF(0), validCPF(1), validCPF(2), validCPF(3)), "")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		require.Len(t, sink.events, 1)
+		w = postJSON(router, "/customer/lookup", `{"cpfs":["11144477735"]}`, "")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	})
+
+	t.Run("watches validation", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindConflicts", mock.Anything, mock.Anything, mock.Anything).Return([]*domain.Customer{customer}, nil)
+		sink := &recordingSink{}
+		guard := EnumerationGuard(abuse.NewDetector(enumerationThresholds, sink), abuse.Allowlist{})
+		router := setupEnumerationRouter(mockRepo, guard, nil)
+
+		for i := 0; i < 4; i++ {
+			postJSON(router, "/customer/validate",
+				fmt.Sprintf(`{"name":"John Doe","cpf":%q,"email":"john@example.com"}`, validCPF(i)), "")
+		}
+
+		require.Len(t, sink.events, 1)
+		assert.Equal(t, abuse.ReasonDistinctCPFs, sink.events[0].Reason)
+	})
+
+	t.Run("free CPFs checked before registration are not unknown customers", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindConflicts", mock.Anything, mock.Anything, mock.Anything).Return([]*domain.Customer{}, nil)
+		sink := &recordingSink{}
+		guard := EnumerationGuard(abuse.NewDetector(enumerationThresholds, sink), abuse.Allowlist{})
+		router := setupEnumerationRouter(mockRepo, guard, nil)
+
+		for i := 0; i < 3; i++ {
+			w := postJSON(router, "/customer/validate",
+				fmt.Sprintf(`{"name":"John Doe","cpf":%q,"email":"john@example.com"}`, validCPF(i)), "")
+			assert.Equal(t, http.StatusOK, w.Code)
+		}
+
+		assert.Empty(t, sink.events)
+	})
+
+	t.Run("ignores requests that say nothing about the CPF", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		sink := &recordingSink{}
+		guard := EnumerationGuard(abuse.NewDetector(enumerationThresholds, sink), abuse.Allowlist{})
+		router := setupEnumerationRouter(mockRepo, guard, &auth.Principal{Subject: "kiosk-1"})
+
+		for i := 0; i < 10; i++ {
+			w := doRequest(router, http.MethodGet, "/customer/"+validCPF(i), "192.0.2.1:1234")
+			assert.Equal(t, http.StatusForbidden, w.Code)
+		}
+
+		assert.Empty(t, sink.events)
+	})
+
+	t.Run("tells authenticated clients apart by subject", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, mock.Anything).Return(customer, nil)
+		sink := &recordingSink{}
+		guard := EnumerationGuard(abuse.NewDetector(enumerationThresholds, sink), abuse.Allowlist{})
+		router := setupEnumerationRouter(mockRepo, guard, &auth.Principal{Subject: "kiosk-1", Scopes: []string{auth.ScopeRead}})
+
+		for i := 0; i < 4; i++ {
+			doRequest(router, http.MethodGet, "/customer/"+validCPF(i), "")
+		}
+
+		require.Len(t, sink.events, 1)
+		assert.Equal(t, "sub:kiosk-1", sink.events[0].Client)
+	})
+
+	t.Run("exempts allowlisted callers", func(t *testing.T) {
+		allowlist, err := abuse.ParseAllowlist("order-service,10.0.0.0/8")
+		require.NoError(t, err)
+
+		for _, tc := range []struct {
+			principal  *auth.Principal
+			remoteAddr string
+		}{
+			{principal: &auth.Principal{Subject: "order-service", Scopes: []string{auth.ScopeRead}}, remoteAddr: "192.0.2.1:1234"},
+			{remoteAddr: "10.1.2.3:1234"},
+		} {
+			mockRepo := new(MockRepository)
+			mockRepo.On("FindByCPF", mock.Anything, mock.Anything).Return(nil, nil)
+			sink := &recordingSink{}
+			guard := EnumerationGuard(abuse.NewDetector(enumerationThresholds, sink), allowlist)
+			router := setupEnumerationRouter(mockRepo, guard, tc.principal)
+
+			for i := 0; i < 10; i++ {
+				w := doRequest(router, http.MethodGet, "/customer/"+validCPF(i), tc.remoteAddr)
+				assert.Equal(t, http.StatusNotFound, w.Code)
+			}
+			assert.Empty(t, sink.events)
+		}
+	})
+}
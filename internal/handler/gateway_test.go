@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"customer-service/internal/auth"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGatewayAuthentication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/whoami", GatewayAuthentication(), func(c *gin.Context) {
+		principal := auth.FromContext(c.Request.Context())
+		c.JSON(http.StatusOK, gin.H{"subject": principal.Subject, "scopes": principal.Scopes})
+	})
+
+	t.Run("Reads the caller from the gateway headers", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		req.Header.Set(GatewaySubjectHeader, "order-service")
+		req.Header.Set(GatewayScopesHeader, "customers:read  customers:pii:read")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"subject":"order-service","scopes":["customers:read","customers:pii:read"]}`, w.Body.String())
+	})
+
+	t.Run("Missing subject", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		req.Header.Set(GatewayScopesHeader, "customers:admin")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "UNAUTHENTICATED")
+	})
+}
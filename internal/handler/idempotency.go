@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/repository"
+	"customer-service/pkg/errors"
+	"encoding/hex"
+	"io"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader   = "Idempotency-Key"
+	maxIdempotencyKeyBytes = 255
+)
+
+// Keys under which Idempotency and the create handlers pass the customer of
+// an idempotent request in the gin context.
+const (
+	idempotentReplayKey   = "idempotentReplay"
+	idempotentCustomerKey = "idempotentCustomer"
+)
+
+// Idempotency answers a request retried with the same Idempotency-Key with
+// the customer the original request created, and rejects reuse of a key
+// with a different body. Only the status and the customer ID are stored:
+// the handler renders the customer again for the retry, masked for the
+// caller as any other response. Requests that created nothing changed
+// nothing either, so their key is released and a retry runs again. Keys are
+// scoped to the caller, so two clients picking the same key never see each
+// other's customers. Requests without the header, and dry runs, which
+// change nothing, are passed through unchanged.
+func Idempotency(repo repository.IdempotencyRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if dryRun, _ := parseDryRun(c); dryRun {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyBytes {
+			abortWithError(c, errors.NewValidationError("Idempotency-Key is too long", "INVALID_IDEMPOTENCY_KEY"))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			abortWithError(c, errors.NewValidationError("Invalid request body", "INVALID_REQUEST"))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// The key must be released or completed even when the client hangs up
+		ctx := context.WithoutCancel(c.Request.Context())
+		key = callerKey(c, key)
+		fingerprint := requestFingerprint(c.Request.Method, c.FullPath(), body)
+		existing, err := repo.Reserve(ctx, key, fingerprint)
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+
+		if existing != nil {
+			if existing.Fingerprint != fingerprint {
+				abortWithError(c, errors.NewUnprocessableError("Idempotency-Key was already used with a different request", "IDEMPOTENCY_KEY_MISMATCH"))
+				return
+			}
+			if !existing.Completed() {
+				abortWithError(c, errors.NewConflictError("Request with this Idempotency-Key is still being processed", "IDEMPOTENCY_REQUEST_IN_PROGRESS"))
+				return
+			}
+
+			c.Header("Idempotent-Replayed", "true")
+			c.Set(idempotentReplayKey, existing)
+			c.Next()
+			return
+		}
+
+		// A panic would otherwise leave the key reserved until it expires,
+		// answering every retry with IDEMPOTENCY_REQUEST_IN_PROGRESS
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				releaseIdempotencyKey(ctx, repo, key)
+				panic(recovered)
+			}
+		}()
+
+		c.Next()
+
+		customerID := c.GetString(idempotentCustomerKey)
+		if customerID == "" {
+			releaseIdempotencyKey(ctx, repo, key)
+			return
+		}
+		if err := repo.Complete(ctx, key, c.Writer.Status(), customerID); err != nil {
+			log.Printf("Failed to store idempotent response: %s", errors.From(err).Internal())
+		}
+	}
+}
+
+// idempotentReplay returns the record of the original request when the
+// request is a retry that Idempotency let through to be answered again.
+func idempotentReplay(c *gin.Context) (*domain.IdempotencyRecord, bool) {
+	value, ok := c.Get(idempotentReplayKey)
+	if !ok {
+		return nil, false
+	}
+	record, ok := value.(*domain.IdempotencyRecord)
+	return record, ok
+}
+
+// rememberCreatedCustomer tells Idempotency which customer the request
+// created, to answer its retries with.
+func rememberCreatedCustomer(c *gin.Context, id string) {
+	c.Set(idempotentCustomerKey, id)
+}
+
+func releaseIdempotencyKey(ctx context.Context, repo repository.IdempotencyRepository, key string) {
+	if err := repo.Release(ctx, key); err != nil {
+		log.Printf("Failed to release idempotency key: %s", errors.From(err).Internal())
+	}
+}
+
+// callerKey namespaces key by the authenticated caller, e.g. the API key
+// "apikey:<id>". Header values cannot contain a newline, so the separator
+// keeps different callers' keys apart.
+func callerKey(c *gin.Context, key string) string {
+	principal := auth.FromContext(c.Request.Context())
+	if principal == nil {
+		return key
+	}
+	return principal.Subject + "\n" + key
+}
+
+func requestFingerprint(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func abortWithError(c *gin.Context, err error) {
+	handleError(c, err)
+	c.Abort()
+}
@@ -0,0 +1,328 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/mailer"
+	"customer-service/internal/usecase"
+	"customer-service/pkg/errors"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockIdempotencyRepository struct {
+	mock.Mock
+}
+
+func (m *MockIdempotencyRepository) Reserve(ctx context.Context, key, fingerprint string) (*domain.IdempotencyRecord, error) {
+	args := m.Called(ctx, key, fingerprint)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.IdempotencyRecord), args.Error(1)
+}
+
+func (m *MockIdempotencyRepository) Complete(ctx context.Context, key string, statusCode int, customerID string) error {
+	args := m.Called(ctx, key, statusCode, customerID)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyRepository) Release(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func setupIdempotencyRouter(repo *MockRepository, idempotencyRepo *MockIdempotencyRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	handler := NewCustomerHandler(
+		usecase.NewCreateCustomerUseCase(repo, nil),
+		usecase.NewGetCustomerByCPFUseCase(repo, nil),
+		usecase.NewUpdateCustomerUseCase(repo, mailer.NewLogMailer(), nil),
+		usecase.NewDeleteCustomerUseCase(repo),
+	)
+	router.POST("/customer", Idempotency(idempotencyRepo), handler.CreateCustomer)
+
+	return router
+}
+
+func newIdempotentRequest(key string, body CreateCustomerRequest) *http.Request {
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/customer", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	return req
+}
+
+func TestIdempotency(t *testing.T) {
+	body := CreateCustomerRequest{
+		Name:  "John Doe",
+		CPF:   "111.444.777-35",
+		Email: "john@example.com",
+	}
+	payload, _ := json.Marshal(body)
+	fingerprint := requestFingerprint(http.MethodPost, "/customer", payload)
+
+	t.Run("Request without key is passed through", func(t *testing.T) {
+		repo := new(MockRepository)
+		idempotencyRepo := new(MockIdempotencyRepository)
+		repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+		w := httptest.NewRecorder()
+		setupIdempotencyRouter(repo, idempotencyRepo).ServeHTTP(w, newIdempotentRequest("", body))
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		idempotencyRepo.AssertNotCalled(t, "Reserve", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Dry run is passed through", func(t *testing.T) {
+		repo := new(MockRepository)
+		idempotencyRepo := new(MockIdempotencyRepository)
+		repo.On("FindConflicts", mock.Anything, mock.Anything, mock.Anything).Return([]*domain.Customer{}, nil)
+
+		req := newIdempotentRequest("key-1", body)
+		req.URL.RawQuery = "dryRun=true"
+		w := httptest.NewRecorder()
+		setupIdempotencyRouter(repo, idempotencyRepo).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		idempotencyRepo.AssertNotCalled(t, "Reserve", mock.Anything, mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("First request stores the status and customer ID", func(t *testing.T) {
+		repo := new(MockRepository)
+		idempotencyRepo := new(MockIdempotencyRepository)
+		repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+		idempotencyRepo.On("Reserve", mock.Anything, "key-1", fingerprint).Return(nil, nil)
+		idempotencyRepo.On("Complete", mock.Anything, "key-1", http.StatusCreated, mock.Anything).Return(nil)
+
+		w := httptest.NewRecorder()
+		setupIdempotencyRouter(repo, idempotencyRepo).ServeHTTP(w, newIdempotentRequest("key-1", body))
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response CustomerResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, response.ID, idempotencyRepo.Calls[1].Arguments.String(3))
+		repo.AssertExpectations(t)
+		idempotencyRepo.AssertExpectations(t)
+	})
+
+	t.Run("Retry renders the created customer again", func(t *testing.T) {
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		repo := new(MockRepository)
+		idempotencyRepo := new(MockIdempotencyRepository)
+		repo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		idempotencyRepo.On("Reserve", mock.Anything, "key-1", fingerprint).Return(&domain.IdempotencyRecord{
+			Key:         "key-1",
+			Fingerprint: fingerprint,
+			StatusCode:  http.StatusCreated,
+			CustomerID:  customer.ID,
+		}, nil)
+
+		w := httptest.NewRecorder()
+		setupIdempotencyRouter(repo, idempotencyRepo).ServeHTTP(w, newIdempotentRequest("key-1", body))
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+		var response CustomerResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, customer.ID, response.ID)
+		assert.Equal(t, "11144477735", response.CPF)
+		repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		idempotencyRepo.AssertNotCalled(t, "Complete", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Retry is masked for a caller who may not read personal data", func(t *testing.T) {
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		repo := new(MockRepository)
+		idempotencyRepo := new(MockIdempotencyRepository)
+		repo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		idempotencyRepo.On("Reserve", mock.Anything, "kiosk\nkey-1", fingerprint).Return(&domain.IdempotencyRecord{
+			Key:         "kiosk\nkey-1",
+			Fingerprint: fingerprint,
+			StatusCode:  http.StatusCreated,
+			CustomerID:  customer.ID,
+		}, nil)
+
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			principal := &auth.Principal{Subject: "kiosk", Scopes: []string{auth.ScopeCreate}}
+			c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+		})
+		router.POST("/customer", Idempotency(idempotencyRepo), NewCustomerHandler(
+			usecase.NewCreateCustomerUseCase(repo, nil),
+			usecase.NewGetCustomerByCPFUseCase(repo, nil),
+			usecase.NewUpdateCustomerUseCase(repo, mailer.NewLogMailer(), nil),
+			usecase.NewDeleteCustomerUseCase(repo),
+		).CreateCustomer)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newIdempotentRequest("key-1", body))
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response CustomerResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "***.444.777-**", response.CPF)
+	})
+
+	t.Run("Retry after the customer was deleted", func(t *testing.T) {
+		repo := new(MockRepository)
+		idempotencyRepo := new(MockIdempotencyRepository)
+		repo.On("FindByID", mock.Anything, "123").Return(nil, nil)
+		idempotencyRepo.On("Reserve", mock.Anything, "key-1", fingerprint).Return(&domain.IdempotencyRecord{
+			Key:         "key-1",
+			Fingerprint: fingerprint,
+			StatusCode:  http.StatusCreated,
+			CustomerID:  "123",
+		}, nil)
+
+		w := httptest.NewRecorder()
+		setupIdempotencyRouter(repo, idempotencyRepo).ServeHTTP(w, newIdempotentRequest("key-1", body))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Client error releases the key", func(t *testing.T) {
+		repo := new(MockRepository)
+		idempotencyRepo := new(MockIdempotencyRepository)
+		repo.On("Create", mock.Anything, mock.Anything).Return(errors.NewFieldConflictError(domain.ErrCPFAlreadyExists))
+		idempotencyRepo.On("Reserve", mock.Anything, "key-1", fingerprint).Return(nil, nil)
+		idempotencyRepo.On("Release", mock.Anything, "key-1").Return(nil)
+
+		w := httptest.NewRecorder()
+		setupIdempotencyRouter(repo, idempotencyRepo).ServeHTTP(w, newIdempotentRequest("key-1", body))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		idempotencyRepo.AssertExpectations(t)
+		idempotencyRepo.AssertNotCalled(t, "Complete", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Same key from another caller is kept apart", func(t *testing.T) {
+		repo := new(MockRepository)
+		idempotencyRepo := new(MockIdempotencyRepository)
+		repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+		idempotencyRepo.On("Reserve", mock.Anything, "apikey:partner\nkey-1", fingerprint).Return(nil, nil)
+		idempotencyRepo.On("Complete", mock.Anything, "apikey:partner\nkey-1", http.StatusCreated, mock.Anything).Return(nil)
+
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			principal := &auth.Principal{Subject: "apikey:partner", Scopes: []string{auth.ScopeCreate}}
+			c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+		})
+		router.POST("/customer", Idempotency(idempotencyRepo), NewCustomerHandler(
+			usecase.NewCreateCustomerUseCase(repo, nil),
+			usecase.NewGetCustomerByCPFUseCase(repo, nil),
+			usecase.NewUpdateCustomerUseCase(repo, mailer.NewLogMailer(), nil),
+			usecase.NewDeleteCustomerUseCase(repo),
+		).CreateCustomer)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newIdempotentRequest("key-1", body))
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		idempotencyRepo.AssertExpectations(t)
+	})
+
+	t.Run("Key reused with a different body", func(t *testing.T) {
+		repo := new(MockRepository)
+		idempotencyRepo := new(MockIdempotencyRepository)
+		idempotencyRepo.On("Reserve", mock.Anything, "key-1", fingerprint).Return(&domain.IdempotencyRecord{
+			Key:         "key-1",
+			Fingerprint: "other",
+			StatusCode:  http.StatusCreated,
+		}, nil)
+
+		w := httptest.NewRecorder()
+		setupIdempotencyRouter(repo, idempotencyRepo).ServeHTTP(w, newIdempotentRequest("key-1", body))
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "IDEMPOTENCY_KEY_MISMATCH", response["error"])
+	})
+
+	t.Run("Original request still in progress", func(t *testing.T) {
+		repo := new(MockRepository)
+		idempotencyRepo := new(MockIdempotencyRepository)
+		idempotencyRepo.On("Reserve", mock.Anything, "key-1", fingerprint).Return(&domain.IdempotencyRecord{
+			Key:         "key-1",
+			Fingerprint: fingerprint,
+		}, nil)
+
+		w := httptest.NewRecorder()
+		setupIdempotencyRouter(repo, idempotencyRepo).ServeHTTP(w, newIdempotentRequest("key-1", body))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "IDEMPOTENCY_REQUEST_IN_PROGRESS", response["error"])
+	})
+
+	t.Run("Server error releases the key", func(t *testing.T) {
+		repo := new(MockRepository)
+		idempotencyRepo := new(MockIdempotencyRepository)
+		repo.On("Create", mock.Anything, mock.Anything).
+			Return(errors.NewInternalError("database error"))
+		idempotencyRepo.On("Reserve", mock.Anything, "key-1", fingerprint).Return(nil, nil)
+		idempotencyRepo.On("Release", mock.Anything, "key-1").Return(nil)
+
+		w := httptest.NewRecorder()
+		setupIdempotencyRouter(repo, idempotencyRepo).ServeHTTP(w, newIdempotentRequest("key-1", body))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		idempotencyRepo.AssertExpectations(t)
+		idempotencyRepo.AssertNotCalled(t, "Complete", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Panic releases the key", func(t *testing.T) {
+		idempotencyRepo := new(MockIdempotencyRepository)
+		idempotencyRepo.On("Reserve", mock.Anything, "key-1", fingerprint).Return(nil, nil)
+		idempotencyRepo.On("Release", mock.Anything, "key-1").Return(nil)
+
+		router := gin.New()
+		router.POST("/customer", Idempotency(idempotencyRepo), func(c *gin.Context) { panic("boom") })
+
+		assert.Panics(t, func() {
+			router.ServeHTTP(httptest.NewRecorder(), newIdempotentRequest("key-1", body))
+		})
+		idempotencyRepo.AssertExpectations(t)
+	})
+
+	t.Run("Response is stored after the client hangs up", func(t *testing.T) {
+		repo := new(MockRepository)
+		idempotencyRepo := new(MockIdempotencyRepository)
+		repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+		live := mock.MatchedBy(func(ctx context.Context) bool { return ctx.Err() == nil })
+		idempotencyRepo.On("Reserve", live, "key-1", fingerprint).Return(nil, nil)
+		idempotencyRepo.On("Complete", live, "key-1", http.StatusCreated, mock.Anything).Return(nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		w := httptest.NewRecorder()
+		setupIdempotencyRouter(repo, idempotencyRepo).ServeHTTP(w, newIdempotentRequest("key-1", body).WithContext(ctx))
+
+		idempotencyRepo.AssertExpectations(t)
+	})
+
+	t.Run("Key too long", func(t *testing.T) {
+		repo := new(MockRepository)
+		idempotencyRepo := new(MockIdempotencyRepository)
+
+		w := httptest.NewRecorder()
+		setupIdempotencyRouter(repo, idempotencyRepo).ServeHTTP(w, newIdempotentRequest(strings.Repeat("k", 256), body))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"customer-service/internal/auth"
+	"customer-service/internal/usecase"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jwksMaxAge lets verifiers cache the key set briefly. A new signing key is
+// still picked up at once, since verifiers refetch on an unknown key ID.
+const jwksMaxAge = "public, max-age=300"
+
+type IdentifyRequest struct {
+	CPF string `json:"cpf"`
+}
+
+type TokenResponse struct {
+	AccessToken string `json:"accessToken"`
+	TokenType   string `json:"tokenType"`
+	ExpiresIn   int    `json:"expiresIn"`
+	Scope       string `json:"scope"`
+	CustomerID  string `json:"customerId,omitempty"`
+}
+
+type IdentifyHandler struct {
+	identifyUseCase *usecase.IdentifyCustomerUseCase
+	keys            *auth.KeyRing
+}
+
+func NewIdentifyHandler(identifyUC *usecase.IdentifyCustomerUseCase, keys *auth.KeyRing) *IdentifyHandler {
+	return &IdentifyHandler{identifyUseCase: identifyUC, keys: keys}
+}
+
+// Identify godoc
+// @Summary Issue a token for a kiosk session
+// @Description Exchanges the CPF typed at the kiosk for a short-lived token whose subject is the customer ID. Without a CPF the token is anonymous.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body IdentifyRequest false "CPF of the customer, omitted for anonymous sessions"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/identify [post]
+func (h *IdentifyHandler) Identify(c *gin.Context) {
+	var req IdentifyRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			handleBindingError(c, &req, err)
+			return
+		}
+	}
+
+	identity, err := h.identifyUseCase.Execute(c.Request.Context(), req.CPF)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, TokenResponse{
+		AccessToken: identity.Token.Value,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(time.Until(identity.Token.ExpiresAt).Round(time.Second).Seconds()),
+		Scope:       strings.Join(identity.Token.Scopes, " "),
+		CustomerID:  identity.CustomerID,
+	})
+}
+
+// JWKS godoc
+// @Summary Keys that sign the service's tokens
+// @Description JWK Set with the current signing key and the ones it recently replaced
+// @Tags auth
+// @Produce json
+// @Success 200 {object} auth.JWKSet
+// @Router /.well-known/jwks.json [get]
+func (h *IdentifyHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", jwksMaxAge)
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
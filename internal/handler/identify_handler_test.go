@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/usecase"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestIdentifyHandler(t *testing.T, mockRepo *MockRepository) (*IdentifyHandler, *auth.TokenIssuer) {
+	key, err := auth.GenerateSigningKey()
+	require.NoError(t, err)
+	keys := auth.NewKeyRing(time.Hour, key)
+	issuer := auth.NewTokenIssuer(keys, "customer-service", "kiosk", 15*time.Minute)
+	identifyUC := usecase.NewIdentifyCustomerUseCase(usecase.NewGetCustomerByCPFUseCase(mockRepo, nil), issuer)
+	return NewIdentifyHandler(identifyUC, keys), issuer
+}
+
+func setupIdentifyRouter(h *IdentifyHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	kiosk := &auth.Principal{Subject: "kiosk-7", Scopes: []string{auth.ScopeIdentify, auth.ScopeRead}}
+	router.POST("/auth/identify", func(c *gin.Context) {
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), kiosk))
+	}, h.Identify)
+	router.GET("/.well-known/jwks.json", h.JWKS)
+	return router
+}
+
+func TestIdentify(t *testing.T) {
+	t.Run("CPF", func(t *testing.T) {
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+		h, issuer := newTestIdentifyHandler(t, mockRepo)
+
+		w := postJSON(setupIdentifyRouter(h), "/auth/identify", `{"cpf":"11144477735"}`, "")
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		var response TokenResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "Bearer", response.TokenType)
+		assert.Equal(t, customer.ID, response.CustomerID)
+		assert.Equal(t, auth.ScopeIdentified, response.Scope)
+		assert.InDelta(t, 900, response.ExpiresIn, 1)
+
+		principal, err := issuer.Verifier().Verify(context.Background(), response.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, customer.ID, principal.Subject)
+	})
+
+	t.Run("A CPF alone does not open the customer's profile", func(t *testing.T) {
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+		h, issuer := newTestIdentifyHandler(t, mockRepo)
+
+		w := postJSON(setupIdentifyRouter(h), "/auth/identify", `{"cpf":"11144477735"}`, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var response TokenResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		principal, err := issuer.Verifier().Verify(context.Background(), response.AccessToken)
+		require.NoError(t, err)
+
+		assert.False(t, auth.CanReadPII(auth.WithPrincipal(context.Background(), principal), customer.ID))
+		router := setupAuthorizedRouter(mockRepo, principal)
+		for _, method := range []string{http.MethodGet, http.MethodPatch, http.MethodDelete} {
+			for _, path := range []string{"/customer/me", "/customer/" + customer.ID} {
+				if method == http.MethodGet && path != "/customer/me" {
+					continue
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(`{"email":"attacker@example.com"}`)))
+				assert.Equal(t, http.StatusForbidden, w.Code, method+" "+path)
+			}
+		}
+		mockRepo.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+		mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Anonymous without a body", func(t *testing.T) {
+		h, _ := newTestIdentifyHandler(t, new(MockRepository))
+
+		w := httptest.NewRecorder()
+		setupIdentifyRouter(h).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/identify", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var response TokenResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Empty(t, response.CustomerID)
+		assert.Equal(t, auth.ScopeAnonymous, response.Scope)
+	})
+
+	t.Run("Unknown CPF", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(nil, nil)
+		h, _ := newTestIdentifyHandler(t, mockRepo)
+
+		w := postJSON(setupIdentifyRouter(h), "/auth/identify", `{"cpf":"11144477735"}`, "")
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "CUSTOMER_NOT_FOUND")
+	})
+
+	t.Run("Malformed body", func(t *testing.T) {
+		h, _ := newTestIdentifyHandler(t, new(MockRepository))
+
+		w := postJSON(setupIdentifyRouter(h), "/auth/identify", `{"cpf":111}`, "")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestJWKS(t *testing.T) {
+	h, issuer := newTestIdentifyHandler(t, new(MockRepository))
+	token, err := issuer.Issue("customer-1", []string{auth.ScopeSelf})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	setupIdentifyRouter(h).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, jwksMaxAge, w.Header().Get("Cache-Control"))
+
+	// A downstream service verifies the token with the published keys only
+	keys, err := auth.ParseJWKS(w.Body.Bytes())
+	require.NoError(t, err)
+	_, err = auth.NewTokenVerifier(auth.NewJWKS(keys), "customer-service", "kiosk").Verify(context.Background(), token.Value)
+	assert.NoError(t, err)
+}
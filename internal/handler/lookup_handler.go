@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"customer-service/internal/usecase"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LookupHandler struct {
+	batchUseCase *usecase.BatchGetCustomersUseCase
+}
+
+func NewLookupHandler(batchUC *usecase.BatchGetCustomersUseCase) *LookupHandler {
+	return &LookupHandler{batchUseCase: batchUC}
+}
+
+type LookupCustomersRequest struct {
+	IDs  []string `json:"ids"`
+	CPFs []string `json:"cpfs"`
+}
+
+// LookupCustomersResponse maps every key that matched, exactly as sent, to
+// its customer.
+type LookupCustomersResponse struct {
+	Customers map[string]CustomerResponse `json:"customers"`
+	Missing   []string                    `json:"missing"`
+}
+
+// LookupCustomers godoc
+// @Summary Look up many customers at once
+// @Description Resolves up to 100 IDs and CPFs in a single query. Keys without a customer are returned in "missing".
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param lookup body LookupCustomersRequest true "IDs and CPFs to look up"
+// @Success 200 {object} LookupCustomersResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v2/customers/lookup [post]
+// @Router /customer/lookup [post]
+func (h *LookupHandler) LookupCustomers(c *gin.Context) {
+	var req LookupCustomersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleBindingError(c, &req, err)
+		return
+	}
+
+	result, err := h.batchUseCase.Lookup(c.Request.Context(), req.IDs, req.CPFs)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response := LookupCustomersResponse{
+		Customers: make(map[string]CustomerResponse, len(result.Found)),
+		Missing:   result.Missing,
+	}
+	for key, customer := range result.Found {
+		response.Customers[key] = newCustomerResponse(c.Request.Context(), customer)
+	}
+	c.JSON(http.StatusOK, response)
+}
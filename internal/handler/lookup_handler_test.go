@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"customer-service/internal/domain"
+	"customer-service/internal/usecase"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func setupLookupRouter(mockRepo *MockRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/customer/lookup", NewLookupHandler(usecase.NewBatchGetCustomersUseCase(mockRepo, nil)).LookupCustomers)
+	return router
+}
+
+func TestLookupCustomers(t *testing.T) {
+	first, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+	second, _ := domain.NewCustomer("Jane Doe", "52998224725", "jane@example.com")
+
+	t.Run("Returns found customers keyed as requested and misses", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByIDsOrCPFs", mock.Anything, []string{first.ID, "unknown"}, []string{"52998224725"}).
+			Return([]*domain.Customer{first, second}, nil).Once()
+
+		w := postJSON(setupLookupRouter(mockRepo), "/customer/lookup",
+			`{"ids":["`+first.ID+`","unknown"],"cpfs":["529.982.247-25"]}`, "")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response LookupCustomersResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Len(t, response.Customers, 2)
+		assert.Equal(t, "John Doe", response.Customers[first.ID].Name)
+		assert.Equal(t, second.ID, response.Customers["529.982.247-25"].ID)
+		assert.Equal(t, "52998224725", response.Customers["529.982.247-25"].CPF)
+		assert.Equal(t, []string{"unknown"}, response.Missing)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Empty lookup returns empty result", func(t *testing.T) {
+		w := postJSON(setupLookupRouter(new(MockRepository)), "/customer/lookup", `{}`, "")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"customers":{},"missing":[]}`, w.Body.String())
+	})
+
+	t.Run("Too many keys", func(t *testing.T) {
+		ids := make([]string, usecase.MaxBatchSize+1)
+		for i := range ids {
+			ids[i] = fmt.Sprintf("id-%d", i)
+		}
+		body, _ := json.Marshal(LookupCustomersRequest{IDs: ids})
+
+		w := postJSON(setupLookupRouter(new(MockRepository)), "/customer/lookup", string(body), "")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "BATCH_TOO_LARGE")
+	})
+
+	t.Run("Invalid request body", func(t *testing.T) {
+		w := postJSON(setupLookupRouter(new(MockRepository)), "/customer/lookup", `{"ids":"not-a-list"}`, "")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "INVALID_REQUEST")
+	})
+}
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"context"
+	"customer-service/internal/audit"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/usecase"
+	"customer-service/pkg/errors"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// selfEditableFields lists what customers may change about themselves.
+// Everything else, such as the CPF, can only be changed by an operator.
+var selfEditableFields = []string{"name", "email"}
+
+// MeHandler serves the profile of the customer identified by the access
+// token, whose subject is the customer ID.
+type MeHandler struct {
+	getByIDUseCase *usecase.GetCustomerByIDUseCase
+	updateUseCase  *usecase.UpdateCustomerUseCase
+	deleteUseCase  *usecase.DeleteCustomerUseCase
+}
+
+func NewMeHandler(
+	getByIDUC *usecase.GetCustomerByIDUseCase,
+	updateUC *usecase.UpdateCustomerUseCase,
+	deleteUC *usecase.DeleteCustomerUseCase,
+) *MeHandler {
+	return &MeHandler{
+		getByIDUseCase: getByIDUC,
+		updateUseCase:  updateUC,
+		deleteUseCase:  deleteUC,
+	}
+}
+
+// GetMe godoc
+// @Summary Get the signed-in customer
+// @Description Returns the profile of the customer identified by the bearer token
+// @Tags me
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} CustomerResponseV2
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v2/customers/me [get]
+// @Router /customer/me [get]
+func (h *MeHandler) GetMe(c *gin.Context) {
+	id, err := selfID(c)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	customer, err := h.getByIDUseCase.Execute(c.Request.Context(), id)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newSelfResponse(c.Request.Context(), customer))
+}
+
+// UpdateMe godoc
+// @Summary Update the signed-in customer
+// @Description Changes the customer's name right away; a new email only takes effect once confirmed. Other fields cannot be changed through self-service.
+// @Tags me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param customer body UpdateCustomerRequest true "Customer fields to update"
+// @Success 200 {object} CustomerResponseV2
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v2/customers/me [patch]
+// @Router /customer/me [patch]
+func (h *MeHandler) UpdateMe(c *gin.Context) {
+	id, err := selfID(c)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	var req UpdateCustomerRequest
+	body, err := c.GetRawData()
+	if err != nil {
+		handleError(c, errors.NewValidationError("Invalid request body", "INVALID_REQUEST"))
+		return
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		handleBindingError(c, &req, err)
+		return
+	}
+	if err := checkSelfEditable(fields); err != nil {
+		handleError(c, err)
+		return
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		handleBindingError(c, &req, err)
+		return
+	}
+
+	customer, err := h.updateUseCase.Execute(c.Request.Context(), id, req.Name, req.Email)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newSelfResponse(c.Request.Context(), customer))
+}
+
+// DeleteMe godoc
+// @Summary Delete the signed-in customer
+// @Description Deletes the customer identified by the bearer token
+// @Tags me
+// @Security BearerAuth
+// @Success 204
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v2/customers/me [delete]
+// @Router /customer/me [delete]
+func (h *MeHandler) DeleteMe(c *gin.Context) {
+	id, err := selfID(c)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	if err := h.deleteUseCase.Execute(c.Request.Context(), id); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// selfID returns the customer ID of a customer's own token. Service and
+// operator tokens have no profile of their own.
+func selfID(c *gin.Context) (string, error) {
+	principal := auth.FromContext(c.Request.Context())
+	if principal == nil {
+		return "", errors.NewUnauthorizedError("Missing access token", "UNAUTHENTICATED")
+	}
+	if !principal.HasScope(auth.ScopeSelf) {
+		return "", errors.NewForbiddenError("Only customers have a profile", "FORBIDDEN")
+	}
+	return principal.Subject, nil
+}
+
+// checkSelfEditable rejects every member of the body customers may not
+// change themselves, instead of silently ignoring it.
+func checkSelfEditable(body map[string]json.RawMessage) error {
+	var restricted []string
+	for field := range body {
+		if !slices.Contains(selfEditableFields, field) {
+			restricted = append(restricted, field)
+		}
+	}
+	if len(restricted) == 0 {
+		return nil
+	}
+	sort.Strings(restricted)
+
+	appErr := errors.NewForbiddenError(restricted[0]+" cannot be changed through self-service", "FIELD_RESTRICTED")
+	appErr.Params = map[string]string{"field": restricted[0]}
+	for _, field := range restricted {
+		appErr.Fields = append(appErr.Fields, errors.FieldError{
+			Pointer: "/" + field,
+			Code:    "FIELD_RESTRICTED",
+			Message: field + " cannot be changed through self-service",
+			Params:  map[string]string{"field": field},
+		})
+	}
+	return appErr
+}
+
+// newSelfResponse shows customers their own data, which Redact leaves
+// unmasked for them.
+func newSelfResponse(ctx context.Context, customer *domain.Customer) CustomerResponseV2 {
+	return newCustomerEnvelopeV2(audit.Redact(ctx, customer)).Data
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/mailer"
+	"customer-service/internal/usecase"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func setupMeRouter(mockRepo *MockRepository, principal *auth.Principal) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if principal != nil {
+			c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+		}
+	})
+
+	me := NewMeHandler(
+		usecase.NewGetCustomerByIDUseCase(mockRepo, nil),
+		usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
+		usecase.NewDeleteCustomerUseCase(mockRepo),
+	)
+	router.GET("/customer/me", me.GetMe)
+	router.PATCH("/customer/me", me.UpdateMe)
+	router.DELETE("/customer/me", me.DeleteMe)
+	return router
+}
+
+func TestMeHandler(t *testing.T) {
+	customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+	self := &auth.Principal{Subject: customer.ID, Scopes: []string{auth.ScopeSelf}}
+
+	t.Run("Get returns the caller's own data unmasked", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+
+		w := httptest.NewRecorder()
+		setupMeRouter(mockRepo, self).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/customer/me", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var response CustomerResponseV2
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, customer.ID, response.ID)
+		assert.Equal(t, "11144477735", response.CPF)
+		assert.Equal(t, "john@example.com", response.Email)
+	})
+
+	t.Run("Update changes the name", func(t *testing.T) {
+		current, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		current.ID = customer.ID
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(current, nil)
+		mockRepo.On("Update", mock.Anything, mock.Anything).Return(nil)
+
+		w := patchJSON(setupMeRouter(mockRepo, self), "/customer/me", `{"name":"Johnny Doe"}`)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "Johnny Doe")
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Update rejects restricted fields", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+
+		req := httptest.NewRequest(http.MethodPatch, "/customer/me", strings.NewReader(`{"name":"Johnny Doe","cpf":"52998224725","id":"someone-else"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/problem+json")
+		w := httptest.NewRecorder()
+		setupMeRouter(mockRepo, self).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "FIELD_RESTRICTED")
+		assert.Contains(t, w.Body.String(), "/cpf")
+		assert.Contains(t, w.Body.String(), "/id")
+		mockRepo.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Update rejects a malformed body", func(t *testing.T) {
+		w := patchJSON(setupMeRouter(new(MockRepository), self), "/customer/me", `{"name":`)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Delete removes the caller", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("Delete", mock.Anything, customer.ID).Return(nil)
+
+		w := httptest.NewRecorder()
+		setupMeRouter(mockRepo, self).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/customer/me", nil))
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Service tokens have no profile", func(t *testing.T) {
+		service := &auth.Principal{Subject: "order-service", Scopes: []string{auth.ScopeRead}}
+
+		w := httptest.NewRecorder()
+		setupMeRouter(new(MockRepository), service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/customer/me", nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Anonymous callers are unauthenticated", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		setupMeRouter(new(MockRepository), nil).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/customer/me", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "UNAUTHENTICATED")
+	})
+}
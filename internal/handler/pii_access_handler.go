@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"customer-service/internal/audit"
+	"customer-service/internal/domain"
+	"customer-service/internal/usecase"
+	"customer-service/pkg/errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccessPurposeHeader carries why the caller reads customer data, e.g.
+// "order-fulfillment". It is recorded in the PII access log.
+const AccessPurposeHeader = "X-Access-Purpose"
+
+// defaultPIIAccessLimit is how many entries are returned without ?limit=.
+const defaultPIIAccessLimit = 100
+
+// AccessPurpose stores the declared purpose of the request for the PII
+// access log.
+func AccessPurpose() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if purpose := c.GetHeader(AccessPurposeHeader); purpose != "" {
+			c.Request = c.Request.WithContext(audit.WithPurpose(c.Request.Context(), purpose))
+		}
+		c.Next()
+	}
+}
+
+// PIIAccessHandler lets administrators query the PII access log.
+type PIIAccessHandler struct {
+	listUseCase *usecase.ListPIIAccessUseCase
+}
+
+func NewPIIAccessHandler(listUC *usecase.ListPIIAccessUseCase) *PIIAccessHandler {
+	return &PIIAccessHandler{listUseCase: listUC}
+}
+
+// PIIAccessResponse is one read of a customer's personal data.
+type PIIAccessResponse struct {
+	ID         string    `json:"id"`
+	Actor      string    `json:"actor"`
+	Purpose    string    `json:"purpose,omitempty"`
+	CustomerID string    `json:"customerId"`
+	Fields     []string  `json:"fields"`
+	AccessedAt time.Time `json:"accessedAt"`
+}
+
+func newPIIAccessResponse(entry *domain.PIIAccess) PIIAccessResponse {
+	return PIIAccessResponse{
+		ID:         entry.ID,
+		Actor:      entry.Actor,
+		Purpose:    entry.Purpose,
+		CustomerID: entry.CustomerID,
+		Fields:     entry.Fields,
+		AccessedAt: entry.AccessedAt,
+	}
+}
+
+// ListPIIAccess godoc
+// @Summary Query the PII access log
+// @Description Lists who was shown a customer's unmasked CPF or email, or whose data an actor was shown, newest first. Filter by exactly one of customerId and actor.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param customerId query string false "Customer whose data was read"
+// @Param actor query string false "Subject that read the data"
+// @Param limit query int false "Maximum entries to return (1-500, default 100)"
+// @Success 200 {array} PIIAccessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/pii-access [get]
+func (h *PIIAccessHandler) ListPIIAccess(c *gin.Context) {
+	limit := defaultPIIAccessLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			appErr := errors.NewValidationError(
+				fmt.Sprintf("limit must be between 1 and %d", usecase.MaxPIIAccessEntries),
+				"INVALID_LIMIT",
+			)
+			appErr.Params = map[string]string{"max": strconv.Itoa(usecase.MaxPIIAccessEntries)}
+			handleError(c, appErr)
+			return
+		}
+		limit = parsed
+	}
+
+	entries, err := h.listUseCase.Execute(c.Request.Context(), c.Query("customerId"), c.Query("actor"), limit)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response := make([]PIIAccessResponse, 0, len(entries))
+	for _, entry := range entries {
+		response = append(response, newPIIAccessResponse(entry))
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, response)
+}
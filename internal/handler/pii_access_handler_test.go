@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"context"
+	"customer-service/internal/audit"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/mailer"
+	"customer-service/internal/usecase"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockPIIAccessRepository is a mock implementation of PIIAccessRepository
+type MockPIIAccessRepository struct {
+	mock.Mock
+}
+
+func (m *MockPIIAccessRepository) Append(ctx context.Context, entries []*domain.PIIAccess) error {
+	args := m.Called(ctx, entries)
+	return args.Error(0)
+}
+
+func (m *MockPIIAccessRepository) FindByCustomer(ctx context.Context, customerID string, limit int) ([]*domain.PIIAccess, error) {
+	args := m.Called(ctx, customerID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.PIIAccess), args.Error(1)
+}
+
+func (m *MockPIIAccessRepository) FindByActor(ctx context.Context, actor string, limit int) ([]*domain.PIIAccess, error) {
+	args := m.Called(ctx, actor, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.PIIAccess), args.Error(1)
+}
+
+func setupPIIAccessRouter(mockRepo *MockPIIAccessRepository, principal *auth.Principal) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+	})
+
+	h := NewPIIAccessHandler(usecase.NewListPIIAccessUseCase(mockRepo))
+	router.GET("/admin/pii-access", h.ListPIIAccess)
+	return router
+}
+
+func TestAccessPurpose(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(AccessPurpose())
+	router.GET("/purpose", func(c *gin.Context) {
+		c.String(http.StatusOK, audit.Purpose(c.Request.Context()))
+	})
+
+	t.Run("Stores the declared purpose", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/purpose", nil)
+		req.Header.Set(AccessPurposeHeader, "  order-fulfillment ")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, "order-fulfillment", w.Body.String())
+	})
+
+	t.Run("Truncates long purposes", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/purpose", nil)
+		req.Header.Set(AccessPurposeHeader, strings.Repeat("a", 500))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Len(t, w.Body.String(), audit.MaxPurposeLength)
+	})
+}
+
+func TestListPIIAccess(t *testing.T) {
+	admin := &auth.Principal{Subject: "dpo@fiap.com", Scopes: []string{auth.ScopeAdmin}}
+	entries := []*domain.PIIAccess{
+		domain.NewPIIAccess("order-service", "order-fulfillment", "customer-1", []string{domain.PIIFieldCPF}, time.Now()),
+	}
+
+	t.Run("Lists reads of a customer's data", func(t *testing.T) {
+		mockRepo := new(MockPIIAccessRepository)
+		mockRepo.On("FindByCustomer", mock.Anything, "customer-1", defaultPIIAccessLimit).Return(entries, nil)
+
+		w := httptest.NewRecorder()
+		setupPIIAccessRouter(mockRepo, admin).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/pii-access?customerId=customer-1", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		var response []PIIAccessResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Len(t, response, 1)
+		assert.Equal(t, "order-service", response[0].Actor)
+		assert.Equal(t, "order-fulfillment", response[0].Purpose)
+		assert.Equal(t, []string{"cpf"}, response[0].Fields)
+	})
+
+	t.Run("Lists reads by an actor", func(t *testing.T) {
+		mockRepo := new(MockPIIAccessRepository)
+		mockRepo.On("FindByActor", mock.Anything, "order-service", 10).Return(entries, nil)
+
+		w := httptest.NewRecorder()
+		setupPIIAccessRouter(mockRepo, admin).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/pii-access?actor=order-service&limit=10", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Non-numeric limit", func(t *testing.T) {
+		mockRepo := new(MockPIIAccessRepository)
+
+		w := httptest.NewRecorder()
+		setupPIIAccessRouter(mockRepo, admin).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/pii-access?actor=order-service&limit=ten", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "INVALID_LIMIT", response["error"])
+	})
+
+	t.Run("Requires the admin scope", func(t *testing.T) {
+		mockRepo := new(MockPIIAccessRepository)
+		reader := &auth.Principal{Subject: "backoffice", Scopes: []string{auth.ScopeRead, auth.ScopeReadPII}}
+
+		w := httptest.NewRecorder()
+		setupPIIAccessRouter(mockRepo, reader).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/pii-access?customerId=customer-1", nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockRepo.AssertNotCalled(t, "FindByCustomer", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestGetCustomerByCPFV2_AccessLog(t *testing.T) {
+	customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+
+	get := func(principal *auth.Principal, path string) []*domain.PIIAccess {
+		var recorded []*domain.PIIAccess
+		accessRepo := new(MockPIIAccessRepository)
+		accessRepo.On("Append", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			recorded = append(recorded, args.Get(1).([]*domain.PIIAccess)...)
+		}).Return(nil)
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+
+		handler := NewCustomerHandler(
+			usecase.NewCreateCustomerUseCase(mockRepo, nil),
+			usecase.NewGetCustomerByCPFUseCase(mockRepo, audit.NewAccessLog(accessRepo)),
+			usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
+			usecase.NewDeleteCustomerUseCase(mockRepo),
+		)
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+		}, AccessPurpose())
+		SetupRoutes(router, handler, RouteConfig{})
+
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(AccessPurposeHeader, "support-ticket")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		return recorded
+	}
+
+	t.Run("Masked reads are not logged", func(t *testing.T) {
+		reader := &auth.Principal{Subject: "kiosk", Scopes: []string{auth.ScopeRead}}
+
+		assert.Empty(t, get(reader, "/v2/customers/cpf/11144477735"))
+	})
+
+	t.Run("Unmasked reads are logged with the purpose", func(t *testing.T) {
+		backoffice := &auth.Principal{Subject: "backoffice", Scopes: []string{auth.ScopeRead, auth.ScopeReadPII}}
+
+		recorded := get(backoffice, "/v2/customers/cpf/11144477735")
+
+		require.Len(t, recorded, 1)
+		assert.Equal(t, "backoffice", recorded[0].Actor)
+		assert.Equal(t, "support-ticket", recorded[0].Purpose)
+		assert.Equal(t, customer.ID, recorded[0].CustomerID)
+		assert.Equal(t, []string{domain.PIIFieldCPF, domain.PIIFieldEmail}, recorded[0].Fields)
+	})
+
+	t.Run("Only the selected PII fields are logged", func(t *testing.T) {
+		backoffice := &auth.Principal{Subject: "backoffice", Scopes: []string{auth.ScopeRead, auth.ScopeReadPII}}
+
+		recorded := get(backoffice, "/v2/customers/cpf/11144477735?fields=id,email")
+		require.Len(t, recorded, 1)
+		assert.Equal(t, []string{domain.PIIFieldEmail}, recorded[0].Fields)
+
+		assert.Empty(t, get(backoffice, "/v2/customers/cpf/11144477735?fields=id,name"))
+	})
+}
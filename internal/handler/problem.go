@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"customer-service/pkg/errors"
+	"customer-service/pkg/i18n"
+	"encoding/json"
+	stderrors "errors"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// ProblemContentType is the RFC 7807 media type clients can ask for through
+// the Accept header instead of the legacy error body.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 error document. Code carries the same stable code
+// as the "error" key of the legacy body.
+type Problem struct {
+	Type     string         `json:"type"`
+	Title    string         `json:"title"`
+	Status   int            `json:"status"`
+	Detail   string         `json:"detail,omitempty"`
+	Instance string         `json:"instance,omitempty"`
+	Code     string         `json:"code"`
+	Errors   []ProblemField `json:"errors,omitempty"`
+}
+
+// ProblemField points at one invalid member of the request body.
+type ProblemField struct {
+	Pointer string `json:"pointer"`
+	Code    string `json:"code"`
+	Detail  string `json:"detail"`
+}
+
+func wantsProblem(c *gin.Context) bool {
+	if c.Request == nil {
+		return false
+	}
+	return c.NegotiateFormat(gin.MIMEJSON, ProblemContentType) == ProblemContentType
+}
+
+// logError records the internal detail of an error that the client only
+// sees as a generic message.
+func logError(c *gin.Context, appErr *errors.AppError) {
+	if c.Request == nil {
+		log.Printf("Request failed: %s", appErr.Internal())
+		return
+	}
+	log.Printf("%s %s failed: %s", c.Request.Method, c.Request.URL.Path, appErr.Internal())
+}
+
+// requestLanguage returns the negotiated Accept-Language, or "" when the
+// client did not ask for one.
+func requestLanguage(c *gin.Context) string {
+	if c.Request == nil {
+		return ""
+	}
+	return i18n.Negotiate(c.GetHeader("Accept-Language"))
+}
+
+func writeProblem(c *gin.Context, appErr *errors.AppError) {
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(appErr.StatusCode),
+		Status:   appErr.StatusCode,
+		Detail:   appErr.Message,
+		Instance: c.Request.URL.Path,
+		Code:     appErr.Code,
+	}
+	for _, field := range appErr.Fields {
+		problem.Errors = append(problem.Errors, ProblemField{
+			Pointer: field.Pointer,
+			Code:    field.Code,
+			Detail:  field.Message,
+		})
+	}
+
+	c.Header("Content-Type", ProblemContentType)
+	c.JSON(appErr.StatusCode, problem)
+}
+
+// handleBindingError reports ShouldBindJSON failures with one field error per
+// invalid member of req, using the same structure as domain validation.
+func handleBindingError(c *gin.Context, req interface{}, err error) {
+	appErr := errors.NewValidationError("Invalid request body", "INVALID_REQUEST")
+
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case stderrors.As(err, &validationErrs):
+		for _, fieldErr := range validationErrs {
+			appErr.Fields = append(appErr.Fields, bindingFieldError(req, fieldErr))
+		}
+	case stderrors.As(err, &typeErr) && typeErr.Field != "":
+		appErr.Fields = append(appErr.Fields, errors.FieldError{
+			Pointer: "/" + strings.ReplaceAll(typeErr.Field, ".", "/"),
+			Code:    "INVALID_TYPE",
+			Message: typeErr.Field + " must be a " + typeErr.Type.String(),
+			Params:  map[string]string{"field": typeErr.Field, "type": typeErr.Type.String()},
+		})
+	}
+
+	handleError(c, appErr)
+}
+
+// HandleBindingError is handleBindingError for handlers outside this package,
+// such as GraphQL, so their malformed bodies are reported the same way.
+func HandleBindingError(c *gin.Context, req interface{}, err error) {
+	handleBindingError(c, req, err)
+}
+
+func bindingFieldError(req interface{}, fieldErr validator.FieldError) errors.FieldError {
+	name := jsonFieldName(req, fieldErr.StructField())
+	field := errors.FieldError{Pointer: "/" + name, Params: map[string]string{"field": name}}
+
+	switch fieldErr.Tag() {
+	case "required":
+		field.Code = "FIELD_REQUIRED"
+		field.Message = name + " is required"
+	case "email":
+		field.Code = "INVALID_EMAIL"
+		field.Message = "Invalid Email"
+	default:
+		field.Code = "INVALID_FIELD"
+		field.Message = name + " is invalid"
+	}
+	return field
+}
+
+// jsonFieldName returns the JSON name of a struct field so pointers match
+// what the client sent rather than the Go field name.
+func jsonFieldName(req interface{}, structField string) string {
+	t := reflect.TypeOf(req)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if f, ok := t.FieldByName(structField); ok {
+		if name := strings.Split(f.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+			return name
+		}
+	}
+	return structField
+}
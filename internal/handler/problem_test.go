@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"bytes"
+	"customer-service/pkg/errors"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func postJSON(router http.Handler, path, body, accept string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestProblemDetails(t *testing.T) {
+	t.Run("Domain validation reports every invalid field", func(t *testing.T) {
+		router := setupTestRouterV2(new(MockRepository))
+
+		w := postJSON(router, "/v2/customers", `{"name":" ","cpf":"12345678901","email":"john@example.com"}`, ProblemContentType)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+
+		var problem Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, "about:blank", problem.Type)
+		assert.Equal(t, "Bad Request", problem.Title)
+		assert.Equal(t, http.StatusBadRequest, problem.Status)
+		assert.Equal(t, "/v2/customers", problem.Instance)
+		assert.Equal(t, "NAME_EMPTY", problem.Code)
+		assert.Equal(t, []ProblemField{
+			{Pointer: "/name", Code: "NAME_EMPTY", Detail: "Name cannot be empty"},
+			{Pointer: "/cpf", Code: "INVALID_CPF", Detail: "Invalid CPF"},
+		}, problem.Errors)
+	})
+
+	t.Run("Binding errors use JSON field names", func(t *testing.T) {
+		router := setupTestRouterV2(new(MockRepository))
+
+		w := postJSON(router, "/v2/customers", `{"name":"John Doe","email":"not-an-email"}`, ProblemContentType)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var problem Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, "INVALID_REQUEST", problem.Code)
+		assert.Equal(t, []ProblemField{
+			{Pointer: "/cpf", Code: "FIELD_REQUIRED", Detail: "cpf is required"},
+			{Pointer: "/email", Code: "INVALID_EMAIL", Detail: "Invalid Email"},
+		}, problem.Errors)
+	})
+
+	t.Run("Type mismatch points at the field", func(t *testing.T) {
+		router := setupTestRouterV2(new(MockRepository))
+
+		w := postJSON(router, "/v2/customers", `{"name":123,"cpf":"11144477735","email":"john@example.com"}`, ProblemContentType)
+
+		var problem Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		require.Len(t, problem.Errors, 1)
+		assert.Equal(t, "/name", problem.Errors[0].Pointer)
+		assert.Equal(t, "INVALID_TYPE", problem.Errors[0].Code)
+	})
+
+	t.Run("Legacy body stays the default", func(t *testing.T) {
+		router := setupTestRouterV2(new(MockRepository))
+
+		w := postJSON(router, "/v2/customers", `{"name":" ","cpf":"12345678901","email":"john@example.com"}`, "")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "NAME_EMPTY", response["error"])
+		assert.Equal(t, "Name cannot be empty", response["message"])
+		assert.Equal(t, float64(http.StatusBadRequest), response["statusCode"])
+	})
+
+	t.Run("Non-validation errors have no field list", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/v2/customers/cpf/11144477735", nil)
+		c.Request.Header.Set("Accept", "application/json, application/problem+json")
+		handleError(c, errors.NewNotFoundError("Customer not found", "CUSTOMER_NOT_FOUND"))
+
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+		assert.Contains(t, w.Body.String(), `"error":"CUSTOMER_NOT_FOUND"`)
+
+		w = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/v2/customers/cpf/11144477735", nil)
+		c.Request.Header.Set("Accept", ProblemContentType)
+		handleError(c, errors.NewNotFoundError("Customer not found", "CUSTOMER_NOT_FOUND"))
+
+		var problem Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, http.StatusNotFound, problem.Status)
+		assert.Equal(t, "CUSTOMER_NOT_FOUND", problem.Code)
+		assert.Empty(t, problem.Errors)
+	})
+}
+
+func TestLocalizedErrors(t *testing.T) {
+	t.Run("Legacy body is translated and keeps the code", func(t *testing.T) {
+		router := setupTestRouterV2(new(MockRepository))
+
+		req, _ := http.NewRequest(http.MethodPost, "/v2/customers", bytes.NewBufferString(`{"name":"John Doe","cpf":"12345678901","email":"john@example.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept-Language", "pt-BR,pt;q=0.9,en;q=0.8")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "pt-BR", w.Header().Get("Content-Language"))
+
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "INVALID_CPF", response["error"])
+		assert.Equal(t, "CPF inválido", response["message"])
+	})
+
+	t.Run("Problem field errors are translated with parameters", func(t *testing.T) {
+		router := setupTestRouterV2(new(MockRepository))
+
+		req, _ := http.NewRequest(http.MethodPost, "/v2/customers", bytes.NewBufferString(`{"name":"John Doe","email":"john@example.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", ProblemContentType)
+		req.Header.Set("Accept-Language", "pt-BR")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var problem Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, "Corpo da requisição inválido", problem.Detail)
+		assert.Equal(t, []ProblemField{
+			{Pointer: "/cpf", Code: "FIELD_REQUIRED", Detail: "cpf é obrigatório"},
+		}, problem.Errors)
+	})
+
+	t.Run("Without Accept-Language messages are unchanged", func(t *testing.T) {
+		router := setupTestRouterV2(new(MockRepository))
+
+		w := postJSON(router, "/v2/customers", `{"name":"John Doe","cpf":"12345678901","email":"john@example.com"}`, "")
+
+		assert.Empty(t, w.Header().Get("Content-Language"))
+		assert.Contains(t, w.Body.String(), `"message":"Invalid CPF"`)
+	})
+}
+
+func TestHandleError_HidesInternalDetails(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("FindByCPF", mock.Anything, "11144477735").
+		Return(nil, errors.WrapError(fmt.Errorf("server selection timeout: mongo-0:27017"), "Failed to find customer by CPF"))
+	router := setupTestRouterV2(mockRepo)
+
+	req, _ := http.NewRequest(http.MethodGet, "/v2/customers/cpf/11144477735", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotContains(t, w.Body.String(), "mongo-0")
+	assert.NotContains(t, w.Body.String(), "Failed to find customer by CPF")
+	assert.Contains(t, w.Body.String(), `"message":"Internal server error"`)
+
+	assert.Contains(t, logs.String(), "GET /v2/customers/cpf/11144477735 failed")
+	assert.Contains(t, logs.String(), "server selection timeout: mongo-0:27017")
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"context"
+	"customer-service/internal/abuse"
+	"customer-service/internal/auth"
+	"customer-service/pkg/errors"
+	"customer-service/pkg/ratelimit"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimits configures RateLimit. A zero CPFLookup limit makes CPF lookups
+// count against Default like any other request.
+type RateLimits struct {
+	Default   ratelimit.Limit
+	CPFLookup ratelimit.Limit
+}
+
+// ClientRateLimit spends one token per request from the bucket of the
+// client's IP address under limit, before the request is authenticated, so
+// that failed authentication attempts are limited as well. RateLimit then
+// limits authenticated clients by their own identity.
+func ClientRateLimit(store ratelimit.Store, limit ratelimit.Limit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit.Requests == 0 || spend(c, store, "client:ip:"+c.ClientIP(), limit, 1) {
+			c.Next()
+		}
+	}
+}
+
+// RateLimit gives every client a token bucket per route class and rejects
+// requests once it is empty with 429. Clients are told apart by API key or
+// token subject once authenticated, by IP address otherwise. Every request
+// spends one token from the default bucket. CPF lookups tell whether a CPF
+// belongs to a customer, so they are limited more strictly: the use cases
+// spend one token per CPF from the CPF lookup bucket through
+// abuse.WithBudget, whichever route or GraphQL query looked it up. Every
+// response carries the RateLimit-* headers of the bucket it spent from last.
+func RateLimit(store ratelimit.Store, limits RateLimits) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client := clientKey(c)
+		if limits.Default.Requests > 0 && !spend(c, store, "default:"+client, limits.Default, 1) {
+			return
+		}
+
+		class, limit := "cpf", limits.CPFLookup
+		if limit.Requests == 0 {
+			class, limit = "default", limits.Default
+		}
+		if limit.Requests > 0 {
+			c.Request = c.Request.WithContext(abuse.WithBudget(c.Request.Context(), func(ctx context.Context, n int) error {
+				result, ok := take(ctx, store, class+":"+client, limit, n)
+				if !ok {
+					return nil
+				}
+				setRateLimitHeaders(c, limit, result)
+				if !result.Allowed {
+					return errors.NewTooManyRequestsError("Too many requests", "RATE_LIMITED")
+				}
+				return nil
+			}))
+		}
+		c.Next()
+	}
+}
+
+// spend takes n tokens from the bucket of key and answers 429 when there
+// are not enough, reporting whether the request may go on.
+func spend(c *gin.Context, store ratelimit.Store, key string, limit ratelimit.Limit, n int) bool {
+	result, ok := take(c.Request.Context(), store, key, limit, n)
+	if !ok {
+		return true
+	}
+	setRateLimitHeaders(c, limit, result)
+	if !result.Allowed {
+		handleError(c, errors.NewTooManyRequestsError("Too many requests", "RATE_LIMITED"))
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// take reports false when the store failed, which lets the request through:
+// an unavailable store must not take the API down with it.
+func take(ctx context.Context, store ratelimit.Store, key string, limit ratelimit.Limit, n int) (ratelimit.Result, bool) {
+	result, err := store.Take(ctx, key, limit, n)
+	if err != nil {
+		log.Printf("Rate limit store failed: %v", err)
+		return ratelimit.Result{}, false
+	}
+	return result, true
+}
+
+func setRateLimitHeaders(c *gin.Context, limit ratelimit.Limit, result ratelimit.Result) {
+	c.Header("RateLimit-Policy", strconv.Itoa(limit.Requests)+";w="+strconv.Itoa(ceilSeconds(limit.Period)))
+	c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+	if !result.Allowed {
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+	}
+}
+
+// clientKey tells clients apart by API key or token subject once
+// authenticated, by IP address otherwise.
+func clientKey(c *gin.Context) string {
+	if principal := auth.FromContext(c.Request.Context()); principal != nil {
+		return "sub:" + principal.Subject
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
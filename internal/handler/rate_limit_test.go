@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"context"
+	"customer-service/internal/abuse"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/usecase"
+	"customer-service/pkg/ratelimit"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, ratelimit.Limit, int) (ratelimit.Result, error) {
+	return ratelimit.Result{}, stderrors.New("store unavailable")
+}
+
+func setupRateLimitRouter(store ratelimit.Store, limits RateLimits, principal *auth.Principal) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	group := router.Group("/customer", func(c *gin.Context) {
+		if principal != nil {
+			c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+		}
+	}, RateLimit(store, limits))
+	group.GET("/:cpf", func(c *gin.Context) {
+		if err := abuse.Spend(c.Request.Context(), 1); err != nil {
+			handleError(c, err)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	group.PATCH("/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func doRequest(router http.Handler, method, path, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if remoteAddr != "" {
+		req.RemoteAddr = remoteAddr
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit(t *testing.T) {
+	limits := RateLimits{
+		Default:   ratelimit.Limit{Requests: 5, Period: time.Minute},
+		CPFLookup: ratelimit.Limit{Requests: 2, Period: time.Minute},
+	}
+
+	t.Run("CPF lookups are limited more strictly", func(t *testing.T) {
+		router := setupRateLimitRouter(ratelimit.NewMemoryStore(), limits, nil)
+
+		w := doRequest(router, http.MethodGet, "/customer/11144477735", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "2;w=60", w.Header().Get("RateLimit-Policy"))
+		assert.Equal(t, "30", w.Header().Get("RateLimit-Reset"))
+
+		doRequest(router, http.MethodGet, "/customer/52998224725", "")
+		w = doRequest(router, http.MethodGet, "/customer/11144477735", "")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "30", w.Header().Get("Retry-After"))
+		assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+		assert.Contains(t, w.Body.String(), "RATE_LIMITED")
+
+		// Other routes spend from their own bucket
+		w = doRequest(router, http.MethodPatch, "/customer/123", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "5", w.Header().Get("RateLimit-Limit"))
+	})
+
+	t.Run("Batch lookups spend one token per CPF", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo.On("FindByIDsOrCPFs", mock.Anything, []string{}, []string{"11144477735", "52998224725", "39053344705"}).
+			Return([]*domain.Customer{customer}, nil).Once()
+		router := gin.New()
+		router.POST("/customer/lookup", RateLimit(ratelimit.NewMemoryStore(), limits),
+			NewLookupHandler(usecase.NewBatchGetCustomersUseCase(mockRepo, nil)).LookupCustomers)
+
+		// A batch larger than the limit needs a full bucket and leaves the
+		// client in debt until the bucket refills
+		w := postJSON(router, "/customer/lookup", `{"cpfs":["11144477735","52998224725","39053344705"]}`, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+		w = postJSON(router, "/customer/lookup", `{"cpfs":["11144477735"]}`, "")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "60", w.Header().Get("Retry-After"))
+		assert.Contains(t, w.Body.String(), "RATE_LIMITED")
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Anonymous clients are told apart by IP", func(t *testing.T) {
+		router := setupRateLimitRouter(ratelimit.NewMemoryStore(), limits, nil)
+		doRequest(router, http.MethodGet, "/customer/11144477735", "10.0.0.1:1234")
+		doRequest(router, http.MethodGet, "/customer/11144477735", "10.0.0.1:1234")
+
+		assert.Equal(t, http.StatusTooManyRequests, doRequest(router, http.MethodGet, "/customer/11144477735", "10.0.0.1:1234").Code)
+		assert.Equal(t, http.StatusOK, doRequest(router, http.MethodGet, "/customer/11144477735", "10.0.0.2:1234").Code)
+	})
+
+	t.Run("Authenticated clients are told apart by subject", func(t *testing.T) {
+		store := ratelimit.NewMemoryStore()
+		kiosk := setupRateLimitRouter(store, limits, &auth.Principal{Subject: "kiosk-7"})
+		partner := setupRateLimitRouter(store, limits, &auth.Principal{Subject: "apikey:123"})
+		doRequest(kiosk, http.MethodGet, "/customer/11144477735", "10.0.0.1:1234")
+		doRequest(kiosk, http.MethodGet, "/customer/11144477735", "10.0.0.1:1234")
+
+		assert.Equal(t, http.StatusTooManyRequests, doRequest(kiosk, http.MethodGet, "/customer/11144477735", "10.0.0.1:1234").Code)
+		assert.Equal(t, http.StatusOK, doRequest(partner, http.MethodGet, "/customer/11144477735", "10.0.0.1:1234").Code)
+	})
+
+	t.Run("Without a CPF limit lookups count as any request", func(t *testing.T) {
+		router := setupRateLimitRouter(ratelimit.NewMemoryStore(), RateLimits{Default: limits.Default}, nil)
+
+		w := doRequest(router, http.MethodGet, "/customer/11144477735", "")
+
+		assert.Equal(t, "5", w.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "3", w.Header().Get("RateLimit-Remaining"))
+	})
+
+	t.Run("Failing store lets requests through", func(t *testing.T) {
+		router := setupRateLimitRouter(failingStore{}, limits, nil)
+
+		w := doRequest(router, http.MethodGet, "/customer/11144477735", "")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+	})
+}
+
+func TestClientRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ClientRateLimit(ratelimit.NewMemoryStore(), ratelimit.Limit{Requests: 2, Period: time.Minute}),
+		func(c *gin.Context) {
+			if !strings.HasPrefix(c.GetHeader("Authorization"), "Bearer valid") {
+				c.AbortWithStatus(http.StatusUnauthorized)
+			}
+		})
+	router.GET("/customer/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	t.Run("Failed authentication attempts are limited by IP", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, doRequest(router, http.MethodGet, "/customer/1", "10.0.0.1:1234").Code)
+		assert.Equal(t, http.StatusUnauthorized, doRequest(router, http.MethodGet, "/customer/1", "10.0.0.1:1234").Code)
+
+		w := doRequest(router, http.MethodGet, "/customer/1", "10.0.0.1:1234")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "30", w.Header().Get("Retry-After"))
+
+		assert.Equal(t, http.StatusUnauthorized, doRequest(router, http.MethodGet, "/customer/1", "10.0.0.2:1234").Code)
+	})
+}
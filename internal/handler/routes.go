@@ -4,12 +4,139 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(router *gin.Engine, handler *CustomerHandler) {
-	customerGroup := router.Group("/customer")
+// RouteConfig holds optional middleware and endpoints mounted on the
+// customer routes. Nil entries are skipped.
+type RouteConfig struct {
+	// ClientRateLimit runs before Authentication, RateLimit after it
+	ClientRateLimit gin.HandlerFunc
+	Authentication  gin.HandlerFunc
+	Authorization   gin.HandlerFunc
+	RateLimit       gin.HandlerFunc
+	Enumeration     gin.HandlerFunc
+	Idempotency     gin.HandlerFunc
+	V1Deprecation   gin.HandlerFunc
+	Lookup          gin.HandlerFunc
+	Validate        gin.HandlerFunc
+	ConfirmEmail    gin.HandlerFunc
+	CorrectCPF      gin.HandlerFunc
+	GetByCode       gin.HandlerFunc
+	GetByCodeV2     gin.HandlerFunc
+	GetMe           gin.HandlerFunc
+	UpdateMe        gin.HandlerFunc
+	DeleteMe        gin.HandlerFunc
+	Identify        gin.HandlerFunc
+	JWKS            gin.HandlerFunc
+	CreateAPIKey    gin.HandlerFunc
+	ListAPIKeys     gin.HandlerFunc
+	RevokeAPIKey    gin.HandlerFunc
+	ListPIIAccess   gin.HandlerFunc
+}
+
+func SetupRoutes(router *gin.Engine, handler *CustomerHandler, config RouteConfig) {
+	// The unversioned routes keep serving the v1 contract used by the order service
+	customerGroup := router.Group("/customer", chain(config.V1Deprecation, config.ClientRateLimit, config.Authentication, config.RateLimit, config.Authorization)...)
+	setupV1Routes(customerGroup, handler, config)
+	setupUnversionedAliases(customerGroup, config)
+	setupV1Routes(router.Group("/v1/customer", chain(config.V1Deprecation, config.ClientRateLimit, config.Authentication, config.RateLimit, config.Authorization)...), handler, config)
+
+	v2Group := router.Group("/v2/customers", chain(config.ClientRateLimit, config.Authentication, config.RateLimit, config.Authorization)...)
+	{
+		v2Group.POST("", chain(config.Idempotency, handler.CreateCustomerV2)...)
+		v2Group.GET("/cpf/:cpf", chain(config.Enumeration, handler.GetCustomerByCPFV2)...)
+		v2Group.PATCH("/:id", handler.UpdateCustomerV2)
+		v2Group.DELETE("/:id", handler.DeleteCustomerV2)
+		if config.ConfirmEmail != nil {
+			v2Group.POST("/:id/email/confirm", config.ConfirmEmail)
+		}
+		if config.GetByCodeV2 != nil {
+			v2Group.GET("/code/:code", config.GetByCodeV2)
+		}
+		setupCustomerEndpoints(v2Group, config)
+	}
+
+	adminGroup := router.Group("/admin/customers", chain(config.ClientRateLimit, config.Authentication, config.RateLimit, config.Authorization)...)
+	{
+		if config.CorrectCPF != nil {
+			adminGroup.PUT("/:id/cpf", config.CorrectCPF)
+		}
+	}
+
+	if config.ListPIIAccess != nil {
+		router.GET("/admin/pii-access", chain(config.ClientRateLimit, config.Authentication, config.RateLimit, config.Authorization, config.ListPIIAccess)...)
+	}
+
+	apiKeyGroup := router.Group("/admin/api-keys", chain(config.ClientRateLimit, config.Authentication, config.RateLimit, config.Authorization)...)
+	{
+		if config.CreateAPIKey != nil {
+			apiKeyGroup.POST("", config.CreateAPIKey)
+		}
+		if config.ListAPIKeys != nil {
+			apiKeyGroup.GET("", config.ListAPIKeys)
+		}
+		if config.RevokeAPIKey != nil {
+			apiKeyGroup.DELETE("/:id", config.RevokeAPIKey)
+		}
+	}
+
+	authGroup := router.Group("/auth", chain(config.ClientRateLimit, config.Authentication, config.RateLimit, config.Authorization)...)
 	{
-		customerGroup.POST("", handler.CreateCustomer)
-		customerGroup.GET("/:cpf", handler.GetCustomerByCPF)
-		customerGroup.PATCH("/:id", handler.UpdateCustomer)
-		customerGroup.DELETE("/:id", handler.DeleteCustomer)
+		if config.Identify != nil {
+			authGroup.POST("/identify", chain(config.Enumeration, config.Identify)...)
+		}
+	}
+
+	// Other services fetch the signing keys without credentials
+	if config.JWKS != nil {
+		router.GET("/.well-known/jwks.json", config.JWKS)
+	}
+}
+
+// setupV1Routes mounts the frozen v1 contract. Endpoints added since live
+// under /v2/customers.
+func setupV1Routes(customerGroup *gin.RouterGroup, handler *CustomerHandler, config RouteConfig) {
+	customerGroup.POST("", chain(config.Idempotency, handler.CreateCustomer)...)
+	customerGroup.GET("/:cpf", chain(config.Enumeration, handler.GetCustomerByCPF)...)
+	customerGroup.PATCH("/:id", handler.UpdateCustomer)
+	customerGroup.DELETE("/:id", handler.DeleteCustomer)
+}
+
+// setupUnversionedAliases keeps the paths under /customer that were asked
+// for by name, e.g. POST /customer/lookup for the order service, as aliases
+// of the /v2/customers endpoints.
+func setupUnversionedAliases(customerGroup *gin.RouterGroup, config RouteConfig) {
+	if config.GetByCode != nil {
+		customerGroup.GET("/code/:code", config.GetByCode)
+	}
+	setupCustomerEndpoints(customerGroup, config)
+}
+
+// setupCustomerEndpoints mounts the endpoints served the same way under
+// /v2/customers and their /customer aliases.
+func setupCustomerEndpoints(group *gin.RouterGroup, config RouteConfig) {
+	if config.Lookup != nil {
+		group.POST("/lookup", chain(config.Enumeration, config.Lookup)...)
+	}
+	if config.Validate != nil {
+		group.POST("/validate", chain(config.Enumeration, config.Validate)...)
+	}
+	if config.GetMe != nil {
+		group.GET("/me", config.GetMe)
+	}
+	if config.UpdateMe != nil {
+		group.PATCH("/me", config.UpdateMe)
+	}
+	if config.DeleteMe != nil {
+		group.DELETE("/me", config.DeleteMe)
+	}
+}
+
+// chain drops nil middleware so optional features can be left unconfigured.
+func chain(handlers ...gin.HandlerFunc) []gin.HandlerFunc {
+	result := make([]gin.HandlerFunc, 0, len(handlers))
+	for _, h := range handlers {
+		if h != nil {
+			result = append(result, h)
+		}
 	}
+	return result
 }
@@ -1,11 +1,15 @@
 package handler
 
 import (
+	"customer-service/internal/mailer"
 	"customer-service/internal/usecase"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestSetupRoutes(t *testing.T) {
@@ -14,22 +18,30 @@ func TestSetupRoutes(t *testing.T) {
 
 	mockRepo := new(MockRepository)
 	handler := NewCustomerHandler(
-		usecase.NewCreateCustomerUseCase(mockRepo),
-		usecase.NewGetCustomerByCPFUseCase(mockRepo),
-		usecase.NewUpdateCustomerUseCase(mockRepo),
+		usecase.NewCreateCustomerUseCase(mockRepo, nil),
+		usecase.NewGetCustomerByCPFUseCase(mockRepo, nil),
+		usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
 		usecase.NewDeleteCustomerUseCase(mockRepo),
 	)
 
-	SetupRoutes(router, handler)
+	SetupRoutes(router, handler, RouteConfig{})
 
 	routes := router.Routes()
 
 	// Verify that all customer routes are registered
 	expectedRoutes := map[string]string{
-		"POST /customer":        "POST",
-		"GET /customer/:cpf":    "GET",
-		"PATCH /customer/:id":   "PATCH",
-		"DELETE /customer/:id":  "DELETE",
+		"POST /customer":             "POST",
+		"GET /customer/:cpf":         "GET",
+		"PATCH /customer/:id":        "PATCH",
+		"DELETE /customer/:id":       "DELETE",
+		"POST /v1/customer":          "POST",
+		"GET /v1/customer/:cpf":      "GET",
+		"PATCH /v1/customer/:id":     "PATCH",
+		"DELETE /v1/customer/:id":    "DELETE",
+		"POST /v2/customers":         "POST",
+		"GET /v2/customers/cpf/:cpf": "GET",
+		"PATCH /v2/customers/:id":    "PATCH",
+		"DELETE /v2/customers/:id":   "DELETE",
 	}
 
 	routeMap := make(map[string]string)
@@ -46,3 +58,321 @@ func TestSetupRoutes(t *testing.T) {
 
 	assert.Equal(t, len(expectedRoutes), len(routes), "Should have exactly %d routes", len(expectedRoutes))
 }
+
+func TestSetupRoutes_Lookup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	mockRepo := new(MockRepository)
+	handler := NewCustomerHandler(
+		usecase.NewCreateCustomerUseCase(mockRepo, nil),
+		usecase.NewGetCustomerByCPFUseCase(mockRepo, nil),
+		usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
+		usecase.NewDeleteCustomerUseCase(mockRepo),
+	)
+
+	SetupRoutes(router, handler, RouteConfig{
+		Lookup: NewLookupHandler(usecase.NewBatchGetCustomersUseCase(mockRepo, nil)).LookupCustomers,
+	})
+
+	routeMap := make(map[string]bool)
+	for _, route := range router.Routes() {
+		routeMap[route.Method+" "+route.Path] = true
+	}
+
+	assert.True(t, routeMap["POST /v2/customers/lookup"])
+	assert.True(t, routeMap["POST /customer/lookup"])
+	assert.False(t, routeMap["POST /v1/customer/lookup"])
+	assert.Len(t, router.Routes(), 14)
+}
+
+func TestSetupRoutes_Validate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	mockRepo := new(MockRepository)
+	handler := NewCustomerHandler(
+		usecase.NewCreateCustomerUseCase(mockRepo, nil),
+		usecase.NewGetCustomerByCPFUseCase(mockRepo, nil),
+		usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
+		usecase.NewDeleteCustomerUseCase(mockRepo),
+	)
+
+	SetupRoutes(router, handler, RouteConfig{
+		Validate: NewValidateHandler(usecase.NewValidateCustomerUseCase(mockRepo)).ValidateCustomer,
+	})
+
+	routeMap := make(map[string]bool)
+	for _, route := range router.Routes() {
+		routeMap[route.Method+" "+route.Path] = true
+	}
+
+	assert.True(t, routeMap["POST /v2/customers/validate"])
+	assert.True(t, routeMap["POST /customer/validate"])
+	assert.False(t, routeMap["POST /v1/customer/validate"])
+	assert.Len(t, router.Routes(), 14)
+}
+
+func TestSetupRoutes_ConfirmEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	mockRepo := new(MockRepository)
+	handler := NewCustomerHandler(
+		usecase.NewCreateCustomerUseCase(mockRepo, nil),
+		usecase.NewGetCustomerByCPFUseCase(mockRepo, nil),
+		usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
+		usecase.NewDeleteCustomerUseCase(mockRepo),
+	)
+
+	SetupRoutes(router, handler, RouteConfig{
+		ConfirmEmail: NewEmailHandler(usecase.NewConfirmEmailUseCase(mockRepo, nil)).ConfirmEmail,
+	})
+
+	routeMap := make(map[string]bool)
+	for _, route := range router.Routes() {
+		routeMap[route.Method+" "+route.Path] = true
+	}
+
+	assert.True(t, routeMap["POST /v2/customers/:id/email/confirm"])
+	assert.False(t, routeMap["POST /customer/:id/email/confirm"])
+	assert.Len(t, router.Routes(), 13)
+}
+
+func TestSetupRoutes_CorrectCPF(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	mockRepo := new(MockRepository)
+	handler := NewCustomerHandler(
+		usecase.NewCreateCustomerUseCase(mockRepo, nil),
+		usecase.NewGetCustomerByCPFUseCase(mockRepo, nil),
+		usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
+		usecase.NewDeleteCustomerUseCase(mockRepo),
+	)
+
+	SetupRoutes(router, handler, RouteConfig{
+		CorrectCPF: NewAdminHandler(usecase.NewCorrectCPFUseCase(mockRepo, nil)).CorrectCPF,
+	})
+
+	routeMap := make(map[string]bool)
+	for _, route := range router.Routes() {
+		routeMap[route.Method+" "+route.Path] = true
+	}
+
+	assert.True(t, routeMap["PUT /admin/customers/:id/cpf"])
+	assert.Len(t, router.Routes(), 13)
+}
+
+func TestSetupRoutes_GetByCode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	mockRepo := new(MockRepository)
+	handler := NewCustomerHandler(
+		usecase.NewCreateCustomerUseCase(mockRepo, nil),
+		usecase.NewGetCustomerByCPFUseCase(mockRepo, nil),
+		usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
+		usecase.NewDeleteCustomerUseCase(mockRepo),
+	)
+
+	codes := NewCodeHandler(usecase.NewGetCustomerByCodeUseCase(mockRepo, nil))
+	SetupRoutes(router, handler, RouteConfig{
+		GetByCode:   codes.GetCustomerByCode,
+		GetByCodeV2: codes.GetCustomerByCodeV2,
+	})
+
+	routeMap := make(map[string]bool)
+	for _, route := range router.Routes() {
+		routeMap[route.Method+" "+route.Path] = true
+	}
+
+	assert.True(t, routeMap["GET /v2/customers/code/:code"])
+	assert.True(t, routeMap["GET /customer/code/:code"])
+	assert.False(t, routeMap["GET /v1/customer/code/:code"])
+	assert.Len(t, router.Routes(), 14)
+}
+
+func TestSetupRoutes_Me(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	mockRepo := new(MockRepository)
+	handler := NewCustomerHandler(
+		usecase.NewCreateCustomerUseCase(mockRepo, nil),
+		usecase.NewGetCustomerByCPFUseCase(mockRepo, nil),
+		usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
+		usecase.NewDeleteCustomerUseCase(mockRepo),
+	)
+	me := NewMeHandler(
+		usecase.NewGetCustomerByIDUseCase(mockRepo, nil),
+		usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
+		usecase.NewDeleteCustomerUseCase(mockRepo),
+	)
+
+	SetupRoutes(router, handler, RouteConfig{
+		GetMe:    me.GetMe,
+		UpdateMe: me.UpdateMe,
+		DeleteMe: me.DeleteMe,
+	})
+
+	routeMap := make(map[string]bool)
+	for _, route := range router.Routes() {
+		routeMap[route.Method+" "+route.Path] = true
+	}
+
+	for _, method := range []string{"GET", "PATCH", "DELETE"} {
+		assert.True(t, routeMap[method+" /v2/customers/me"])
+		assert.True(t, routeMap[method+" /customer/me"])
+		assert.False(t, routeMap[method+" /v1/customer/me"])
+	}
+	assert.Len(t, router.Routes(), 18)
+
+	// /me must win over the /:cpf and /:id parameters
+	for _, path := range []string{"/v2/customers/me", "/customer/me"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestSetupRoutes_Identify(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	mockRepo := new(MockRepository)
+	handler := NewCustomerHandler(
+		usecase.NewCreateCustomerUseCase(mockRepo, nil),
+		usecase.NewGetCustomerByCPFUseCase(mockRepo, nil),
+		usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
+		usecase.NewDeleteCustomerUseCase(mockRepo),
+	)
+	identify, _ := newTestIdentifyHandler(t, mockRepo)
+
+	SetupRoutes(router, handler, RouteConfig{
+		Identify: identify.Identify,
+		JWKS:     identify.JWKS,
+	})
+
+	routeMap := make(map[string]bool)
+	for _, route := range router.Routes() {
+		routeMap[route.Method+" "+route.Path] = true
+	}
+
+	assert.True(t, routeMap["POST /auth/identify"])
+	assert.True(t, routeMap["GET /.well-known/jwks.json"])
+	assert.Len(t, router.Routes(), 14)
+}
+
+func TestSetupRoutes_APIKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	mockRepo := new(MockRepository)
+	handler := NewCustomerHandler(
+		usecase.NewCreateCustomerUseCase(mockRepo, nil),
+		usecase.NewGetCustomerByCPFUseCase(mockRepo, nil),
+		usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
+		usecase.NewDeleteCustomerUseCase(mockRepo),
+	)
+	apiKeys := newTestAPIKeyHandler(new(MockAPIKeyRepository))
+
+	SetupRoutes(router, handler, RouteConfig{
+		CreateAPIKey: apiKeys.CreateAPIKey,
+		ListAPIKeys:  apiKeys.ListAPIKeys,
+		RevokeAPIKey: apiKeys.RevokeAPIKey,
+	})
+
+	routeMap := make(map[string]bool)
+	for _, route := range router.Routes() {
+		routeMap[route.Method+" "+route.Path] = true
+	}
+
+	assert.True(t, routeMap["POST /admin/api-keys"])
+	assert.True(t, routeMap["GET /admin/api-keys"])
+	assert.True(t, routeMap["DELETE /admin/api-keys/:id"])
+	assert.Len(t, router.Routes(), 15)
+}
+
+func TestSetupRoutes_Authentication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	mockRepo := new(MockRepository)
+	handler := NewCustomerHandler(
+		usecase.NewCreateCustomerUseCase(mockRepo, nil),
+		usecase.NewGetCustomerByCPFUseCase(mockRepo, nil),
+		usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
+		usecase.NewDeleteCustomerUseCase(mockRepo),
+	)
+
+	SetupRoutes(router, handler, RouteConfig{
+		Authentication: func(c *gin.Context) {
+			c.AbortWithStatus(http.StatusUnauthorized)
+		},
+		CorrectCPF: NewAdminHandler(usecase.NewCorrectCPFUseCase(mockRepo, nil)).CorrectCPF,
+	})
+
+	for _, path := range []string{"/customer/11144477735", "/v1/customer/11144477735", "/v2/customers/cpf/11144477735"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code, path)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/admin/customers/123/cpf", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSetupRoutes_RateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	mockRepo := new(MockRepository)
+	handler := NewCustomerHandler(
+		usecase.NewCreateCustomerUseCase(mockRepo, nil),
+		usecase.NewGetCustomerByCPFUseCase(mockRepo, nil),
+		usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
+		usecase.NewDeleteCustomerUseCase(mockRepo),
+	)
+
+	SetupRoutes(router, handler, RouteConfig{
+		RateLimit: func(c *gin.Context) {
+			c.AbortWithStatus(http.StatusTooManyRequests)
+		},
+	})
+
+	for _, path := range []string{"/customer/11144477735", "/v1/customer/11144477735", "/v2/customers/cpf/11144477735"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusTooManyRequests, w.Code, path)
+	}
+	mockRepo.AssertNotCalled(t, "FindByCPF", mock.Anything, mock.Anything)
+}
+
+func TestSetupRoutes_Enumeration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	mockRepo := new(MockRepository)
+	handler := NewCustomerHandler(
+		usecase.NewCreateCustomerUseCase(mockRepo, nil),
+		usecase.NewGetCustomerByCPFUseCase(mockRepo, nil),
+		usecase.NewUpdateCustomerUseCase(mockRepo, mailer.NewLogMailer(), nil),
+		usecase.NewDeleteCustomerUseCase(mockRepo),
+	)
+
+	SetupRoutes(router, handler, RouteConfig{
+		Enumeration: func(c *gin.Context) {
+			c.AbortWithStatus(http.StatusTooManyRequests)
+		},
+	})
+
+	for _, path := range []string{"/customer/11144477735", "/v1/customer/11144477735", "/v2/customers/cpf/11144477735"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusTooManyRequests, w.Code, path)
+	}
+	mockRepo.AssertNotCalled(t, "FindByCPF", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"customer-service/internal/usecase"
+	"customer-service/pkg/i18n"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ValidateHandler struct {
+	validateUseCase *usecase.ValidateCustomerUseCase
+}
+
+func NewValidateHandler(validateUC *usecase.ValidateCustomerUseCase) *ValidateHandler {
+	return &ValidateHandler{validateUseCase: validateUC}
+}
+
+// ValidateCustomerRequest has no binding rules: missing fields are reported
+// by the domain rules like any other invalid value.
+type ValidateCustomerRequest struct {
+	Name  string `json:"name"`
+	CPF   string `json:"cpf"`
+	Email string `json:"email"`
+}
+
+// ValidateCustomerResponse lists every problem with the candidate customer,
+// using the same field errors as problem+json responses.
+type ValidateCustomerResponse struct {
+	Valid  bool           `json:"valid"`
+	Errors []ProblemField `json:"errors"`
+}
+
+// ValidateCustomer godoc
+// @Summary Validate a customer before creating it
+// @Description Applies every creation rule and checks that the CPF and email are still available. Nothing is created.
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param customer body ValidateCustomerRequest true "Candidate customer"
+// @Success 200 {object} ValidateCustomerResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v2/customers/validate [post]
+// @Router /customer/validate [post]
+func (h *ValidateHandler) ValidateCustomer(c *gin.Context) {
+	var req ValidateCustomerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleBindingError(c, &req, err)
+		return
+	}
+
+	result, err := h.validateUseCase.Execute(c.Request.Context(), req.Name, req.CPF, req.Email)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	lang := requestLanguage(c)
+	if lang != "" {
+		c.Header("Content-Language", lang)
+	}
+
+	response := ValidateCustomerResponse{
+		Valid:  result.Valid,
+		Errors: make([]ProblemField, 0, len(result.Errors)),
+	}
+	for _, field := range result.Errors {
+		detail := field.Message
+		if message, ok := i18n.Translate(lang, field.Code, field.Params); ok {
+			detail = message
+		}
+		response.Errors = append(response.Errors, ProblemField{
+			Pointer: field.Pointer,
+			Code:    field.Code,
+			Detail:  detail,
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"bytes"
+	"customer-service/internal/domain"
+	"customer-service/internal/usecase"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupValidateRouter(mockRepo *MockRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/customer/validate", NewValidateHandler(usecase.NewValidateCustomerUseCase(mockRepo)).ValidateCustomer)
+	return router
+}
+
+func TestValidateCustomer(t *testing.T) {
+	t.Run("Valid customer", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindConflicts", mock.Anything, "11144477735", "john@example.com").Return([]*domain.Customer{}, nil)
+
+		w := postJSON(setupValidateRouter(mockRepo), "/customer/validate",
+			`{"name":"John Doe","cpf":"111.444.777-35","email":"john@example.com"}`, "")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"valid":true,"errors":[]}`, w.Body.String())
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Reports rule violations and taken fields together", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		existing, _ := domain.NewCustomer("Jane Doe", "52998224725", "john@example.com")
+		mockRepo.On("FindConflicts", mock.Anything, "", "john@example.com").Return([]*domain.Customer{existing}, nil)
+
+		w := postJSON(setupValidateRouter(mockRepo), "/customer/validate", `{"cpf":"123","email":"john@example.com"}`, "")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"valid":false,"errors":[
+			{"pointer":"/name","code":"NAME_EMPTY","detail":"Name cannot be empty"},
+			{"pointer":"/cpf","code":"INVALID_CPF","detail":"Invalid CPF"},
+			{"pointer":"/email","code":"EMAIL_ALREADY_EXISTS","detail":"Email is already registered"}
+		]}`, w.Body.String())
+	})
+
+	t.Run("Translates messages", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		existing, _ := domain.NewCustomer("Jane Doe", "11144477735", "jane@example.com")
+		mockRepo.On("FindConflicts", mock.Anything, "11144477735", "john@example.com").Return([]*domain.Customer{existing}, nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/customer/validate",
+			bytes.NewBufferString(`{"name":"John Doe","cpf":"11144477735","email":"john@example.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept-Language", "pt-BR")
+		w := httptest.NewRecorder()
+		setupValidateRouter(mockRepo).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "pt-BR", w.Header().Get("Content-Language"))
+		assert.Contains(t, w.Body.String(), "CPF já cadastrado")
+	})
+
+	t.Run("Repository error", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindConflicts", mock.Anything, mock.Anything, mock.Anything).Return(nil, assert.AnError)
+
+		w := postJSON(setupValidateRouter(mockRepo), "/customer/validate",
+			`{"name":"John Doe","cpf":"11144477735","email":"john@example.com"}`, "")
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
@@ -0,0 +1,36 @@
+package mailer
+
+import (
+	"context"
+	"customer-service/pkg/validator"
+	"log"
+)
+
+// Mailer delivers the messages of the email change flow.
+type Mailer interface {
+	// SendEmailVerification sends the token that confirms ownership of a new
+	// email address.
+	SendEmailVerification(ctx context.Context, to, name, token string) error
+	// SendEmailChangeNotice warns the current address that a change to
+	// newEmail was requested.
+	SendEmailChangeNotice(ctx context.Context, to, name, newEmail string) error
+}
+
+// LogMailer writes to the log that messages would have been sent, without
+// sending them. It is meant for tests: verification tokens are left out of
+// the log, so email changes can never be confirmed through it.
+type LogMailer struct{}
+
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+func (m *LogMailer) SendEmailVerification(_ context.Context, to, _, _ string) error {
+	log.Printf("mail to %s: email verification token withheld", validator.MaskEmail(to))
+	return nil
+}
+
+func (m *LogMailer) SendEmailChangeNotice(_ context.Context, to, _, newEmail string) error {
+	log.Printf("mail to %s: a change of the email to %s was requested", validator.MaskEmail(to), validator.MaskEmail(newEmail))
+	return nil
+}
@@ -0,0 +1,116 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"customer-service/pkg/validator"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// smtpTimeout bounds a delivery when ctx has no deadline of its own.
+const smtpTimeout = 10 * time.Second
+
+// SMTPMailer delivers messages through an SMTP relay, such as Amazon SES.
+// The connection is upgraded with STARTTLS whenever the server offers it.
+type SMTPMailer struct {
+	addr string
+	host string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPMailer sends from the given address through the relay at addr
+// (host:port). Without a username no authentication is attempted.
+func NewSMTPMailer(addr, from, username, password string) (*SMTPMailer, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP address %q: %w", addr, err)
+	}
+	if !validator.IsValidEmail(from) {
+		return nil, fmt.Errorf("invalid sender address %q", from)
+	}
+
+	m := &SMTPMailer{addr: addr, host: host, from: from}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m, nil
+}
+
+func (m *SMTPMailer) SendEmailVerification(ctx context.Context, to, name, token string) error {
+	return m.send(ctx, to, "Confirm your new email",
+		fmt.Sprintf("Hello %s,\r\n\r\nConfirm your new email with this token:\r\n\r\n%s\r\n\r\nIf you did not ask for this change, ignore this message.\r\n", name, token))
+}
+
+func (m *SMTPMailer) SendEmailChangeNotice(ctx context.Context, to, name, newEmail string) error {
+	return m.send(ctx, to, "Your email is being changed",
+		fmt.Sprintf("Hello %s,\r\n\r\nA change of your email to %s was requested. If it was not you, contact us.\r\n", name, validator.MaskEmail(newEmail)))
+}
+
+func (m *SMTPMailer) send(ctx context.Context, to, subject, body string) error {
+	// Addresses end up in headers, so a line break would forge new ones
+	if strings.ContainsAny(to, "\r\n") {
+		return fmt.Errorf("invalid recipient address")
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, smtpTimeout)
+		defer cancel()
+	}
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return fmt.Errorf("failed to reach SMTP server: %w", err)
+	}
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to greet SMTP server: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if m.auth != nil {
+		if err := client.Auth(m.auth); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+	if err := client.Mail(m.from); err != nil {
+		return fmt.Errorf("sender rejected: %w", err)
+	}
+	if err := client.Rcpt(to); err != nil {
+		return fmt.Errorf("recipient rejected: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start message: %w", err)
+	}
+	message := "From: " + m.from + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body
+	if _, err := w.Write([]byte(message)); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("message rejected: %w", err)
+	}
+	return client.Quit()
+}
@@ -0,0 +1,98 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTPServer accepts one delivery and sends the message it received on
+// the returned channel.
+func fakeSMTPServer(t *testing.T) (string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost ESMTP")
+		var data strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch verb := strings.ToUpper(strings.Fields(line + " ")[0]); verb {
+			case "EHLO", "HELO":
+				reply("250 localhost")
+			case "DATA":
+				reply("354 go ahead")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				messages <- data.String()
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return listener.Addr().String(), messages
+}
+
+func TestNewSMTPMailer(t *testing.T) {
+	_, err := NewSMTPMailer("localhost", "no-reply@example.com", "", "")
+	assert.Error(t, err)
+
+	_, err = NewSMTPMailer("localhost:25", "not-an-email", "", "")
+	assert.Error(t, err)
+
+	_, err = NewSMTPMailer("localhost:25", "no-reply@example.com", "", "")
+	assert.NoError(t, err)
+}
+
+func TestSMTPMailer_SendEmailVerification(t *testing.T) {
+	addr, messages := fakeSMTPServer(t)
+	m, err := NewSMTPMailer(addr, "no-reply@example.com", "", "")
+	require.NoError(t, err)
+
+	err = m.SendEmailVerification(context.Background(), "john@example.com", "John Doe", "token-123")
+
+	require.NoError(t, err)
+	message := <-messages
+	assert.Contains(t, message, "To: john@example.com\r\n")
+	assert.Contains(t, message, "From: no-reply@example.com\r\n")
+	assert.Contains(t, message, "token-123")
+}
+
+func TestSMTPMailer_RejectsHeaderInjection(t *testing.T) {
+	m, err := NewSMTPMailer("localhost:25", "no-reply@example.com", "", "")
+	require.NoError(t, err)
+
+	err = m.SendEmailVerification(context.Background(), "john@example.com\r\nBcc: eve@example.com", "John Doe", "token-123")
+
+	assert.Error(t, err)
+}
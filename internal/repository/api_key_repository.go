@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"customer-service/internal/domain"
+	"time"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *domain.APIKey) error
+	FindByID(ctx context.Context, id string) (*domain.APIKey, error)
+	List(ctx context.Context) ([]*domain.APIKey, error)
+	// Revoke marks the key revoked at the given time. Revoking a key twice
+	// keeps the first time.
+	Revoke(ctx context.Context, id string, at time.Time) error
+	// TouchLastUsed records that the key was used at the given time.
+	TouchLastUsed(ctx context.Context, id string, at time.Time) error
+}
@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"context"
+	"customer-service/internal/domain"
+	"customer-service/pkg/errors"
+	"customer-service/pkg/fieldcrypt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Blind index fields, also used as the HMAC domain of each index.
+const (
+	cpfIndexField   = "cpfIndex"
+	emailIndexField = "emailIndex"
+)
+
+// customerDocument is a customer as stored. Name, CPF, email, pending email
+// and replaced CPFs are encrypted with the customer's data key, kept apart
+// in the customer_keys collection; CPF and email are found through their
+// blind indexes.
+type customerDocument struct {
+	ID                string                    `bson:"_id"`
+	Name              string                    `bson:"name"`
+	CPF               string                    `bson:"cpf"`
+	CPFIndex          string                    `bson:"cpfIndex"`
+	Email             string                    `bson:"email"`
+	EmailIndex        string                    `bson:"emailIndex"`
+	CreatedAt         time.Time                 `bson:"createdAt"`
+	UpdatedAt         time.Time                 `bson:"updatedAt"`
+	Code              string                    `bson:"code,omitempty"`
+	PendingEmail      string                    `bson:"pendingEmail,omitempty"`
+	EmailVerification *domain.EmailVerification `bson:"emailVerification,omitempty"`
+	CPFHistory        []domain.CPFChange        `bson:"cpfHistory,omitempty"`
+}
+
+// customerKeyDocument is a customer's data key, wrapped by a master key.
+// Deleting it shreds the customer: every copy of their document, including
+// those in backups and exports, can no longer be decrypted.
+type customerKeyDocument struct {
+	CustomerID string    `bson:"_id"`
+	KeyID      string    `bson:"keyId"`
+	Key        []byte    `bson:"key"`
+	CreatedAt  time.Time `bson:"createdAt"`
+}
+
+// fieldSealer encrypts the fields of one customer, binding each ciphertext
+// to the customer and field it belongs to.
+type fieldSealer struct {
+	id  string
+	key *fieldcrypt.DataKey
+}
+
+func (s fieldSealer) seal(field, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	ciphertext, err := s.key.Encrypt(value, s.id+"/"+field)
+	if err != nil {
+		return "", errors.WrapError(err, "Failed to encrypt customer "+field)
+	}
+	return ciphertext, nil
+}
+
+func (s fieldSealer) open(field, ciphertext string) (string, error) {
+	if ciphertext == "" {
+		return "", nil
+	}
+	value, err := s.key.Decrypt(ciphertext, s.id+"/"+field)
+	if err != nil {
+		return "", errors.WrapError(err, "Failed to decrypt customer "+field)
+	}
+	return value, nil
+}
+
+func (s fieldSealer) sealHistory(history []domain.CPFChange) ([]domain.CPFChange, error) {
+	sealed := make([]domain.CPFChange, len(history))
+	for i, change := range history {
+		previous, err := s.seal("cpfHistory", change.PreviousCPF)
+		if err != nil {
+			return nil, err
+		}
+		change.PreviousCPF = previous
+		sealed[i] = change
+	}
+	return sealed, nil
+}
+
+// cpfIndex and emailIndex return the blind indexes customers are looked up
+// by.
+func (r *MongoDBCustomerRepository) cpfIndex(cpf string) string {
+	return r.encryptor.BlindIndex(cpfIndexField, cpf)
+}
+
+func (r *MongoDBCustomerRepository) emailIndex(email string) string {
+	return r.encryptor.BlindIndex(emailIndexField, email)
+}
+
+// erasedIndexes replace the blind indexes of an erased customer. They keep
+// the unique indexes satisfied without anything to brute-force the CPF or
+// email from. It is an update pipeline stage, so it can be applied to
+// many customers at once.
+var erasedIndexes = bson.M{"$set": bson.M{
+	cpfIndexField:   bson.M{"$concat": bson.A{"erased:", "$_id"}},
+	emailIndexField: bson.M{"$concat": bson.A{"erased:", "$_id"}},
+}}
+
+func (r *MongoDBCustomerRepository) cpfIndexes(cpfs []string) []string {
+	indexes := make([]string, len(cpfs))
+	for i, cpf := range cpfs {
+		indexes[i] = r.cpfIndex(cpf)
+	}
+	return indexes
+}
+
+// newDocument encrypts a customer under a new data key.
+func (r *MongoDBCustomerRepository) newDocument(customer *domain.Customer) (*customerDocument, *customerKeyDocument, error) {
+	key, err := r.encryptor.NewDataKey()
+	if err != nil {
+		return nil, nil, errors.WrapError(err, "Failed to generate data key")
+	}
+	sealer := fieldSealer{id: customer.ID, key: key}
+
+	doc := &customerDocument{
+		ID:                customer.ID,
+		CPFIndex:          r.cpfIndex(customer.CPF),
+		EmailIndex:        r.emailIndex(customer.Email),
+		CreatedAt:         customer.CreatedAt,
+		UpdatedAt:         customer.UpdatedAt,
+		Code:              customer.Code,
+		EmailVerification: customer.EmailVerification,
+	}
+	if doc.Name, err = sealer.seal("name", customer.Name); err != nil {
+		return nil, nil, err
+	}
+	if doc.CPF, err = sealer.seal("cpf", customer.CPF); err != nil {
+		return nil, nil, err
+	}
+	if doc.Email, err = sealer.seal("email", customer.Email); err != nil {
+		return nil, nil, err
+	}
+	if doc.PendingEmail, err = sealer.seal("pendingEmail", customer.PendingEmail); err != nil {
+		return nil, nil, err
+	}
+	if doc.CPFHistory, err = sealer.sealHistory(customer.CPFHistory); err != nil {
+		return nil, nil, err
+	}
+
+	keyDoc := &customerKeyDocument{
+		CustomerID: customer.ID,
+		KeyID:      key.Wrapped().KeyID,
+		Key:        key.Wrapped().Ciphertext,
+		CreatedAt:  time.Now(),
+	}
+	return doc, keyDoc, nil
+}
+
+// sealer unwraps a customer's data key.
+func (r *MongoDBCustomerRepository) sealer(key *customerKeyDocument) (fieldSealer, error) {
+	dataKey, err := r.encryptor.OpenDataKey(fieldcrypt.WrappedKey{KeyID: key.KeyID, Ciphertext: key.Key})
+	if err != nil {
+		return fieldSealer{}, errors.WrapError(err, "Failed to unwrap customer data key")
+	}
+	return fieldSealer{id: key.CustomerID, key: dataKey}, nil
+}
+
+// findKey loads a customer's data key. It returns nil once the customer was
+// shredded.
+func (r *MongoDBCustomerRepository) findKey(ctx context.Context, id string) (*customerKeyDocument, error) {
+	var key customerKeyDocument
+	err := r.keys.FindOne(ctx, bson.M{"_id": id}).Decode(&key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, errors.WrapError(err, "Failed to load customer data key")
+	}
+	return &key, nil
+}
+
+// storedSealer loads the data key of a stored customer, to encrypt the
+// fields an update changes. Shredded customers cannot be updated.
+func (r *MongoDBCustomerRepository) storedSealer(ctx context.Context, id string) (fieldSealer, error) {
+	key, err := r.findKey(ctx, id)
+	if err != nil {
+		return fieldSealer{}, err
+	}
+	if key == nil {
+		count, err := r.collection.CountDocuments(ctx, bson.M{"_id": id})
+		if err != nil {
+			return fieldSealer{}, errors.WrapError(err, "Failed to find customer")
+		}
+		if count == 0 {
+			return fieldSealer{}, errors.NewNotFoundError("Customer not found", "CUSTOMER_NOT_FOUND")
+		}
+		return fieldSealer{}, errors.NewConflictError("Customer data was erased", "CUSTOMER_ANONYMIZED")
+	}
+	return r.sealer(key)
+}
+
+// customer decrypts a stored customer, or returns it anonymized if it was
+// shredded.
+func (r *MongoDBCustomerRepository) customer(ctx context.Context, doc *customerDocument) (*domain.Customer, error) {
+	key, err := r.findKey(ctx, doc.ID)
+	if err != nil {
+		return nil, err
+	}
+	return r.decrypt(doc, key)
+}
+
+// customers decrypts stored customers, loading their data keys at once.
+func (r *MongoDBCustomerRepository) customers(ctx context.Context, docs []customerDocument) ([]*domain.Customer, error) {
+	customers := make([]*domain.Customer, 0, len(docs))
+	if len(docs) == 0 {
+		return customers, nil
+	}
+
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.ID
+	}
+	cursor, err := r.keys.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, errors.WrapError(err, "Failed to load customer data keys")
+	}
+	var keys []customerKeyDocument
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, errors.WrapError(err, "Failed to decode customer data keys")
+	}
+	byID := make(map[string]*customerKeyDocument, len(keys))
+	for i := range keys {
+		byID[keys[i].CustomerID] = &keys[i]
+	}
+
+	for i := range docs {
+		customer, err := r.decrypt(&docs[i], byID[docs[i].ID])
+		if err != nil {
+			return nil, err
+		}
+		customers = append(customers, customer)
+	}
+	return customers, nil
+}
+
+func (r *MongoDBCustomerRepository) decrypt(doc *customerDocument, key *customerKeyDocument) (*domain.Customer, error) {
+	if key == nil {
+		return anonymized(doc), nil
+	}
+	sealer, err := r.sealer(key)
+	if err != nil {
+		return nil, err
+	}
+
+	customer := &domain.Customer{
+		ID:                doc.ID,
+		CreatedAt:         doc.CreatedAt,
+		UpdatedAt:         doc.UpdatedAt,
+		Code:              doc.Code,
+		EmailVerification: doc.EmailVerification,
+	}
+	if customer.Name, err = sealer.open("name", doc.Name); err != nil {
+		return nil, err
+	}
+	if customer.CPF, err = sealer.open("cpf", doc.CPF); err != nil {
+		return nil, err
+	}
+	if customer.Email, err = sealer.open("email", doc.Email); err != nil {
+		return nil, err
+	}
+	if customer.PendingEmail, err = sealer.open("pendingEmail", doc.PendingEmail); err != nil {
+		return nil, err
+	}
+	for _, change := range doc.CPFHistory {
+		if change.PreviousCPF, err = sealer.open("cpfHistory", change.PreviousCPF); err != nil {
+			return nil, err
+		}
+		customer.CPFHistory = append(customer.CPFHistory, change)
+	}
+	return customer, nil
+}
+
+// anonymized keeps what is left of a shredded customer: the fields that
+// were never encrypted and say nothing about the person.
+func anonymized(doc *customerDocument) *domain.Customer {
+	return &domain.Customer{
+		ID:         doc.ID,
+		CreatedAt:  doc.CreatedAt,
+		UpdatedAt:  doc.UpdatedAt,
+		Code:       doc.Code,
+		Anonymized: true,
+	}
+}
@@ -8,9 +8,15 @@ import (
 type CustomerRepository interface {
 	Create(ctx context.Context, customer *domain.Customer) error
 	FindByID(ctx context.Context, id string) (*domain.Customer, error)
+	FindByIDs(ctx context.Context, ids []string) ([]*domain.Customer, error)
 	FindByCPF(ctx context.Context, cpf string) (*domain.Customer, error)
-	FindByCPFOrEmail(ctx context.Context, cpf, email string) (*domain.Customer, error)
+	FindByCode(ctx context.Context, code string) (*domain.Customer, error)
+	GetVersionByCPF(ctx context.Context, cpf string) (*domain.CustomerVersion, error)
+	FindByCPFs(ctx context.Context, cpfs []string) ([]*domain.Customer, error)
+	FindByIDsOrCPFs(ctx context.Context, ids, cpfs []string) ([]*domain.Customer, error)
+	FindConflicts(ctx context.Context, cpf, email string) ([]*domain.Customer, error)
 	Update(ctx context.Context, customer *domain.Customer) error
+	UpdateCPF(ctx context.Context, customer *domain.Customer) error
 	Delete(ctx context.Context, id string) error
 	GetEmailByID(ctx context.Context, id string) (string, error)
 }
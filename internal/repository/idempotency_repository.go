@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+	"customer-service/internal/domain"
+)
+
+type IdempotencyRepository interface {
+	// Reserve claims the key for a new request. It returns the existing record
+	// when the key has already been used, or nil when the key was reserved.
+	Reserve(ctx context.Context, key, fingerprint string) (*domain.IdempotencyRecord, error)
+	Complete(ctx context.Context, key string, statusCode int, customerID string) error
+	Release(ctx context.Context, key string) error
+}
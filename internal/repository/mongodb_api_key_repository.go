@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"customer-service/internal/domain"
+	"customer-service/pkg/errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// lastUsedResolution limits how often the last-used time is written, so a
+// busy key does not cost a write per request.
+const lastUsedResolution = time.Minute
+
+type MongoDBAPIKeyRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoDBAPIKeyRepository(db *mongo.Database) *MongoDBAPIKeyRepository {
+	return &MongoDBAPIKeyRepository{
+		collection: db.Collection("api_keys"),
+	}
+}
+
+func (r *MongoDBAPIKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	if _, err := r.collection.InsertOne(ctx, key); err != nil {
+		return errors.WrapError(err, "Failed to create API key")
+	}
+	return nil
+}
+
+func (r *MongoDBAPIKeyRepository) FindByID(ctx context.Context, id string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, errors.WrapError(err, "Failed to find API key")
+	}
+	return &key, nil
+}
+
+func (r *MongoDBAPIKeyRepository) List(ctx context.Context) ([]*domain.APIKey, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, errors.WrapError(err, "Failed to list API keys")
+	}
+
+	keys := make([]*domain.APIKey, 0)
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, errors.WrapError(err, "Failed to decode API keys")
+	}
+	return keys, nil
+}
+
+func (r *MongoDBAPIKeyRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.A{bson.M{"$set": bson.M{"revokedAt": bson.M{"$ifNull": bson.A{"$revokedAt", at}}}}},
+	)
+	if err != nil {
+		return errors.WrapError(err, "Failed to revoke API key")
+	}
+
+	if result.MatchedCount == 0 {
+		return errors.NewNotFoundError("API key not found", "API_KEY_NOT_FOUND")
+	}
+
+	return nil
+}
+
+func (r *MongoDBAPIKeyRepository) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	filter := bson.M{
+		"_id": id,
+		"$or": bson.A{
+			bson.M{"lastUsedAt": bson.M{"$exists": false}},
+			bson.M{"lastUsedAt": bson.M{"$lt": at.Add(-lastUsedResolution)}},
+		},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"lastUsedAt": at}})
+	if err != nil {
+		return errors.WrapError(err, "Failed to record API key use")
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"customer-service/internal/domain"
+	"customer-service/pkg/errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestAPIKeyRepository_Create(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Key stored", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		key, _, _ := domain.NewAPIKey("partner", []string{"customers:read"}, time.Now().Add(time.Hour), "admin")
+
+		repo := &MongoDBAPIKeyRepository{collection: mt.Coll}
+		err := repo.Create(context.Background(), key)
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("Database error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "boom"}))
+		key, _, _ := domain.NewAPIKey("partner", []string{"customers:read"}, time.Now().Add(time.Hour), "admin")
+
+		repo := &MongoDBAPIKeyRepository{collection: mt.Coll}
+		err := repo.Create(context.Background(), key)
+
+		assert.Equal(t, "INTERNAL_ERROR", errors.From(err).Code)
+	})
+}
+
+func TestAPIKeyRepository_FindByID(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Key found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "customer_db.api_keys", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: "key-1"},
+			{Key: "name", Value: "partner"},
+			{Key: "scopes", Value: bson.A{"customers:read"}},
+		}))
+
+		repo := &MongoDBAPIKeyRepository{collection: mt.Coll}
+		key, err := repo.FindByID(context.Background(), "key-1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "partner", key.Name)
+		assert.Equal(t, []string{"customers:read"}, key.Scopes)
+	})
+
+	mt.Run("Key not found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "customer_db.api_keys", mtest.FirstBatch))
+
+		repo := &MongoDBAPIKeyRepository{collection: mt.Coll}
+		key, err := repo.FindByID(context.Background(), "key-1")
+
+		assert.NoError(t, err)
+		assert.Nil(t, key)
+	})
+}
+
+func TestAPIKeyRepository_List(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Keys listed", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(1, "customer_db.api_keys", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: "key-2"}, {Key: "name", Value: "export"}},
+				bson.D{{Key: "_id", Value: "key-1"}, {Key: "name", Value: "partner"}},
+			),
+			mtest.CreateCursorResponse(0, "customer_db.api_keys", mtest.NextBatch),
+		)
+
+		repo := &MongoDBAPIKeyRepository{collection: mt.Coll}
+		keys, err := repo.List(context.Background())
+
+		assert.NoError(t, err)
+		assert.Len(t, keys, 2)
+		assert.Equal(t, "key-2", keys[0].ID)
+	})
+}
+
+func TestAPIKeyRepository_Revoke(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Key revoked", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: 1},
+			bson.E{Key: "nModified", Value: 1},
+		))
+
+		repo := &MongoDBAPIKeyRepository{collection: mt.Coll}
+		err := repo.Revoke(context.Background(), "key-1", time.Now())
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("Key not found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: 0},
+			bson.E{Key: "nModified", Value: 0},
+		))
+
+		repo := &MongoDBAPIKeyRepository{collection: mt.Coll}
+		err := repo.Revoke(context.Background(), "key-1", time.Now())
+
+		appErr := errors.From(err)
+		assert.Equal(t, 404, appErr.StatusCode)
+		assert.Equal(t, "API_KEY_NOT_FOUND", appErr.Code)
+	})
+}
+
+func TestAPIKeyRepository_TouchLastUsed(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Recently used key is left alone", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: 0},
+			bson.E{Key: "nModified", Value: 0},
+		))
+
+		repo := &MongoDBAPIKeyRepository{collection: mt.Coll}
+		err := repo.TouchLastUsed(context.Background(), "key-1", time.Now())
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("Database error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "boom"}))
+
+		repo := &MongoDBAPIKeyRepository{collection: mt.Coll}
+		err := repo.TouchLastUsed(context.Background(), "key-1", time.Now())
+
+		assert.Error(t, err)
+	})
+}
@@ -4,41 +4,113 @@ import (
 	"context"
 	"customer-service/internal/domain"
 	"customer-service/pkg/errors"
+	"customer-service/pkg/fieldcrypt"
+	stderrors "errors"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Unique index names, matched against duplicate key errors to tell which
+// field collided.
+const (
+	cpfIndexName   = "cpfIndex_1"
+	emailIndexName = "emailIndex_1"
+	codeIndexName  = "code_1"
+)
+
+// Unique indexes on the plaintext CPF and email, from before they were
+// encrypted. MigrateEncryption drops them.
+var plaintextIndexNames = []string{"cpf_1", "email_1"}
+
+// ErrCodeTaken is returned by Create when the customer's short code is
+// already in use. Codes are random, so the caller should draw a new one and
+// try again.
+var ErrCodeTaken = stderrors.New("customer code already taken")
+
+// ErrMigrationPending is returned by EnsureSchema while customers are
+// stored in an older layout, until MigrateEncryption is run.
+var ErrMigrationPending = stderrors.New("customers stored in an older layout remain, run encrypt-pii first")
+
+// legacyFilter matches customers stored in plaintext or with their data key
+// in the document.
+var legacyFilter = bson.M{"$or": []bson.M{
+	{cpfIndexField: bson.M{"$exists": false}},
+	{"dataKey": bson.M{"$exists": true}},
+}}
+
+// MongoDBCustomerRepository stores customers' personal data encrypted, see
+// customerDocument, and their data keys in a separate collection, see
+// customerKeyDocument.
 type MongoDBCustomerRepository struct {
 	collection *mongo.Collection
+	keys       *mongo.Collection
+	encryptor  *fieldcrypt.Encryptor
+}
+
+func NewMongoDBCustomerRepository(db *mongo.Database, encryptor *fieldcrypt.Encryptor) *MongoDBCustomerRepository {
+	return &MongoDBCustomerRepository{
+		collection: db.Collection("customers"),
+		keys:       db.Collection("customer_keys"),
+		encryptor:  encryptor,
+	}
 }
 
-func NewMongoDBCustomerRepository(db *mongo.Database) *MongoDBCustomerRepository {
-	collection := db.Collection("customers")
+// EnsureSchema checks that customers can be served and creates the unique
+// indexes. It fails with ErrMigrationPending while any customer is stored in
+// an older layout: FindByCPF could not find it, and the indexes that guard
+// against duplicates could not be built over it.
+func (r *MongoDBCustomerRepository) EnsureSchema(ctx context.Context) error {
+	pending, err := r.collection.CountDocuments(ctx, legacyFilter, options.Count().SetLimit(1))
+	if err != nil {
+		return errors.WrapError(err, "Failed to look for customers to migrate")
+	}
+	if pending > 0 {
+		return ErrMigrationPending
+	}
+	if err := r.createIndexes(ctx); err != nil {
+		return errors.WrapError(err, "Failed to create customer indexes")
+	}
+	return nil
+}
 
-	// Create unique indexes for CPF and Email
-	_, _ = collection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+// createIndexes creates the unique indexes on the CPF and email blind
+// indexes. They are the only guard against duplicates, so writes must not
+// check availability first.
+func (r *MongoDBCustomerRepository) createIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: cpfIndexField, Value: 1}},
+			Options: options.Index().SetUnique(true).SetName(cpfIndexName),
+		},
 		{
-			Keys:    bson.D{{Key: "cpf", Value: 1}},
-			Options: options.Index().SetUnique(true),
+			Keys:    bson.D{{Key: emailIndexField, Value: 1}},
+			Options: options.Index().SetUnique(true).SetName(emailIndexName),
 		},
 		{
-			Keys:    bson.D{{Key: "email", Value: 1}},
-			Options: options.Index().SetUnique(true),
+			// Sparse because customers created before codes existed have none
+			Keys:    bson.D{{Key: "code", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true).SetName(codeIndexName),
 		},
 	})
-
-	return &MongoDBCustomerRepository{
-		collection: collection,
-	}
+	return err
 }
 
 func (r *MongoDBCustomerRepository) Create(ctx context.Context, customer *domain.Customer) error {
-	_, err := r.collection.InsertOne(ctx, customer)
+	doc, key, err := r.newDocument(customer)
 	if err != nil {
+		return err
+	}
+	if _, err := r.keys.InsertOne(ctx, key); err != nil {
+		return errors.WrapError(err, "Failed to store customer data key")
+	}
+	_, err = r.collection.InsertOne(ctx, doc)
+	if err != nil {
+		// The caller may retry with the same ID, e.g. after ErrCodeTaken
+		_, _ = r.keys.DeleteOne(ctx, bson.M{"_id": customer.ID})
 		if mongo.IsDuplicateKeyError(err) {
-			return errors.NewConflictError("Customer already exists", "CUSTOMER_ALREADY_EXISTS")
+			return duplicateKeyError(err)
 		}
 		return errors.WrapError(err, "Failed to create customer")
 	}
@@ -46,58 +118,171 @@ func (r *MongoDBCustomerRepository) Create(ctx context.Context, customer *domain
 }
 
 func (r *MongoDBCustomerRepository) FindByID(ctx context.Context, id string) (*domain.Customer, error) {
-	var customer domain.Customer
-	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&customer)
+	var doc customerDocument
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&doc)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
 		}
 		return nil, errors.WrapError(err, "Failed to find customer by ID")
 	}
-	return &customer, nil
+	return r.customer(ctx, &doc)
+}
+
+func (r *MongoDBCustomerRepository) FindByIDs(ctx context.Context, ids []string) ([]*domain.Customer, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, errors.WrapError(err, "Failed to find customers by ID")
+	}
+
+	docs := make([]customerDocument, 0, len(ids))
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, errors.WrapError(err, "Failed to decode customers")
+	}
+	return r.customers(ctx, docs)
 }
 
 func (r *MongoDBCustomerRepository) FindByCPF(ctx context.Context, cpf string) (*domain.Customer, error) {
-	var customer domain.Customer
-	err := r.collection.FindOne(ctx, bson.M{"cpf": cpf}).Decode(&customer)
+	var doc customerDocument
+	err := r.collection.FindOne(ctx, bson.M{cpfIndexField: r.cpfIndex(cpf)}).Decode(&doc)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
 		}
 		return nil, errors.WrapError(err, "Failed to find customer by CPF")
 	}
-	return &customer, nil
+	return r.customer(ctx, &doc)
 }
 
-func (r *MongoDBCustomerRepository) FindByCPFOrEmail(ctx context.Context, cpf, email string) (*domain.Customer, error) {
-	var customer domain.Customer
-	filter := bson.M{
-		"$or": []bson.M{
-			{"cpf": cpf},
-			{"email": email},
-		},
+func (r *MongoDBCustomerRepository) FindByCode(ctx context.Context, code string) (*domain.Customer, error) {
+	var doc customerDocument
+	err := r.collection.FindOne(ctx, bson.M{"code": code}).Decode(&doc)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, errors.WrapError(err, "Failed to find customer by code")
 	}
-	err := r.collection.FindOne(ctx, filter).Decode(&customer)
+	return r.customer(ctx, &doc)
+}
+
+// GetVersionByCPF loads only the ID and update time so revalidation does not
+// decode the whole document.
+func (r *MongoDBCustomerRepository) GetVersionByCPF(ctx context.Context, cpf string) (*domain.CustomerVersion, error) {
+	var version domain.CustomerVersion
+
+	opts := options.FindOne().SetProjection(bson.M{"_id": 1, "updatedAt": 1})
+	err := r.collection.FindOne(ctx, bson.M{cpfIndexField: r.cpfIndex(cpf)}, opts).Decode(&version)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
 		}
-		return nil, errors.WrapError(err, "Failed to find customer by CPF or Email")
+		return nil, errors.WrapError(err, "Failed to get customer version")
+	}
+	return &version, nil
+}
+
+func (r *MongoDBCustomerRepository) FindByCPFs(ctx context.Context, cpfs []string) ([]*domain.Customer, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{cpfIndexField: bson.M{"$in": r.cpfIndexes(cpfs)}})
+	if err != nil {
+		return nil, errors.WrapError(err, "Failed to find customers by CPF")
+	}
+
+	docs := make([]customerDocument, 0, len(cpfs))
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, errors.WrapError(err, "Failed to decode customers")
+	}
+	return r.customers(ctx, docs)
+}
+
+// FindByIDsOrCPFs fetches customers matching any of the IDs or CPFs with a
+// single query.
+func (r *MongoDBCustomerRepository) FindByIDsOrCPFs(ctx context.Context, ids, cpfs []string) ([]*domain.Customer, error) {
+	conditions := make([]bson.M, 0, 2)
+	if len(ids) > 0 {
+		conditions = append(conditions, bson.M{"_id": bson.M{"$in": ids}})
+	}
+	if len(cpfs) > 0 {
+		conditions = append(conditions, bson.M{cpfIndexField: bson.M{"$in": r.cpfIndexes(cpfs)}})
+	}
+	if len(conditions) == 0 {
+		return []*domain.Customer{}, nil
 	}
-	return &customer, nil
+
+	cursor, err := r.collection.Find(ctx, bson.M{"$or": conditions})
+	if err != nil {
+		return nil, errors.WrapError(err, "Failed to look up customers")
+	}
+
+	docs := make([]customerDocument, 0, len(ids)+len(cpfs))
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, errors.WrapError(err, "Failed to decode customers")
+	}
+	return r.customers(ctx, docs)
+}
+
+// FindConflicts returns every customer already holding cpf or email. Both
+// are unique, so there are at most two. Empty values are not checked.
+func (r *MongoDBCustomerRepository) FindConflicts(ctx context.Context, cpf, email string) ([]*domain.Customer, error) {
+	conditions := make([]bson.M, 0, 2)
+	if cpf != "" {
+		conditions = append(conditions, bson.M{cpfIndexField: r.cpfIndex(cpf)})
+	}
+	if email != "" {
+		conditions = append(conditions, bson.M{emailIndexField: r.emailIndex(email)})
+	}
+	if len(conditions) == 0 {
+		return []*domain.Customer{}, nil
+	}
+
+	opts := options.Find().SetLimit(2)
+	cursor, err := r.collection.Find(ctx, bson.M{"$or": conditions}, opts)
+	if err != nil {
+		return nil, errors.WrapError(err, "Failed to find conflicting customers")
+	}
+
+	docs := make([]customerDocument, 0, 2)
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, errors.WrapError(err, "Failed to decode customers")
+	}
+	return r.customers(ctx, docs)
 }
 
 func (r *MongoDBCustomerRepository) Update(ctx context.Context, customer *domain.Customer) error {
-	update := bson.M{
-		"$set": bson.M{
-			"name":      customer.Name,
-			"email":     customer.Email,
-			"updatedAt": customer.UpdatedAt,
-		},
+	sealer, err := r.storedSealer(ctx, customer.ID)
+	if err != nil {
+		return err
+	}
+	name, err := sealer.seal("name", customer.Name)
+	if err != nil {
+		return err
+	}
+	email, err := sealer.seal("email", customer.Email)
+	if err != nil {
+		return err
+	}
+
+	set := bson.M{
+		"name":          name,
+		"email":         email,
+		emailIndexField: r.emailIndex(customer.Email),
+		"updatedAt":     customer.UpdatedAt,
+	}
+	update := bson.M{"$set": set}
+	if customer.PendingEmail != "" {
+		if set["pendingEmail"], err = sealer.seal("pendingEmail", customer.PendingEmail); err != nil {
+			return err
+		}
+		set["emailVerification"] = customer.EmailVerification
+	} else {
+		update["$unset"] = bson.M{"pendingEmail": "", "emailVerification": ""}
 	}
 
 	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": customer.ID}, update)
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return duplicateKeyError(err)
+		}
 		return errors.WrapError(err, "Failed to update customer")
 	}
 
@@ -108,7 +293,66 @@ func (r *MongoDBCustomerRepository) Update(ctx context.Context, customer *domain
 	return nil
 }
 
+// UpdateCPF stores a corrected CPF and its history. The write only applies
+// while the customer still has the CPF being replaced, so two concurrent
+// corrections cannot both succeed.
+func (r *MongoDBCustomerRepository) UpdateCPF(ctx context.Context, customer *domain.Customer) error {
+	if len(customer.CPFHistory) == 0 {
+		return errors.NewInternalError("Customer has no CPF correction to store")
+	}
+	previous := customer.CPFHistory[len(customer.CPFHistory)-1].PreviousCPF
+
+	sealer, err := r.storedSealer(ctx, customer.ID)
+	if err != nil {
+		return err
+	}
+	cpf, err := sealer.seal("cpf", customer.CPF)
+	if err != nil {
+		return err
+	}
+	history, err := sealer.sealHistory(customer.CPFHistory)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"_id": customer.ID, cpfIndexField: r.cpfIndex(previous)}
+	update := bson.M{
+		"$set": bson.M{
+			"cpf":         cpf,
+			cpfIndexField: r.cpfIndex(customer.CPF),
+			"cpfHistory":  history,
+			"updatedAt":   customer.UpdatedAt,
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return duplicateKeyError(err)
+		}
+		return errors.WrapError(err, "Failed to update customer CPF")
+	}
+
+	if result.MatchedCount == 0 {
+		return errors.NewConflictError("Customer was changed concurrently", "CONCURRENT_UPDATE")
+	}
+
+	return nil
+}
+
+// Delete shreds the customer by destroying their data key before removing
+// the document, so copies left in backups and exports can no longer be
+// read.
 func (r *MongoDBCustomerRepository) Delete(ctx context.Context, id string) error {
+	// The blind indexes go first, so a customer left behind by a failure
+	// below cannot be matched against a guessed CPF
+	if _, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.A{erasedIndexes}); err != nil {
+		return errors.WrapError(err, "Failed to erase customer indexes")
+	}
+	if _, err := r.keys.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+		return errors.WrapError(err, "Failed to destroy customer data key")
+	}
+
 	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
 	if err != nil {
 		return errors.WrapError(err, "Failed to delete customer")
@@ -121,6 +365,7 @@ func (r *MongoDBCustomerRepository) Delete(ctx context.Context, id string) error
 	return nil
 }
 
+// GetEmailByID returns an empty email for a shredded customer.
 func (r *MongoDBCustomerRepository) GetEmailByID(ctx context.Context, id string) (string, error) {
 	var result struct {
 		Email string `bson:"email"`
@@ -135,5 +380,167 @@ func (r *MongoDBCustomerRepository) GetEmailByID(ctx context.Context, id string)
 		return "", errors.WrapError(err, "Failed to get customer email")
 	}
 
-	return result.Email, nil
+	key, err := r.findKey(ctx, id)
+	if err != nil || key == nil {
+		return "", err
+	}
+	sealer, err := r.sealer(key)
+	if err != nil {
+		return "", err
+	}
+	return sealer.open("email", result.Email)
+}
+
+// legacyDocument is a customer as stored before data keys moved to their
+// own collection: the data key was kept in the document and the name was
+// not encrypted.
+type legacyDocument struct {
+	Customer customerDocument `bson:",inline"`
+	DataKey  *struct {
+		KeyID string `bson:"keyId"`
+		Key   []byte `bson:"key"`
+	} `bson:"dataKey"`
+}
+
+// MigrateEncryption brings customers stored in an older layout to the
+// current one: customers stored in plaintext and customers whose data key
+// is kept in their document are encrypted under a data key in the key
+// collection. Customers restored without their data key lose their blind
+// indexes, and the unique indexes on the plaintext fields are replaced with
+// ones on the blind indexes. It can be run again after a failure and
+// returns how many customers it migrated.
+func (r *MongoDBCustomerRepository) MigrateEncryption(ctx context.Context) (int, error) {
+	cursor, err := r.collection.Find(ctx, legacyFilter)
+	if err != nil {
+		return 0, errors.WrapError(err, "Failed to find customers to migrate")
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		customer, err := r.decodeLegacy(cursor)
+		if err != nil {
+			return migrated, err
+		}
+		doc, key, err := r.newDocument(customer)
+		if err != nil {
+			return migrated, err
+		}
+		// Upserted so a key left by an interrupted run is replaced
+		keyOpts := options.Replace().SetUpsert(true)
+		if _, err := r.keys.ReplaceOne(ctx, bson.M{"_id": customer.ID}, key, keyOpts); err != nil {
+			return migrated, errors.WrapError(err, "Failed to store data key of customer "+customer.ID)
+		}
+		filter := bson.M{"_id": customer.ID, "$or": []bson.M{
+			{cpfIndexField: bson.M{"$exists": false}},
+			{"dataKey": bson.M{"$exists": true}},
+		}}
+		if _, err := r.collection.ReplaceOne(ctx, filter, doc); err != nil {
+			return migrated, errors.WrapError(err, "Failed to migrate customer "+customer.ID)
+		}
+		migrated++
+	}
+	if err := cursor.Err(); err != nil {
+		return migrated, errors.WrapError(err, "Failed to read customers to migrate")
+	}
+
+	if err := r.eraseShreddedIndexes(ctx); err != nil {
+		return migrated, err
+	}
+
+	for _, name := range plaintextIndexNames {
+		if _, err := r.collection.Indexes().DropOne(ctx, name); err != nil && !isIndexNotFound(err) {
+			return migrated, errors.WrapError(err, "Failed to drop index "+name)
+		}
+	}
+	if err := r.createIndexes(ctx); err != nil {
+		return migrated, errors.WrapError(err, "Failed to create customer indexes")
+	}
+	return migrated, nil
+}
+
+// eraseShreddedIndexes clears the blind indexes of customers whose data key
+// is gone, e.g. restored from a backup taken before they were erased.
+func (r *MongoDBCustomerRepository) eraseShreddedIndexes(ctx context.Context) error {
+	cursor, err := r.collection.Aggregate(ctx, bson.A{
+		bson.M{"$match": bson.M{cpfIndexField: bson.M{"$not": bson.M{"$regex": "^erased:"}}}},
+		bson.M{"$lookup": bson.M{"from": r.keys.Name(), "localField": "_id", "foreignField": "_id", "as": "key"}},
+		bson.M{"$match": bson.M{"key": bson.M{"$size": 0}}},
+		bson.M{"$project": bson.M{"_id": 1}},
+	})
+	if err != nil {
+		return errors.WrapError(err, "Failed to find shredded customers")
+	}
+	var shredded []struct {
+		ID string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &shredded); err != nil {
+		return errors.WrapError(err, "Failed to read shredded customers")
+	}
+	if len(shredded) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(shredded))
+	for i, doc := range shredded {
+		ids[i] = doc.ID
+	}
+	if _, err := r.collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, bson.A{erasedIndexes}); err != nil {
+		return errors.WrapError(err, "Failed to erase shredded customer indexes")
+	}
+	return nil
+}
+
+// decodeLegacy reads a customer stored in plaintext, as domain.Customer, or
+// with its data key in the document.
+func (r *MongoDBCustomerRepository) decodeLegacy(cursor *mongo.Cursor) (*domain.Customer, error) {
+	var doc legacyDocument
+	if err := cursor.Decode(&doc); err != nil {
+		return nil, errors.WrapError(err, "Failed to decode customer to migrate")
+	}
+	if doc.DataKey == nil {
+		var customer domain.Customer
+		if err := cursor.Decode(&customer); err != nil {
+			return nil, errors.WrapError(err, "Failed to decode plaintext customer")
+		}
+		return &customer, nil
+	}
+
+	// The name was stored in plaintext
+	name := doc.Customer.Name
+	doc.Customer.Name = ""
+	customer, err := r.decrypt(&doc.Customer, &customerKeyDocument{
+		CustomerID: doc.Customer.ID,
+		KeyID:      doc.DataKey.KeyID,
+		Key:        doc.DataKey.Key,
+	})
+	if err != nil {
+		return nil, err
+	}
+	customer.Name = name
+	return customer, nil
+}
+
+// isIndexNotFound reports whether a dropped index did not exist, e.g. on a
+// database created after encryption.
+func isIndexNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	return stderrors.As(err, &cmdErr) && (cmdErr.Name == "IndexNotFound" || cmdErr.Name == "NamespaceNotFound")
+}
+
+// duplicateKeyError reports which unique field a duplicate key error came
+// from. MongoDB stops at the first violated index, so only one is reported.
+func duplicateKeyError(err error) error {
+	var serverErr mongo.ServerError
+	if stderrors.As(err, &serverErr) {
+		switch {
+		case serverErr.HasErrorMessage("index: " + cpfIndexName + " "):
+			return errors.NewFieldConflictError(domain.ErrCPFAlreadyExists)
+		case serverErr.HasErrorMessage("index: " + emailIndexName + " "):
+			return errors.NewFieldConflictError(domain.ErrEmailAlreadyExists)
+		case serverErr.HasErrorMessage("index: " + codeIndexName + " "):
+			return ErrCodeTaken
+		}
+	}
+	return errors.WrapError(err, "Unexpected duplicate key")
 }
@@ -6,12 +6,14 @@ package repository
 import (
 	"context"
 	"customer-service/internal/domain"
+	"customer-service/pkg/errors"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -48,8 +50,9 @@ func TestMongoDBCustomerRepository_Integration(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo := NewMongoDBCustomerRepository(db)
+	repo := NewMongoDBCustomerRepository(db, testEncryptor)
 	ctx := context.Background()
+	require.NoError(t, repo.EnsureSchema(ctx))
 
 	t.Run("Create and Find Customer", func(t *testing.T) {
 		customer, err := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
@@ -82,6 +85,11 @@ func TestMongoDBCustomerRepository_Integration(t *testing.T) {
 		customer2, _ := domain.NewCustomer("John Smith", "12345678909", "john.smith@example.com")
 		err = repo.Create(ctx, customer2)
 		assert.Error(t, err)
+		assert.Equal(t, "CPF_ALREADY_EXISTS", errors.From(err).Code)
+
+		customer3, _ := domain.NewCustomer("Jane Smith", "52998224725", "jane@example.com")
+		err = repo.Create(ctx, customer3)
+		assert.Equal(t, "EMAIL_ALREADY_EXISTS", errors.From(err).Code)
 	})
 
 	t.Run("Update Customer", func(t *testing.T) {
@@ -108,33 +116,49 @@ func TestMongoDBCustomerRepository_Integration(t *testing.T) {
 		err := repo.Create(ctx, customer)
 		require.NoError(t, err)
 
+		backup, err := db.Collection("customers").FindOne(ctx, bson.M{"_id": customer.ID}).Raw()
+		require.NoError(t, err)
+
 		err = repo.Delete(ctx, customer.ID)
 		assert.NoError(t, err)
 
 		found, err := repo.FindByID(ctx, customer.ID)
 		assert.NoError(t, err)
 		assert.Nil(t, found)
+
+		keys, err := db.Collection("customer_keys").CountDocuments(ctx, bson.M{"_id": customer.ID})
+		require.NoError(t, err)
+		assert.Zero(t, keys)
+
+		// A copy restored from a backup can no longer be read
+		_, err = db.Collection("customers").InsertOne(ctx, backup)
+		require.NoError(t, err)
+		restored, err := repo.FindByID(ctx, customer.ID)
+		require.NoError(t, err)
+		assert.True(t, restored.Anonymized)
+		assert.Empty(t, restored.CPF)
+		assert.Empty(t, restored.Email)
 	})
 
-	t.Run("FindByCPFOrEmail", func(t *testing.T) {
+	t.Run("FindConflicts", func(t *testing.T) {
 		customer, _ := domain.NewCustomer("Charlie", "15935745600", "charlie@example.com")
 		err := repo.Create(ctx, customer)
 		require.NoError(t, err)
 
 		// Find by CPF
-		found, err := repo.FindByCPFOrEmail(ctx, customer.CPF, "other@example.com")
+		found, err := repo.FindConflicts(ctx, customer.CPF, "other@example.com")
 		assert.NoError(t, err)
-		assert.NotNil(t, found)
+		assert.Len(t, found, 1)
 
 		// Find by Email
-		found, err = repo.FindByCPFOrEmail(ctx, "00000000000", customer.Email)
+		found, err = repo.FindConflicts(ctx, "00000000000", customer.Email)
 		assert.NoError(t, err)
-		assert.NotNil(t, found)
+		assert.Len(t, found, 1)
 
 		// Not found
-		found, err = repo.FindByCPFOrEmail(ctx, "00000000000", "notfound@example.com")
+		found, err = repo.FindConflicts(ctx, "00000000000", "notfound@example.com")
 		assert.NoError(t, err)
-		assert.Nil(t, found)
+		assert.Empty(t, found)
 	})
 
 	t.Run("GetEmailByID", func(t *testing.T) {
@@ -150,4 +174,46 @@ func TestMongoDBCustomerRepository_Integration(t *testing.T) {
 		_, err = repo.GetEmailByID(ctx, "non-existent-id")
 		assert.Error(t, err)
 	})
+	t.Run("Stores personal data encrypted", func(t *testing.T) {
+		customer, _ := domain.NewCustomer("Eve", "86288366757", "eve@example.com")
+		require.NoError(t, repo.Create(ctx, customer))
+
+		raw, err := db.Collection("customers").FindOne(ctx, bson.M{"_id": customer.ID}).Raw()
+		require.NoError(t, err)
+		assert.NotContains(t, raw.String(), customer.Name)
+		assert.NotContains(t, raw.String(), customer.CPF)
+		assert.NotContains(t, raw.String(), customer.Email)
+	})
+
+	t.Run("MigrateEncryption", func(t *testing.T) {
+		_, err := db.Collection("customers").InsertOne(ctx, bson.M{
+			"_id":   "plaintext-customer",
+			"name":  "Frank",
+			"cpf":   "71428793860",
+			"email": "frank@example.com",
+		})
+		require.NoError(t, err)
+		assert.ErrorIs(t, repo.EnsureSchema(ctx), ErrMigrationPending)
+
+		encrypted, err := repo.MigrateEncryption(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, encrypted)
+		assert.NoError(t, repo.EnsureSchema(ctx))
+
+		found, err := repo.FindByCPF(ctx, "71428793860")
+		require.NoError(t, err)
+		require.NotNil(t, found)
+		assert.Equal(t, "plaintext-customer", found.ID)
+		assert.Equal(t, "frank@example.com", found.Email)
+
+		// The customer restored after being deleted lost its blind indexes
+		restored, err := repo.FindByCPF(ctx, "45678912300")
+		require.NoError(t, err)
+		assert.Nil(t, restored)
+
+		// Running it again finds nothing left to do
+		encrypted, err = repo.MigrateEncryption(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, encrypted)
+	})
 }
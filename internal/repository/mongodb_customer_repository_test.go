@@ -1,23 +1,126 @@
 package repository
 
 import (
+	"bytes"
 	"context"
 	"customer-service/internal/domain"
 	"customer-service/pkg/errors"
+	"customer-service/pkg/fieldcrypt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
 )
 
+var testEncryptor = func() *fieldcrypt.Encryptor {
+	encryptor, err := fieldcrypt.New(bytes.Repeat([]byte{1}, fieldcrypt.KeySize), bytes.Repeat([]byte{2}, fieldcrypt.KeySize))
+	if err != nil {
+		panic(err)
+	}
+	return encryptor
+}()
+
+func newTestRepository(mt *mtest.T) *MongoDBCustomerRepository {
+	return &MongoDBCustomerRepository{
+		collection: mt.Coll,
+		keys:       mt.DB.Collection("customer_keys"),
+		encryptor:  testEncryptor,
+	}
+}
+
+func toBSON(t *testing.T, v interface{}) bson.D {
+	raw, err := bson.Marshal(v)
+	require.NoError(t, err)
+	var d bson.D
+	require.NoError(t, bson.Unmarshal(raw, &d))
+	return d
+}
+
+// storedCustomer returns customer as the repository stores it, and its data
+// key.
+func storedCustomer(t *testing.T, repo *MongoDBCustomerRepository, customer *domain.Customer) (bson.D, bson.D) {
+	doc, key, err := repo.newDocument(customer)
+	require.NoError(t, err)
+	return toBSON(t, doc), toBSON(t, key)
+}
+
+// customerResponse answers a single customer lookup and the data key lookup
+// after it.
+func customerResponse(t *testing.T, repo *MongoDBCustomerRepository, customer *domain.Customer) []bson.D {
+	doc, key := storedCustomer(t, repo, customer)
+	return []bson.D{
+		mtest.CreateCursorResponse(0, "customer_db.customers", mtest.FirstBatch, doc),
+		keysResponse(key),
+	}
+}
+
+// keysResponse answers a data key lookup. Without keys, the customers were
+// shredded.
+func keysResponse(keys ...bson.D) bson.D {
+	return mtest.CreateCursorResponse(0, "customer_db.customer_keys", mtest.FirstBatch, keys...)
+}
+
+// startedUpdate returns the update command sent, skipping the data key
+// lookup before it.
+func startedUpdate(mt *mtest.T) bson.Raw {
+	for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+		if event.CommandName == "update" {
+			return event.Command
+		}
+	}
+	mt.Fatal("no update command sent")
+	return nil
+}
+
 func TestNewMongoDBCustomerRepository(t *testing.T) {
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
 
 	mt.Run("Create repository", func(mt *mtest.T) {
-		repo := NewMongoDBCustomerRepository(mt.DB)
+		repo := NewMongoDBCustomerRepository(mt.DB, testEncryptor)
 		assert.NotNil(t, repo)
 		assert.NotNil(t, repo.collection)
+		assert.Equal(t, "customer_keys", repo.keys.Name())
+	})
+}
+
+func TestEnsureSchema(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	countResponse := func(n int) bson.D {
+		return mtest.CreateCursorResponse(0, "customer_db.customers", mtest.FirstBatch, bson.D{{Key: "n", Value: n}})
+	}
+
+	mt.Run("Creates the indexes once every customer is migrated", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "customer_db.customers", mtest.FirstBatch), mtest.CreateSuccessResponse())
+
+		err := newTestRepository(mt).EnsureSchema(context.Background())
+
+		require.NoError(t, err)
+		mt.GetStartedEvent()
+		assert.Equal(t, "createIndexes", mt.GetStartedEvent().CommandName)
+	})
+
+	mt.Run("Refuses while customers are left to migrate", func(mt *mtest.T) {
+		mt.AddMockResponses(countResponse(1))
+
+		err := newTestRepository(mt).EnsureSchema(context.Background())
+
+		assert.ErrorIs(t, err, ErrMigrationPending)
+	})
+
+	mt.Run("Reports a failure to create the indexes", func(mt *mtest.T) {
+		mt.AddMockResponses(countResponse(0), mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    11000,
+			Message: "E11000 duplicate key error collection: customer_db.customers index: cpfIndex_1",
+		}))
+
+		err := newTestRepository(mt).EnsureSchema(context.Background())
+
+		require.Error(t, err)
+		assert.Equal(t, "INTERNAL_ERROR", errors.From(err).Code)
 	})
 }
 
@@ -25,30 +128,112 @@ func TestCreate(t *testing.T) {
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
 
 	mt.Run("Successfully create customer", func(mt *mtest.T) {
-		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
 
-		repo := &MongoDBCustomerRepository{collection: mt.Coll}
+		repo := newTestRepository(mt)
 		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
 
 		err := repo.Create(context.Background(), customer)
 		assert.NoError(t, err)
 	})
 
-	mt.Run("Duplicate key error", func(mt *mtest.T) {
-		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{
+	mt.Run("Stores personal data encrypted under a separate key", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+
+		repo := newTestRepository(mt)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+
+		err := repo.Create(context.Background(), customer)
+		require.NoError(t, err)
+
+		keyInsert := mt.GetStartedEvent().Command
+		assert.Equal(t, "customer_keys", keyInsert.Lookup("insert").StringValue())
+		key := keyInsert.Lookup("documents").Array().Index(0).Value().Document()
+		assert.Equal(t, customer.ID, key.Lookup("_id").StringValue())
+		_, wrapped := key.Lookup("key").Binary()
+		assert.NotEmpty(t, wrapped)
+
+		inserted := mt.GetStartedEvent().Command.Lookup("documents").Array().Index(0).Value().Document()
+		assert.NotContains(t, inserted.String(), "John Doe")
+		assert.NotContains(t, inserted.String(), "11144477735")
+		assert.NotContains(t, inserted.String(), "john@example.com")
+		assert.Equal(t, repo.cpfIndex("11144477735"), inserted.Lookup("cpfIndex").StringValue())
+		assert.Equal(t, repo.emailIndex("john@example.com"), inserted.Lookup("emailIndex").StringValue())
+		_, err = inserted.LookupErr("dataKey")
+		assert.Error(t, err, "the data key is not stored with the customer")
+	})
+
+	mt.Run("Duplicate CPF", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateWriteErrorsResponse(mtest.WriteError{
 			Index:   0,
 			Code:    11000, // Duplicate key error code
-			Message: "duplicate key error",
+			Message: `E11000 duplicate key error collection: customer_db.customers index: cpfIndex_1 dup key: { cpfIndex: "3f0c" }`,
 		}))
 
-		repo := &MongoDBCustomerRepository{collection: mt.Coll}
+		repo := newTestRepository(mt)
 		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
 
 		err := repo.Create(context.Background(), customer)
 		assert.Error(t, err)
 		appErr, ok := err.(*errors.AppError)
 		assert.True(t, ok)
-		assert.Equal(t, "CUSTOMER_ALREADY_EXISTS", appErr.Code)
+		assert.Equal(t, 409, appErr.StatusCode)
+		assert.Equal(t, "CPF_ALREADY_EXISTS", appErr.Code)
+		assert.Equal(t, []errors.FieldError{domain.ErrCPFAlreadyExists}, appErr.Fields)
+	})
+
+	mt.Run("Duplicate email", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateWriteErrorsResponse(mtest.WriteError{
+			Index:   0,
+			Code:    11000,
+			Message: `E11000 duplicate key error collection: customer_db.customers index: emailIndex_1 dup key: { emailIndex: "9a1b" }`,
+		}))
+
+		repo := newTestRepository(mt)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+
+		err := repo.Create(context.Background(), customer)
+		appErr, ok := err.(*errors.AppError)
+		assert.True(t, ok)
+		assert.Equal(t, "EMAIL_ALREADY_EXISTS", appErr.Code)
+		assert.Equal(t, []errors.FieldError{domain.ErrEmailAlreadyExists}, appErr.Fields)
+	})
+
+	mt.Run("Duplicate code", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateWriteErrorsResponse(mtest.WriteError{
+			Index:   0,
+			Code:    11000,
+			Message: `E11000 duplicate key error collection: customer_db.customers index: code_1 dup key: { code: "7K3M9QXD" }`,
+		}), mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+
+		repo := newTestRepository(mt)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+
+		err := repo.Create(context.Background(), customer)
+		assert.ErrorIs(t, err, ErrCodeTaken)
+
+		// The key is removed so a retry can store a new one
+		mt.GetStartedEvent()
+		mt.GetStartedEvent()
+		cleanup := mt.GetStartedEvent().Command
+		assert.Equal(t, "customer_keys", cleanup.Lookup("delete").StringValue())
+		assert.Equal(t, customer.ID, cleanup.Lookup("deletes").Array().Index(0).Value().Document().Lookup("q", "_id").StringValue())
+	})
+
+	mt.Run("Duplicate key on an unknown index", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateWriteErrorsResponse(mtest.WriteError{
+			Index:   0,
+			Code:    11000,
+			Message: `E11000 duplicate key error collection: customer_db.customers index: _id_ dup key: { _id: "1" }`,
+		}))
+
+		repo := newTestRepository(mt)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+
+		err := repo.Create(context.Background(), customer)
+		appErr, ok := err.(*errors.AppError)
+		assert.True(t, ok)
+		assert.Equal(t, "INTERNAL_ERROR", appErr.Code)
 	})
 
 	mt.Run("Generic error", func(mt *mtest.T) {
@@ -58,7 +243,7 @@ func TestCreate(t *testing.T) {
 			Message: "internal error",
 		}))
 
-		repo := &MongoDBCustomerRepository{collection: mt.Coll}
+		repo := newTestRepository(mt)
 		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
 
 		err := repo.Create(context.Background(), customer)
@@ -70,26 +255,62 @@ func TestFindByID(t *testing.T) {
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
 
 	mt.Run("Successfully find customer", func(mt *mtest.T) {
+		repo := newTestRepository(mt)
 		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
-		mt.AddMockResponses(mtest.CreateCursorResponse(1, "customer_db.customers", mtest.FirstBatch, bson.D{
-			{Key: "_id", Value: customer.ID},
-			{Key: "name", Value: customer.Name},
-			{Key: "cpf", Value: customer.CPF},
-			{Key: "email", Value: customer.Email},
-		}))
+		mt.AddMockResponses(customerResponse(mt.T, repo, customer)...)
 
-		repo := &MongoDBCustomerRepository{collection: mt.Coll}
 		result, err := repo.FindByID(context.Background(), customer.ID)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, result)
 		assert.Equal(t, customer.Name, result.Name)
+		assert.Equal(t, customer.CPF, result.CPF)
+		assert.Equal(t, customer.Email, result.Email)
+		assert.False(t, result.Anonymized)
+	})
+
+	mt.Run("Ciphertext moved to another customer", func(mt *mtest.T) {
+		repo := newTestRepository(mt)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		stored, key := storedCustomer(mt.T, repo, customer)
+		stored[0].Value = "another-customer"
+		key[0].Value = "another-customer"
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "customer_db.customers", mtest.FirstBatch, stored),
+			keysResponse(key),
+		)
+
+		result, err := repo.FindByID(context.Background(), "another-customer")
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+	})
+
+	mt.Run("Shredded customer is returned anonymized", func(mt *mtest.T) {
+		repo := newTestRepository(mt)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		customer.Code = "7K3M9QXD"
+		stored, _ := storedCustomer(mt.T, repo, customer)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "customer_db.customers", mtest.FirstBatch, stored),
+			keysResponse(),
+		)
+
+		result, err := repo.FindByID(context.Background(), customer.ID)
+
+		require.NoError(t, err)
+		assert.True(t, result.Anonymized)
+		assert.Equal(t, customer.ID, result.ID)
+		assert.Equal(t, "7K3M9QXD", result.Code)
+		assert.Empty(t, result.Name)
+		assert.Empty(t, result.CPF)
+		assert.Empty(t, result.Email)
 	})
 
 	mt.Run("Customer not found", func(mt *mtest.T) {
 		mt.AddMockResponses(mtest.CreateCursorResponse(0, "customer_db.customers", mtest.FirstBatch))
 
-		repo := &MongoDBCustomerRepository{collection: mt.Coll}
+		repo := newTestRepository(mt)
 		result, err := repo.FindByID(context.Background(), "nonexistent")
 
 		assert.NoError(t, err)
@@ -102,7 +323,7 @@ func TestFindByID(t *testing.T) {
 			Message: "database error",
 		}))
 
-		repo := &MongoDBCustomerRepository{collection: mt.Coll}
+		repo := newTestRepository(mt)
 		result, err := repo.FindByID(context.Background(), "123")
 
 		assert.Error(t, err)
@@ -110,34 +331,59 @@ func TestFindByID(t *testing.T) {
 	})
 }
 
-func TestFindByCPF(t *testing.T) {
+func TestFindByIDs(t *testing.T) {
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
 
-	mt.Run("Successfully find customer", func(mt *mtest.T) {
-		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
-		mt.AddMockResponses(mtest.CreateCursorResponse(1, "customer_db.customers", mtest.FirstBatch, bson.D{
-			{Key: "_id", Value: customer.ID},
-			{Key: "name", Value: customer.Name},
-			{Key: "cpf", Value: customer.CPF},
-			{Key: "email", Value: customer.Email},
-		}))
+	mt.Run("Successfully find customers", func(mt *mtest.T) {
+		first, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		second, _ := domain.NewCustomer("Jane Doe", "52998224725", "jane@example.com")
+		repo := newTestRepository(mt)
+		firstDoc, firstKey := storedCustomer(mt.T, repo, first)
+		secondDoc, secondKey := storedCustomer(mt.T, repo, second)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(1, "customer_db.customers", mtest.FirstBatch, firstDoc),
+			mtest.CreateCursorResponse(0, "customer_db.customers", mtest.NextBatch, secondDoc),
+			keysResponse(secondKey, firstKey),
+		)
 
-		repo := &MongoDBCustomerRepository{collection: mt.Coll}
-		result, err := repo.FindByCPF(context.Background(), "11144477735")
+		result, err := repo.FindByIDs(context.Background(), []string{first.ID, second.ID})
 
 		assert.NoError(t, err)
-		assert.NotNil(t, result)
-		assert.Equal(t, customer.CPF, result.CPF)
+		assert.Len(t, result, 2)
+		assert.Equal(t, first.ID, result[0].ID)
+		assert.Equal(t, first.Name, result[0].Name)
+		assert.Equal(t, second.ID, result[1].ID)
+		assert.Equal(t, second.Name, result[1].Name)
 	})
 
-	mt.Run("Customer not found", func(mt *mtest.T) {
+	mt.Run("Shredded customers are returned anonymized", func(mt *mtest.T) {
+		first, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		second, _ := domain.NewCustomer("Jane Doe", "52998224725", "jane@example.com")
+		repo := newTestRepository(mt)
+		firstDoc, firstKey := storedCustomer(mt.T, repo, first)
+		secondDoc, _ := storedCustomer(mt.T, repo, second)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "customer_db.customers", mtest.FirstBatch, firstDoc, secondDoc),
+			keysResponse(firstKey),
+		)
+
+		result, err := repo.FindByIDs(context.Background(), []string{first.ID, second.ID})
+
+		require.NoError(t, err)
+		require.Len(t, result, 2)
+		assert.Equal(t, first.CPF, result[0].CPF)
+		assert.True(t, result[1].Anonymized)
+		assert.Empty(t, result[1].CPF)
+	})
+
+	mt.Run("No customers found", func(mt *mtest.T) {
 		mt.AddMockResponses(mtest.CreateCursorResponse(0, "customer_db.customers", mtest.FirstBatch))
 
-		repo := &MongoDBCustomerRepository{collection: mt.Coll}
-		result, err := repo.FindByCPF(context.Background(), "11144477735")
+		repo := newTestRepository(mt)
+		result, err := repo.FindByIDs(context.Background(), []string{"nonexistent"})
 
 		assert.NoError(t, err)
-		assert.Nil(t, result)
+		assert.Empty(t, result)
 	})
 
 	mt.Run("Database error", func(mt *mtest.T) {
@@ -146,38 +392,39 @@ func TestFindByCPF(t *testing.T) {
 			Message: "database error",
 		}))
 
-		repo := &MongoDBCustomerRepository{collection: mt.Coll}
-		result, err := repo.FindByCPF(context.Background(), "11144477735")
+		repo := newTestRepository(mt)
+		result, err := repo.FindByIDs(context.Background(), []string{"123"})
 
 		assert.Error(t, err)
 		assert.Nil(t, result)
 	})
 }
 
-func TestFindByCPFOrEmail(t *testing.T) {
+func TestFindByCPF(t *testing.T) {
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
 
 	mt.Run("Successfully find customer", func(mt *mtest.T) {
+		repo := newTestRepository(mt)
 		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
-		mt.AddMockResponses(mtest.CreateCursorResponse(1, "customer_db.customers", mtest.FirstBatch, bson.D{
-			{Key: "_id", Value: customer.ID},
-			{Key: "name", Value: customer.Name},
-			{Key: "cpf", Value: customer.CPF},
-			{Key: "email", Value: customer.Email},
-		}))
+		mt.AddMockResponses(customerResponse(mt.T, repo, customer)...)
 
-		repo := &MongoDBCustomerRepository{collection: mt.Coll}
-		result, err := repo.FindByCPFOrEmail(context.Background(), "11144477735", "john@example.com")
+		result, err := repo.FindByCPF(context.Background(), "11144477735")
 
 		assert.NoError(t, err)
 		assert.NotNil(t, result)
+		assert.Equal(t, customer.CPF, result.CPF)
+
+		// The CPF is looked up by its blind index, never sent in plaintext
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		assert.Equal(t, repo.cpfIndex("11144477735"), filter.Lookup("cpfIndex").StringValue())
+		assert.NotContains(t, filter.String(), "11144477735")
 	})
 
 	mt.Run("Customer not found", func(mt *mtest.T) {
 		mt.AddMockResponses(mtest.CreateCursorResponse(0, "customer_db.customers", mtest.FirstBatch))
 
-		repo := &MongoDBCustomerRepository{collection: mt.Coll}
-		result, err := repo.FindByCPFOrEmail(context.Background(), "11144477735", "john@example.com")
+		repo := newTestRepository(mt)
+		result, err := repo.FindByCPF(context.Background(), "11144477735")
 
 		assert.NoError(t, err)
 		assert.Nil(t, result)
@@ -189,8 +436,37 @@ func TestFindByCPFOrEmail(t *testing.T) {
 			Message: "database error",
 		}))
 
-		repo := &MongoDBCustomerRepository{collection: mt.Coll}
-		result, err := repo.FindByCPFOrEmail(context.Background(), "11144477735", "john@example.com")
+		repo := newTestRepository(mt)
+		result, err := repo.FindByCPF(context.Background(), "11144477735")
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+	})
+}
+
+func TestFindByCPFs(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Successfully find customers", func(mt *mtest.T) {
+		repo := newTestRepository(mt)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mt.AddMockResponses(customerResponse(mt.T, repo, customer)...)
+
+		result, err := repo.FindByCPFs(context.Background(), []string{"11144477735", "52998224725"})
+
+		assert.NoError(t, err)
+		assert.Len(t, result, 1)
+		assert.Equal(t, customer.CPF, result[0].CPF)
+	})
+
+	mt.Run("Database error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    500,
+			Message: "database error",
+		}))
+
+		repo := newTestRepository(mt)
+		result, err := repo.FindByCPFs(context.Background(), []string{"11144477735"})
 
 		assert.Error(t, err)
 		assert.Nil(t, result)
@@ -200,25 +476,28 @@ func TestFindByCPFOrEmail(t *testing.T) {
 func TestUpdate(t *testing.T) {
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
 
+	// dataKeyResponse answers the data key lookup every update starts with
+	dataKeyResponse := func(mt *mtest.T, repo *MongoDBCustomerRepository, customer *domain.Customer) bson.D {
+		_, key := storedCustomer(mt.T, repo, customer)
+		return keysResponse(key)
+	}
+
 	mt.Run("Successfully update customer", func(mt *mtest.T) {
-		mt.AddMockResponses(mtest.CreateSuccessResponse(
+		repo := newTestRepository(mt)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mt.AddMockResponses(dataKeyResponse(mt, repo, customer), mtest.CreateSuccessResponse(
 			bson.E{Key: "n", Value: 1},
 			bson.E{Key: "nModified", Value: 1},
 		))
 
-		repo := &MongoDBCustomerRepository{collection: mt.Coll}
-		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
-
 		err := repo.Update(context.Background(), customer)
 		assert.NoError(t, err)
 	})
 
 	mt.Run("Customer not found", func(mt *mtest.T) {
-		mt.AddMockResponses(mtest.CreateSuccessResponse(
-			bson.E{Key: "n", Value: 0},
-		))
+		mt.AddMockResponses(keysResponse(), mtest.CreateCursorResponse(0, "customer_db.customers", mtest.FirstBatch))
 
-		repo := &MongoDBCustomerRepository{collection: mt.Coll}
+		repo := newTestRepository(mt)
 		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
 
 		err := repo.Update(context.Background(), customer)
@@ -228,17 +507,209 @@ func TestUpdate(t *testing.T) {
 		assert.Equal(t, "CUSTOMER_NOT_FOUND", appErr.Code)
 	})
 
+	mt.Run("Customer deleted before the write", func(mt *mtest.T) {
+		repo := newTestRepository(mt)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mt.AddMockResponses(dataKeyResponse(mt, repo, customer), mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: 0},
+		))
+
+		err := repo.Update(context.Background(), customer)
+		appErr, ok := err.(*errors.AppError)
+		assert.True(t, ok)
+		assert.Equal(t, "CUSTOMER_NOT_FOUND", appErr.Code)
+	})
+
 	mt.Run("Database error", func(mt *mtest.T) {
-		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{
+		repo := newTestRepository(mt)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mt.AddMockResponses(dataKeyResponse(mt, repo, customer), mtest.CreateWriteErrorsResponse(mtest.WriteError{
 			Index:   0,
 			Code:    500,
 			Message: "database error",
 		}))
 
-		repo := &MongoDBCustomerRepository{collection: mt.Coll}
+		err := repo.Update(context.Background(), customer)
+		assert.Error(t, err)
+	})
+
+	mt.Run("Stores the email encrypted with its blind index", func(mt *mtest.T) {
+		repo := newTestRepository(mt)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		_, stored := storedCustomer(mt.T, repo, customer)
+		mt.AddMockResponses(keysResponse(stored), mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: 1},
+			bson.E{Key: "nModified", Value: 1},
+		))
+
+		err := repo.Update(context.Background(), customer)
+		require.NoError(t, err)
+
+		set := startedUpdate(mt).Lookup("updates").Array().Index(0).Value().Document().Lookup("u", "$set").Document()
+		assert.NotContains(t, set.String(), "John Doe")
+		assert.NotContains(t, set.String(), "john@example.com")
+		assert.Equal(t, repo.emailIndex("john@example.com"), set.Lookup("emailIndex").StringValue())
+
+		// It is encrypted with the customer's existing data key
+		raw, _ := bson.Marshal(stored)
+		var key customerKeyDocument
+		require.NoError(t, bson.Unmarshal(raw, &key))
+		sealer, err := repo.sealer(&key)
+		require.NoError(t, err)
+		email, err := sealer.open("email", set.Lookup("email").StringValue())
+		require.NoError(t, err)
+		assert.Equal(t, "john@example.com", email)
+		name, err := sealer.open("name", set.Lookup("name").StringValue())
+		require.NoError(t, err)
+		assert.Equal(t, "John Doe", name)
+	})
+
+	mt.Run("Stores a pending email with its verification", func(mt *mtest.T) {
+		repo := newTestRepository(mt)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mt.AddMockResponses(dataKeyResponse(mt, repo, customer), mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: 1},
+			bson.E{Key: "nModified", Value: 1},
+		))
+		newEmail := "jane@example.com"
+		_ = customer.Update(nil, &newEmail)
+		_, _ = customer.IssueEmailVerification()
+
+		err := repo.Update(context.Background(), customer)
+		assert.NoError(t, err)
+
+		update := startedUpdate(mt).Lookup("updates").Array().Index(0).Value().Document().Lookup("u").Document()
+		set := update.Lookup("$set").Document()
+		assert.NotEmpty(t, set.Lookup("pendingEmail").StringValue())
+		assert.NotContains(t, set.String(), newEmail)
+		assert.NotEmpty(t, set.Lookup("emailVerification", "tokenHash").StringValue())
+		_, hasUnset := update.Lookup("$unset").DocumentOK()
+		assert.False(t, hasUnset)
+	})
+
+	mt.Run("Clears the pending email once there is none", func(mt *mtest.T) {
+		repo := newTestRepository(mt)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mt.AddMockResponses(dataKeyResponse(mt, repo, customer), mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: 1},
+			bson.E{Key: "nModified", Value: 1},
+		))
+
+		err := repo.Update(context.Background(), customer)
+		assert.NoError(t, err)
+
+		update := startedUpdate(mt).Lookup("updates").Array().Index(0).Value().Document().Lookup("u").Document()
+		unset := update.Lookup("$unset").Document()
+		assert.NoError(t, unset.Validate())
+		_, err = unset.LookupErr("pendingEmail")
+		assert.NoError(t, err)
+		_, err = unset.LookupErr("emailVerification")
+		assert.NoError(t, err)
+	})
+
+	mt.Run("Duplicate email", func(mt *mtest.T) {
+		repo := newTestRepository(mt)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mt.AddMockResponses(dataKeyResponse(mt, repo, customer), mtest.CreateWriteErrorsResponse(mtest.WriteError{
+			Index:   0,
+			Code:    11000,
+			Message: `E11000 duplicate key error collection: customer_db.customers index: emailIndex_1 dup key: { emailIndex: "9a1b" }`,
+		}))
+
+		err := repo.Update(context.Background(), customer)
+		appErr, ok := err.(*errors.AppError)
+		assert.True(t, ok)
+		assert.Equal(t, 409, appErr.StatusCode)
+		assert.Equal(t, "EMAIL_ALREADY_EXISTS", appErr.Code)
+	})
+
+	mt.Run("Shredded customer", func(mt *mtest.T) {
+		mt.AddMockResponses(keysResponse(), mtest.CreateCursorResponse(0, "customer_db.customers", mtest.FirstBatch, bson.D{
+			{Key: "n", Value: 1},
+		}))
+
+		repo := newTestRepository(mt)
 		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
 
 		err := repo.Update(context.Background(), customer)
+		appErr, ok := err.(*errors.AppError)
+		assert.True(t, ok)
+		assert.Equal(t, 409, appErr.StatusCode)
+		assert.Equal(t, "CUSTOMER_ANONYMIZED", appErr.Code)
+		assert.Equal(t, "find", mt.GetStartedEvent().CommandName)
+		assert.Equal(t, "aggregate", mt.GetStartedEvent().CommandName)
+		assert.Nil(t, mt.GetStartedEvent(), "nothing is written")
+	})
+}
+
+func TestUpdateCPF(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	correctedCustomer := func() *domain.Customer {
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		_ = customer.CorrectCPF("52998224725", "Typo at registration", "admin")
+		return customer
+	}
+	dataKeyResponse := func(mt *mtest.T, repo *MongoDBCustomerRepository, customer *domain.Customer) bson.D {
+		_, key := storedCustomer(mt.T, repo, customer)
+		return keysResponse(key)
+	}
+
+	mt.Run("Successfully update CPF", func(mt *mtest.T) {
+		repo := newTestRepository(mt)
+		customer := correctedCustomer()
+		mt.AddMockResponses(dataKeyResponse(mt, repo, customer), mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: 1},
+			bson.E{Key: "nModified", Value: 1},
+		))
+
+		err := repo.UpdateCPF(context.Background(), customer)
+		assert.NoError(t, err)
+
+		statement := startedUpdate(mt).Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, repo.cpfIndex("11144477735"), statement.Lookup("q", "cpfIndex").StringValue())
+		set := statement.Lookup("u", "$set").Document()
+		assert.Equal(t, repo.cpfIndex("52998224725"), set.Lookup("cpfIndex").StringValue())
+		assert.NotContains(t, statement.String(), "11144477735")
+		assert.NotContains(t, statement.String(), "52998224725")
+	})
+
+	mt.Run("CPF changed concurrently", func(mt *mtest.T) {
+		repo := newTestRepository(mt)
+		customer := correctedCustomer()
+		mt.AddMockResponses(dataKeyResponse(mt, repo, customer), mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: 0},
+		))
+
+		err := repo.UpdateCPF(context.Background(), customer)
+
+		appErr, ok := err.(*errors.AppError)
+		assert.True(t, ok)
+		assert.Equal(t, 409, appErr.StatusCode)
+		assert.Equal(t, "CONCURRENT_UPDATE", appErr.Code)
+	})
+
+	mt.Run("Duplicate CPF", func(mt *mtest.T) {
+		repo := newTestRepository(mt)
+		customer := correctedCustomer()
+		mt.AddMockResponses(dataKeyResponse(mt, repo, customer), mtest.CreateWriteErrorsResponse(mtest.WriteError{
+			Index:   0,
+			Code:    11000,
+			Message: `E11000 duplicate key error collection: customer_db.customers index: cpfIndex_1 dup key: { cpfIndex: "3f0c" }`,
+		}))
+
+		err := repo.UpdateCPF(context.Background(), customer)
+
+		appErr, ok := err.(*errors.AppError)
+		assert.True(t, ok)
+		assert.Equal(t, "CPF_ALREADY_EXISTS", appErr.Code)
+	})
+
+	mt.Run("Nothing to store", func(mt *mtest.T) {
+		repo := newTestRepository(mt)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+
+		err := repo.UpdateCPF(context.Background(), customer)
 		assert.Error(t, err)
 	})
 }
@@ -247,22 +718,40 @@ func TestDelete(t *testing.T) {
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
 
 	mt.Run("Successfully delete customer", func(mt *mtest.T) {
-		mt.AddMockResponses(mtest.CreateSuccessResponse(
-			bson.E{Key: "n", Value: 1},
-		))
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
 
-		repo := &MongoDBCustomerRepository{collection: mt.Coll}
+		repo := newTestRepository(mt)
 		err := repo.Delete(context.Background(), "123")
 
 		assert.NoError(t, err)
+
+		// The blind indexes are erased before anything else
+		erase := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, "123", erase.Lookup("q", "_id").StringValue())
+		set := erase.Lookup("u").Array().Index(0).Value().Document().Lookup("$set")
+		assert.Contains(t, set.String(), "erased:")
+		assert.NotContains(t, set.String(), repo.cpfIndex("11144477735"))
+
+		// The data key is destroyed first, so a failure in between leaves
+		// the customer shredded rather than readable
+		shred := mt.GetStartedEvent().Command
+		assert.Equal(t, "customer_keys", shred.Lookup("delete").StringValue())
+		assert.Equal(t, "123", shred.Lookup("deletes").Array().Index(0).Value().Document().Lookup("q", "_id").StringValue())
+		assert.Equal(t, mt.Coll.Name(), mt.GetStartedEvent().Command.Lookup("delete").StringValue())
 	})
 
 	mt.Run("Customer not found", func(mt *mtest.T) {
-		mt.AddMockResponses(mtest.CreateSuccessResponse(
-			bson.E{Key: "n", Value: 0},
-		))
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}),
+		)
 
-		repo := &MongoDBCustomerRepository{collection: mt.Coll}
+		repo := newTestRepository(mt)
 		err := repo.Delete(context.Background(), "123")
 
 		assert.Error(t, err)
@@ -278,7 +767,7 @@ func TestDelete(t *testing.T) {
 			Message: "database error",
 		}))
 
-		repo := &MongoDBCustomerRepository{collection: mt.Coll}
+		repo := newTestRepository(mt)
 		err := repo.Delete(context.Background(), "123")
 
 		assert.Error(t, err)
@@ -289,21 +778,35 @@ func TestGetEmailByID(t *testing.T) {
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
 
 	mt.Run("Successfully get email", func(mt *mtest.T) {
-		mt.AddMockResponses(mtest.CreateCursorResponse(1, "customer_db.customers", mtest.FirstBatch, bson.D{
-			{Key: "email", Value: "john@example.com"},
-		}))
+		repo := newTestRepository(mt)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mt.AddMockResponses(customerResponse(mt.T, repo, customer)...)
 
-		repo := &MongoDBCustomerRepository{collection: mt.Coll}
-		email, err := repo.GetEmailByID(context.Background(), "123")
+		email, err := repo.GetEmailByID(context.Background(), customer.ID)
 
 		assert.NoError(t, err)
 		assert.Equal(t, "john@example.com", email)
 	})
 
+	mt.Run("Shredded customer has no email", func(mt *mtest.T) {
+		repo := newTestRepository(mt)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		stored, _ := storedCustomer(mt.T, repo, customer)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "customer_db.customers", mtest.FirstBatch, stored),
+			keysResponse(),
+		)
+
+		email, err := repo.GetEmailByID(context.Background(), customer.ID)
+
+		assert.NoError(t, err)
+		assert.Empty(t, email)
+	})
+
 	mt.Run("Customer not found", func(mt *mtest.T) {
 		mt.AddMockResponses(mtest.CreateCursorResponse(0, "customer_db.customers", mtest.FirstBatch))
 
-		repo := &MongoDBCustomerRepository{collection: mt.Coll}
+		repo := newTestRepository(mt)
 		email, err := repo.GetEmailByID(context.Background(), "123")
 
 		assert.Error(t, err)
@@ -319,10 +822,307 @@ func TestGetEmailByID(t *testing.T) {
 			Message: "database error",
 		}))
 
-		repo := &MongoDBCustomerRepository{collection: mt.Coll}
+		repo := newTestRepository(mt)
 		email, err := repo.GetEmailByID(context.Background(), "123")
 
 		assert.Error(t, err)
 		assert.Empty(t, email)
 	})
 }
+
+func TestFindByCode(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Successfully find customer", func(mt *mtest.T) {
+		repo := newTestRepository(mt)
+		stored, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		stored.Code = "7K3M9QXD"
+		mt.AddMockResponses(customerResponse(mt.T, repo, stored)...)
+
+		customer, err := repo.FindByCode(context.Background(), "7K3M9QXD")
+
+		assert.NoError(t, err)
+		assert.NotNil(t, customer)
+		assert.Equal(t, "7K3M9QXD", customer.Code)
+		assert.Equal(t, "7K3M9QXD", mt.GetStartedEvent().Command.Lookup("filter", "code").StringValue())
+	})
+
+	mt.Run("Customer not found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "customer_db.customers", mtest.FirstBatch))
+
+		repo := newTestRepository(mt)
+		customer, err := repo.FindByCode(context.Background(), "7K3M9QXD")
+
+		assert.NoError(t, err)
+		assert.Nil(t, customer)
+	})
+
+	mt.Run("Database error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    500,
+			Message: "database error",
+		}))
+
+		repo := newTestRepository(mt)
+		customer, err := repo.FindByCode(context.Background(), "7K3M9QXD")
+
+		assert.Error(t, err)
+		assert.Nil(t, customer)
+	})
+}
+
+func TestGetVersionByCPF(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Successfully get version", func(mt *mtest.T) {
+		updatedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "customer_db.customers", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: "123"},
+			{Key: "updatedAt", Value: updatedAt},
+		}))
+
+		repo := newTestRepository(mt)
+		version, err := repo.GetVersionByCPF(context.Background(), "11144477735")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "123", version.ID)
+		assert.True(t, updatedAt.Equal(version.UpdatedAt))
+	})
+
+	mt.Run("Customer not found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "customer_db.customers", mtest.FirstBatch))
+
+		repo := newTestRepository(mt)
+		version, err := repo.GetVersionByCPF(context.Background(), "11144477735")
+
+		assert.NoError(t, err)
+		assert.Nil(t, version)
+	})
+
+	mt.Run("Database error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    500,
+			Message: "database error",
+		}))
+
+		repo := newTestRepository(mt)
+		version, err := repo.GetVersionByCPF(context.Background(), "11144477735")
+
+		assert.Error(t, err)
+		assert.Nil(t, version)
+	})
+}
+
+func TestFindByIDsOrCPFs(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Successfully find customers", func(mt *mtest.T) {
+		repo := newTestRepository(mt)
+		first, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		second, _ := domain.NewCustomer("Jane Doe", "52998224725", "jane@example.com")
+		firstDoc, firstKey := storedCustomer(mt.T, repo, first)
+		secondDoc, secondKey := storedCustomer(mt.T, repo, second)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "customer_db.customers", mtest.FirstBatch, firstDoc, secondDoc),
+			keysResponse(firstKey, secondKey),
+		)
+
+		customers, err := repo.FindByIDsOrCPFs(context.Background(), []string{first.ID}, []string{"52998224725"})
+
+		assert.NoError(t, err)
+		assert.Len(t, customers, 2)
+
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		conditions, _ := filter.Lookup("$or").Array().Values()
+		assert.Len(t, conditions, 2)
+	})
+
+	mt.Run("Empty keys skip the query", func(mt *mtest.T) {
+		repo := newTestRepository(mt)
+		customers, err := repo.FindByIDsOrCPFs(context.Background(), nil, nil)
+
+		assert.NoError(t, err)
+		assert.Empty(t, customers)
+		assert.Nil(t, mt.GetStartedEvent())
+	})
+
+	mt.Run("Database error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    500,
+			Message: "database error",
+		}))
+
+		repo := newTestRepository(mt)
+		customers, err := repo.FindByIDsOrCPFs(context.Background(), []string{"1"}, nil)
+
+		assert.Error(t, err)
+		assert.Nil(t, customers)
+	})
+}
+
+func TestFindConflicts(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Returns customers holding the CPF or email", func(mt *mtest.T) {
+		repo := newTestRepository(mt)
+		first, _ := domain.NewCustomer("John Doe", "11144477735", "john.doe@example.com")
+		second, _ := domain.NewCustomer("Jane Doe", "52998224725", "john@example.com")
+		firstDoc, firstKey := storedCustomer(mt.T, repo, first)
+		secondDoc, secondKey := storedCustomer(mt.T, repo, second)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "customer_db.customers", mtest.FirstBatch, firstDoc, secondDoc),
+			keysResponse(firstKey, secondKey),
+		)
+
+		customers, err := repo.FindConflicts(context.Background(), "11144477735", "john@example.com")
+
+		assert.NoError(t, err)
+		assert.Len(t, customers, 2)
+
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		conditions, _ := filter.Lookup("$or").Array().Values()
+		assert.Equal(t, repo.cpfIndex("11144477735"), conditions[0].Document().Lookup("cpfIndex").StringValue())
+		assert.Equal(t, repo.emailIndex("john@example.com"), conditions[1].Document().Lookup("emailIndex").StringValue())
+	})
+
+	mt.Run("Only checks non-empty values", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "customer_db.customers", mtest.FirstBatch))
+
+		repo := newTestRepository(mt)
+		customers, err := repo.FindConflicts(context.Background(), "", "john@example.com")
+
+		assert.NoError(t, err)
+		assert.Empty(t, customers)
+
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		conditions, _ := filter.Lookup("$or").Array().Values()
+		assert.Len(t, conditions, 1)
+	})
+
+	mt.Run("Nothing to check skips the query", func(mt *mtest.T) {
+		repo := newTestRepository(mt)
+		customers, err := repo.FindConflicts(context.Background(), "", "")
+
+		assert.NoError(t, err)
+		assert.Empty(t, customers)
+		assert.Nil(t, mt.GetStartedEvent())
+	})
+
+	mt.Run("Database error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    500,
+			Message: "database error",
+		}))
+
+		repo := newTestRepository(mt)
+		customers, err := repo.FindConflicts(context.Background(), "11144477735", "")
+
+		assert.Error(t, err)
+		assert.Nil(t, customers)
+	})
+}
+
+func TestMigrateEncryption(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	// inlineKeyCustomer stores a customer the way it was stored while data
+	// keys were kept in the document and names were not encrypted.
+	inlineKeyCustomer := func(t *testing.T) bson.D {
+		key, err := testEncryptor.NewDataKey()
+		require.NoError(t, err)
+		cpf, err := key.Encrypt("52998224725", "456/cpf")
+		require.NoError(t, err)
+		email, err := key.Encrypt("jane@example.com", "456/email")
+		require.NoError(t, err)
+		return bson.D{
+			{Key: "_id", Value: "456"},
+			{Key: "name", Value: "Jane Doe"},
+			{Key: "cpf", Value: cpf},
+			{Key: "cpfIndex", Value: testEncryptor.BlindIndex(cpfIndexField, "52998224725")},
+			{Key: "email", Value: email},
+			{Key: "emailIndex", Value: testEncryptor.BlindIndex(emailIndexField, "jane@example.com")},
+			{Key: "dataKey", Value: bson.D{
+				{Key: "keyId", Value: key.Wrapped().KeyID},
+				{Key: "key", Value: key.Wrapped().Ciphertext},
+			}},
+		}
+	}
+
+	mt.Run("Moves customers to data keys of their own and replaces the plaintext indexes", func(mt *mtest.T) {
+		upserted := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1})
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "customer_db.customers", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: "123"},
+				{Key: "name", Value: "John Doe"},
+				{Key: "cpf", Value: "11144477735"},
+				{Key: "email", Value: "john@example.com"},
+				{Key: "cpfHistory", Value: bson.A{bson.D{{Key: "previousCpf", Value: "52998224725"}}}},
+			}, inlineKeyCustomer(mt.T)),
+			upserted, upserted, upserted, upserted,
+			mtest.CreateCursorResponse(0, "customer_db.customers", mtest.FirstBatch, bson.D{{Key: "_id", Value: "789"}}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 27, Name: "IndexNotFound", Message: "index not found with name [email_1]"}),
+			mtest.CreateSuccessResponse(),
+		)
+
+		repo := newTestRepository(mt)
+		migrated, err := repo.MigrateEncryption(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, migrated)
+
+		mt.GetStartedEvent() // find
+		for _, want := range []domain.Customer{
+			{ID: "123", Name: "John Doe", CPF: "11144477735", Email: "john@example.com"},
+			{ID: "456", Name: "Jane Doe", CPF: "52998224725", Email: "jane@example.com"},
+		} {
+			keyWrite := mt.GetStartedEvent().Command
+			assert.Equal(t, "customer_keys", keyWrite.Lookup("update").StringValue())
+			keyStatement := keyWrite.Lookup("updates").Array().Index(0).Value().Document()
+			assert.True(t, keyStatement.Lookup("upsert").Boolean())
+
+			replace := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+			assert.Equal(t, want.ID, replace.Lookup("q", "_id").StringValue())
+			replacement := replace.Lookup("u").Document()
+			for _, plaintext := range []string{want.Name, want.CPF, want.Email, "52998224725"} {
+				assert.NotContains(t, replacement.String(), plaintext)
+			}
+			_, err := replacement.LookupErr("dataKey")
+			assert.Error(t, err, "the inline data key is dropped")
+			assert.Equal(t, repo.cpfIndex(want.CPF), replacement.Lookup("cpfIndex").StringValue())
+
+			var doc customerDocument
+			require.NoError(t, bson.Unmarshal(replacement, &doc))
+			var key customerKeyDocument
+			require.NoError(t, bson.Unmarshal(keyStatement.Lookup("u").Document(), &key))
+			customer, err := repo.decrypt(&doc, &key)
+			require.NoError(t, err)
+			assert.Equal(t, want.Name, customer.Name)
+			assert.Equal(t, want.CPF, customer.CPF)
+			assert.Equal(t, want.Email, customer.Email)
+		}
+
+		// Customers restored without a data key lose their blind indexes
+		assert.Equal(t, "aggregate", mt.GetStartedEvent().CommandName)
+		erase := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, "789", erase.Lookup("q", "_id", "$in").Array().Index(0).Value().StringValue())
+		assert.True(t, erase.Lookup("multi").Boolean())
+
+		assert.Equal(t, "cpf_1", mt.GetStartedEvent().Command.Lookup("index").StringValue())
+		assert.Equal(t, "email_1", mt.GetStartedEvent().Command.Lookup("index").StringValue())
+		assert.Equal(t, "createIndexes", mt.GetStartedEvent().CommandName)
+	})
+
+	mt.Run("Database error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    500,
+			Message: "database error",
+		}))
+
+		repo := newTestRepository(mt)
+		_, err := repo.MigrateEncryption(context.Background())
+
+		assert.Error(t, err)
+	})
+}
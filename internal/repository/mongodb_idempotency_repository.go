@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"customer-service/internal/domain"
+	"customer-service/pkg/errors"
+	stderrors "errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const createdAtIndexName = "createdAt_1"
+
+type MongoDBIdempotencyRepository struct {
+	collection *mongo.Collection
+	ttl        time.Duration
+}
+
+func NewMongoDBIdempotencyRepository(db *mongo.Database, ttl time.Duration) *MongoDBIdempotencyRepository {
+	return &MongoDBIdempotencyRepository{
+		collection: db.Collection("idempotency_keys"),
+		ttl:        ttl,
+	}
+}
+
+// EnsureSchema expires keys through a TTL index on the creation date.
+// Without it keys would pile up forever. An index created under a different
+// IDEMPOTENCY_TTL is changed in place with collMod. Keys stored by earlier
+// versions, which kept the whole response with its personal data, are
+// deleted: their retries run again instead of being replayed.
+func (r *MongoDBIdempotencyRepository) EnsureSchema(ctx context.Context) error {
+	expireAfter := int32(r.ttl.Seconds())
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "createdAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(expireAfter).SetName(createdAtIndexName),
+	})
+	if isIndexOptionsConflict(err) {
+		err = r.collection.Database().RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: r.collection.Name()},
+			{Key: "index", Value: bson.D{
+				{Key: "name", Value: createdAtIndexName},
+				{Key: "expireAfterSeconds", Value: expireAfter},
+			}},
+		}).Err()
+	}
+	if err != nil {
+		return errors.WrapError(err, "Failed to create idempotency key index")
+	}
+
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"body": bson.M{"$exists": true}}); err != nil {
+		return errors.WrapError(err, "Failed to delete stored responses")
+	}
+	return nil
+}
+
+func (r *MongoDBIdempotencyRepository) Reserve(ctx context.Context, key, fingerprint string) (*domain.IdempotencyRecord, error) {
+	record := domain.IdempotencyRecord{
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   time.Now(),
+	}
+
+	_, err := r.collection.InsertOne(ctx, record)
+	if err == nil {
+		return nil, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, errors.WrapError(err, "Failed to reserve idempotency key")
+	}
+
+	var existing domain.IdempotencyRecord
+	err = r.collection.FindOne(ctx, bson.M{"_id": key}).Decode(&existing)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			// The key expired between the insert and the lookup; let the client retry
+			return nil, errors.NewConflictError("Request with this Idempotency-Key is still being processed", "IDEMPOTENCY_REQUEST_IN_PROGRESS")
+		}
+		return nil, errors.WrapError(err, "Failed to find idempotency key")
+	}
+	return &existing, nil
+}
+
+func (r *MongoDBIdempotencyRepository) Complete(ctx context.Context, key string, statusCode int, customerID string) error {
+	update := bson.M{
+		"$set": bson.M{
+			"statusCode": statusCode,
+			"customerId": customerID,
+		},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": key}, update)
+	if err != nil {
+		return errors.WrapError(err, "Failed to store idempotent response")
+	}
+	return nil
+}
+
+func (r *MongoDBIdempotencyRepository) Release(ctx context.Context, key string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": key})
+	if err != nil {
+		return errors.WrapError(err, "Failed to release idempotency key")
+	}
+	return nil
+}
+
+// isIndexOptionsConflict reports whether an index with the same keys already
+// exists with other options.
+func isIndexOptionsConflict(err error) bool {
+	var cmdErr mongo.CommandError
+	return stderrors.As(err, &cmdErr) && cmdErr.Name == "IndexOptionsConflict"
+}
@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"customer-service/pkg/errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestNewMongoDBIdempotencyRepository(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Create repository", func(mt *mtest.T) {
+		repo := NewMongoDBIdempotencyRepository(mt.DB, 24*time.Hour)
+		assert.NotNil(t, repo)
+		assert.NotNil(t, repo.collection)
+	})
+}
+
+func TestEnsureIdempotencySchema(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Creates the TTL index", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}))
+
+		err := NewMongoDBIdempotencyRepository(mt.DB, time.Hour).EnsureSchema(context.Background())
+
+		require.NoError(t, err)
+		event := mt.GetStartedEvent()
+		assert.Equal(t, "createIndexes", event.CommandName)
+		assert.Contains(t, event.Command.String(), `"expireAfterSeconds": {"$numberInt":"3600"}`)
+	})
+
+	mt.Run("Changes the TTL of an existing index", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 85, Name: "IndexOptionsConflict", Message: "An equivalent index already exists with different options"}),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}),
+		)
+
+		err := NewMongoDBIdempotencyRepository(mt.DB, time.Hour).EnsureSchema(context.Background())
+
+		require.NoError(t, err)
+		mt.GetStartedEvent()
+		event := mt.GetStartedEvent()
+		assert.Equal(t, "collMod", event.CommandName)
+		assert.Contains(t, event.Command.String(), `"expireAfterSeconds": {"$numberInt":"3600"}`)
+	})
+
+	mt.Run("Deletes responses stored by earlier versions", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 3}))
+
+		err := NewMongoDBIdempotencyRepository(mt.DB, time.Hour).EnsureSchema(context.Background())
+
+		require.NoError(t, err)
+		mt.GetStartedEvent()
+		event := mt.GetStartedEvent()
+		assert.Equal(t, "delete", event.CommandName)
+		assert.Contains(t, event.Command.String(), `"body": {"$exists": true}`)
+	})
+
+	mt.Run("Reports a failure to create the index", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 13, Name: "Unauthorized", Message: "not authorized"}))
+
+		err := NewMongoDBIdempotencyRepository(mt.DB, time.Hour).EnsureSchema(context.Background())
+
+		require.Error(t, err)
+		assert.Equal(t, "INTERNAL_ERROR", errors.From(err).Code)
+	})
+}
+
+func TestReserve(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Key reserved", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		repo := &MongoDBIdempotencyRepository{collection: mt.Coll}
+		existing, err := repo.Reserve(context.Background(), "key-1", "fingerprint")
+
+		assert.NoError(t, err)
+		assert.Nil(t, existing)
+	})
+
+	mt.Run("Key already used", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{
+				Index:   0,
+				Code:    11000,
+				Message: "duplicate key error",
+			}),
+			mtest.CreateCursorResponse(1, "customer_db.idempotency_keys", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: "key-1"},
+				{Key: "fingerprint", Value: "fingerprint"},
+				{Key: "statusCode", Value: 201},
+				{Key: "customerId", Value: "123"},
+			}),
+		)
+
+		repo := &MongoDBIdempotencyRepository{collection: mt.Coll}
+		existing, err := repo.Reserve(context.Background(), "key-1", "fingerprint")
+
+		assert.NoError(t, err)
+		assert.NotNil(t, existing)
+		assert.True(t, existing.Completed())
+		assert.Equal(t, 201, existing.StatusCode)
+		assert.Equal(t, "123", existing.CustomerID)
+	})
+
+	mt.Run("Key expired between insert and lookup", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{
+				Index:   0,
+				Code:    11000,
+				Message: "duplicate key error",
+			}),
+			mtest.CreateCursorResponse(0, "customer_db.idempotency_keys", mtest.FirstBatch),
+		)
+
+		repo := &MongoDBIdempotencyRepository{collection: mt.Coll}
+		existing, err := repo.Reserve(context.Background(), "key-1", "fingerprint")
+
+		assert.Error(t, err)
+		assert.Nil(t, existing)
+		appErr, ok := err.(*errors.AppError)
+		assert.True(t, ok)
+		assert.Equal(t, "IDEMPOTENCY_REQUEST_IN_PROGRESS", appErr.Code)
+	})
+
+	mt.Run("Database error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{
+			Index:   0,
+			Code:    500,
+			Message: "database error",
+		}))
+
+		repo := &MongoDBIdempotencyRepository{collection: mt.Coll}
+		existing, err := repo.Reserve(context.Background(), "key-1", "fingerprint")
+
+		assert.Error(t, err)
+		assert.Nil(t, existing)
+	})
+}
+
+func TestComplete(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Stores the status and customer ID only", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: 1},
+			bson.E{Key: "nModified", Value: 1},
+		))
+
+		repo := &MongoDBIdempotencyRepository{collection: mt.Coll}
+		err := repo.Complete(context.Background(), "key-1", 201, "123")
+
+		assert.NoError(t, err)
+		update := mt.GetStartedEvent().Command.String()
+		assert.Contains(t, update, `"customerId": "123"`)
+		assert.NotContains(t, update, "body")
+	})
+
+	mt.Run("Database error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{
+			Index:   0,
+			Code:    500,
+			Message: "database error",
+		}))
+
+		repo := &MongoDBIdempotencyRepository{collection: mt.Coll}
+		err := repo.Complete(context.Background(), "key-1", 201, "123")
+
+		assert.Error(t, err)
+	})
+}
+
+func TestRelease(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Successfully release key", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: 1},
+		))
+
+		repo := &MongoDBIdempotencyRepository{collection: mt.Coll}
+		err := repo.Release(context.Background(), "key-1")
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("Database error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{
+			Index:   0,
+			Code:    500,
+			Message: "database error",
+		}))
+
+		repo := &MongoDBIdempotencyRepository{collection: mt.Coll}
+		err := repo.Release(context.Background(), "key-1")
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"customer-service/internal/domain"
+	"customer-service/pkg/errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoDBPIIAccessRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoDBPIIAccessRepository(db *mongo.Database) *MongoDBPIIAccessRepository {
+	collection := db.Collection("pii_access_log")
+
+	// Both queries read the newest entries of one customer or actor
+	_, _ = collection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "customerId", Value: 1}, {Key: "accessedAt", Value: -1}}},
+		{Keys: bson.D{{Key: "actor", Value: 1}, {Key: "accessedAt", Value: -1}}},
+	})
+
+	return &MongoDBPIIAccessRepository{
+		collection: collection,
+	}
+}
+
+func (r *MongoDBPIIAccessRepository) Append(ctx context.Context, entries []*domain.PIIAccess) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		docs[i] = entry
+	}
+	if _, err := r.collection.InsertMany(ctx, docs); err != nil {
+		return errors.WrapError(err, "Failed to record PII access")
+	}
+	return nil
+}
+
+func (r *MongoDBPIIAccessRepository) FindByCustomer(ctx context.Context, customerID string, limit int) ([]*domain.PIIAccess, error) {
+	return r.find(ctx, bson.M{"customerId": customerID}, limit)
+}
+
+func (r *MongoDBPIIAccessRepository) FindByActor(ctx context.Context, actor string, limit int) ([]*domain.PIIAccess, error) {
+	return r.find(ctx, bson.M{"actor": actor}, limit)
+}
+
+func (r *MongoDBPIIAccessRepository) find(ctx context.Context, filter bson.M, limit int) ([]*domain.PIIAccess, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "accessedAt", Value: -1}}).
+		SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, errors.WrapError(err, "Failed to find PII accesses")
+	}
+
+	entries := make([]*domain.PIIAccess, 0)
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, errors.WrapError(err, "Failed to decode PII accesses")
+	}
+	return entries, nil
+}
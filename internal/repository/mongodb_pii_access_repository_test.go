@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"customer-service/internal/domain"
+	"customer-service/pkg/errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestPIIAccessRepository_Append(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Entries stored at once", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		now := time.Now()
+		entries := []*domain.PIIAccess{
+			domain.NewPIIAccess("order-service", "order-fulfillment", "customer-1", []string{"cpf"}, now),
+			domain.NewPIIAccess("order-service", "order-fulfillment", "customer-2", []string{"cpf", "email"}, now),
+		}
+
+		repo := &MongoDBPIIAccessRepository{collection: mt.Coll}
+		err := repo.Append(context.Background(), entries)
+
+		assert.NoError(t, err)
+		inserted := mt.GetStartedEvent().Command.Lookup("documents").Array()
+		values, _ := inserted.Values()
+		assert.Len(t, values, 2)
+		assert.Equal(t, "customer-2", values[1].Document().Lookup("customerId").StringValue())
+	})
+
+	mt.Run("Nothing to store", func(mt *mtest.T) {
+		repo := &MongoDBPIIAccessRepository{collection: mt.Coll}
+		err := repo.Append(context.Background(), nil)
+
+		assert.NoError(t, err)
+		assert.Nil(t, mt.GetStartedEvent())
+	})
+
+	mt.Run("Database error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "boom"}))
+		entries := []*domain.PIIAccess{domain.NewPIIAccess("admin", "", "customer-1", []string{"cpf"}, time.Now())}
+
+		repo := &MongoDBPIIAccessRepository{collection: mt.Coll}
+		err := repo.Append(context.Background(), entries)
+
+		assert.Equal(t, "INTERNAL_ERROR", errors.From(err).Code)
+	})
+}
+
+func TestPIIAccessRepository_Find(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	entry := bson.D{
+		{Key: "_id", Value: "entry-1"},
+		{Key: "actor", Value: "order-service"},
+		{Key: "purpose", Value: "order-fulfillment"},
+		{Key: "customerId", Value: "customer-1"},
+		{Key: "fields", Value: bson.A{"cpf", "email"}},
+	}
+
+	mt.Run("By customer", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "customer_db.pii_access_log", mtest.FirstBatch, entry))
+
+		repo := &MongoDBPIIAccessRepository{collection: mt.Coll}
+		entries, err := repo.FindByCustomer(context.Background(), "customer-1", 50)
+
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, "order-service", entries[0].Actor)
+		assert.Equal(t, []string{"cpf", "email"}, entries[0].Fields)
+
+		command := mt.GetStartedEvent().Command
+		assert.Equal(t, "customer-1", command.Lookup("filter", "customerId").StringValue())
+		assert.Equal(t, int32(-1), command.Lookup("sort", "accessedAt").Int32())
+		assert.Equal(t, int64(50), command.Lookup("limit").Int64())
+	})
+
+	mt.Run("By actor", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "customer_db.pii_access_log", mtest.FirstBatch, entry))
+
+		repo := &MongoDBPIIAccessRepository{collection: mt.Coll}
+		entries, err := repo.FindByActor(context.Background(), "order-service", 50)
+
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, "order-service", mt.GetStartedEvent().Command.Lookup("filter", "actor").StringValue())
+	})
+
+	mt.Run("Nothing found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "customer_db.pii_access_log", mtest.FirstBatch))
+
+		repo := &MongoDBPIIAccessRepository{collection: mt.Coll}
+		entries, err := repo.FindByActor(context.Background(), "nobody", 50)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, entries)
+		assert.Empty(t, entries)
+	})
+
+	mt.Run("Database error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "boom"}))
+
+		repo := &MongoDBPIIAccessRepository{collection: mt.Coll}
+		entries, err := repo.FindByCustomer(context.Background(), "customer-1", 50)
+
+		assert.Error(t, err)
+		assert.Nil(t, entries)
+	})
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"customer-service/internal/domain"
+)
+
+// PIIAccessRepository stores the PII access log. It is append-only: entries
+// are never changed or removed.
+type PIIAccessRepository interface {
+	Append(ctx context.Context, entries []*domain.PIIAccess) error
+	// FindByCustomer returns the newest entries about a customer first, at
+	// most limit.
+	FindByCustomer(ctx context.Context, customerID string, limit int) ([]*domain.PIIAccess, error)
+	// FindByActor returns the newest entries of an actor first, at most
+	// limit.
+	FindByActor(ctx context.Context, actor string, limit int) ([]*domain.PIIAccess, error)
+}
@@ -0,0 +1,54 @@
+package usecase
+
+import (
+	"context"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/repository"
+	"customer-service/pkg/errors"
+	"log"
+	"time"
+)
+
+// apiKeySubjectPrefix starts the subject of callers using an API key.
+const apiKeySubjectPrefix = "apikey:"
+
+type AuthenticateAPIKeyUseCase struct {
+	repo repository.APIKeyRepository
+}
+
+func NewAuthenticateAPIKeyUseCase(repo repository.APIKeyRepository) *AuthenticateAPIKeyUseCase {
+	return &AuthenticateAPIKeyUseCase{repo: repo}
+}
+
+// Execute returns the caller an API key identifies. Unknown, malformed,
+// expired and revoked keys all fail the same way, so a caller cannot tell
+// which keys exist.
+func (uc *AuthenticateAPIKeyUseCase) Execute(ctx context.Context, apiKey string) (*auth.Principal, error) {
+	id, secret, ok := domain.ParseAPIKey(apiKey)
+	if !ok {
+		return nil, invalidAPIKey()
+	}
+
+	key, err := uc.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if key == nil || !key.Matches(secret) || !key.Active(now) {
+		return nil, invalidAPIKey()
+	}
+
+	// The key is valid either way; failing to record its use must not
+	// reject the request
+	if err := uc.repo.TouchLastUsed(ctx, key.ID, now); err != nil {
+		log.Printf("Failed to record use of API key %s: %s", key.ID, errors.From(err).Internal())
+	}
+
+	return &auth.Principal{Subject: apiKeySubjectPrefix + key.ID, Scopes: key.Scopes}, nil
+}
+
+func invalidAPIKey() error {
+	return errors.NewUnauthorizedError("Invalid API key", "INVALID_API_KEY")
+}
@@ -0,0 +1,107 @@
+package usecase
+
+import (
+	"context"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/pkg/errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticateAPIKeyUseCase_Execute(t *testing.T) {
+	newKey := func(t *testing.T) (*domain.APIKey, string) {
+		key, apiKey, err := domain.NewAPIKey("partner", []string{auth.ScopeRead}, time.Now().Add(time.Hour), "admin")
+		require.NoError(t, err)
+		return key, apiKey
+	}
+
+	t.Run("Valid key", func(t *testing.T) {
+		key, apiKey := newKey(t)
+		mockRepo := new(MockAPIKeyRepository)
+		mockRepo.On("FindByID", mock.Anything, key.ID).Return(key, nil)
+		mockRepo.On("TouchLastUsed", mock.Anything, key.ID, mock.AnythingOfType("time.Time")).Return(nil)
+
+		principal, err := NewAuthenticateAPIKeyUseCase(mockRepo).Execute(context.Background(), apiKey)
+
+		require.NoError(t, err)
+		assert.Equal(t, "apikey:"+key.ID, principal.Subject)
+		assert.Equal(t, []string{auth.ScopeRead}, principal.Scopes)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Failing to record use does not reject the key", func(t *testing.T) {
+		key, apiKey := newKey(t)
+		mockRepo := new(MockAPIKeyRepository)
+		mockRepo.On("FindByID", mock.Anything, key.ID).Return(key, nil)
+		mockRepo.On("TouchLastUsed", mock.Anything, key.ID, mock.Anything).Return(errors.NewInternalError("down"))
+
+		_, err := NewAuthenticateAPIKeyUseCase(mockRepo).Execute(context.Background(), apiKey)
+
+		assert.NoError(t, err)
+	})
+
+	rejected := []struct {
+		name  string
+		setup func(t *testing.T, m *MockAPIKeyRepository) string
+	}{
+		{
+			name: "Malformed key",
+			setup: func(t *testing.T, m *MockAPIKeyRepository) string {
+				return "not-a-key"
+			},
+		},
+		{
+			name: "Unknown key",
+			setup: func(t *testing.T, m *MockAPIKeyRepository) string {
+				m.On("FindByID", mock.Anything, "missing").Return(nil, nil)
+				return "csk_missing.secret"
+			},
+		},
+		{
+			name: "Wrong secret",
+			setup: func(t *testing.T, m *MockAPIKeyRepository) string {
+				key, _ := newKey(t)
+				m.On("FindByID", mock.Anything, key.ID).Return(key, nil)
+				return "csk_" + key.ID + ".guess"
+			},
+		},
+		{
+			name: "Expired key",
+			setup: func(t *testing.T, m *MockAPIKeyRepository) string {
+				key, apiKey := newKey(t)
+				key.ExpiresAt = time.Now().Add(-time.Minute)
+				m.On("FindByID", mock.Anything, key.ID).Return(key, nil)
+				return apiKey
+			},
+		},
+		{
+			name: "Revoked key",
+			setup: func(t *testing.T, m *MockAPIKeyRepository) string {
+				key, apiKey := newKey(t)
+				revokedAt := time.Now()
+				key.RevokedAt = &revokedAt
+				m.On("FindByID", mock.Anything, key.ID).Return(key, nil)
+				return apiKey
+			},
+		},
+	}
+
+	for _, tt := range rejected {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockAPIKeyRepository)
+			apiKey := tt.setup(t, mockRepo)
+
+			_, err := NewAuthenticateAPIKeyUseCase(mockRepo).Execute(context.Background(), apiKey)
+
+			appErr := errors.From(err)
+			assert.Equal(t, 401, appErr.StatusCode)
+			assert.Equal(t, "INVALID_API_KEY", appErr.Code)
+			mockRepo.AssertNotCalled(t, "TouchLastUsed", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package usecase
+
+import (
+	"context"
+	"customer-service/internal/abuse"
+	"customer-service/internal/audit"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/repository"
+	"customer-service/pkg/errors"
+	"customer-service/pkg/validator"
+	"fmt"
+	"strconv"
+)
+
+// MaxBatchSize bounds how many customers a single batch lookup may request.
+const MaxBatchSize = 100
+
+// BatchResult holds the customers found by a batch lookup and the keys
+// that did not match any customer, in request order.
+type BatchResult struct {
+	Customers []*domain.Customer
+	Missing   []string
+}
+
+// LookupResult maps every requested ID or CPF, exactly as sent, to its
+// customer and lists the keys that did not match, in request order.
+type LookupResult struct {
+	Found   map[string]*domain.Customer
+	Missing []string
+}
+
+type BatchGetCustomersUseCase struct {
+	repo      repository.CustomerRepository
+	accessLog *audit.AccessLog
+}
+
+func NewBatchGetCustomersUseCase(repo repository.CustomerRepository, accessLog *audit.AccessLog) *BatchGetCustomersUseCase {
+	return &BatchGetCustomersUseCase{repo: repo, accessLog: accessLog}
+}
+
+func (uc *BatchGetCustomersUseCase) ExecuteByIDs(ctx context.Context, ids []string) (*BatchResult, error) {
+	if err := auth.Authorize(ctx, auth.OpReadCustomer); err != nil {
+		return nil, err
+	}
+
+	ids = uniqueKeys(ids)
+	if len(ids) == 0 {
+		return &BatchResult{Customers: []*domain.Customer{}, Missing: []string{}}, nil
+	}
+	if err := checkBatchSize(len(ids)); err != nil {
+		return nil, err
+	}
+
+	customers, err := uc.repo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*domain.Customer, len(customers))
+	for _, customer := range customers {
+		byID[customer.ID] = customer
+	}
+
+	result := &BatchResult{Customers: []*domain.Customer{}, Missing: []string{}}
+	for _, id := range ids {
+		if customer, ok := byID[id]; ok {
+			result.Customers = append(result.Customers, customer)
+		} else {
+			result.Missing = append(result.Missing, id)
+		}
+	}
+
+	if err := uc.accessLog.Record(ctx, result.Customers...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ExecuteByCPFs accepts formatted or unformatted CPFs. Invalid CPFs are
+// reported as missing without reaching the repository.
+func (uc *BatchGetCustomersUseCase) ExecuteByCPFs(ctx context.Context, cpfs []string) (*BatchResult, error) {
+	if err := auth.Authorize(ctx, auth.OpReadCustomer); err != nil {
+		return nil, err
+	}
+
+	cpfs = uniqueKeys(cpfs)
+	if err := checkBatchSize(len(cpfs)); err != nil {
+		return nil, err
+	}
+	if err := abuse.Spend(ctx, len(cpfs)); err != nil {
+		return nil, err
+	}
+
+	cleanCPFs := make(map[string]string, len(cpfs))
+	valid := make([]string, 0, len(cpfs))
+	for _, cpf := range cpfs {
+		cleanCPF := validator.CleanCPF(cpf)
+		if validator.IsValidCPF(cleanCPF) {
+			cleanCPFs[cpf] = cleanCPF
+			valid = append(valid, cleanCPF)
+		}
+	}
+	valid = uniqueKeys(valid)
+
+	byCPF := make(map[string]*domain.Customer, len(valid))
+	if len(valid) > 0 {
+		customers, err := uc.repo.FindByCPFs(ctx, valid)
+		if err != nil {
+			return nil, err
+		}
+		indexByCPF(byCPF, customers)
+	}
+
+	result := &BatchResult{Customers: []*domain.Customer{}, Missing: []string{}}
+	added := make(map[string]bool, len(byCPF))
+	for _, cpf := range cpfs {
+		customer, ok := matchCPF(byCPF, cleanCPFs, cpf)
+		observeCPF(ctx, cpf, cleanCPFs, ok)
+		if !ok {
+			result.Missing = append(result.Missing, cpf)
+			continue
+		}
+		if !added[customer.ID] {
+			added[customer.ID] = true
+			result.Customers = append(result.Customers, customer)
+		}
+	}
+
+	if err := uc.accessLog.Record(ctx, result.Customers...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Lookup resolves a mix of IDs and CPFs with a single repository query. The
+// batch limit applies to both lists together.
+func (uc *BatchGetCustomersUseCase) Lookup(ctx context.Context, ids, cpfs []string) (*LookupResult, error) {
+	if err := auth.Authorize(ctx, auth.OpReadCustomer); err != nil {
+		return nil, err
+	}
+
+	ids = uniqueKeys(ids)
+	cpfs = uniqueKeys(cpfs)
+	if err := checkBatchSize(len(ids) + len(cpfs)); err != nil {
+		return nil, err
+	}
+	if err := abuse.Spend(ctx, len(cpfs)); err != nil {
+		return nil, err
+	}
+
+	cleanCPFs := make(map[string]string, len(cpfs))
+	valid := make([]string, 0, len(cpfs))
+	for _, cpf := range cpfs {
+		cleanCPF := validator.CleanCPF(cpf)
+		if validator.IsValidCPF(cleanCPF) {
+			cleanCPFs[cpf] = cleanCPF
+			valid = append(valid, cleanCPF)
+		}
+	}
+	valid = uniqueKeys(valid)
+
+	byID := make(map[string]*domain.Customer)
+	byCPF := make(map[string]*domain.Customer)
+	if len(ids) > 0 || len(valid) > 0 {
+		customers, err := uc.repo.FindByIDsOrCPFs(ctx, ids, valid)
+		if err != nil {
+			return nil, err
+		}
+		for _, customer := range customers {
+			byID[customer.ID] = customer
+		}
+		indexByCPF(byCPF, customers)
+	}
+
+	result := &LookupResult{Found: make(map[string]*domain.Customer), Missing: []string{}}
+	for _, id := range ids {
+		if customer, ok := byID[id]; ok {
+			result.Found[id] = customer
+		} else {
+			result.Missing = append(result.Missing, id)
+		}
+	}
+	for _, cpf := range cpfs {
+		customer, ok := matchCPF(byCPF, cleanCPFs, cpf)
+		observeCPF(ctx, cpf, cleanCPFs, ok)
+		if ok {
+			result.Found[cpf] = customer
+		} else {
+			result.Missing = append(result.Missing, cpf)
+		}
+	}
+
+	// Customers found by both ID and CPF are only recorded once
+	found := make([]*domain.Customer, 0, len(result.Found))
+	for _, customer := range result.Found {
+		found = append(found, customer)
+	}
+	if err := uc.accessLog.Record(ctx, found...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// indexByCPF keys customers by their CPF. Anonymized customers have none,
+// so they are left out and read as missing.
+func indexByCPF(byCPF map[string]*domain.Customer, customers []*domain.Customer) {
+	for _, customer := range customers {
+		if customer.CPF != "" {
+			byCPF[customer.CPF] = customer
+		}
+	}
+}
+
+// matchCPF finds the customer of a requested CPF. Invalid CPFs, missing
+// from cleanCPFs, never match.
+func matchCPF(byCPF map[string]*domain.Customer, cleanCPFs map[string]string, cpf string) (*domain.Customer, bool) {
+	cleanCPF, valid := cleanCPFs[cpf]
+	if !valid {
+		return nil, false
+	}
+	customer, ok := byCPF[cleanCPF]
+	return customer, ok
+}
+
+// observeCPF reports a CPF of a batch to the enumeration detector. CPFs
+// missing from cleanCPFs were invalid.
+func observeCPF(ctx context.Context, cpf string, cleanCPFs map[string]string, found bool) {
+	cleanCPF, valid := cleanCPFs[cpf]
+	if !valid {
+		abuse.Observe(ctx, validator.CleanCPF(cpf), abuse.OutcomeInvalid)
+		return
+	}
+	abuse.Observe(ctx, cleanCPF, lookupOutcome(found))
+}
+
+func checkBatchSize(size int) error {
+	if size > MaxBatchSize {
+		err := errors.NewValidationError(
+			fmt.Sprintf("A batch may contain at most %d keys", MaxBatchSize),
+			"BATCH_TOO_LARGE",
+		)
+		err.Params = map[string]string{"max": strconv.Itoa(MaxBatchSize)}
+		return err
+	}
+	return nil
+}
+
+// uniqueKeys drops empty and repeated keys while keeping their order.
+func uniqueKeys(keys []string) []string {
+	seen := make(map[string]bool, len(keys))
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, key)
+	}
+	return result
+}
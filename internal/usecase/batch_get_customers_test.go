@@ -0,0 +1,327 @@
+package usecase
+
+import (
+	"context"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/pkg/errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchGetCustomersUseCase_ExecuteByIDs(t *testing.T) {
+	first, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+	second, _ := domain.NewCustomer("Jane Doe", "52998224725", "jane@example.com")
+
+	tooMany := make([]string, MaxBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("id-%d", i)
+	}
+
+	tests := []struct {
+		name            string
+		ids             []string
+		mockSetup       func(*MockCustomerRepository)
+		expectError     bool
+		expectedError   string
+		expectedFound   []string
+		expectedMissing []string
+	}{
+		{
+			name: "Returns found customers in request order and misses",
+			ids:  []string{second.ID, "unknown", first.ID, second.ID},
+			mockSetup: func(m *MockCustomerRepository) {
+				m.On("FindByIDs", mock.Anything, []string{second.ID, "unknown", first.ID}).
+					Return([]*domain.Customer{first, second}, nil)
+			},
+			expectedFound:   []string{second.ID, first.ID},
+			expectedMissing: []string{"unknown"},
+		},
+		{
+			name:            "Empty batch does not query the repository",
+			ids:             []string{"", ""},
+			mockSetup:       func(m *MockCustomerRepository) {},
+			expectedFound:   []string{},
+			expectedMissing: []string{},
+		},
+		{
+			name:          "Batch too large",
+			ids:           tooMany,
+			mockSetup:     func(m *MockCustomerRepository) {},
+			expectError:   true,
+			expectedError: "BATCH_TOO_LARGE",
+		},
+		{
+			name: "FindByIDs returns error",
+			ids:  []string{first.ID},
+			mockSetup: func(m *MockCustomerRepository) {
+				m.On("FindByIDs", mock.Anything, []string{first.ID}).
+					Return(nil, errors.NewInternalError("database error"))
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockCustomerRepository)
+			tt.mockSetup(mockRepo)
+
+			uc := NewBatchGetCustomersUseCase(mockRepo, nil)
+			result, err := uc.ExecuteByIDs(context.Background(), tt.ids)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Nil(t, result)
+				if tt.expectedError != "" {
+					appErr, ok := err.(*errors.AppError)
+					assert.True(t, ok)
+					assert.Equal(t, tt.expectedError, appErr.Code)
+				}
+			} else {
+				assert.NoError(t, err)
+				found := make([]string, 0, len(result.Customers))
+				for _, customer := range result.Customers {
+					found = append(found, customer.ID)
+				}
+				assert.Equal(t, tt.expectedFound, found)
+				assert.Equal(t, tt.expectedMissing, result.Missing)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBatchGetCustomersUseCase_ExecuteByCPFs(t *testing.T) {
+	first, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+	second, _ := domain.NewCustomer("Jane Doe", "52998224725", "jane@example.com")
+	erased := &domain.Customer{ID: "erased", Anonymized: true}
+
+	tests := []struct {
+		name            string
+		cpfs            []string
+		mockSetup       func(*MockCustomerRepository)
+		expectError     bool
+		expectedFound   []string
+		expectedMissing []string
+	}{
+		{
+			name: "Cleans CPFs and reports invalid ones as missing",
+			cpfs: []string{"111.444.777-35", "invalid", "52998224725", "11144477735", "98765432100"},
+			mockSetup: func(m *MockCustomerRepository) {
+				m.On("FindByCPFs", mock.Anything, []string{"11144477735", "52998224725", "98765432100"}).
+					Return([]*domain.Customer{first, second}, nil)
+			},
+			expectedFound:   []string{first.ID, second.ID},
+			expectedMissing: []string{"invalid", "98765432100"},
+		},
+		{
+			name: "Anonymized customers match neither their CPF nor an invalid one",
+			cpfs: []string{"invalid", "11144477735"},
+			mockSetup: func(m *MockCustomerRepository) {
+				m.On("FindByCPFs", mock.Anything, []string{"11144477735"}).
+					Return([]*domain.Customer{erased}, nil)
+			},
+			expectedFound:   []string{},
+			expectedMissing: []string{"invalid", "11144477735"},
+		},
+		{
+			name:            "Only invalid CPFs does not query the repository",
+			cpfs:            []string{"invalid"},
+			mockSetup:       func(m *MockCustomerRepository) {},
+			expectedFound:   []string{},
+			expectedMissing: []string{"invalid"},
+		},
+		{
+			name: "FindByCPFs returns error",
+			cpfs: []string{"11144477735"},
+			mockSetup: func(m *MockCustomerRepository) {
+				m.On("FindByCPFs", mock.Anything, []string{"11144477735"}).
+					Return(nil, errors.NewInternalError("database error"))
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockCustomerRepository)
+			tt.mockSetup(mockRepo)
+
+			uc := NewBatchGetCustomersUseCase(mockRepo, nil)
+			result, err := uc.ExecuteByCPFs(context.Background(), tt.cpfs)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				found := make([]string, 0, len(result.Customers))
+				for _, customer := range result.Customers {
+					found = append(found, customer.ID)
+				}
+				assert.Equal(t, tt.expectedFound, found)
+				assert.Equal(t, tt.expectedMissing, result.Missing)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBatchGetCustomersUseCase_Lookup(t *testing.T) {
+	first, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+	second, _ := domain.NewCustomer("Jane Doe", "52998224725", "jane@example.com")
+	erased := &domain.Customer{ID: "erased", Anonymized: true}
+
+	tooMany := make([]string, MaxBatchSize)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("id-%d", i)
+	}
+
+	tests := []struct {
+		name            string
+		ids             []string
+		cpfs            []string
+		mockSetup       func(*MockCustomerRepository)
+		expectError     bool
+		expectedError   string
+		expectedFound   map[string]string
+		expectedMissing []string
+	}{
+		{
+			name: "Resolves IDs and CPFs with one query",
+			ids:  []string{first.ID, "unknown", first.ID},
+			cpfs: []string{"529.982.247-25", "invalid", "52998224725"},
+			mockSetup: func(m *MockCustomerRepository) {
+				m.On("FindByIDsOrCPFs", mock.Anything, []string{first.ID, "unknown"}, []string{"52998224725"}).
+					Return([]*domain.Customer{first, second}, nil).Once()
+			},
+			expectedFound: map[string]string{
+				first.ID:         first.ID,
+				"529.982.247-25": second.ID,
+				"52998224725":    second.ID,
+			},
+			expectedMissing: []string{"unknown", "invalid"},
+		},
+		{
+			name: "Anonymized customers are only found by ID",
+			ids:  []string{"erased"},
+			cpfs: []string{"invalid", "11144477735"},
+			mockSetup: func(m *MockCustomerRepository) {
+				m.On("FindByIDsOrCPFs", mock.Anything, []string{"erased"}, []string{"11144477735"}).
+					Return([]*domain.Customer{erased}, nil)
+			},
+			expectedFound:   map[string]string{"erased": "erased"},
+			expectedMissing: []string{"invalid", "11144477735"},
+		},
+		{
+			name: "Only invalid CPFs do not query the repository",
+			cpfs: []string{"invalid"},
+			mockSetup: func(m *MockCustomerRepository) {
+			},
+			expectedFound:   map[string]string{},
+			expectedMissing: []string{"invalid"},
+		},
+		{
+			name:          "Limit applies to IDs and CPFs together",
+			ids:           tooMany,
+			cpfs:          []string{"11144477735"},
+			mockSetup:     func(m *MockCustomerRepository) {},
+			expectError:   true,
+			expectedError: "BATCH_TOO_LARGE",
+		},
+		{
+			name: "FindByIDsOrCPFs returns error",
+			ids:  []string{first.ID},
+			mockSetup: func(m *MockCustomerRepository) {
+				m.On("FindByIDsOrCPFs", mock.Anything, []string{first.ID}, []string{}).
+					Return(nil, errors.NewInternalError("database error"))
+			},
+			expectError:   true,
+			expectedError: "INTERNAL_ERROR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockCustomerRepository)
+			tt.mockSetup(mockRepo)
+
+			uc := NewBatchGetCustomersUseCase(mockRepo, nil)
+			result, err := uc.Lookup(context.Background(), tt.ids, tt.cpfs)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Nil(t, result)
+				appErr, ok := err.(*errors.AppError)
+				assert.True(t, ok)
+				assert.Equal(t, tt.expectedError, appErr.Code)
+			} else {
+				assert.NoError(t, err)
+				found := make(map[string]string, len(result.Found))
+				for key, customer := range result.Found {
+					found[key] = customer.ID
+				}
+				assert.Equal(t, tt.expectedFound, found)
+				assert.Equal(t, tt.expectedMissing, result.Missing)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBatchGetCustomersUseCase_AccessLog(t *testing.T) {
+	first, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+	second, _ := domain.NewCustomer("Jane Doe", "52998224725", "jane@example.com")
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "order-service",
+		Scopes:  []string{auth.ScopeRead, auth.ScopeReadPII},
+	})
+
+	t.Run("Records every customer returned once", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByIDsOrCPFs", mock.Anything, []string{first.ID}, []string{"11144477735", "52998224725"}).
+			Return([]*domain.Customer{first, second}, nil)
+		accessLog, entries := recordedAccesses()
+
+		_, err := NewBatchGetCustomersUseCase(mockRepo, accessLog).Lookup(ctx, []string{first.ID}, []string{"11144477735", "52998224725"})
+
+		require.NoError(t, err)
+		customers := make([]string, 0, len(*entries))
+		for _, entry := range *entries {
+			customers = append(customers, entry.CustomerID)
+		}
+		assert.ElementsMatch(t, []string{first.ID, second.ID}, customers)
+	})
+
+	t.Run("Records the customers of a batch by ID", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByIDs", mock.Anything, []string{first.ID, "unknown"}).Return([]*domain.Customer{first}, nil)
+		accessLog, entries := recordedAccesses()
+
+		_, err := NewBatchGetCustomersUseCase(mockRepo, accessLog).ExecuteByIDs(ctx, []string{first.ID, "unknown"})
+
+		require.NoError(t, err)
+		require.Len(t, *entries, 1)
+		assert.Equal(t, first.ID, (*entries)[0].CustomerID)
+	})
+
+	t.Run("Records the customers of a batch by CPF", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByCPFs", mock.Anything, []string{"52998224725"}).Return([]*domain.Customer{second}, nil)
+		accessLog, entries := recordedAccesses()
+
+		_, err := NewBatchGetCustomersUseCase(mockRepo, accessLog).ExecuteByCPFs(ctx, []string{"52998224725"})
+
+		require.NoError(t, err)
+		require.Len(t, *entries, 1)
+		assert.Equal(t, second.ID, (*entries)[0].CustomerID)
+	})
+}
@@ -0,0 +1,48 @@
+package usecase
+
+import (
+	"context"
+	"customer-service/internal/audit"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/repository"
+	"customer-service/pkg/errors"
+)
+
+type ConfirmEmailUseCase struct {
+	repo      repository.CustomerRepository
+	accessLog *audit.AccessLog
+}
+
+func NewConfirmEmailUseCase(repo repository.CustomerRepository, accessLog *audit.AccessLog) *ConfirmEmailUseCase {
+	return &ConfirmEmailUseCase{repo: repo, accessLog: accessLog}
+}
+
+// Execute promotes the customer's pending email when token is the one sent
+// to it. If the address was taken in the meantime, the unique email index
+// rejects the change.
+func (uc *ConfirmEmailUseCase) Execute(ctx context.Context, id, token string) (*domain.Customer, error) {
+	if err := auth.AuthorizeCustomer(ctx, auth.OpConfirmEmail, id); err != nil {
+		return nil, err
+	}
+
+	customer, err := uc.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if customer == nil {
+		return nil, errors.NewNotFoundError("Customer not found", "CUSTOMER_NOT_FOUND")
+	}
+
+	if err := customer.ConfirmEmail(token); err != nil {
+		return nil, err
+	}
+
+	if err := uc.repo.Update(ctx, customer); err != nil {
+		return nil, err
+	}
+
+	uc.accessLog.RecordWrite(ctx, customer)
+	return customer, nil
+}
@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"context"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/pkg/errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func customerWithPendingEmail(t *testing.T) (*domain.Customer, string) {
+	customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+	newEmail := "jane@example.com"
+	require.NoError(t, customer.Update(nil, &newEmail))
+	token, err := customer.IssueEmailVerification()
+	require.NoError(t, err)
+	return customer, token
+}
+
+func TestConfirmEmailUseCase_Execute(t *testing.T) {
+	t.Run("Promotes the pending email", func(t *testing.T) {
+		customer, token := customerWithPendingEmail(t)
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(c *domain.Customer) bool {
+			return c.Email == "jane@example.com" && c.PendingEmail == ""
+		})).Return(nil)
+
+		uc := NewConfirmEmailUseCase(mockRepo, nil)
+		result, err := uc.Execute(context.Background(), customer.ID, token)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "jane@example.com", result.Email)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Wrong token", func(t *testing.T) {
+		customer, _ := customerWithPendingEmail(t)
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+
+		uc := NewConfirmEmailUseCase(mockRepo, nil)
+		_, err := uc.Execute(context.Background(), customer.ID, "wrong")
+
+		assert.Equal(t, "INVALID_EMAIL_TOKEN", errors.From(err).Code)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Address taken in the meantime", func(t *testing.T) {
+		customer, token := customerWithPendingEmail(t)
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("Update", mock.Anything, mock.Anything).Return(errors.NewFieldConflictError(domain.ErrEmailAlreadyExists))
+
+		uc := NewConfirmEmailUseCase(mockRepo, nil)
+		_, err := uc.Execute(context.Background(), customer.ID, token)
+
+		assert.Equal(t, "EMAIL_ALREADY_EXISTS", errors.From(err).Code)
+	})
+
+	t.Run("Customer not found", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByID", mock.Anything, "missing").Return(nil, nil)
+
+		uc := NewConfirmEmailUseCase(mockRepo, nil)
+		_, err := uc.Execute(context.Background(), "missing", "token")
+
+		assert.Equal(t, "CUSTOMER_NOT_FOUND", errors.From(err).Code)
+	})
+}
+
+func TestConfirmEmailUseCase_AccessLog(t *testing.T) {
+	t.Run("Records the customer confirmed", func(t *testing.T) {
+		customer, token := customerWithPendingEmail(t)
+		ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: customer.ID, Scopes: []string{auth.ScopeSelf}})
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("Update", mock.Anything, customer).Return(nil)
+		accessLog, entries := recordedAccesses()
+
+		_, err := NewConfirmEmailUseCase(mockRepo, accessLog).Execute(ctx, customer.ID, token)
+
+		require.NoError(t, err)
+		require.Len(t, *entries, 1)
+		assert.Equal(t, customer.ID, (*entries)[0].Actor)
+		assert.Equal(t, []string{"cpf", "email"}, (*entries)[0].Fields)
+	})
+}
@@ -0,0 +1,50 @@
+package usecase
+
+import (
+	"context"
+	"customer-service/internal/audit"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/repository"
+	"customer-service/pkg/errors"
+)
+
+// CorrectCPFUseCase lets an administrator fix a mistyped CPF. Regular updates
+// can never change the CPF.
+type CorrectCPFUseCase struct {
+	repo      repository.CustomerRepository
+	accessLog *audit.AccessLog
+}
+
+func NewCorrectCPFUseCase(repo repository.CustomerRepository, accessLog *audit.AccessLog) *CorrectCPFUseCase {
+	return &CorrectCPFUseCase{repo: repo, accessLog: accessLog}
+}
+
+// Execute replaces the customer's CPF and records the previous one together
+// with the reason and the administrator who changed it. A CPF held by
+// another customer is rejected by the unique index.
+func (uc *CorrectCPFUseCase) Execute(ctx context.Context, id, cpf, reason string) (*domain.Customer, error) {
+	if err := auth.Authorize(ctx, auth.OpCorrectCPF); err != nil {
+		return nil, err
+	}
+
+	customer, err := uc.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if customer == nil {
+		return nil, errors.NewNotFoundError("Customer not found", "CUSTOMER_NOT_FOUND")
+	}
+
+	if err := customer.CorrectCPF(cpf, reason, auth.FromContext(ctx).Subject); err != nil {
+		return nil, err
+	}
+
+	if err := uc.repo.UpdateCPF(ctx, customer); err != nil {
+		return nil, err
+	}
+
+	uc.accessLog.RecordWrite(ctx, customer)
+	return customer, nil
+}
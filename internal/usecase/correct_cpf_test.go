@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"context"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCorrectCPFUseCase_Execute(t *testing.T) {
+	adminCtx := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "admin@fiap.com",
+		Scopes:  []string{auth.ScopeAdmin},
+	})
+
+	t.Run("Corrects the CPF", func(t *testing.T) {
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("UpdateCPF", mock.Anything, customer).Return(nil)
+
+		uc := NewCorrectCPFUseCase(mockRepo, nil)
+		result, err := uc.Execute(adminCtx, customer.ID, "52998224725", "Typo at registration")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "52998224725", result.CPF)
+		assert.Len(t, result.CPFHistory, 1)
+		assert.Equal(t, "admin@fiap.com", result.CPFHistory[0].ChangedBy)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Callers without the admin scope are refused", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+
+		uc := NewCorrectCPFUseCase(mockRepo, nil)
+		_, err := uc.Execute(context.Background(), "123", "52998224725", "Typo at registration")
+
+		appErr := errors.From(err)
+		assert.Equal(t, 403, appErr.StatusCode)
+		assert.Equal(t, "FORBIDDEN", appErr.Code)
+		mockRepo.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Customer not found", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByID", mock.Anything, "999").Return(nil, nil)
+
+		uc := NewCorrectCPFUseCase(mockRepo, nil)
+		_, err := uc.Execute(adminCtx, "999", "52998224725", "Typo at registration")
+
+		assert.Equal(t, "CUSTOMER_NOT_FOUND", errors.From(err).Code)
+	})
+
+	t.Run("Invalid correction is not stored", func(t *testing.T) {
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+
+		uc := NewCorrectCPFUseCase(mockRepo, nil)
+		_, err := uc.Execute(adminCtx, customer.ID, "11144477735", " ")
+
+		appErr := errors.From(err)
+		assert.Len(t, appErr.Fields, 2)
+		assert.Equal(t, "CPF_UNCHANGED", appErr.Fields[0].Code)
+		assert.Equal(t, "REASON_EMPTY", appErr.Fields[1].Code)
+		mockRepo.AssertNotCalled(t, "UpdateCPF", mock.Anything, mock.Anything)
+	})
+
+	t.Run("CPF held by another customer", func(t *testing.T) {
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("UpdateCPF", mock.Anything, customer).Return(errors.NewFieldConflictError(domain.ErrCPFAlreadyExists))
+
+		uc := NewCorrectCPFUseCase(mockRepo, nil)
+		_, err := uc.Execute(adminCtx, customer.ID, "52998224725", "Typo at registration")
+
+		assert.Equal(t, "CPF_ALREADY_EXISTS", errors.From(err).Code)
+	})
+}
+
+func TestCorrectCPFUseCase_AccessLog(t *testing.T) {
+	t.Run("Records the customer corrected", func(t *testing.T) {
+		ctx := auth.WithPrincipal(context.Background(), &auth.Principal{
+			Subject: "admin@fiap.com",
+			Scopes:  []string{auth.ScopeAdmin, auth.ScopeReadPII},
+		})
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("UpdateCPF", mock.Anything, customer).Return(nil)
+		accessLog, entries := recordedAccesses()
+
+		_, err := NewCorrectCPFUseCase(mockRepo, accessLog).Execute(ctx, customer.ID, "52998224725", "Typo at registration")
+
+		require.NoError(t, err)
+		require.Len(t, *entries, 1)
+		assert.Equal(t, "admin@fiap.com", (*entries)[0].Actor)
+		assert.Equal(t, customer.ID, (*entries)[0].CustomerID)
+	})
+
+	t.Run("Masked responses are not recorded", func(t *testing.T) {
+		ctx := auth.WithPrincipal(context.Background(), &auth.Principal{
+			Subject: "admin@fiap.com",
+			Scopes:  []string{auth.ScopeAdmin},
+		})
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("UpdateCPF", mock.Anything, customer).Return(nil)
+		accessLog, entries := recordedAccesses()
+
+		_, err := NewCorrectCPFUseCase(mockRepo, accessLog).Execute(ctx, customer.ID, "52998224725", "Typo at registration")
+
+		require.NoError(t, err)
+		assert.Empty(t, *entries)
+	})
+}
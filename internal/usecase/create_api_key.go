@@ -0,0 +1,56 @@
+package usecase
+
+import (
+	"context"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/repository"
+	"customer-service/pkg/errors"
+	"slices"
+	"time"
+)
+
+type CreateAPIKeyUseCase struct {
+	repo repository.APIKeyRepository
+}
+
+func NewCreateAPIKeyUseCase(repo repository.APIKeyRepository) *CreateAPIKeyUseCase {
+	return &CreateAPIKeyUseCase{repo: repo}
+}
+
+// Execute stores a new key and returns it with its secret, which cannot be
+// recovered afterwards.
+func (uc *CreateAPIKeyUseCase) Execute(ctx context.Context, name string, scopes []string, expiresAt time.Time) (*domain.APIKey, string, error) {
+	if err := auth.Authorize(ctx, auth.OpManageAPIKeys); err != nil {
+		return nil, "", err
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(auth.GrantableScopes, scope) {
+			appErr := errors.NewFieldValidationError(errors.FieldError{
+				Pointer: "/scopes",
+				Code:    "INVALID_SCOPE",
+				Message: "Scope " + scope + " cannot be granted to an API key",
+				Params:  map[string]string{"scope": scope},
+			})
+			appErr.Params = map[string]string{"scope": scope}
+			return nil, "", appErr
+		}
+	}
+
+	var createdBy string
+	if principal := auth.FromContext(ctx); principal != nil {
+		createdBy = principal.Subject
+	}
+
+	key, secret, err := domain.NewAPIKey(name, scopes, expiresAt, createdBy)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := uc.repo.Create(ctx, key); err != nil {
+		return nil, "", err
+	}
+
+	return key, secret, nil
+}
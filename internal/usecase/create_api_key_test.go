@@ -0,0 +1,130 @@
+package usecase
+
+import (
+	"context"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/pkg/errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockAPIKeyRepository is a mock implementation of APIKeyRepository
+type MockAPIKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) FindByID(ctx context.Context, id string) (*domain.APIKey, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) List(ctx context.Context) ([]*domain.APIKey, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func TestCreateAPIKeyUseCase_Execute(t *testing.T) {
+	adminCtx := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "admin@fiap.com",
+		Scopes:  []string{auth.ScopeAdmin},
+	})
+	expiresAt := time.Now().Add(30 * 24 * time.Hour)
+
+	t.Run("Creates the key", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+		mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.APIKey")).Return(nil)
+
+		uc := NewCreateAPIKeyUseCase(mockRepo)
+		key, secret, err := uc.Execute(adminCtx, "Nightly export", []string{auth.ScopeRead}, expiresAt)
+
+		require.NoError(t, err)
+		assert.Equal(t, "admin@fiap.com", key.CreatedBy)
+		_, keySecret, ok := domain.ParseAPIKey(secret)
+		require.True(t, ok)
+		assert.True(t, key.Matches(keySecret))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Customer scopes cannot be granted", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+
+		uc := NewCreateAPIKeyUseCase(mockRepo)
+		_, _, err := uc.Execute(adminCtx, "Nightly export", []string{auth.ScopeRead, auth.ScopeSelf}, expiresAt)
+
+		appErr := errors.From(err)
+		assert.Equal(t, "INVALID_SCOPE", appErr.Code)
+		assert.Equal(t, auth.ScopeSelf, appErr.Params["scope"])
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Invalid key is not stored", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+
+		uc := NewCreateAPIKeyUseCase(mockRepo)
+		_, _, err := uc.Execute(adminCtx, "", []string{auth.ScopeRead}, expiresAt)
+
+		assert.Equal(t, "NAME_EMPTY", errors.From(err).Code)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Only administrators create keys", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+		ctx := auth.WithPrincipal(context.Background(), &auth.Principal{
+			Subject: "order-service",
+			Scopes:  []string{auth.ScopeRead, auth.ScopeCreate},
+		})
+
+		uc := NewCreateAPIKeyUseCase(mockRepo)
+		_, _, err := uc.Execute(ctx, "Escalation", []string{auth.ScopeAdmin}, expiresAt)
+
+		assert.Equal(t, "FORBIDDEN", errors.From(err).Code)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestRevokeAPIKeyUseCase_Execute(t *testing.T) {
+	t.Run("Revokes the key", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+		mockRepo.On("Revoke", mock.Anything, "key-1", mock.AnythingOfType("time.Time")).Return(nil)
+		ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "admin", Scopes: []string{auth.ScopeAdmin}})
+
+		err := NewRevokeAPIKeyUseCase(mockRepo).Execute(ctx, "key-1")
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Internal calls cannot manage keys", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+
+		err := NewRevokeAPIKeyUseCase(mockRepo).Execute(context.Background(), "key-1")
+
+		assert.Equal(t, "FORBIDDEN", errors.From(err).Code)
+	})
+}
@@ -2,38 +2,100 @@ package usecase
 
 import (
 	"context"
+	"customer-service/internal/audit"
+	"customer-service/internal/auth"
 	"customer-service/internal/domain"
 	"customer-service/internal/repository"
 	"customer-service/pkg/errors"
+	stderrors "errors"
 )
 
+// maxCodeAttempts bounds how many short codes creation draws before giving
+// up. With 32^8 possible codes a second attempt is already rare.
+const maxCodeAttempts = 5
+
 type CreateCustomerUseCase struct {
-	repo repository.CustomerRepository
+	repo      repository.CustomerRepository
+	accessLog *audit.AccessLog
 }
 
-func NewCreateCustomerUseCase(repo repository.CustomerRepository) *CreateCustomerUseCase {
-	return &CreateCustomerUseCase{repo: repo}
+func NewCreateCustomerUseCase(repo repository.CustomerRepository, accessLog *audit.AccessLog) *CreateCustomerUseCase {
+	return &CreateCustomerUseCase{repo: repo, accessLog: accessLog}
 }
 
+// Execute creates the customer. Duplicate CPFs and emails are rejected by the
+// repository's unique indexes, so there is no check-then-insert race. A
+// colliding short code is replaced and the insert retried.
 func (uc *CreateCustomerUseCase) Execute(ctx context.Context, name, cpf, email string) (*domain.Customer, error) {
+	if err := auth.Authorize(ctx, auth.OpCreateCustomer); err != nil {
+		return nil, err
+	}
+
 	customer, err := domain.NewCustomer(name, cpf, email)
 	if err != nil {
 		return nil, err
 	}
 
-	// Check if customer already exists
-	existingCustomer, err := uc.repo.FindByCPFOrEmail(ctx, customer.CPF, customer.Email)
+	for attempt := 1; ; attempt++ {
+		err := uc.repo.Create(ctx, customer)
+		if err == nil {
+			uc.accessLog.RecordWrite(ctx, customer)
+			return customer, nil
+		}
+		if !stderrors.Is(err, repository.ErrCodeTaken) {
+			return nil, err
+		}
+		if attempt == maxCodeAttempts {
+			return nil, errors.WrapError(err, "Failed to find a free customer code")
+		}
+		if err := customer.RegenerateCode(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Replay returns the customer created by an earlier request, for answering
+// its retries. The caller only needs to be allowed to create customers, as
+// the original request did.
+func (uc *CreateCustomerUseCase) Replay(ctx context.Context, id string) (*domain.Customer, error) {
+	if err := auth.Authorize(ctx, auth.OpCreateCustomer); err != nil {
+		return nil, err
+	}
+
+	customer, err := uc.repo.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	if existingCustomer != nil {
-		return nil, errors.NewConflictError("Customer already exists.", "CUSTOMER_ALREADY_EXISTS")
+	if customer == nil {
+		return nil, errors.NewNotFoundError("Customer not found", "CUSTOMER_NOT_FOUND")
+	}
+
+	if err := uc.accessLog.Record(ctx, customer); err != nil {
+		return nil, err
+	}
+	return customer, nil
+}
+
+// DryRun runs every check Execute does and returns the customer that would
+// be created, without persisting it. With nothing written, the unique
+// indexes cannot reject a duplicate, so DryRun looks for one instead.
+func (uc *CreateCustomerUseCase) DryRun(ctx context.Context, name, cpf, email string) (*domain.Customer, error) {
+	if err := auth.Authorize(ctx, auth.OpCreateCustomer); err != nil {
+		return nil, err
+	}
+
+	customer, err := domain.NewCustomer(name, cpf, email)
+	if err != nil {
+		return nil, err
 	}
 
-	err = uc.repo.Create(ctx, customer)
+	conflicts, err := uc.repo.FindConflicts(ctx, customer.CPF, customer.Email)
 	if err != nil {
 		return nil, err
 	}
+	if fields := conflictFields(conflicts, customer.CPF, customer.Email, ""); len(fields) > 0 {
+		return nil, errors.NewFieldConflictError(fields...)
+	}
 
 	return customer, nil
 }
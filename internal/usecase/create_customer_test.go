@@ -2,8 +2,12 @@ package usecase
 
 import (
 	"context"
+	"customer-service/internal/audit"
+	"customer-service/internal/auth"
 	"customer-service/internal/domain"
+	"customer-service/internal/repository"
 	"customer-service/pkg/errors"
+	"github.com/stretchr/testify/require"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -27,6 +31,14 @@ func (m *MockCustomerRepository) FindByID(ctx context.Context, id string) (*doma
 	return args.Get(0).(*domain.Customer), args.Error(1)
 }
 
+func (m *MockCustomerRepository) FindByIDs(ctx context.Context, ids []string) ([]*domain.Customer, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Customer), args.Error(1)
+}
+
 func (m *MockCustomerRepository) FindByCPF(ctx context.Context, cpf string) (*domain.Customer, error) {
 	args := m.Called(ctx, cpf)
 	if args.Get(0) == nil {
@@ -35,19 +47,56 @@ func (m *MockCustomerRepository) FindByCPF(ctx context.Context, cpf string) (*do
 	return args.Get(0).(*domain.Customer), args.Error(1)
 }
 
-func (m *MockCustomerRepository) FindByCPFOrEmail(ctx context.Context, cpf, email string) (*domain.Customer, error) {
-	args := m.Called(ctx, cpf, email)
+func (m *MockCustomerRepository) FindByCode(ctx context.Context, code string) (*domain.Customer, error) {
+	args := m.Called(ctx, code)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*domain.Customer), args.Error(1)
 }
 
+func (m *MockCustomerRepository) GetVersionByCPF(ctx context.Context, cpf string) (*domain.CustomerVersion, error) {
+	args := m.Called(ctx, cpf)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.CustomerVersion), args.Error(1)
+}
+
+func (m *MockCustomerRepository) FindByCPFs(ctx context.Context, cpfs []string) ([]*domain.Customer, error) {
+	args := m.Called(ctx, cpfs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Customer), args.Error(1)
+}
+
+func (m *MockCustomerRepository) FindByIDsOrCPFs(ctx context.Context, ids, cpfs []string) ([]*domain.Customer, error) {
+	args := m.Called(ctx, ids, cpfs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Customer), args.Error(1)
+}
+
+func (m *MockCustomerRepository) FindConflicts(ctx context.Context, cpf, email string) ([]*domain.Customer, error) {
+	args := m.Called(ctx, cpf, email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Customer), args.Error(1)
+}
+
 func (m *MockCustomerRepository) Update(ctx context.Context, customer *domain.Customer) error {
 	args := m.Called(ctx, customer)
 	return args.Error(0)
 }
 
+func (m *MockCustomerRepository) UpdateCPF(ctx context.Context, customer *domain.Customer) error {
+	args := m.Called(ctx, customer)
+	return args.Error(0)
+}
+
 func (m *MockCustomerRepository) Delete(ctx context.Context, id string) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
@@ -74,25 +123,22 @@ func TestCreateCustomerUseCase_Execute(t *testing.T) {
 			cpf:          "11144477735",
 			email:        "john@example.com",
 			mockSetup: func(m *MockCustomerRepository) {
-				m.On("FindByCPFOrEmail", mock.Anything, mock.Anything, mock.Anything).
-					Return(nil, nil)
 				m.On("Create", mock.Anything, mock.Anything).
 					Return(nil)
 			},
 			expectError: false,
 		},
 		{
-			name:         "Customer already exists",
+			name:         "CPF already exists",
 			customerName: "John Doe",
 			cpf:          "11144477735",
 			email:        "john@example.com",
 			mockSetup: func(m *MockCustomerRepository) {
-				existingCustomer, _ := domain.NewCustomer("Jane Doe", "11144477735", "jane@example.com")
-				m.On("FindByCPFOrEmail", mock.Anything, mock.Anything, mock.Anything).
-					Return(existingCustomer, nil)
+				m.On("Create", mock.Anything, mock.Anything).
+					Return(errors.NewFieldConflictError(domain.ErrCPFAlreadyExists))
 			},
 			expectError:   true,
-			expectedError: "CUSTOMER_ALREADY_EXISTS",
+			expectedError: "CPF_ALREADY_EXISTS",
 		},
 		{
 			name:         "Invalid CPF",
@@ -110,25 +156,12 @@ func TestCreateCustomerUseCase_Execute(t *testing.T) {
 			mockSetup:    func(m *MockCustomerRepository) {},
 			expectError:  true,
 		},
-		{
-			name:         "FindByCPFOrEmail returns error",
-			customerName: "John Doe",
-			cpf:          "11144477735",
-			email:        "john@example.com",
-			mockSetup: func(m *MockCustomerRepository) {
-				m.On("FindByCPFOrEmail", mock.Anything, mock.Anything, mock.Anything).
-					Return(nil, errors.NewInternalError("database error"))
-			},
-			expectError: true,
-		},
 		{
 			name:         "Create returns error",
 			customerName: "John Doe",
 			cpf:          "11144477735",
 			email:        "john@example.com",
 			mockSetup: func(m *MockCustomerRepository) {
-				m.On("FindByCPFOrEmail", mock.Anything, mock.Anything, mock.Anything).
-					Return(nil, nil)
 				m.On("Create", mock.Anything, mock.Anything).
 					Return(errors.NewInternalError("create failed"))
 			},
@@ -141,7 +174,7 @@ func TestCreateCustomerUseCase_Execute(t *testing.T) {
 			mockRepo := new(MockCustomerRepository)
 			tt.mockSetup(mockRepo)
 
-			uc := NewCreateCustomerUseCase(mockRepo)
+			uc := NewCreateCustomerUseCase(mockRepo, nil)
 			customer, err := uc.Execute(context.Background(), tt.customerName, tt.cpf, tt.email)
 
 			if tt.expectError {
@@ -161,3 +194,152 @@ func TestCreateCustomerUseCase_Execute(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateCustomerUseCase_CodeCollision(t *testing.T) {
+	t.Run("Retries with a new code", func(t *testing.T) {
+		var codes []string
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("Create", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) { codes = append(codes, args.Get(1).(*domain.Customer).Code) }).
+			Return(repository.ErrCodeTaken).Once()
+		mockRepo.On("Create", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) { codes = append(codes, args.Get(1).(*domain.Customer).Code) }).
+			Return(nil).Once()
+
+		uc := NewCreateCustomerUseCase(mockRepo, nil)
+		customer, err := uc.Execute(context.Background(), "John Doe", "11144477735", "john@example.com")
+
+		assert.NoError(t, err)
+		assert.Len(t, codes, 2)
+		assert.NotEqual(t, codes[0], codes[1])
+		assert.Equal(t, codes[1], customer.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Gives up after maxCodeAttempts", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("Create", mock.Anything, mock.Anything).Return(repository.ErrCodeTaken)
+
+		uc := NewCreateCustomerUseCase(mockRepo, nil)
+		customer, err := uc.Execute(context.Background(), "John Doe", "11144477735", "john@example.com")
+
+		assert.Nil(t, customer)
+		assert.Equal(t, "INTERNAL_ERROR", errors.From(err).Code)
+		mockRepo.AssertNumberOfCalls(t, "Create", maxCodeAttempts)
+	})
+}
+
+func TestCreateCustomerUseCase_DryRun(t *testing.T) {
+	t.Run("Returns the would-be customer without creating it", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindConflicts", mock.Anything, "11144477735", "john@example.com").Return([]*domain.Customer{}, nil)
+
+		uc := NewCreateCustomerUseCase(mockRepo, nil)
+		customer, err := uc.DryRun(context.Background(), "John Doe", "111.444.777-35", "John@Example.com")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "11144477735", customer.CPF)
+		assert.Equal(t, "john@example.com", customer.Email)
+		mockRepo.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Reports every taken field", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		byCPF, _ := domain.NewCustomer("Jane Doe", "11144477735", "jane@example.com")
+		byEmail, _ := domain.NewCustomer("Joe Doe", "52998224725", "john@example.com")
+		mockRepo.On("FindConflicts", mock.Anything, "11144477735", "john@example.com").Return([]*domain.Customer{byCPF, byEmail}, nil)
+
+		uc := NewCreateCustomerUseCase(mockRepo, nil)
+		customer, err := uc.DryRun(context.Background(), "John Doe", "11144477735", "john@example.com")
+
+		assert.Nil(t, customer)
+		appErr := errors.From(err)
+		assert.Equal(t, 409, appErr.StatusCode)
+		assert.Equal(t, "CPF_ALREADY_EXISTS", appErr.Code)
+		assert.Equal(t, []errors.FieldError{domain.ErrCPFAlreadyExists, domain.ErrEmailAlreadyExists}, appErr.Fields)
+	})
+
+	t.Run("Repository error", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindConflicts", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.NewInternalError("database error"))
+
+		uc := NewCreateCustomerUseCase(mockRepo, nil)
+		customer, err := uc.DryRun(context.Background(), "John Doe", "11144477735", "john@example.com")
+
+		assert.Error(t, err)
+		assert.Nil(t, customer)
+	})
+}
+
+func TestCreateCustomerUseCase_Replay(t *testing.T) {
+	customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+
+	t.Run("Returns the customer created before", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+
+		uc := NewCreateCustomerUseCase(mockRepo, nil)
+		ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "kiosk", Scopes: []string{auth.ScopeCreate}})
+		replayed, err := uc.Replay(ctx, customer.ID)
+
+		require.NoError(t, err)
+		assert.Equal(t, customer, replayed)
+	})
+
+	t.Run("Customer deleted since", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(nil, nil)
+
+		uc := NewCreateCustomerUseCase(mockRepo, nil)
+		replayed, err := uc.Replay(context.Background(), customer.ID)
+
+		assert.Nil(t, replayed)
+		assert.Equal(t, "CUSTOMER_NOT_FOUND", errors.From(err).Code)
+	})
+}
+
+func TestCreateCustomerUseCase_AccessLog(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "backoffice",
+		Scopes:  []string{auth.ScopeCreate, auth.ScopeReadPII},
+	})
+
+	t.Run("Records the customer created", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+		accessLog, entries := recordedAccesses()
+
+		customer, err := NewCreateCustomerUseCase(mockRepo, accessLog).Execute(ctx, "John Doe", "11144477735", "john@example.com")
+
+		require.NoError(t, err)
+		require.Len(t, *entries, 1)
+		assert.Equal(t, "backoffice", (*entries)[0].Actor)
+		assert.Equal(t, customer.ID, (*entries)[0].CustomerID)
+		assert.Equal(t, []string{"cpf", "email"}, (*entries)[0].Fields)
+	})
+
+	t.Run("Failed creation records nothing", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("Create", mock.Anything, mock.Anything).Return(errors.NewFieldConflictError(domain.ErrCPFAlreadyExists))
+		accessLog, entries := recordedAccesses()
+
+		_, err := NewCreateCustomerUseCase(mockRepo, accessLog).Execute(ctx, "John Doe", "11144477735", "john@example.com")
+
+		assert.Error(t, err)
+		assert.Empty(t, *entries)
+	})
+
+	t.Run("Log failure keeps the stored customer", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+		logRepo := new(MockPIIAccessRepository)
+		logRepo.On("Append", mock.Anything, mock.Anything).Return(errors.NewInternalError("database error"))
+
+		customer, err := NewCreateCustomerUseCase(mockRepo, audit.NewAccessLog(logRepo)).Execute(ctx, "John Doe", "11144477735", "john@example.com")
+
+		require.NoError(t, err)
+		assert.Equal(t, "11144477735", customer.CPF)
+		logRepo.AssertExpectations(t)
+	})
+}
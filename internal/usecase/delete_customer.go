@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"context"
+	"customer-service/internal/auth"
 	"customer-service/internal/repository"
 	"customer-service/pkg/errors"
 	"fmt"
@@ -16,6 +17,10 @@ func NewDeleteCustomerUseCase(repo repository.CustomerRepository) *DeleteCustome
 }
 
 func (uc *DeleteCustomerUseCase) Execute(ctx context.Context, id string) error {
+	if err := auth.AuthorizeCustomer(ctx, auth.OpDeleteCustomer, id); err != nil {
+		return err
+	}
+
 	customer, err := uc.repo.FindByID(ctx, id)
 	if err != nil {
 		return err
@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"context"
+	"customer-service/internal/auth"
 	"customer-service/internal/domain"
 	"customer-service/pkg/errors"
 	"testing"
@@ -69,7 +70,10 @@ func TestDeleteCustomerUseCase_Execute(t *testing.T) {
 			tt.mockSetup(mockRepo)
 
 			uc := NewDeleteCustomerUseCase(mockRepo)
-			err := uc.Execute(context.Background(), tt.customerID)
+			err := uc.Execute(auth.WithPrincipal(context.Background(), &auth.Principal{
+				Subject: "backoffice",
+				Scopes:  []string{auth.ScopeDelete},
+			}), tt.customerID)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -86,3 +90,27 @@ func TestDeleteCustomerUseCase_Execute(t *testing.T) {
 		})
 	}
 }
+
+func TestDeleteCustomerUseCase_RequiresCaller(t *testing.T) {
+	mockRepo := new(MockCustomerRepository)
+
+	err := NewDeleteCustomerUseCase(mockRepo).Execute(context.Background(), "123")
+
+	assert.Equal(t, "FORBIDDEN", errors.From(err).Code)
+	mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestDeleteCustomerUseCase_RequiresDeleteScope(t *testing.T) {
+	mockRepo := new(MockCustomerRepository)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "order-service",
+		Scopes:  []string{auth.ScopeRead},
+	})
+
+	uc := NewDeleteCustomerUseCase(mockRepo)
+	err := uc.Execute(ctx, "123")
+
+	assert.Equal(t, "FORBIDDEN", errors.From(err).Code)
+	mockRepo.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
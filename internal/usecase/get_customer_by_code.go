@@ -0,0 +1,51 @@
+package usecase
+
+import (
+	"context"
+	"customer-service/internal/audit"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/repository"
+	"customer-service/pkg/errors"
+	"customer-service/pkg/shortcode"
+	"fmt"
+)
+
+type GetCustomerByCodeUseCase struct {
+	repo      repository.CustomerRepository
+	accessLog *audit.AccessLog
+}
+
+func NewGetCustomerByCodeUseCase(repo repository.CustomerRepository, accessLog *audit.AccessLog) *GetCustomerByCodeUseCase {
+	return &GetCustomerByCodeUseCase{repo: repo, accessLog: accessLog}
+}
+
+// Execute finds the customer by short code. The code may be typed in any
+// case, grouped with hyphens or with I, L and O in place of 1 and 0.
+func (uc *GetCustomerByCodeUseCase) Execute(ctx context.Context, code string) (*domain.Customer, error) {
+	if err := auth.Authorize(ctx, auth.OpReadCustomer); err != nil {
+		return nil, err
+	}
+
+	normalized, ok := shortcode.Normalize(code)
+	if !ok {
+		return nil, errors.NewValidationError("Invalid customer code", "INVALID_CODE")
+	}
+
+	customer, err := uc.repo.FindByCode(ctx, normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	if customer == nil {
+		return nil, errors.NewNotFoundError(
+			fmt.Sprintf("Customer with code %s not found", normalized),
+			"CUSTOMER_NOT_FOUND",
+		)
+	}
+
+	if err := uc.accessLog.Record(ctx, customer); err != nil {
+		return nil, err
+	}
+	return customer, nil
+}
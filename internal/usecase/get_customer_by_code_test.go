@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"context"
+	"customer-service/internal/domain"
+	"customer-service/pkg/errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetCustomerByCodeUseCase_Execute(t *testing.T) {
+	tests := []struct {
+		name          string
+		code          string
+		mockSetup     func(*MockCustomerRepository)
+		expectError   bool
+		expectedError string
+	}{
+		{
+			name: "Successfully get customer by code",
+			code: "7K3M9QXD",
+			mockSetup: func(m *MockCustomerRepository) {
+				customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+				m.On("FindByCode", mock.Anything, "7K3M9QXD").
+					Return(customer, nil)
+			},
+			expectError: false,
+		},
+		{
+			name: "Code as read aloud",
+			code: "7k3m-9qxd",
+			mockSetup: func(m *MockCustomerRepository) {
+				customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+				m.On("FindByCode", mock.Anything, "7K3M9QXD").
+					Return(customer, nil)
+			},
+			expectError: false,
+		},
+		{
+			name: "Customer not found",
+			code: "7K3M9QXD",
+			mockSetup: func(m *MockCustomerRepository) {
+				m.On("FindByCode", mock.Anything, "7K3M9QXD").
+					Return(nil, nil)
+			},
+			expectError:   true,
+			expectedError: "CUSTOMER_NOT_FOUND",
+		},
+		{
+			name:          "Invalid code",
+			code:          "7K3M9QXU",
+			mockSetup:     func(m *MockCustomerRepository) {},
+			expectError:   true,
+			expectedError: "INVALID_CODE",
+		},
+		{
+			name: "Repository error",
+			code: "7K3M9QXD",
+			mockSetup: func(m *MockCustomerRepository) {
+				m.On("FindByCode", mock.Anything, "7K3M9QXD").
+					Return(nil, errors.NewInternalError("find failed"))
+			},
+			expectError:   true,
+			expectedError: "INTERNAL_ERROR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockCustomerRepository)
+			tt.mockSetup(mockRepo)
+
+			uc := NewGetCustomerByCodeUseCase(mockRepo, nil)
+			customer, err := uc.Execute(context.Background(), tt.code)
+
+			if tt.expectError {
+				assert.Nil(t, customer)
+				assert.Equal(t, tt.expectedError, errors.From(err).Code)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, customer)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
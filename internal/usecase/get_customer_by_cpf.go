@@ -2,6 +2,9 @@ package usecase
 
 import (
 	"context"
+	"customer-service/internal/abuse"
+	"customer-service/internal/audit"
+	"customer-service/internal/auth"
 	"customer-service/internal/domain"
 	"customer-service/internal/repository"
 	"customer-service/pkg/errors"
@@ -10,16 +13,25 @@ import (
 )
 
 type GetCustomerByCPFUseCase struct {
-	repo repository.CustomerRepository
+	repo      repository.CustomerRepository
+	accessLog *audit.AccessLog
 }
 
-func NewGetCustomerByCPFUseCase(repo repository.CustomerRepository) *GetCustomerByCPFUseCase {
-	return &GetCustomerByCPFUseCase{repo: repo}
+func NewGetCustomerByCPFUseCase(repo repository.CustomerRepository, accessLog *audit.AccessLog) *GetCustomerByCPFUseCase {
+	return &GetCustomerByCPFUseCase{repo: repo, accessLog: accessLog}
 }
 
 func (uc *GetCustomerByCPFUseCase) Execute(ctx context.Context, cpf string) (*domain.Customer, error) {
+	if err := auth.Authorize(ctx, auth.OpReadCustomer); err != nil {
+		return nil, err
+	}
+
+	if err := abuse.Spend(ctx, 1); err != nil {
+		return nil, err
+	}
 	cleanCPF := validator.CleanCPF(cpf)
 	if !validator.IsValidCPF(cleanCPF) {
+		abuse.Observe(ctx, cleanCPF, abuse.OutcomeInvalid)
 		return nil, errors.NewValidationError("Invalid CPF", "INVALID_CPF")
 	}
 
@@ -27,6 +39,7 @@ func (uc *GetCustomerByCPFUseCase) Execute(ctx context.Context, cpf string) (*do
 	if err != nil {
 		return nil, err
 	}
+	abuse.Observe(ctx, cleanCPF, lookupOutcome(customer != nil))
 
 	if customer == nil {
 		return nil, errors.NewNotFoundError(
@@ -35,5 +48,49 @@ func (uc *GetCustomerByCPFUseCase) Execute(ctx context.Context, cpf string) (*do
 		)
 	}
 
+	if err := uc.accessLog.Record(ctx, customer); err != nil {
+		return nil, err
+	}
 	return customer, nil
 }
+
+// Version returns the current revision of the customer without loading the
+// whole document, for answering conditional requests.
+func (uc *GetCustomerByCPFUseCase) Version(ctx context.Context, cpf string) (*domain.CustomerVersion, error) {
+	if err := auth.Authorize(ctx, auth.OpReadCustomer); err != nil {
+		return nil, err
+	}
+
+	if err := abuse.Spend(ctx, 1); err != nil {
+		return nil, err
+	}
+	cleanCPF := validator.CleanCPF(cpf)
+	if !validator.IsValidCPF(cleanCPF) {
+		abuse.Observe(ctx, cleanCPF, abuse.OutcomeInvalid)
+		return nil, errors.NewValidationError("Invalid CPF", "INVALID_CPF")
+	}
+
+	version, err := uc.repo.GetVersionByCPF(ctx, cleanCPF)
+	if err != nil {
+		return nil, err
+	}
+	abuse.Observe(ctx, cleanCPF, lookupOutcome(version != nil))
+
+	if version == nil {
+		return nil, errors.NewNotFoundError(
+			fmt.Sprintf("Customer with CPF %s not found", cpf),
+			"CUSTOMER_NOT_FOUND",
+		)
+	}
+
+	return version, nil
+}
+
+// lookupOutcome tells the enumeration detector whether a CPF lookup found a
+// customer.
+func lookupOutcome(found bool) abuse.Outcome {
+	if found {
+		return abuse.OutcomeFound
+	}
+	return abuse.OutcomeNotFound
+}
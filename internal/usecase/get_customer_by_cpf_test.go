@@ -2,12 +2,16 @@ package usecase
 
 import (
 	"context"
+	"customer-service/internal/audit"
+	"customer-service/internal/auth"
 	"customer-service/internal/domain"
 	"customer-service/pkg/errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetCustomerByCPFUseCase_Execute(t *testing.T) {
@@ -61,7 +65,7 @@ func TestGetCustomerByCPFUseCase_Execute(t *testing.T) {
 			mockRepo := new(MockCustomerRepository)
 			tt.mockSetup(mockRepo)
 
-			uc := NewGetCustomerByCPFUseCase(mockRepo)
+			uc := NewGetCustomerByCPFUseCase(mockRepo, nil)
 			customer, err := uc.Execute(context.Background(), tt.cpf)
 
 			if tt.expectError {
@@ -81,3 +85,111 @@ func TestGetCustomerByCPFUseCase_Execute(t *testing.T) {
 		})
 	}
 }
+
+func TestGetCustomerByCPFUseCase_AccessLog(t *testing.T) {
+	ctx := audit.WithPurpose(auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "order-service",
+		Scopes:  []string{auth.ScopeRead, auth.ScopeReadPII},
+	}), "order-fulfillment")
+
+	t.Run("Records the customer returned", func(t *testing.T) {
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+		accessLog, entries := recordedAccesses()
+
+		_, err := NewGetCustomerByCPFUseCase(mockRepo, accessLog).Execute(ctx, "11144477735")
+
+		require.NoError(t, err)
+		require.Len(t, *entries, 1)
+		entry := (*entries)[0]
+		assert.Equal(t, "order-service", entry.Actor)
+		assert.Equal(t, "order-fulfillment", entry.Purpose)
+		assert.Equal(t, customer.ID, entry.CustomerID)
+		assert.Equal(t, []string{"cpf", "email"}, entry.Fields)
+	})
+
+	t.Run("Nothing returned, nothing recorded", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(nil, nil)
+		accessLog, entries := recordedAccesses()
+
+		_, err := NewGetCustomerByCPFUseCase(mockRepo, accessLog).Execute(ctx, "11144477735")
+
+		assert.Error(t, err)
+		assert.Empty(t, *entries)
+	})
+
+	t.Run("Data is not returned unless the access was recorded", func(t *testing.T) {
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+		logRepo := new(MockPIIAccessRepository)
+		logRepo.On("Append", mock.Anything, mock.Anything).Return(errors.NewInternalError("database error"))
+
+		result, err := NewGetCustomerByCPFUseCase(mockRepo, audit.NewAccessLog(logRepo)).Execute(ctx, "11144477735")
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+	})
+}
+
+func TestGetCustomerByCPFUseCase_Version(t *testing.T) {
+	tests := []struct {
+		name          string
+		cpf           string
+		mockSetup     func(*MockCustomerRepository)
+		expectError   bool
+		expectedError string
+	}{
+		{
+			name: "Successfully get version with formatted CPF",
+			cpf:  "111.444.777-35",
+			mockSetup: func(m *MockCustomerRepository) {
+				m.On("GetVersionByCPF", mock.Anything, "11144477735").
+					Return(&domain.CustomerVersion{ID: "123", UpdatedAt: time.Now()}, nil)
+			},
+			expectError: false,
+		},
+		{
+			name: "Customer not found",
+			cpf:  "11144477735",
+			mockSetup: func(m *MockCustomerRepository) {
+				m.On("GetVersionByCPF", mock.Anything, "11144477735").
+					Return(nil, nil)
+			},
+			expectError:   true,
+			expectedError: "CUSTOMER_NOT_FOUND",
+		},
+		{
+			name:          "Invalid CPF",
+			cpf:           "invalid",
+			mockSetup:     func(m *MockCustomerRepository) {},
+			expectError:   true,
+			expectedError: "INVALID_CPF",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockCustomerRepository)
+			tt.mockSetup(mockRepo)
+
+			uc := NewGetCustomerByCPFUseCase(mockRepo, nil)
+			version, err := uc.Version(context.Background(), tt.cpf)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Nil(t, version)
+				appErr, ok := err.(*errors.AppError)
+				assert.True(t, ok)
+				assert.Equal(t, tt.expectedError, appErr.Code)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "123", version.ID)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
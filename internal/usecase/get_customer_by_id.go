@@ -0,0 +1,43 @@
+package usecase
+
+import (
+	"context"
+	"customer-service/internal/audit"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/repository"
+	"customer-service/pkg/errors"
+	"fmt"
+)
+
+type GetCustomerByIDUseCase struct {
+	repo      repository.CustomerRepository
+	accessLog *audit.AccessLog
+}
+
+func NewGetCustomerByIDUseCase(repo repository.CustomerRepository, accessLog *audit.AccessLog) *GetCustomerByIDUseCase {
+	return &GetCustomerByIDUseCase{repo: repo, accessLog: accessLog}
+}
+
+func (uc *GetCustomerByIDUseCase) Execute(ctx context.Context, id string) (*domain.Customer, error) {
+	if err := auth.AuthorizeCustomer(ctx, auth.OpReadCustomer, id); err != nil {
+		return nil, err
+	}
+
+	customer, err := uc.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if customer == nil {
+		return nil, errors.NewNotFoundError(
+			fmt.Sprintf("Customer with id %s not found", id),
+			"CUSTOMER_NOT_FOUND",
+		)
+	}
+
+	if err := uc.accessLog.Record(ctx, customer); err != nil {
+		return nil, err
+	}
+	return customer, nil
+}
@@ -0,0 +1,76 @@
+package usecase
+
+import (
+	"context"
+	"customer-service/internal/domain"
+	"customer-service/pkg/errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetCustomerByIDUseCase_Execute(t *testing.T) {
+	tests := []struct {
+		name          string
+		customerID    string
+		mockSetup     func(*MockCustomerRepository)
+		expectError   bool
+		expectedError string
+	}{
+		{
+			name:       "Successfully get customer by ID",
+			customerID: "123",
+			mockSetup: func(m *MockCustomerRepository) {
+				customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+				m.On("FindByID", mock.Anything, "123").
+					Return(customer, nil)
+			},
+			expectError: false,
+		},
+		{
+			name:       "Customer not found",
+			customerID: "999",
+			mockSetup: func(m *MockCustomerRepository) {
+				m.On("FindByID", mock.Anything, "999").
+					Return(nil, nil)
+			},
+			expectError:   true,
+			expectedError: "CUSTOMER_NOT_FOUND",
+		},
+		{
+			name:       "FindByID returns error",
+			customerID: "123",
+			mockSetup: func(m *MockCustomerRepository) {
+				m.On("FindByID", mock.Anything, "123").
+					Return(nil, errors.NewInternalError("database error"))
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockCustomerRepository)
+			tt.mockSetup(mockRepo)
+
+			uc := NewGetCustomerByIDUseCase(mockRepo, nil)
+			customer, err := uc.Execute(context.Background(), tt.customerID)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Nil(t, customer)
+				if tt.expectedError != "" {
+					appErr, ok := err.(*errors.AppError)
+					assert.True(t, ok)
+					assert.Equal(t, tt.expectedError, appErr.Code)
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, customer)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
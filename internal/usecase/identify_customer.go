@@ -0,0 +1,60 @@
+package usecase
+
+import (
+	"context"
+	"customer-service/internal/audit"
+	"customer-service/internal/auth"
+	"customer-service/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// anonymousSubjectPrefix starts the subject of anonymous tokens, so it can
+// never be mistaken for a customer ID.
+const anonymousSubjectPrefix = "anonymous:"
+
+// Identity is the token issued for a kiosk session. CustomerID is empty for
+// anonymous sessions.
+type Identity struct {
+	Token      *auth.Token
+	CustomerID string
+}
+
+type IdentifyCustomerUseCase struct {
+	getByCPFUseCase *GetCustomerByCPFUseCase
+	issuer          *auth.TokenIssuer
+}
+
+func NewIdentifyCustomerUseCase(getByCPFUC *GetCustomerByCPFUseCase, issuer *auth.TokenIssuer) *IdentifyCustomerUseCase {
+	return &IdentifyCustomerUseCase{getByCPFUseCase: getByCPFUC, issuer: issuer}
+}
+
+// Execute issues a token for the customer with the given CPF, whose subject
+// is the customer ID. Knowing a CPF proves nothing about who typed it in, so
+// the token only carries ScopeIdentified, never ScopeSelf. Without a CPF it
+// issues an anonymous token instead.
+func (uc *IdentifyCustomerUseCase) Execute(ctx context.Context, cpf string) (*Identity, error) {
+	if err := auth.Authorize(ctx, auth.OpIdentifyCustomer); err != nil {
+		return nil, err
+	}
+
+	if cpf == "" {
+		token, err := uc.issuer.Issue(anonymousSubjectPrefix+uuid.New().String(), []string{auth.ScopeAnonymous})
+		if err != nil {
+			return nil, errors.WrapError(err, "failed to sign anonymous token")
+		}
+		return &Identity{Token: token}, nil
+	}
+
+	// The kiosk only gets a token back, never the customer's data
+	customer, err := uc.getByCPFUseCase.Execute(audit.WithDisclosedFields(ctx), cpf)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := uc.issuer.Issue(customer.ID, []string{auth.ScopeIdentified})
+	if err != nil {
+		return nil, errors.WrapError(err, "failed to sign customer token")
+	}
+	return &Identity{Token: token, CustomerID: customer.ID}, nil
+}
@@ -0,0 +1,104 @@
+package usecase
+
+import (
+	"context"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/pkg/errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTokenIssuer(t *testing.T) *auth.TokenIssuer {
+	key, err := auth.GenerateSigningKey()
+	require.NoError(t, err)
+	return auth.NewTokenIssuer(auth.NewKeyRing(time.Hour, key), "customer-service", "kiosk", 15*time.Minute)
+}
+
+func TestIdentifyCustomerUseCase_Execute(t *testing.T) {
+	kioskCtx := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "kiosk-7",
+		Scopes:  []string{auth.ScopeIdentify, auth.ScopeRead},
+	})
+	issuer := newTestTokenIssuer(t)
+
+	t.Run("Known CPF gets a customer token", func(t *testing.T) {
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+
+		uc := NewIdentifyCustomerUseCase(NewGetCustomerByCPFUseCase(mockRepo, nil), issuer)
+		identity, err := uc.Execute(kioskCtx, "111.444.777-35")
+
+		require.NoError(t, err)
+		assert.Equal(t, customer.ID, identity.CustomerID)
+		principal, err := issuer.Verifier().Verify(context.Background(), identity.Token.Value)
+		require.NoError(t, err)
+		assert.Equal(t, customer.ID, principal.Subject)
+		assert.Equal(t, []string{auth.ScopeIdentified}, principal.Scopes)
+	})
+
+	t.Run("Identifying a customer does not record an access", func(t *testing.T) {
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(customer, nil)
+		accessLog, entries := recordedAccesses()
+
+		uc := NewIdentifyCustomerUseCase(NewGetCustomerByCPFUseCase(mockRepo, accessLog), issuer)
+		_, err := uc.Execute(kioskCtx, "11144477735")
+
+		require.NoError(t, err)
+		assert.Empty(t, *entries, "the kiosk never sees the customer's data")
+	})
+
+	t.Run("No CPF gets an anonymous token", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+
+		uc := NewIdentifyCustomerUseCase(NewGetCustomerByCPFUseCase(mockRepo, nil), issuer)
+		identity, err := uc.Execute(kioskCtx, "")
+
+		require.NoError(t, err)
+		assert.Empty(t, identity.CustomerID)
+		principal, err := issuer.Verifier().Verify(context.Background(), identity.Token.Value)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(principal.Subject, "anonymous:"))
+		assert.Equal(t, []string{auth.ScopeAnonymous}, principal.Scopes)
+		mockRepo.AssertNotCalled(t, "FindByCPF", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unknown CPF", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByCPF", mock.Anything, "11144477735").Return(nil, nil)
+
+		uc := NewIdentifyCustomerUseCase(NewGetCustomerByCPFUseCase(mockRepo, nil), issuer)
+		_, err := uc.Execute(kioskCtx, "11144477735")
+
+		assert.Equal(t, "CUSTOMER_NOT_FOUND", errors.From(err).Code)
+	})
+
+	t.Run("Invalid CPF", func(t *testing.T) {
+		uc := NewIdentifyCustomerUseCase(NewGetCustomerByCPFUseCase(new(MockCustomerRepository), nil), issuer)
+		_, err := uc.Execute(kioskCtx, "12345678900")
+
+		assert.Equal(t, "INVALID_CPF", errors.From(err).Code)
+	})
+
+	t.Run("Requires the identify scope", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		ctx := auth.WithPrincipal(context.Background(), &auth.Principal{
+			Subject: "order-service",
+			Scopes:  []string{auth.ScopeRead},
+		})
+
+		uc := NewIdentifyCustomerUseCase(NewGetCustomerByCPFUseCase(mockRepo, nil), issuer)
+		_, err := uc.Execute(ctx, "11144477735")
+
+		assert.Equal(t, "FORBIDDEN", errors.From(err).Code)
+		mockRepo.AssertNotCalled(t, "FindByCPF", mock.Anything, mock.Anything)
+	})
+}
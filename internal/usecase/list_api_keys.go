@@ -0,0 +1,26 @@
+package usecase
+
+import (
+	"context"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/repository"
+)
+
+type ListAPIKeysUseCase struct {
+	repo repository.APIKeyRepository
+}
+
+func NewListAPIKeysUseCase(repo repository.APIKeyRepository) *ListAPIKeysUseCase {
+	return &ListAPIKeysUseCase{repo: repo}
+}
+
+// Execute returns every key, newest first, including revoked and expired
+// ones.
+func (uc *ListAPIKeysUseCase) Execute(ctx context.Context) ([]*domain.APIKey, error) {
+	if err := auth.Authorize(ctx, auth.OpManageAPIKeys); err != nil {
+		return nil, err
+	}
+
+	return uc.repo.List(ctx)
+}
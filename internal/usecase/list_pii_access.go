@@ -0,0 +1,48 @@
+package usecase
+
+import (
+	"context"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/repository"
+	"customer-service/pkg/errors"
+	"fmt"
+	"strconv"
+)
+
+// MaxPIIAccessEntries bounds how many access log entries a single query
+// returns.
+const MaxPIIAccessEntries = 500
+
+type ListPIIAccessUseCase struct {
+	repo repository.PIIAccessRepository
+}
+
+func NewListPIIAccessUseCase(repo repository.PIIAccessRepository) *ListPIIAccessUseCase {
+	return &ListPIIAccessUseCase{repo: repo}
+}
+
+// Execute returns the newest entries about a customer or of an actor;
+// exactly one of them must be given.
+func (uc *ListPIIAccessUseCase) Execute(ctx context.Context, customerID, actor string, limit int) ([]*domain.PIIAccess, error) {
+	if err := auth.Authorize(ctx, auth.OpReadAccessLog); err != nil {
+		return nil, err
+	}
+
+	if (customerID == "") == (actor == "") {
+		return nil, errors.NewValidationError("Filter by either customerId or actor", "INVALID_ACCESS_LOG_FILTER")
+	}
+	if limit < 1 || limit > MaxPIIAccessEntries {
+		err := errors.NewValidationError(
+			fmt.Sprintf("limit must be between 1 and %d", MaxPIIAccessEntries),
+			"INVALID_LIMIT",
+		)
+		err.Params = map[string]string{"max": strconv.Itoa(MaxPIIAccessEntries)}
+		return nil, err
+	}
+
+	if customerID != "" {
+		return uc.repo.FindByCustomer(ctx, customerID, limit)
+	}
+	return uc.repo.FindByActor(ctx, actor, limit)
+}
@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"context"
+	"customer-service/internal/audit"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/pkg/errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockPIIAccessRepository is a mock implementation of PIIAccessRepository
+type MockPIIAccessRepository struct {
+	mock.Mock
+}
+
+func (m *MockPIIAccessRepository) Append(ctx context.Context, entries []*domain.PIIAccess) error {
+	args := m.Called(ctx, entries)
+	return args.Error(0)
+}
+
+func (m *MockPIIAccessRepository) FindByCustomer(ctx context.Context, customerID string, limit int) ([]*domain.PIIAccess, error) {
+	args := m.Called(ctx, customerID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.PIIAccess), args.Error(1)
+}
+
+func (m *MockPIIAccessRepository) FindByActor(ctx context.Context, actor string, limit int) ([]*domain.PIIAccess, error) {
+	args := m.Called(ctx, actor, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.PIIAccess), args.Error(1)
+}
+
+// recordedAccesses returns an access log whose entries are collected in the
+// returned slice.
+func recordedAccesses() (*audit.AccessLog, *[]*domain.PIIAccess) {
+	var entries []*domain.PIIAccess
+	repo := new(MockPIIAccessRepository)
+	repo.On("Append", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		entries = append(entries, args.Get(1).([]*domain.PIIAccess)...)
+	}).Return(nil)
+	return audit.NewAccessLog(repo), &entries
+}
+
+func TestListPIIAccessUseCase_Execute(t *testing.T) {
+	adminCtx := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "dpo@fiap.com",
+		Scopes:  []string{auth.ScopeAdmin},
+	})
+	entries := []*domain.PIIAccess{
+		domain.NewPIIAccess("order-service", "order-fulfillment", "customer-1", []string{"cpf"}, time.Now()),
+	}
+
+	t.Run("By customer", func(t *testing.T) {
+		mockRepo := new(MockPIIAccessRepository)
+		mockRepo.On("FindByCustomer", mock.Anything, "customer-1", 100).Return(entries, nil)
+
+		result, err := NewListPIIAccessUseCase(mockRepo).Execute(adminCtx, "customer-1", "", 100)
+
+		require.NoError(t, err)
+		assert.Equal(t, entries, result)
+	})
+
+	t.Run("By actor", func(t *testing.T) {
+		mockRepo := new(MockPIIAccessRepository)
+		mockRepo.On("FindByActor", mock.Anything, "order-service", 10).Return(entries, nil)
+
+		result, err := NewListPIIAccessUseCase(mockRepo).Execute(adminCtx, "", "order-service", 10)
+
+		require.NoError(t, err)
+		assert.Equal(t, entries, result)
+	})
+
+	t.Run("Needs exactly one filter", func(t *testing.T) {
+		mockRepo := new(MockPIIAccessRepository)
+		uc := NewListPIIAccessUseCase(mockRepo)
+
+		_, err := uc.Execute(adminCtx, "", "", 100)
+		assert.Equal(t, "INVALID_ACCESS_LOG_FILTER", errors.From(err).Code)
+
+		_, err = uc.Execute(adminCtx, "customer-1", "order-service", 100)
+		assert.Equal(t, "INVALID_ACCESS_LOG_FILTER", errors.From(err).Code)
+		mockRepo.AssertNotCalled(t, "FindByCustomer")
+	})
+
+	t.Run("Limit out of range", func(t *testing.T) {
+		uc := NewListPIIAccessUseCase(new(MockPIIAccessRepository))
+
+		for _, limit := range []int{0, MaxPIIAccessEntries + 1} {
+			_, err := uc.Execute(adminCtx, "customer-1", "", limit)
+			appErr := errors.From(err)
+			assert.Equal(t, "INVALID_LIMIT", appErr.Code)
+			assert.Equal(t, "500", appErr.Params["max"])
+		}
+	})
+
+	t.Run("Only administrators", func(t *testing.T) {
+		ctx := auth.WithPrincipal(context.Background(), &auth.Principal{
+			Subject: "order-service",
+			Scopes:  []string{auth.ScopeRead, auth.ScopeReadPII},
+		})
+
+		_, err := NewListPIIAccessUseCase(new(MockPIIAccessRepository)).Execute(ctx, "customer-1", "", 100)
+
+		assert.Equal(t, "FORBIDDEN", errors.From(err).Code)
+	})
+}
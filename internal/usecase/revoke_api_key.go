@@ -0,0 +1,26 @@
+package usecase
+
+import (
+	"context"
+	"customer-service/internal/auth"
+	"customer-service/internal/repository"
+	"time"
+)
+
+type RevokeAPIKeyUseCase struct {
+	repo repository.APIKeyRepository
+}
+
+func NewRevokeAPIKeyUseCase(repo repository.APIKeyRepository) *RevokeAPIKeyUseCase {
+	return &RevokeAPIKeyUseCase{repo: repo}
+}
+
+// Execute revokes the key at once. The record is kept so the key still shows
+// up, revoked, in the list.
+func (uc *RevokeAPIKeyUseCase) Execute(ctx context.Context, id string) error {
+	if err := auth.Authorize(ctx, auth.OpManageAPIKeys); err != nil {
+		return err
+	}
+
+	return uc.repo.Revoke(ctx, id, time.Now())
+}
@@ -2,20 +2,90 @@ package usecase
 
 import (
 	"context"
+	"customer-service/internal/audit"
+	"customer-service/internal/auth"
 	"customer-service/internal/domain"
+	"customer-service/internal/mailer"
 	"customer-service/internal/repository"
 	"customer-service/pkg/errors"
+	"log"
 )
 
 type UpdateCustomerUseCase struct {
-	repo repository.CustomerRepository
+	repo      repository.CustomerRepository
+	mailer    mailer.Mailer
+	accessLog *audit.AccessLog
 }
 
-func NewUpdateCustomerUseCase(repo repository.CustomerRepository) *UpdateCustomerUseCase {
-	return &UpdateCustomerUseCase{repo: repo}
+// NewUpdateCustomerUseCase sends the email change messages through mailer. A
+// nil mailer turns email changes off, while other updates keep working.
+func NewUpdateCustomerUseCase(repo repository.CustomerRepository, mailer mailer.Mailer, accessLog *audit.AccessLog) *UpdateCustomerUseCase {
+	return &UpdateCustomerUseCase{repo: repo, mailer: mailer, accessLog: accessLog}
 }
 
+// Execute updates the customer. A new email is kept as the pending email: a
+// verification token is sent to it and a notice to the current address,
+// which stays in place until ConfirmEmailUseCase is run with the token.
+//
+// The token is sent before the update is stored, so that a mailer failure
+// leaves the customer untouched instead of storing a token nobody received.
+// The notice is only sent once the change is stored, and failing to send it
+// does not undo the change.
 func (uc *UpdateCustomerUseCase) Execute(ctx context.Context, id string, name, email *string) (*domain.Customer, error) {
+	customer, err := uc.prepare(ctx, id, name, email)
+	if err != nil {
+		return nil, err
+	}
+
+	emailChanged := customer.PendingEmail != "" && email != nil
+	if emailChanged {
+		token, err := customer.IssueEmailVerification()
+		if err != nil {
+			return nil, err
+		}
+		if err := uc.mailer.SendEmailVerification(ctx, customer.PendingEmail, customer.Name, token); err != nil {
+			return nil, errors.WrapError(err, "Failed to send email verification")
+		}
+	}
+
+	err = uc.repo.Update(ctx, customer)
+	if err != nil {
+		return nil, err
+	}
+
+	if emailChanged {
+		if err := uc.mailer.SendEmailChangeNotice(ctx, customer.Email, customer.Name, customer.PendingEmail); err != nil {
+			log.Printf("Failed to send email change notice to customer %s: %s", customer.ID, errors.From(err).Internal())
+		}
+	}
+
+	uc.accessLog.RecordWrite(ctx, customer)
+	return customer, nil
+}
+
+// DryRun returns the customer as Execute would leave it, without persisting
+// it or sending any email.
+func (uc *UpdateCustomerUseCase) DryRun(ctx context.Context, id string, name, email *string) (*domain.Customer, error) {
+	customer, err := uc.prepare(ctx, id, name, email)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.accessLog.Record(ctx, customer); err != nil {
+		return nil, err
+	}
+	return customer, nil
+}
+
+// prepare applies the update in memory. A pending email is only written to
+// Email on confirmation, where the unique index has the final say, so its
+// availability is checked up front to avoid verifying an address that is
+// already taken.
+func (uc *UpdateCustomerUseCase) prepare(ctx context.Context, id string, name, email *string) (*domain.Customer, error) {
+	if err := auth.AuthorizeCustomer(ctx, auth.OpUpdateCustomer, id); err != nil {
+		return nil, err
+	}
+
 	customer, err := uc.repo.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
@@ -30,10 +100,20 @@ func (uc *UpdateCustomerUseCase) Execute(ctx context.Context, id string, name, e
 		return nil, err
 	}
 
-	err = uc.repo.Update(ctx, customer)
+	if customer.PendingEmail == "" || email == nil {
+		return customer, nil
+	}
+	if uc.mailer == nil {
+		return nil, errors.NewUnprocessableError("Email changes are disabled", "EMAIL_CHANGE_DISABLED")
+	}
+
+	conflicts, err := uc.repo.FindConflicts(ctx, "", customer.PendingEmail)
 	if err != nil {
 		return nil, err
 	}
+	if fields := conflictFields(conflicts, "", customer.PendingEmail, customer.ID); len(fields) > 0 {
+		return nil, errors.NewFieldConflictError(fields...)
+	}
 
 	return customer, nil
 }
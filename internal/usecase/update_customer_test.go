@@ -2,14 +2,30 @@ package usecase
 
 import (
 	"context"
+	"customer-service/internal/auth"
 	"customer-service/internal/domain"
 	"customer-service/pkg/errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
+type MockMailer struct {
+	mock.Mock
+}
+
+func (m *MockMailer) SendEmailVerification(ctx context.Context, to, name, token string) error {
+	args := m.Called(ctx, to, name, token)
+	return args.Error(0)
+}
+
+func (m *MockMailer) SendEmailChangeNotice(ctx context.Context, to, name, newEmail string) error {
+	args := m.Called(ctx, to, name, newEmail)
+	return args.Error(0)
+}
+
 func TestUpdateCustomerUseCase_Execute(t *testing.T) {
 	newName := "Jane Doe"
 	newEmail := "jane@example.com"
@@ -33,6 +49,8 @@ func TestUpdateCustomerUseCase_Execute(t *testing.T) {
 				customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
 				m.On("FindByID", mock.Anything, "123").
 					Return(customer, nil)
+				m.On("FindConflicts", mock.Anything, "", newEmail).
+					Return([]*domain.Customer{}, nil)
 				m.On("Update", mock.Anything, mock.Anything).
 					Return(nil)
 			},
@@ -59,6 +77,8 @@ func TestUpdateCustomerUseCase_Execute(t *testing.T) {
 				customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
 				m.On("FindByID", mock.Anything, "123").
 					Return(customer, nil)
+				m.On("FindConflicts", mock.Anything, "", newEmail).
+					Return([]*domain.Customer{}, nil)
 				m.On("Update", mock.Anything, mock.Anything).
 					Return(nil)
 			},
@@ -117,7 +137,11 @@ func TestUpdateCustomerUseCase_Execute(t *testing.T) {
 			mockRepo := new(MockCustomerRepository)
 			tt.mockSetup(mockRepo)
 
-			uc := NewUpdateCustomerUseCase(mockRepo)
+			mockMailer := new(MockMailer)
+			mockMailer.On("SendEmailVerification", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+			mockMailer.On("SendEmailChangeNotice", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			uc := NewUpdateCustomerUseCase(mockRepo, mockMailer, nil)
 			customer, err := uc.Execute(context.Background(), tt.customerID, tt.updateName, tt.updateEmail)
 
 			if tt.expectError {
@@ -135,7 +159,8 @@ func TestUpdateCustomerUseCase_Execute(t *testing.T) {
 					assert.Equal(t, *tt.updateName, customer.Name)
 				}
 				if tt.updateEmail != nil {
-					assert.Equal(t, *tt.updateEmail, customer.Email)
+					assert.Equal(t, "john@example.com", customer.Email)
+					assert.Equal(t, *tt.updateEmail, customer.PendingEmail)
 				}
 			}
 
@@ -143,3 +168,243 @@ func TestUpdateCustomerUseCase_Execute(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateCustomerUseCase_DryRun(t *testing.T) {
+	newName := "Jane Doe"
+	newEmail := "jane@example.com"
+
+	t.Run("Returns the would-be customer without updating it", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("FindConflicts", mock.Anything, "", newEmail).Return([]*domain.Customer{}, nil)
+
+		uc := NewUpdateCustomerUseCase(mockRepo, new(MockMailer), nil)
+		result, err := uc.DryRun(context.Background(), customer.ID, &newName, &newEmail)
+
+		assert.NoError(t, err)
+		assert.Equal(t, newName, result.Name)
+		assert.Equal(t, newEmail, result.PendingEmail)
+		assert.Nil(t, result.EmailVerification)
+		mockRepo.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Skips the availability check when the email is unchanged", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+
+		uc := NewUpdateCustomerUseCase(mockRepo, new(MockMailer), nil)
+		_, err := uc.DryRun(context.Background(), customer.ID, &newName, nil)
+
+		assert.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "FindConflicts", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Keeping its own email is not a conflict", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		sameEmail := "john@example.com"
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+
+		uc := NewUpdateCustomerUseCase(mockRepo, new(MockMailer), nil)
+		_, err := uc.DryRun(context.Background(), customer.ID, nil, &sameEmail)
+
+		assert.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "FindConflicts", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Email taken by another customer", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		other, _ := domain.NewCustomer("Jane Doe", "52998224725", newEmail)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("FindConflicts", mock.Anything, "", newEmail).Return([]*domain.Customer{other}, nil)
+
+		uc := NewUpdateCustomerUseCase(mockRepo, new(MockMailer), nil)
+		result, err := uc.DryRun(context.Background(), customer.ID, nil, &newEmail)
+
+		assert.Nil(t, result)
+		appErr := errors.From(err)
+		assert.Equal(t, 409, appErr.StatusCode)
+		assert.Equal(t, "EMAIL_ALREADY_EXISTS", appErr.Code)
+	})
+
+	t.Run("Customer not found", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByID", mock.Anything, "missing").Return(nil, nil)
+
+		uc := NewUpdateCustomerUseCase(mockRepo, new(MockMailer), nil)
+		_, err := uc.DryRun(context.Background(), "missing", &newName, nil)
+
+		assert.Equal(t, "CUSTOMER_NOT_FOUND", errors.From(err).Code)
+	})
+}
+
+func TestUpdateCustomerUseCase_EmailChange(t *testing.T) {
+	newEmail := "jane@example.com"
+
+	t.Run("Sends a token to the new address and a notice to the current one", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		mockMailer := new(MockMailer)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("FindConflicts", mock.Anything, "", newEmail).Return([]*domain.Customer{}, nil)
+		mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(c *domain.Customer) bool {
+			return c.Email == "john@example.com" && c.PendingEmail == newEmail && c.EmailVerification != nil
+		})).Return(nil)
+
+		var token string
+		mockMailer.On("SendEmailVerification", mock.Anything, newEmail, "John Doe", mock.Anything).
+			Run(func(args mock.Arguments) { token = args.String(3) }).Return(nil)
+		mockMailer.On("SendEmailChangeNotice", mock.Anything, "john@example.com", "John Doe", newEmail).Return(nil)
+
+		uc := NewUpdateCustomerUseCase(mockRepo, mockMailer, nil)
+		result, err := uc.Execute(context.Background(), customer.ID, nil, &newEmail)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "john@example.com", result.Email)
+		assert.NotEmpty(t, token)
+		assert.NotContains(t, result.EmailVerification.TokenHash, token)
+		assert.NoError(t, result.ConfirmEmail(token))
+		mockRepo.AssertExpectations(t)
+		mockMailer.AssertExpectations(t)
+	})
+
+	t.Run("Name-only update sends nothing", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		mockMailer := new(MockMailer)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		customer.PendingEmail = newEmail
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("Update", mock.Anything, mock.Anything).Return(nil)
+
+		name := "Johnny"
+		uc := NewUpdateCustomerUseCase(mockRepo, mockMailer, nil)
+		result, err := uc.Execute(context.Background(), customer.ID, &name, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, newEmail, result.PendingEmail)
+		mockMailer.AssertNotCalled(t, "SendEmailVerification", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Verification failure leaves the customer untouched", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		mockMailer := new(MockMailer)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("FindConflicts", mock.Anything, "", newEmail).Return([]*domain.Customer{}, nil)
+		mockMailer.On("SendEmailVerification", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(assert.AnError)
+
+		uc := NewUpdateCustomerUseCase(mockRepo, mockMailer, nil)
+		result, err := uc.Execute(context.Background(), customer.ID, nil, &newEmail)
+
+		assert.Nil(t, result)
+		assert.Equal(t, "INTERNAL_ERROR", errors.From(err).Code)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		mockMailer.AssertNotCalled(t, "SendEmailChangeNotice", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Notice failure keeps the stored change", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		mockMailer := new(MockMailer)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("FindConflicts", mock.Anything, "", newEmail).Return([]*domain.Customer{}, nil)
+		mockRepo.On("Update", mock.Anything, mock.Anything).Return(nil)
+		mockMailer.On("SendEmailVerification", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockMailer.On("SendEmailChangeNotice", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(assert.AnError)
+
+		uc := NewUpdateCustomerUseCase(mockRepo, mockMailer, nil)
+		result, err := uc.Execute(context.Background(), customer.ID, nil, &newEmail)
+
+		require.NoError(t, err)
+		assert.Equal(t, newEmail, result.PendingEmail)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Storage failure sends no notice", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		mockMailer := new(MockMailer)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("FindConflicts", mock.Anything, "", newEmail).Return([]*domain.Customer{}, nil)
+		mockRepo.On("Update", mock.Anything, mock.Anything).Return(errors.NewInternalError("database error"))
+		mockMailer.On("SendEmailVerification", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		uc := NewUpdateCustomerUseCase(mockRepo, mockMailer, nil)
+		_, err := uc.Execute(context.Background(), customer.ID, nil, &newEmail)
+
+		assert.Error(t, err)
+		mockMailer.AssertNotCalled(t, "SendEmailChangeNotice", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Without a mailer only the email cannot change", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("Update", mock.Anything, mock.Anything).Return(nil)
+
+		uc := NewUpdateCustomerUseCase(mockRepo, nil, nil)
+		_, err := uc.Execute(context.Background(), customer.ID, nil, &newEmail)
+
+		assert.Equal(t, "EMAIL_CHANGE_DISABLED", errors.From(err).Code)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+
+		name := "Johnny"
+		result, err := uc.Execute(context.Background(), customer.ID, &name, nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, "Johnny", result.Name)
+	})
+}
+
+func TestUpdateCustomerUseCase_AccessLog(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "backoffice",
+		Scopes:  []string{auth.ScopeUpdate, auth.ScopeReadPII},
+	})
+	name := "Jane Doe"
+
+	t.Run("Records the customer updated", func(t *testing.T) {
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("Update", mock.Anything, customer).Return(nil)
+		accessLog, entries := recordedAccesses()
+
+		_, err := NewUpdateCustomerUseCase(mockRepo, new(MockMailer), accessLog).Execute(ctx, customer.ID, &name, nil)
+
+		require.NoError(t, err)
+		require.Len(t, *entries, 1)
+		assert.Equal(t, customer.ID, (*entries)[0].CustomerID)
+		assert.Equal(t, []string{"cpf", "email"}, (*entries)[0].Fields)
+	})
+
+	t.Run("Records the customer of a dry run", func(t *testing.T) {
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		accessLog, entries := recordedAccesses()
+
+		_, err := NewUpdateCustomerUseCase(mockRepo, new(MockMailer), accessLog).DryRun(ctx, customer.ID, &name, nil)
+
+		require.NoError(t, err)
+		require.Len(t, *entries, 1)
+		assert.Equal(t, customer.ID, (*entries)[0].CustomerID)
+	})
+
+	t.Run("Failed update records nothing", func(t *testing.T) {
+		customer, _ := domain.NewCustomer("John Doe", "11144477735", "john@example.com")
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindByID", mock.Anything, customer.ID).Return(customer, nil)
+		mockRepo.On("Update", mock.Anything, customer).Return(errors.NewInternalError("database error"))
+		accessLog, entries := recordedAccesses()
+
+		_, err := NewUpdateCustomerUseCase(mockRepo, new(MockMailer), accessLog).Execute(ctx, customer.ID, &name, nil)
+
+		assert.Error(t, err)
+		assert.Empty(t, *entries)
+	})
+}
@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"context"
+	"customer-service/internal/abuse"
+	"customer-service/internal/auth"
+	"customer-service/internal/domain"
+	"customer-service/internal/repository"
+	"customer-service/pkg/errors"
+	"customer-service/pkg/validator"
+	stderrors "errors"
+	"strings"
+)
+
+// ValidationResult lists every problem found in a candidate customer. It is
+// valid when there are none.
+type ValidationResult struct {
+	Valid  bool
+	Errors []errors.FieldError
+}
+
+// ValidateCustomerUseCase checks a candidate customer without creating it, so
+// that forms can report problems before they are submitted.
+type ValidateCustomerUseCase struct {
+	repo repository.CustomerRepository
+}
+
+func NewValidateCustomerUseCase(repo repository.CustomerRepository) *ValidateCustomerUseCase {
+	return &ValidateCustomerUseCase{repo: repo}
+}
+
+// Execute applies the domain rules and then checks whether the CPF and email
+// are still available. Availability is only checked for well-formed values.
+func (uc *ValidateCustomerUseCase) Execute(ctx context.Context, name, cpf, email string) (*ValidationResult, error) {
+	if err := auth.Authorize(ctx, auth.OpValidateCustomer); err != nil {
+		return nil, err
+	}
+
+	var fields []errors.FieldError
+	if _, err := domain.NewCustomer(name, cpf, email); err != nil {
+		var appErr *errors.AppError
+		if !stderrors.As(err, &appErr) || len(appErr.Fields) == 0 {
+			return nil, err
+		}
+		fields = appErr.Fields
+	}
+
+	cleanCPF := validator.CleanCPF(cpf)
+	if hasField(fields, "/cpf") {
+		cleanCPF = ""
+	}
+	cleanEmail := strings.ToLower(strings.TrimSpace(email))
+	if hasField(fields, "/email") {
+		cleanEmail = ""
+	}
+
+	if strings.TrimSpace(cpf) != "" {
+		if err := abuse.Spend(ctx, 1); err != nil {
+			return nil, err
+		}
+	}
+	conflicts, err := uc.repo.FindConflicts(ctx, cleanCPF, cleanEmail)
+	if err != nil {
+		return nil, err
+	}
+	conflicting := conflictFields(conflicts, cleanCPF, cleanEmail, "")
+	fields = append(fields, conflicting...)
+
+	// Whether the CPF is taken tells whether it belongs to a customer
+	switch {
+	case strings.TrimSpace(cpf) == "":
+	case cleanCPF == "":
+		abuse.Observe(ctx, validator.CleanCPF(cpf), abuse.OutcomeInvalid)
+	case hasField(conflicting, "/cpf"):
+		abuse.Observe(ctx, cleanCPF, abuse.OutcomeFound)
+	default:
+		abuse.Observe(ctx, cleanCPF, abuse.OutcomeAvailable)
+	}
+
+	return &ValidationResult{Valid: len(fields) == 0, Errors: fields}, nil
+}
+
+// conflictFields reports which of cpf and email are held by a customer other
+// than excludeID.
+func conflictFields(conflicts []*domain.Customer, cpf, email, excludeID string) []errors.FieldError {
+	var cpfTaken, emailTaken bool
+	for _, c := range conflicts {
+		if c.ID == excludeID {
+			continue
+		}
+		if cpf != "" && c.CPF == cpf {
+			cpfTaken = true
+		}
+		if email != "" && c.Email == email {
+			emailTaken = true
+		}
+	}
+
+	var fields []errors.FieldError
+	if cpfTaken {
+		fields = append(fields, domain.ErrCPFAlreadyExists)
+	}
+	if emailTaken {
+		fields = append(fields, domain.ErrEmailAlreadyExists)
+	}
+	return fields
+}
+
+func hasField(fields []errors.FieldError, pointer string) bool {
+	for _, f := range fields {
+		if f.Pointer == pointer {
+			return true
+		}
+	}
+	return false
+}
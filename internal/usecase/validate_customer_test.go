@@ -0,0 +1,71 @@
+package usecase
+
+import (
+	"context"
+	"customer-service/internal/domain"
+	"customer-service/pkg/errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func fieldCodes(fields []errors.FieldError) []string {
+	codes := make([]string, 0, len(fields))
+	for _, f := range fields {
+		codes = append(codes, f.Code)
+	}
+	return codes
+}
+
+func TestValidateCustomerUseCase_Execute(t *testing.T) {
+	t.Run("Valid and available", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindConflicts", mock.Anything, "11144477735", "john@example.com").Return([]*domain.Customer{}, nil)
+
+		uc := NewValidateCustomerUseCase(mockRepo)
+		result, err := uc.Execute(context.Background(), "John Doe", "111.444.777-35", " John@Example.com ")
+
+		assert.NoError(t, err)
+		assert.True(t, result.Valid)
+		assert.Empty(t, result.Errors)
+	})
+
+	t.Run("Reports each taken field", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		byCPF, _ := domain.NewCustomer("Jane Doe", "11144477735", "jane@example.com")
+		byEmail, _ := domain.NewCustomer("Joe Doe", "52998224725", "john@example.com")
+		mockRepo.On("FindConflicts", mock.Anything, "11144477735", "john@example.com").Return([]*domain.Customer{byCPF, byEmail}, nil)
+
+		uc := NewValidateCustomerUseCase(mockRepo)
+		result, err := uc.Execute(context.Background(), "John Doe", "11144477735", "john@example.com")
+
+		assert.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, []string{"CPF_ALREADY_EXISTS", "EMAIL_ALREADY_EXISTS"}, fieldCodes(result.Errors))
+	})
+
+	t.Run("Combines rule violations with availability", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		byEmail, _ := domain.NewCustomer("Joe Doe", "52998224725", "john@example.com")
+		mockRepo.On("FindConflicts", mock.Anything, "", "john@example.com").Return([]*domain.Customer{byEmail}, nil)
+
+		uc := NewValidateCustomerUseCase(mockRepo)
+		result, err := uc.Execute(context.Background(), "", "invalid", "john@example.com")
+
+		assert.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, []string{"NAME_EMPTY", "INVALID_CPF", "EMAIL_ALREADY_EXISTS"}, fieldCodes(result.Errors))
+	})
+
+	t.Run("Repository error", func(t *testing.T) {
+		mockRepo := new(MockCustomerRepository)
+		mockRepo.On("FindConflicts", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.NewInternalError("database error"))
+
+		uc := NewValidateCustomerUseCase(mockRepo)
+		result, err := uc.Execute(context.Background(), "John Doe", "11144477735", "john@example.com")
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+	})
+}
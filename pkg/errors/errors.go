@@ -1,30 +1,131 @@
 package errors
 
-import "fmt"
+import (
+	stderrors "errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+)
 
+// FieldError describes one invalid field. Pointer is a JSON pointer
+// (RFC 6901) into the request body, e.g. "/cpf".
+type FieldError struct {
+	Pointer string
+	Code    string
+	Message string
+	Params  map[string]string
+}
+
+// AppError carries a stable Code for clients and an English Message.
+// Params hold the values interpolated into translated messages.
+//
+// Message is the only text that may reach a client. Detail and Cause describe
+// what went wrong internally and Caller records where the error was created;
+// they are meant for the logs only (see Internal).
 type AppError struct {
 	Message    string
 	StatusCode int
 	Code       string
+	Fields     []FieldError
+	Params     map[string]string
+
+	Detail string
+	Cause  error
+	Caller string
 }
 
+// Error returns the public message, so an AppError is always safe to render.
 func (e *AppError) Error() string {
 	return e.Message
 }
 
+// Unwrap exposes the cause to errors.Is and errors.As.
+func (e *AppError) Unwrap() error {
+	return e.Cause
+}
+
+// Internal describes the error with its detail, cause and call site for
+// logging. It must never be sent to clients.
+func (e *AppError) Internal() string {
+	s := e.Code + ": " + e.Message
+	if e.Detail != "" {
+		s += ": " + e.Detail
+	}
+	if e.Cause != nil {
+		s += ": " + e.Cause.Error()
+	}
+	if e.Caller != "" {
+		s += " (at " + e.Caller + ")"
+	}
+	return s
+}
+
+// From returns the AppError in err's chain. Any other error becomes an
+// internal error that keeps err as its cause.
+func From(err error) *AppError {
+	var appErr *AppError
+	if stderrors.As(err, &appErr) {
+		return appErr
+	}
+	return &AppError{
+		Message:    "Internal server error",
+		StatusCode: 500,
+		Code:       "INTERNAL_ERROR",
+		Cause:      err,
+		Caller:     caller(2),
+	}
+}
+
 func NewValidationError(message, code string) *AppError {
 	return &AppError{
 		Message:    message,
 		StatusCode: 400,
 		Code:       code,
+		Caller:     caller(2),
 	}
 }
 
+// NewFieldValidationError reports every invalid field at once. The first
+// field sets Code and Message so clients reading only those keep working.
+func NewFieldValidationError(fields ...FieldError) *AppError {
+	err := &AppError{
+		Message:    "Validation failed",
+		StatusCode: 400,
+		Code:       "VALIDATION_FAILED",
+		Fields:     fields,
+		Caller:     caller(2),
+	}
+	if len(fields) > 0 {
+		err.Message = fields[0].Message
+		err.Code = fields[0].Code
+	}
+	return err
+}
+
 func NewNotFoundError(message, code string) *AppError {
 	return &AppError{
 		Message:    message,
 		StatusCode: 404,
 		Code:       code,
+		Caller:     caller(2),
+	}
+}
+
+func NewUnauthorizedError(message, code string) *AppError {
+	return &AppError{
+		Message:    message,
+		StatusCode: 401,
+		Code:       code,
+		Caller:     caller(2),
+	}
+}
+
+func NewForbiddenError(message, code string) *AppError {
+	return &AppError{
+		Message:    message,
+		StatusCode: 403,
+		Code:       code,
+		Caller:     caller(2),
 	}
 }
 
@@ -33,6 +134,43 @@ func NewConflictError(message, code string) *AppError {
 		Message:    message,
 		StatusCode: 409,
 		Code:       code,
+		Caller:     caller(2),
+	}
+}
+
+// NewFieldConflictError reports the fields that collide with an existing
+// resource. Like NewFieldValidationError, the first field sets Code and
+// Message.
+func NewFieldConflictError(fields ...FieldError) *AppError {
+	err := &AppError{
+		Message:    "Resource already exists",
+		StatusCode: 409,
+		Code:       "CONFLICT",
+		Fields:     fields,
+		Caller:     caller(2),
+	}
+	if len(fields) > 0 {
+		err.Message = fields[0].Message
+		err.Code = fields[0].Code
+	}
+	return err
+}
+
+func NewUnprocessableError(message, code string) *AppError {
+	return &AppError{
+		Message:    message,
+		StatusCode: 422,
+		Code:       code,
+		Caller:     caller(2),
+	}
+}
+
+func NewTooManyRequestsError(message, code string) *AppError {
+	return &AppError{
+		Message:    message,
+		StatusCode: 429,
+		Code:       code,
+		Caller:     caller(2),
 	}
 }
 
@@ -41,13 +179,29 @@ func NewInternalError(message string) *AppError {
 		Message:    message,
 		StatusCode: 500,
 		Code:       "INTERNAL_ERROR",
+		Caller:     caller(2),
 	}
 }
 
+// WrapError turns an unexpected failure into an internal error. message and
+// err are kept as the internal detail and cause; clients only ever see the
+// generic public message.
 func WrapError(err error, message string) *AppError {
 	return &AppError{
-		Message:    fmt.Sprintf("%s: %v", message, err),
+		Message:    "Internal server error",
 		StatusCode: 500,
 		Code:       "INTERNAL_ERROR",
+		Detail:     message,
+		Cause:      err,
+		Caller:     caller(2),
+	}
+}
+
+// caller returns "dir/file.go:line" for the frame skip levels above it.
+func caller(skip int) string {
+	_, file, line, ok := runtime.Caller(skip)
+	if !ok {
+		return ""
 	}
+	return fmt.Sprintf("%s:%d", filepath.Join(filepath.Base(filepath.Dir(file)), filepath.Base(file)), line)
 }
@@ -1,6 +1,7 @@
 package errors
 
 import (
+	stderrors "errors"
 	"fmt"
 	"testing"
 
@@ -26,6 +27,70 @@ func TestNewValidationError(t *testing.T) {
 	assert.Equal(t, "INVALID_INPUT", err.Code)
 }
 
+func TestNewFieldValidationError(t *testing.T) {
+	t.Run("First field sets code and message", func(t *testing.T) {
+		err := NewFieldValidationError(
+			FieldError{Pointer: "/cpf", Code: "INVALID_CPF", Message: "Invalid CPF"},
+			FieldError{Pointer: "/email", Code: "INVALID_EMAIL", Message: "Invalid Email"},
+		)
+
+		assert.Equal(t, 400, err.StatusCode)
+		assert.Equal(t, "INVALID_CPF", err.Code)
+		assert.Equal(t, "Invalid CPF", err.Message)
+		assert.Len(t, err.Fields, 2)
+		assert.Equal(t, "/email", err.Fields[1].Pointer)
+	})
+
+	t.Run("Without fields", func(t *testing.T) {
+		err := NewFieldValidationError()
+
+		assert.Equal(t, 400, err.StatusCode)
+		assert.Equal(t, "VALIDATION_FAILED", err.Code)
+	})
+}
+
+func TestNewFieldConflictError(t *testing.T) {
+	t.Run("First field sets code and message", func(t *testing.T) {
+		err := NewFieldConflictError(FieldError{Pointer: "/email", Code: "EMAIL_ALREADY_EXISTS", Message: "Email is already registered"})
+
+		assert.Equal(t, 409, err.StatusCode)
+		assert.Equal(t, "EMAIL_ALREADY_EXISTS", err.Code)
+		assert.Equal(t, "Email is already registered", err.Message)
+		assert.Len(t, err.Fields, 1)
+	})
+
+	t.Run("Without fields", func(t *testing.T) {
+		err := NewFieldConflictError()
+
+		assert.Equal(t, 409, err.StatusCode)
+		assert.Equal(t, "CONFLICT", err.Code)
+	})
+}
+
+func TestNewUnauthorizedError(t *testing.T) {
+	err := NewUnauthorizedError("Invalid access token", "INVALID_TOKEN")
+
+	assert.Equal(t, "Invalid access token", err.Message)
+	assert.Equal(t, 401, err.StatusCode)
+	assert.Equal(t, "INVALID_TOKEN", err.Code)
+}
+
+func TestNewForbiddenError(t *testing.T) {
+	err := NewForbiddenError("Not allowed", "FORBIDDEN")
+
+	assert.Equal(t, "Not allowed", err.Message)
+	assert.Equal(t, 403, err.StatusCode)
+	assert.Equal(t, "FORBIDDEN", err.Code)
+}
+
+func TestNewTooManyRequestsError(t *testing.T) {
+	err := NewTooManyRequestsError("Too many requests", "RATE_LIMITED")
+
+	assert.Equal(t, "Too many requests", err.Message)
+	assert.Equal(t, 429, err.StatusCode)
+	assert.Equal(t, "RATE_LIMITED", err.Code)
+}
+
 func TestNewNotFoundError(t *testing.T) {
 	err := NewNotFoundError("Resource not found", "NOT_FOUND")
 
@@ -44,6 +109,15 @@ func TestNewConflictError(t *testing.T) {
 	assert.Equal(t, "ALREADY_EXISTS", err.Code)
 }
 
+func TestNewUnprocessableError(t *testing.T) {
+	err := NewUnprocessableError("Request cannot be processed", "UNPROCESSABLE")
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "Request cannot be processed", err.Message)
+	assert.Equal(t, 422, err.StatusCode)
+	assert.Equal(t, "UNPROCESSABLE", err.Code)
+}
+
 func TestNewInternalError(t *testing.T) {
 	err := NewInternalError("Internal server error")
 
@@ -58,8 +132,45 @@ func TestWrapError(t *testing.T) {
 	wrappedErr := WrapError(originalErr, "Failed to process")
 
 	assert.NotNil(t, wrappedErr)
-	assert.Contains(t, wrappedErr.Message, "Failed to process")
-	assert.Contains(t, wrappedErr.Message, "original error")
+	assert.Equal(t, "Internal server error", wrappedErr.Message)
+	assert.NotContains(t, wrappedErr.Error(), "original error")
+	assert.Equal(t, "Failed to process", wrappedErr.Detail)
 	assert.Equal(t, 500, wrappedErr.StatusCode)
 	assert.Equal(t, "INTERNAL_ERROR", wrappedErr.Code)
+	assert.True(t, stderrors.Is(wrappedErr, originalErr))
+	assert.Contains(t, wrappedErr.Caller, "errors/errors_test.go:")
+}
+
+func TestAppError_Internal(t *testing.T) {
+	wrappedErr := WrapError(fmt.Errorf("connection refused"), "Failed to find customer")
+
+	internal := wrappedErr.Internal()
+
+	assert.Contains(t, internal, "INTERNAL_ERROR")
+	assert.Contains(t, internal, "Failed to find customer")
+	assert.Contains(t, internal, "connection refused")
+	assert.Contains(t, internal, "(at errors/errors_test.go:")
+}
+
+func TestFrom(t *testing.T) {
+	t.Run("Finds AppError in the chain", func(t *testing.T) {
+		appErr := NewNotFoundError("Customer not found", "CUSTOMER_NOT_FOUND")
+
+		found := From(fmt.Errorf("lookup: %w", appErr))
+
+		assert.Same(t, appErr, found)
+
+		var target *AppError
+		assert.True(t, stderrors.As(fmt.Errorf("lookup: %w", appErr), &target))
+	})
+
+	t.Run("Wraps other errors as internal", func(t *testing.T) {
+		cause := fmt.Errorf("boom")
+
+		appErr := From(cause)
+
+		assert.Equal(t, 500, appErr.StatusCode)
+		assert.Equal(t, "Internal server error", appErr.Message)
+		assert.Same(t, cause, appErr.Cause)
+	})
 }
@@ -0,0 +1,220 @@
+// Package fieldcrypt encrypts individual document fields with envelope
+// encryption: values are sealed with AES-256-GCM under a random data key,
+// and the data key is stored wrapped by a master key. Exact-match lookups
+// go through blind indexes, keyed HMACs of the plaintext.
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeySize is the size of master, index and data keys: 256 bits.
+const KeySize = 32
+
+// ErrUnknownMasterKey is returned when a data key was wrapped by a master key
+// that is not configured, e.g. one retired too early.
+var ErrUnknownMasterKey = errors.New("data key wrapped by an unknown master key")
+
+// WrappedKey is a data key sealed by the master key named KeyID.
+type WrappedKey struct {
+	KeyID      string
+	Ciphertext []byte
+}
+
+// Encryptor wraps data keys and computes blind indexes.
+type Encryptor struct {
+	masters  []masterKey // active first
+	indexKey []byte
+}
+
+type masterKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// New returns an Encryptor wrapping new data keys with the first master key.
+// The other master keys only unwrap data keys wrapped before a rotation. The
+// index key must never change, or existing blind indexes stop matching.
+func New(indexKey []byte, masterKeys ...[]byte) (*Encryptor, error) {
+	if len(masterKeys) == 0 {
+		return nil, errors.New("at least one master key is required")
+	}
+	if len(indexKey) != KeySize {
+		return nil, fmt.Errorf("index key must be %d bytes", KeySize)
+	}
+
+	e := &Encryptor{indexKey: indexKey}
+	for _, raw := range masterKeys {
+		aead, err := newAEAD(raw)
+		if err != nil {
+			return nil, fmt.Errorf("master key: %w", err)
+		}
+		e.masters = append(e.masters, masterKey{id: keyID(raw), aead: aead})
+	}
+	return e, nil
+}
+
+// NewDataKey generates a data key, wrapped by the active master key.
+func (e *Encryptor) NewDataKey() (*DataKey, error) {
+	raw := make([]byte, KeySize)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	master := e.masters[0]
+	sealed, err := seal(master.aead, raw, []byte(master.id))
+	if err != nil {
+		return nil, err
+	}
+	return &DataKey{aead: aead, wrapped: WrappedKey{KeyID: master.id, Ciphertext: sealed}}, nil
+}
+
+// OpenDataKey unwraps a data key stored with a document.
+func (e *Encryptor) OpenDataKey(wrapped WrappedKey) (*DataKey, error) {
+	for _, master := range e.masters {
+		if master.id != wrapped.KeyID {
+			continue
+		}
+		raw, err := open(master.aead, wrapped.Ciphertext, []byte(master.id))
+		if err != nil {
+			return nil, fmt.Errorf("unwrap data key: %w", err)
+		}
+		aead, err := newAEAD(raw)
+		if err != nil {
+			return nil, err
+		}
+		return &DataKey{aead: aead, wrapped: wrapped}, nil
+	}
+	return nil, ErrUnknownMasterKey
+}
+
+// BlindIndex returns a deterministic, non-reversible token for value, to
+// look it up and enforce uniqueness without storing it. field keeps equal
+// values of different fields from sharing a token.
+func (e *Encryptor) BlindIndex(field, value string) string {
+	mac := hmac.New(sha256.New, e.indexKey)
+	mac.Write([]byte(field))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// DataKey seals the fields of one document.
+type DataKey struct {
+	aead    cipher.AEAD
+	wrapped WrappedKey
+}
+
+// Wrapped returns the key as it is stored with the document.
+func (k *DataKey) Wrapped() WrappedKey {
+	return k.wrapped
+}
+
+// Encrypt seals value. aad binds the ciphertext to where it is stored, e.g.
+// the document ID and field, so it cannot be moved to another document.
+func (k *DataKey) Encrypt(value, aad string) (string, error) {
+	sealed, err := seal(k.aead, []byte(value), []byte(aad))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value sealed by Encrypt with the same aad.
+func (k *DataKey) Decrypt(ciphertext, aad string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("decrypt: %w", err)
+	}
+	plain, err := open(k.aead, sealed, []byte(aad))
+	if err != nil {
+		return "", fmt.Errorf("decrypt: %w", err)
+	}
+	return string(plain), nil
+}
+
+// DecodeKey reads a base64 key of KeySize bytes.
+func DecodeKey(s string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("key is not base64: %w", err)
+	}
+	if len(raw) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(raw))
+	}
+	return raw, nil
+}
+
+// LoadKeys reads base64 keys from a file, one per line. Blank lines and
+// lines starting with # are skipped.
+func LoadKeys(path string) ([][]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keys [][]byte
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, err := DecodeKey(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s: no keys", path)
+	}
+	return keys, nil
+}
+
+// keyID names a master key by a hash prefix, so the key needed to unwrap a
+// data key is known without trying them all.
+func keyID(raw []byte) string {
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:8])
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes", KeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal returns nonce || ciphertext.
+func seal(aead cipher.AEAD, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(aead cipher.AEAD, sealed, aad []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, aad)
+}
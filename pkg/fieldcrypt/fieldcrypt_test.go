@@ -0,0 +1,159 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+func TestDataKey_EncryptDecrypt(t *testing.T) {
+	encryptor, err := New(testKey(1), testKey(2))
+	require.NoError(t, err)
+
+	key, err := encryptor.NewDataKey()
+	require.NoError(t, err)
+
+	ciphertext, err := key.Encrypt("11144477735", "123/cpf")
+	require.NoError(t, err)
+	assert.NotContains(t, ciphertext, "11144477735")
+
+	again, err := key.Encrypt("11144477735", "123/cpf")
+	require.NoError(t, err)
+	assert.NotEqual(t, ciphertext, again, "every encryption uses a new nonce")
+
+	value, err := key.Decrypt(ciphertext, "123/cpf")
+	require.NoError(t, err)
+	assert.Equal(t, "11144477735", value)
+
+	t.Run("bound to its aad", func(t *testing.T) {
+		_, err := key.Decrypt(ciphertext, "456/cpf")
+		assert.Error(t, err)
+	})
+
+	t.Run("tampering is detected", func(t *testing.T) {
+		raw, _ := base64.StdEncoding.DecodeString(ciphertext)
+		raw[len(raw)-1] ^= 1
+		_, err := key.Decrypt(base64.StdEncoding.EncodeToString(raw), "123/cpf")
+		assert.Error(t, err)
+	})
+
+	t.Run("garbage is rejected", func(t *testing.T) {
+		_, err := key.Decrypt("not base64!", "123/cpf")
+		assert.Error(t, err)
+		_, err = key.Decrypt("AAAA", "123/cpf")
+		assert.Error(t, err)
+	})
+}
+
+func TestEncryptor_OpenDataKey(t *testing.T) {
+	old, err := New(testKey(1), testKey(2))
+	require.NoError(t, err)
+	key, err := old.NewDataKey()
+	require.NoError(t, err)
+	ciphertext, err := key.Encrypt("john@example.com", "123/email")
+	require.NoError(t, err)
+
+	t.Run("after a master key rotation", func(t *testing.T) {
+		rotated, err := New(testKey(1), testKey(3), testKey(2))
+		require.NoError(t, err)
+
+		opened, err := rotated.OpenDataKey(key.Wrapped())
+		require.NoError(t, err)
+		value, err := opened.Decrypt(ciphertext, "123/email")
+		require.NoError(t, err)
+		assert.Equal(t, "john@example.com", value)
+
+		fresh, err := rotated.NewDataKey()
+		require.NoError(t, err)
+		assert.NotEqual(t, key.Wrapped().KeyID, fresh.Wrapped().KeyID, "new data keys are wrapped by the new master key")
+	})
+
+	t.Run("unknown master key", func(t *testing.T) {
+		other, err := New(testKey(1), testKey(3))
+		require.NoError(t, err)
+
+		_, err = other.OpenDataKey(key.Wrapped())
+		assert.ErrorIs(t, err, ErrUnknownMasterKey)
+	})
+
+	t.Run("tampered data key", func(t *testing.T) {
+		wrapped := key.Wrapped()
+		wrapped.Ciphertext = append([]byte(nil), wrapped.Ciphertext...)
+		wrapped.Ciphertext[0] ^= 1
+
+		_, err := old.OpenDataKey(wrapped)
+		assert.Error(t, err)
+	})
+}
+
+func TestEncryptor_BlindIndex(t *testing.T) {
+	encryptor, err := New(testKey(1), testKey(2))
+	require.NoError(t, err)
+
+	index := encryptor.BlindIndex("cpfIndex", "11144477735")
+	assert.Equal(t, index, encryptor.BlindIndex("cpfIndex", "11144477735"), "deterministic")
+	assert.Len(t, index, 64)
+	assert.NotEqual(t, index, encryptor.BlindIndex("cpfIndex", "52998224725"))
+	assert.NotEqual(t, index, encryptor.BlindIndex("emailIndex", "11144477735"), "separated by field")
+
+	rotated, err := New(testKey(1), testKey(3))
+	require.NoError(t, err)
+	assert.Equal(t, index, rotated.BlindIndex("cpfIndex", "11144477735"), "independent of the master key")
+
+	otherIndexKey, err := New(testKey(4), testKey(2))
+	require.NoError(t, err)
+	assert.NotEqual(t, index, otherIndexKey.BlindIndex("cpfIndex", "11144477735"))
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(testKey(1))
+	assert.Error(t, err, "no master key")
+
+	_, err = New(testKey(1)[:16], testKey(2))
+	assert.Error(t, err, "short index key")
+
+	_, err = New(testKey(1), testKey(2)[:16])
+	assert.Error(t, err, "short master key")
+}
+
+func TestDecodeKey(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString(testKey(7))
+
+	key, err := DecodeKey(" " + encoded + "\n")
+	require.NoError(t, err)
+	assert.Equal(t, testKey(7), key)
+
+	_, err = DecodeKey("not base64!")
+	assert.Error(t, err)
+
+	_, err = DecodeKey(base64.StdEncoding.EncodeToString(testKey(7)[:16]))
+	assert.Error(t, err)
+}
+
+func TestLoadKeys(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "keys")
+	content := "# active\n" + base64.StdEncoding.EncodeToString(testKey(1)) + "\n\n" + base64.StdEncoding.EncodeToString(testKey(2)) + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	keys, err := LoadKeys(path)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{testKey(1), testKey(2)}, keys)
+
+	empty := filepath.Join(dir, "empty")
+	require.NoError(t, os.WriteFile(empty, []byte("# nothing\n"), 0o600))
+	_, err = LoadKeys(empty)
+	assert.Error(t, err)
+
+	_, err = LoadKeys(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,92 @@
+package i18n
+
+// catalog maps each language to the message templates for every stable error
+// code. Placeholders such as {field} are filled from the error parameters.
+var catalog = map[string]map[string]string{
+	English: {
+		"NAME_EMPTY":                      "Name cannot be empty",
+		"INVALID_CPF":                     "Invalid CPF",
+		"INVALID_EMAIL":                   "Invalid Email",
+		"INVALID_REQUEST":                 "Invalid request body",
+		"VALIDATION_FAILED":               "Validation failed",
+		"FIELD_REQUIRED":                  "{field} is required",
+		"INVALID_TYPE":                    "{field} must be a {type}",
+		"INVALID_FIELD":                   "{field} is invalid",
+		"INVALID_FIELDS":                  "Unknown field: {field}",
+		"BATCH_TOO_LARGE":                 "A batch may contain at most {max} keys",
+		"CUSTOMER_NOT_FOUND":              "Customer not found",
+		"CONFLICT":                        "Resource already exists",
+		"CPF_ALREADY_EXISTS":              "CPF is already registered",
+		"EMAIL_ALREADY_EXISTS":            "Email is already registered",
+		"INVALID_DRY_RUN":                 "dryRun must be true or false",
+		"NO_PENDING_EMAIL":                "No email change is pending",
+		"INVALID_EMAIL_TOKEN":             "Invalid email verification token",
+		"EMAIL_CHANGE_DISABLED":           "Email changes are not available",
+		"EMAIL_TOKEN_EXPIRED":             "Email verification token has expired",
+		"CPF_UNCHANGED":                   "CPF is already the current one",
+		"REASON_EMPTY":                    "Reason cannot be empty",
+		"FORBIDDEN":                       "You are not allowed to perform this operation",
+		"CONCURRENT_UPDATE":               "Customer was changed concurrently",
+		"CUSTOMER_ANONYMIZED":             "Customer data was erased",
+		"FIELD_RESTRICTED":                "{field} cannot be changed through self-service",
+		"SCOPES_EMPTY":                    "At least one scope is required",
+		"INVALID_SCOPE":                   "Scope {scope} cannot be granted to an API key",
+		"INVALID_EXPIRY":                  "Expiry must be in the future",
+		"INVALID_API_KEY":                 "Invalid API key",
+		"API_KEY_NOT_FOUND":               "API key not found",
+		"INVALID_ACCESS_LOG_FILTER":       "Filter by either customerId or actor",
+		"INVALID_LIMIT":                   "limit must be between 1 and {max}",
+		"RATE_LIMITED":                    "Too many requests",
+		"CLIENT_BLOCKED":                  "Client temporarily blocked",
+		"INVALID_CODE":                    "Invalid customer code",
+		"UNAUTHENTICATED":                 "Missing access token",
+		"INVALID_TOKEN":                   "Invalid access token",
+		"INVALID_IDEMPOTENCY_KEY":         "Idempotency-Key is too long",
+		"IDEMPOTENCY_REQUEST_IN_PROGRESS": "Request with this Idempotency-Key is still being processed",
+		"IDEMPOTENCY_KEY_MISMATCH":        "Idempotency-Key was already used with a different request",
+		"INTERNAL_ERROR":                  "Internal server error",
+	},
+	Portuguese: {
+		"NAME_EMPTY":                      "O nome não pode estar vazio",
+		"INVALID_CPF":                     "CPF inválido",
+		"INVALID_EMAIL":                   "Email inválido",
+		"INVALID_REQUEST":                 "Corpo da requisição inválido",
+		"VALIDATION_FAILED":               "Falha na validação",
+		"FIELD_REQUIRED":                  "{field} é obrigatório",
+		"INVALID_TYPE":                    "{field} deve ser do tipo {type}",
+		"INVALID_FIELD":                   "{field} é inválido",
+		"INVALID_FIELDS":                  "Campo desconhecido: {field}",
+		"BATCH_TOO_LARGE":                 "Um lote pode conter no máximo {max} chaves",
+		"CUSTOMER_NOT_FOUND":              "Cliente não encontrado",
+		"CONFLICT":                        "O recurso já existe",
+		"CPF_ALREADY_EXISTS":              "CPF já cadastrado",
+		"EMAIL_ALREADY_EXISTS":            "Email já cadastrado",
+		"INVALID_DRY_RUN":                 "dryRun deve ser true ou false",
+		"NO_PENDING_EMAIL":                "Não há troca de email pendente",
+		"INVALID_EMAIL_TOKEN":             "Token de verificação de email inválido",
+		"EMAIL_CHANGE_DISABLED":           "A troca de email não está disponível",
+		"EMAIL_TOKEN_EXPIRED":             "O token de verificação de email expirou",
+		"CPF_UNCHANGED":                   "O CPF informado já é o atual",
+		"REASON_EMPTY":                    "O motivo não pode estar vazio",
+		"FORBIDDEN":                       "Você não tem permissão para esta operação",
+		"CONCURRENT_UPDATE":               "O cliente foi alterado por outra requisição",
+		"CUSTOMER_ANONYMIZED":             "Os dados do cliente foram apagados",
+		"FIELD_RESTRICTED":                "{field} não pode ser alterado pelo próprio cliente",
+		"SCOPES_EMPTY":                    "Informe ao menos um escopo",
+		"INVALID_SCOPE":                   "O escopo {scope} não pode ser concedido a uma chave de API",
+		"INVALID_EXPIRY":                  "A validade deve ser uma data futura",
+		"INVALID_API_KEY":                 "Chave de API inválida",
+		"API_KEY_NOT_FOUND":               "Chave de API não encontrada",
+		"INVALID_ACCESS_LOG_FILTER":       "Filtre por customerId ou por actor, não pelos dois",
+		"INVALID_LIMIT":                   "limit deve estar entre 1 e {max}",
+		"RATE_LIMITED":                    "Muitas requisições; tente novamente mais tarde",
+		"CLIENT_BLOCKED":                  "Cliente bloqueado temporariamente",
+		"INVALID_CODE":                    "Código de cliente inválido",
+		"UNAUTHENTICATED":                 "Token de acesso ausente",
+		"INVALID_TOKEN":                   "Token de acesso inválido",
+		"INVALID_IDEMPOTENCY_KEY":         "Idempotency-Key muito longa",
+		"IDEMPOTENCY_REQUEST_IN_PROGRESS": "A requisição com esta Idempotency-Key ainda está em processamento",
+		"IDEMPOTENCY_KEY_MISMATCH":        "A Idempotency-Key já foi usada com outra requisição",
+		"INTERNAL_ERROR":                  "Erro interno do servidor",
+	},
+}
@@ -0,0 +1,70 @@
+package i18n
+
+import (
+	"customer-service/pkg/errors"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+const (
+	English    = "en"
+	Portuguese = "pt-BR"
+)
+
+var (
+	supported = []string{English, Portuguese}
+	matcher   = language.NewMatcher([]language.Tag{language.English, language.BrazilianPortuguese})
+)
+
+// Negotiate picks the supported language that best matches an
+// Accept-Language header. It returns "" when the header is empty or invalid
+// so callers can keep the original messages.
+func Negotiate(acceptLanguage string) string {
+	if strings.TrimSpace(acceptLanguage) == "" {
+		return ""
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return ""
+	}
+
+	_, index, _ := matcher.Match(tags...)
+	return supported[index]
+}
+
+// Translate returns the message for code in lang with every {name}
+// placeholder replaced by params[name].
+func Translate(lang, code string, params map[string]string) (string, bool) {
+	template, ok := catalog[lang][code]
+	if !ok {
+		return "", false
+	}
+
+	replacements := make([]string, 0, len(params)*2)
+	for name, value := range params {
+		replacements = append(replacements, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(replacements...).Replace(template), true
+}
+
+// Localize returns a copy of err with its messages translated to lang.
+// Codes without a catalog entry keep their original message.
+func Localize(err *errors.AppError, lang string) *errors.AppError {
+	localized := *err
+	if message, ok := Translate(lang, err.Code, err.Params); ok {
+		localized.Message = message
+	}
+
+	if len(err.Fields) > 0 {
+		localized.Fields = make([]errors.FieldError, len(err.Fields))
+		for i, field := range err.Fields {
+			if message, ok := Translate(lang, field.Code, field.Params); ok {
+				field.Message = message
+			}
+			localized.Fields[i] = field
+		}
+	}
+	return &localized
+}
@@ -0,0 +1,79 @@
+package i18n
+
+import (
+	"customer-service/pkg/errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{name: "Empty header", header: "", expected: ""},
+		{name: "Brazilian Portuguese", header: "pt-BR", expected: Portuguese},
+		{name: "Generic Portuguese", header: "pt", expected: Portuguese},
+		{name: "English variant", header: "en-US", expected: English},
+		{name: "Quality values", header: "en;q=0.5, pt-BR;q=0.9", expected: Portuguese},
+		{name: "Unsupported falls back to English", header: "fr-FR", expected: English},
+		{name: "Invalid header", header: "not a language;;", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Negotiate(tt.header))
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	t.Run("Known code", func(t *testing.T) {
+		message, ok := Translate(Portuguese, "INVALID_CPF", nil)
+
+		assert.True(t, ok)
+		assert.Equal(t, "CPF inválido", message)
+	})
+
+	t.Run("Interpolates parameters", func(t *testing.T) {
+		message, ok := Translate(Portuguese, "FIELD_REQUIRED", map[string]string{"field": "cpf"})
+
+		assert.True(t, ok)
+		assert.Equal(t, "cpf é obrigatório", message)
+	})
+
+	t.Run("Unknown code", func(t *testing.T) {
+		_, ok := Translate(English, "UNKNOWN", nil)
+
+		assert.False(t, ok)
+	})
+}
+
+func TestCatalogIsComplete(t *testing.T) {
+	for code := range catalog[English] {
+		_, ok := catalog[Portuguese][code]
+		assert.True(t, ok, "missing pt-BR translation for %s", code)
+	}
+	for code := range catalog[Portuguese] {
+		_, ok := catalog[English][code]
+		assert.True(t, ok, "missing en translation for %s", code)
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	original := errors.NewFieldValidationError(
+		errors.FieldError{Pointer: "/name", Code: "NAME_EMPTY", Message: "Name cannot be empty"},
+		errors.FieldError{Pointer: "/cpf", Code: "CUSTOM_CODE", Message: "Custom message"},
+	)
+
+	localized := Localize(original, Portuguese)
+
+	assert.Equal(t, "NAME_EMPTY", localized.Code)
+	assert.Equal(t, "O nome não pode estar vazio", localized.Message)
+	assert.Equal(t, "O nome não pode estar vazio", localized.Fields[0].Message)
+	assert.Equal(t, "Custom message", localized.Fields[1].Message)
+	assert.Equal(t, "Name cannot be empty", original.Message)
+	assert.Equal(t, "Name cannot be empty", original.Fields[0].Message)
+}
@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Requests per Period on average. A client that stayed idle may
+// spend up to Requests at once.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseLimit reads a limit such as "60/m", "10/s" or "1000/1h".
+func ParseLimit(s string) (Limit, error) {
+	count, period, found := strings.Cut(strings.TrimSpace(s), "/")
+	if !found {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: want <requests>/<period>", s)
+	}
+
+	requests, err := strconv.Atoi(count)
+	if err != nil || requests <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: requests must be a positive integer", s)
+	}
+
+	if period == "s" || period == "m" || period == "h" {
+		period = "1" + period
+	}
+	duration, err := time.ParseDuration(period)
+	if err != nil || duration <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: bad period", s)
+	}
+
+	return Limit{Requests: requests, Period: duration}, nil
+}
+
+// String formats the limit the way ParseLimit reads it.
+func (l Limit) String() string {
+	return strconv.Itoa(l.Requests) + "/" + l.Period.String()
+}
+
+// Result is the state of a client's bucket after a request.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next request is allowed; zero when
+	// this one was.
+	RetryAfter time.Duration
+}
+
+// Store keeps a token bucket per key. MemoryStore serves a single replica;
+// replicas sharing limits need a Store backed by a shared database, such as
+// Redis, that applies the same arithmetic atomically.
+type Store interface {
+	// Take spends n tokens from the bucket of key under limit.
+	Take(ctx context.Context, key string, limit Limit, n int) (Result, error)
+}
+
+// bucket is a token bucket holding up to limit.Requests tokens and refilled
+// at limit.Requests per limit.Period.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// take refills the bucket for the time elapsed since its last update and
+// spends n tokens if there are that many. A request costing more than the
+// bucket holds is let through once the bucket is full, leaving it in debt
+// until the rest has refilled.
+func (b *bucket) take(now time.Time, limit Limit, n int) Result {
+	capacity := float64(limit.Requests)
+	rate := capacity / limit.Period.Seconds()
+
+	b.tokens = min(capacity, b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+
+	result := Result{Limit: limit.Requests}
+	if cost := min(float64(n), capacity); b.tokens >= cost {
+		b.tokens -= float64(n)
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((cost - b.tokens) / rate)
+	}
+	result.Remaining = int(max(b.tokens, 0))
+	result.Reset = seconds((capacity - b.tokens) / rate)
+	return result
+}
+
+// fullAt returns when the bucket will be full again, after which it is the
+// same as a new one.
+func (b *bucket) fullAt(limit Limit) time.Time {
+	capacity := float64(limit.Requests)
+	rate := capacity / limit.Period.Seconds()
+	return b.updated.Add(seconds((capacity - b.tokens) / rate))
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Limit
+		valid    bool
+	}{
+		{input: "60/m", expected: Limit{Requests: 60, Period: time.Minute}, valid: true},
+		{input: "10/s", expected: Limit{Requests: 10, Period: time.Second}, valid: true},
+		{input: " 1000/1h ", expected: Limit{Requests: 1000, Period: time.Hour}, valid: true},
+		{input: "5/30s", expected: Limit{Requests: 5, Period: 30 * time.Second}, valid: true},
+		{input: "60", valid: false},
+		{input: "0/m", valid: false},
+		{input: "-1/m", valid: false},
+		{input: "ten/m", valid: false},
+		{input: "60/fortnight", valid: false},
+		{input: "60/0s", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			limit, err := ParseLimit(tt.input)
+
+			if !tt.valid {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, limit)
+		})
+	}
+}
+
+func newTestStore() (*MemoryStore, *time.Time) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	return store, &now
+}
+
+func TestMemoryStore_Take(t *testing.T) {
+	limit := Limit{Requests: 3, Period: time.Minute}
+	ctx := context.Background()
+
+	t.Run("Burst up to the limit, then wait for a token", func(t *testing.T) {
+		store, _ := newTestStore()
+
+		for remaining := 2; remaining >= 0; remaining-- {
+			result, err := store.Take(ctx, "client", limit, 1)
+			require.NoError(t, err)
+			assert.True(t, result.Allowed)
+			assert.Equal(t, remaining, result.Remaining)
+		}
+
+		result, err := store.Take(ctx, "client", limit, 1)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+		assert.Equal(t, 3, result.Limit)
+		assert.Equal(t, 20*time.Second, result.RetryAfter)
+		assert.Equal(t, time.Minute, result.Reset)
+	})
+
+	t.Run("Tokens refill over time", func(t *testing.T) {
+		store, now := newTestStore()
+		for range 3 {
+			_, _ = store.Take(ctx, "client", limit, 1)
+		}
+
+		*now = now.Add(20 * time.Second)
+		result, _ := store.Take(ctx, "client", limit, 1)
+
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+	})
+
+	t.Run("Requests can cost several tokens", func(t *testing.T) {
+		store, _ := newTestStore()
+
+		result, _ := store.Take(ctx, "client", limit, 2)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 1, result.Remaining)
+
+		result, _ = store.Take(ctx, "client", limit, 2)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, 20*time.Second, result.RetryAfter)
+	})
+
+	t.Run("Costs above the limit wait for a full bucket and leave a debt", func(t *testing.T) {
+		store, now := newTestStore()
+
+		result, _ := store.Take(ctx, "client", limit, 6)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+
+		*now = now.Add(time.Minute)
+		result, _ = store.Take(ctx, "client", limit, 1)
+		assert.False(t, result.Allowed, "the debt of three tokens took a minute to refill")
+		assert.Equal(t, 20*time.Second, result.RetryAfter)
+	})
+
+	t.Run("Keys have their own buckets", func(t *testing.T) {
+		store, _ := newTestStore()
+		for range 3 {
+			_, _ = store.Take(ctx, "client-1", limit, 1)
+		}
+
+		result, _ := store.Take(ctx, "client-2", limit, 1)
+
+		assert.True(t, result.Allowed)
+	})
+
+	t.Run("Refilled buckets are forgotten", func(t *testing.T) {
+		store, now := newTestStore()
+		_, _ = store.Take(ctx, "client", limit, 1)
+		store.lastSweep = *now
+
+		*now = now.Add(2 * time.Minute)
+		_, _ = store.Take(ctx, "other", limit, 1)
+
+		assert.NotContains(t, store.buckets, "client")
+		assert.Contains(t, store.buckets, "other")
+	})
+}
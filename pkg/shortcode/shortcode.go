@@ -0,0 +1,56 @@
+package shortcode
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+// Length is the number of characters in a code. 32^8 codes leave plenty of
+// room before collisions become frequent.
+const Length = 8
+
+// alphabet is Crockford's base32: digits and letters without I, L, O and U,
+// so a code read aloud or copied by hand is hard to get wrong.
+const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// Generate returns a random code of Length characters.
+func Generate() (string, error) {
+	raw := make([]byte, Length)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+
+	code := make([]byte, Length)
+	for i, b := range raw {
+		// 256 is a multiple of 32, so this keeps the distribution uniform
+		code[i] = alphabet[b%32]
+	}
+	return string(code), nil
+}
+
+// Normalize turns a code as typed by a person into its canonical form. It
+// ignores case, hyphens and spaces and reads I and L as 1 and O as 0, as
+// Crockford's base32 prescribes. ok is false when the result is not a valid
+// code.
+func Normalize(code string) (normalized string, ok bool) {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(code) {
+		switch r {
+		case '-', ' ':
+			continue
+		case 'I', 'L':
+			r = '1'
+		case 'O':
+			r = '0'
+		}
+		if !strings.ContainsRune(alphabet, r) {
+			return "", false
+		}
+		b.WriteRune(r)
+	}
+
+	if b.Len() != Length {
+		return "", false
+	}
+	return b.String(), true
+}
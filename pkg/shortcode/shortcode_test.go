@@ -0,0 +1,56 @@
+package shortcode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		code, err := Generate()
+		require.NoError(t, err)
+
+		assert.Len(t, code, Length)
+		normalized, ok := Normalize(code)
+		assert.True(t, ok)
+		assert.Equal(t, code, normalized)
+		assert.NotContains(t, code, "I")
+		assert.NotContains(t, code, "L")
+		assert.NotContains(t, code, "O")
+		assert.NotContains(t, code, "U")
+
+		seen[code] = true
+	}
+	assert.Len(t, seen, 100)
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected string
+		ok       bool
+	}{
+		{name: "Canonical", code: "7K3M9QXD", expected: "7K3M9QXD", ok: true},
+		{name: "Lowercase", code: "7k3m9qxd", expected: "7K3M9QXD", ok: true},
+		{name: "Grouped", code: "7K3M-9QXD", expected: "7K3M9QXD", ok: true},
+		{name: "Spaces", code: " 7K3M 9QXD ", expected: "7K3M9QXD", ok: true},
+		{name: "Ambiguous letters", code: "IL0O1234", expected: "11001234", ok: true},
+		{name: "Too short", code: "7K3M9QX", ok: false},
+		{name: "Too long", code: "7K3M9QXD2", ok: false},
+		{name: "Excluded letter U", code: "7K3M9QXU", ok: false},
+		{name: "Symbols", code: "7K3M9QX!", ok: false},
+		{name: "Empty", code: "", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normalized, ok := Normalize(tt.code)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, normalized)
+		})
+	}
+}
@@ -63,3 +63,12 @@ func IsValidCPF(cpf string) bool {
 
 	return true
 }
+
+// MaskCPF hides the first three and the check digits, e.g. ***.444.777-**
+func MaskCPF(cpf string) string {
+	cpf = CleanCPF(cpf)
+	if len(cpf) != 11 {
+		return "***.***.***-**"
+	}
+	return "***." + cpf[3:6] + "." + cpf[6:9] + "-**"
+}
@@ -50,3 +50,24 @@ func TestIsValidCPF(t *testing.T) {
 		})
 	}
 }
+
+func TestMaskCPF(t *testing.T) {
+	tests := []struct {
+		name     string
+		cpf      string
+		expected string
+	}{
+		{"Clean CPF", "11144477735", "***.444.777-**"},
+		{"Formatted CPF", "111.444.777-35", "***.444.777-**"},
+		{"Wrong length", "123", "***.***.***-**"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := MaskCPF(tt.cpf)
+			if result != tt.expected {
+				t.Errorf("MaskCPF(%s) = %s; want %s", tt.cpf, result, tt.expected)
+			}
+		})
+	}
+}
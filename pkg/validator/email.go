@@ -1,9 +1,22 @@
 package validator
 
-import "regexp"
+import (
+	"regexp"
+	"strings"
+)
 
 // IsValidEmail validates an email address
 func IsValidEmail(email string) bool {
 	re := regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
 	return re.MatchString(email)
 }
+
+// MaskEmail keeps the first character of the local part and the domain,
+// e.g. j***@example.com
+func MaskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return "***"
+	}
+	return email[:1] + "***" + email[at:]
+}
@@ -28,3 +28,25 @@ func TestIsValidEmail(t *testing.T) {
 		})
 	}
 }
+
+func TestMaskEmail(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		expected string
+	}{
+		{"Regular email", "john@example.com", "j***@example.com"},
+		{"Single character local part", "j@example.com", "j***@example.com"},
+		{"No @", "johnexample.com", "***"},
+		{"Empty local part", "@example.com", "***"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := MaskEmail(tt.email)
+			if result != tt.expected {
+				t.Errorf("MaskEmail(%s) = %s; want %s", tt.email, result, tt.expected)
+			}
+		})
+	}
+}
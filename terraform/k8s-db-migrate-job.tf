@@ -1,6 +1,56 @@
+resource "kubectl_manifest" "db_migrate_job" {
+  depends_on = [
+    kubectl_manifest.mongodb_deployment,
+    kubectl_manifest.db_roles_job,
+    kubectl_manifest.secrets,
+    kubectl_manifest.configmap
+  ]
+  wait_for {
+    field {
+      key   = "status.succeeded"
+      value = "1"
+    }
+  }
+  yaml_body = <<YAML
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: db-migrate-job
+  namespace: tc4-customer
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: wait-for-mongodb
+        image: busybox:1.36
+        command: ['sh', '-c', 'until nc -z mongodb-service.tc4-customer.svc.cluster.local 27017; do echo waiting for mongodb; sleep 2; done;']
+      containers:
+      - name: tc4-customer-migrate-db
+        image: tlnob/tc4-customer:latest
+        imagePullPolicy: IfNotPresent
+        command: ["./customer-service", "encrypt-pii"]
+        envFrom:
+        - configMapRef:
+            name: api-configmap
+        - secretRef:
+            name: api-secrets
+        resources:
+          requests:
+            memory: "128Mi"
+            cpu: "100m"
+          limits:
+            memory: "256Mi"
+            cpu: "200m"
+      restartPolicy: Never
+  backoffLimit: 4
+
+YAML
+}
+
 resource "kubectl_manifest" "db_seed_job" {
   depends_on = [
     kubectl_manifest.mongodb_deployment,
+    kubectl_manifest.db_migrate_job,
     kubectl_manifest.secrets,
     kubectl_manifest.configmap
   ]
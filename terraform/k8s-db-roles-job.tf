@@ -0,0 +1,65 @@
+resource "kubectl_manifest" "db_roles_job" {
+  depends_on = [
+    kubectl_manifest.mongodb_deployment,
+    kubectl_manifest.secrets
+  ]
+  wait_for {
+    field {
+      key   = "status.succeeded"
+      value = "1"
+    }
+  }
+  yaml_body = <<YAML
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: db-roles-job
+  namespace: tc4-customer
+spec:
+  template:
+    spec:
+      containers:
+      - name: tc4-customer-db-roles
+        image: mongo:7.0
+        command: ['sh', '-c', 'until mongosh --quiet --host mongodb-service.tc4-customer.svc.cluster.local -u "$MONGO_USER" -p "$MONGO_PASSWORD" --authenticationDatabase admin --eval "$ROLES_SCRIPT"; do echo waiting for mongodb; sleep 2; done;']
+        envFrom:
+        - secretRef:
+            name: api-secrets
+        env:
+        - name: ROLES_SCRIPT
+          value: |
+            // The service may only insert and read PII access log entries
+            const appDb = db.getSiblingDB(process.env.MONGO_DB_NAME);
+            const actions = ["find", "insert", "update", "remove", "createCollection", "createIndex", "dropIndex", "listIndexes"];
+            const privileges = ["customers", "customer_keys", "api_keys", "idempotency_keys"].map((collection) => ({
+              resource: { db: appDb.getName(), collection: collection },
+              actions: actions,
+            }));
+            privileges.push({
+              resource: { db: appDb.getName(), collection: "pii_access_log" },
+              actions: ["find", "insert", "createCollection", "createIndex", "listIndexes"],
+            });
+            if (appDb.getRole("customerService")) {
+              appDb.updateRole("customerService", { privileges: privileges, roles: [] });
+            } else {
+              appDb.createRole({ role: "customerService", privileges: privileges, roles: [] });
+            }
+            const user = process.env.MONGO_APP_USER;
+            const pwd = process.env.MONGO_APP_PASSWORD;
+            if (appDb.getUser(user)) {
+              appDb.updateUser(user, { pwd: pwd, roles: ["customerService"] });
+            } else {
+              appDb.createUser({ user: user, pwd: pwd, roles: ["customerService"] });
+            }
+        resources:
+          requests:
+            memory: "128Mi"
+            cpu: "100m"
+          limits:
+            memory: "256Mi"
+            cpu: "200m"
+      restartPolicy: Never
+  backoffLimit: 4
+
+YAML
+}
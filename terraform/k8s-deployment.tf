@@ -2,6 +2,8 @@ resource "kubectl_manifest" "deployment" {
   depends_on = [
     kubernetes_namespace.lanchonete_ns,
     kubectl_manifest.mongodb_deployment,
+    kubectl_manifest.db_roles_job,
+    kubectl_manifest.db_migrate_job,
     kubectl_manifest.db_seed_job,
     kubectl_manifest.secrets,
     kubectl_manifest.configmap
@@ -37,6 +39,8 @@ spec:
         imagePullPolicy: Always
         ports:
         - containerPort: 8080
+        - containerPort: 9090
+          name: grpc
         readinessProbe:
           httpGet:
             path: /health
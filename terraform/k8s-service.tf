@@ -30,10 +30,17 @@ resource "kubernetes_service" "api_service_internal" {
       app = "tc4-customer-api"
     }
     port {
+      name        = "http"
       protocol    = "TCP"
       port        = 80
       target_port = 8080
     }
+    port {
+      name        = "grpc"
+      protocol    = "TCP"
+      port        = 9090
+      target_port = 9090
+    }
     type = "ClusterIP"
   }
   depends_on = [kubernetes_namespace.lanchonete_ns]
@@ -20,8 +20,57 @@ variable "mongo_password" {
   sensitive   = true
 }
 
+variable "mongo_app_user" {
+  description = "MongoDB user of the service, which may only insert and read the PII access log"
+  type        = string
+  default     = "customer-service"
+}
+
+variable "mongo_app_password" {
+  description = "MongoDB password of the service user"
+  type        = string
+  sensitive   = true
+}
+
 variable "mongo_db_name" {
   description = "MongoDB database name"
   type        = string
   default     = "tc4customer"
 }
+
+variable "pii_master_keys" {
+  description = "Base64 AES-256 keys wrapping the customer data keys, comma-separated, active first"
+  type        = string
+  sensitive   = true
+}
+
+variable "pii_index_key" {
+  description = "Base64 HMAC key of the CPF and email blind indexes; must never change"
+  type        = string
+  sensitive   = true
+}
+
+variable "mail_smtp_addr" {
+  description = "SMTP relay (host:port) sending the email verification messages; empty disables email changes"
+  type        = string
+  default     = ""
+}
+
+variable "mail_from" {
+  description = "Sender address of the email verification messages"
+  type        = string
+  default     = ""
+}
+
+variable "mail_smtp_username" {
+  description = "SMTP relay user"
+  type        = string
+  default     = ""
+}
+
+variable "mail_smtp_password" {
+  description = "SMTP relay password"
+  type        = string
+  default     = ""
+  sensitive   = true
+}